@@ -16,18 +16,18 @@
 }
 
 @test "[LIST] Source matches example source list" {
-  run ./sinker list source --manifest example --output example/source.txt
+  run ./sinker list source --manifest example --output-file example/source.txt
   git diff --quiet -- example/source.txt
 }
 
 @test "[LIST] Target matches example target list" {
-  run ./sinker list target --manifest example --output example/target.txt
+  run ./sinker list target --manifest example --output-file example/target.txt
   git diff --quiet -- example/target.txt
 }
 
-@test "[PUSH] --dryrun flag lists missing images" {
-  run ./sinker push --dryrun --manifest test/manifests/dryrun-images.yaml
-  [[ "$output" =~ "Image busybox:1.32.0 would be pushed as plexsystems/busybox:1.32.0" ]]
+@test "[PUSH] --dry-run flag prints the planned operations" {
+  run ./sinker push --dry-run --manifest test/manifests/dryrun-images.yaml
+  [[ "$output" =~ "Would tag busybox:1.32.0 as plexsystems/busybox:1.32.0" ]]
 }
 
 @test "[PUSH] All images are pushed" {
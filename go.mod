@@ -4,6 +4,7 @@ go 1.14
 
 require (
 	github.com/avast/retry-go v2.6.0+incompatible
+	github.com/aws/aws-sdk-go v1.34.34
 	github.com/containerd/containerd v1.3.6 // indirect
 	github.com/coreos/prometheus-operator v0.40.0
 	github.com/docker/cli v0.0.0-20191017083524-a8ff7f821017
@@ -15,8 +16,11 @@ require (
 	github.com/sirupsen/logrus v1.6.0
 	github.com/spf13/cobra v1.0.0
 	github.com/spf13/viper v1.7.0
+	golang.org/x/net v0.0.0-20200602114024-627f9648deb9
+	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
 	google.golang.org/grpc v1.30.0 // indirect
 	gopkg.in/yaml.v2 v2.3.0
+	gopkg.in/yaml.v3 v3.0.0-20200603094226-e3079894b1e8
 	k8s.io/api v0.18.5
 	k8s.io/apimachinery v0.18.5
 )
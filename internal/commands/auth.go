@@ -1,47 +1,66 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/plexsystems/sinker/internal/docker"
 )
 
-func getEncodedSourceAuth(source SourceImage) (string, error) {
-	if source.Auth.Password != "" {
-		auth, err := docker.GetEncodedBasicAuth(source.Auth.Username, source.Auth.Password)
-		if err != nil {
-			return "", fmt.Errorf("get encoded auth: %w", err)
-		}
-
-		return auth, nil
+func getEncodedSourceAuth(ctx context.Context, source SourceImage) (string, error) {
+	auth, err := getEncodedAuth(ctx, source.Auth, source.Host)
+	if err != nil {
+		return "", fmt.Errorf("get source auth: %w", err)
 	}
 
-	authHost := getAuthHostFromRegistryHost(source.Host)
-	auth, err := docker.GetEncodedAuthForHost(authHost)
+	return auth, nil
+}
+
+func getEncodedTargetAuth(ctx context.Context, target Target) (string, error) {
+	auth, err := getEncodedAuth(ctx, target.Auth, target.Host)
 	if err != nil {
-		return "", fmt.Errorf("get encoded auth for host: %w", err)
+		return "", fmt.Errorf("get target auth: %w", err)
 	}
 
 	return auth, nil
 }
 
-func getEncodedTargetAuth(target Target) (string, error) {
-	if target.Auth.Password != "" {
-		auth, err := docker.GetEncodedBasicAuth(target.Auth.Username, target.Auth.Password)
+// getEncodedAuth selects the auth to use for a registry host.
+// A token configured on the image takes precedence, followed by the username and password
+// configured on the image, and lastly the credentials found for the host in the environment,
+// the sinker credentials, or the Docker configuration.
+func getEncodedAuth(ctx context.Context, auth Auth, host string) (string, error) {
+	if auth.Token != "" {
+		token := os.Getenv(auth.Token)
+		if token == "" {
+			return "", fmt.Errorf("token environment variable %s is not set", auth.Token)
+		}
+
+		encodedAuth, err := docker.GetEncodedTokenAuth(token)
+		if err != nil {
+			return "", fmt.Errorf("get encoded token auth: %w", err)
+		}
+
+		return encodedAuth, nil
+	}
+
+	if auth.Password != "" {
+		encodedAuth, err := docker.GetEncodedBasicAuth(auth.Username, auth.Password)
 		if err != nil {
 			return "", fmt.Errorf("get encoded auth: %w", err)
 		}
 
-		return auth, nil
+		return encodedAuth, nil
 	}
 
-	authHost := getAuthHostFromRegistryHost(target.Host)
-	auth, err := docker.GetEncodedAuthForHost(authHost)
+	authHost := getAuthHostFromRegistryHost(host)
+	encodedAuth, err := docker.GetEncodedAuthForHost(ctx, authHost)
 	if err != nil {
 		return "", fmt.Errorf("get encoded auth for host: %w", err)
 	}
 
-	return auth, nil
+	return encodedAuth, nil
 }
 
 func getAuthHostFromRegistryHost(host string) string {
@@ -51,3 +70,36 @@ func getAuthHostFromRegistryHost(host string) string {
 
 	return host
 }
+
+// sourceAuths are the encoded auths of the source images of the manifest, by source image
+type sourceAuths map[string]string
+
+// getSourceAuths returns the encoded auth of each of the source images
+func getSourceAuths(ctx context.Context, images []SourceImage) (sourceAuths, error) {
+	auths := make(sourceAuths)
+	for _, image := range images {
+		auth, err := getEncodedSourceAuth(ctx, image)
+		if err != nil {
+			return nil, fmt.Errorf("get %s auth: %w", image, err)
+		}
+
+		auths[image.String()] = auth
+	}
+
+	return auths, nil
+}
+
+// get returns the encoded auth of the source image, or the auth found for the host of the
+// image when it is not a source image of the manifest, such as images given as flags
+func (a sourceAuths) get(ctx context.Context, image string) (string, error) {
+	if auth, exists := a[image]; exists {
+		return auth, nil
+	}
+
+	auth, err := getEncodedAuth(ctx, Auth{}, docker.RegistryPath(image).Host())
+	if err != nil {
+		return "", fmt.Errorf("get %s auth: %w", image, err)
+	}
+
+	return auth, nil
+}
@@ -1,7 +1,12 @@
 package commands
 
 import (
+	"context"
+	"encoding/base64"
+	"os"
 	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
 )
 
 func TestGetAuthHostFromRegistryHost(t *testing.T) {
@@ -31,3 +36,53 @@ func TestGetAuthHostFromRegistryHost(t *testing.T) {
 		}
 	}
 }
+
+func TestGetEncodedAuth(t *testing.T) {
+	os.Setenv("SINKER_TEST_TOKEN", base64.StdEncoding.EncodeToString([]byte("tokenuser:tokenpass")))
+	defer os.Unsetenv("SINKER_TEST_TOKEN")
+
+	tokenAuth, err := docker.GetEncodedBasicAuth("tokenuser", "tokenpass")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	basicAuth, err := docker.GetEncodedBasicAuth("user", "pass")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	testCases := []struct {
+		input        Auth
+		expectedAuth string
+	}{
+		{
+			input:        Auth{Username: "user", Password: "pass"},
+			expectedAuth: basicAuth,
+		},
+		{
+			input:        Auth{Token: "SINKER_TEST_TOKEN"},
+			expectedAuth: tokenAuth,
+		},
+		{
+			input:        Auth{Username: "user", Password: "pass", Token: "SINKER_TEST_TOKEN"},
+			expectedAuth: tokenAuth,
+		},
+	}
+
+	for _, testCase := range testCases {
+		auth, err := getEncodedAuth(context.Background(), testCase.input, "host.com")
+		if err != nil {
+			t.Fatal("get encoded auth:", err)
+		}
+
+		if auth != testCase.expectedAuth {
+			t.Errorf("expected auth to be %s, actual %s", testCase.expectedAuth, auth)
+		}
+	}
+}
+
+func TestGetEncodedAuth_TokenNotSet(t *testing.T) {
+	if _, err := getEncodedAuth(context.Background(), Auth{Token: "SINKER_TEST_UNSET_TOKEN"}, "host.com"); err == nil {
+		t.Errorf("expected error when token environment variable is not set")
+	}
+}
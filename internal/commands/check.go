@@ -2,8 +2,13 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/plexsystems/sinker/internal/docker"
 
@@ -23,6 +28,30 @@ func newCheckCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 				return fmt.Errorf("bind images flag: %w", err)
 			}
 
+			if err := viper.BindPFlag("source-only", cmd.Flags().Lookup("source-only")); err != nil {
+				return fmt.Errorf("bind source-only flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("compare-target", cmd.Flags().Lookup("compare-target")); err != nil {
+				return fmt.Errorf("bind compare-target flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("fail-fast", cmd.Flags().Lookup("fail-fast")); err != nil {
+				return fmt.Errorf("bind fail-fast flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("since", cmd.Flags().Lookup("since")); err != nil {
+				return fmt.Errorf("bind since flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("no-latest", cmd.Flags().Lookup("no-latest")); err != nil {
+				return fmt.Errorf("bind no-latest flag: %w", err)
+			}
+
 			manifestPath := viper.GetString("manifest")
 			if err := runCheckCommand(ctx, logger, manifestPath); err != nil {
 				return fmt.Errorf("check: %w", err)
@@ -33,35 +62,427 @@ func newCheckCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 	}
 
 	cmd.Flags().StringSliceP("images", "i", []string{}, "The fully qualified images to check if newer versions exist (e.g. myhost.com/myrepo:v1.0.0)")
+	cmd.Flags().Bool("source-only", false, "Only check that every source image exists and can be pulled")
+	cmd.Flags().Bool("compare-target", false, "Check that every target image exists and matches the digest of its source image")
+	cmd.Flags().Duration("since", 0, "Only check the images whose source image was created within the duration (e.g. 24h), using the creation time recorded at the registry. Images without a creation time are always checked")
+	cmd.Flags().Bool("fail-fast", false, "Stop at the first image that fails the --source-only or --compare-target check, instead of reporting every image that fails")
+	addNoLatestFlag(&cmd)
+	addOutputFlag(&cmd)
+	cmd.Flags().Lookup("output").Usage = "Output format (table, json, yaml, github). The github format writes the problems found as GitHub Actions annotations"
 
 	return &cmd
 }
 
+// githubOutput writes the problems found by the check as GitHub Actions workflow commands,
+// so that they are shown as annotations on the lines of the manifest
+const githubOutput = "github"
+
+// checkResult is the result of checking a single image
+type checkResult struct {
+	Image         string   `json:"image"`
+	Source        string   `json:"source,omitempty"`
+	Status        string   `json:"status"`
+	NewerVersions []string `json:"newerVersions,omitempty"`
+
+	// position is where the checked image is in the manifest, when it was checked from the manifest
+	position manifestPosition
+}
+
+// imageProblem is the reason that a single image failed the check
+type imageProblem struct {
+	Image   string
+	Problem string
+
+	// position is where the image is in the manifest, when it was checked from the manifest
+	position manifestPosition
+}
+
+// checkError is returned when one or more images fail the check,
+// and lists each of the images along with its problem.
+type checkError struct {
+	description string
+	problems    []imageProblem
+}
+
+func (e *checkError) Error() string {
+	var problems []string
+	for _, problem := range e.problems {
+		description := fmt.Sprintf("%s (%s)", problem.Image, problem.Problem)
+		if problem.position.String() != "" {
+			description = fmt.Sprintf("%s at %s", description, problem.position)
+		}
+
+		problems = append(problems, description)
+	}
+
+	return fmt.Sprintf("%s: %s", e.description, strings.Join(problems, ", "))
+}
+
+func (e *checkError) add(image string, problem string) {
+	e.problems = append(e.problems, imageProblem{Image: image, Problem: problem})
+}
+
+// setPositions sets the position of each problem to the position of the result of its image
+func (e *checkError) setPositions(results []checkResult) {
+	positions := make(map[string]manifestPosition)
+	for _, result := range results {
+		positions[result.Image] = result.position
+	}
+
+	for i, problem := range e.problems {
+		e.problems[i].position = positions[problem.Image]
+	}
+}
+
 func runCheckCommand(ctx context.Context, logger *log.Logger, manifestPath string) error {
+	format := viper.GetString("output")
+	if format != githubOutput {
+		if err := validateOutputFormat(format); err != nil {
+			return fmt.Errorf("validate output: %w", err)
+		}
+	}
+
 	client, err := docker.NewClient(logger)
 	if err != nil {
 		return fmt.Errorf("new client: %w", err)
 	}
 
-	var imagesToCheck []string
-	if len(viper.GetStringSlice("images")) > 0 {
-		imagesToCheck = viper.GetStringSlice("images")
-	} else {
+	var results []checkResult
+	var manifestImages []SourceImage
+	var checkErr error
+	if viper.GetBool("compare-target") {
 		manifest, err := GetManifest(manifestPath)
 		if err != nil {
-			return fmt.Errorf("get manifest: %w", err)
+			return getCheckManifestError(os.Stdout, manifestPath, format, err)
+		}
+		manifestImages = manifest.Images
+
+		if err := checkLatestTags(os.Stdout, client.Logger, format, manifest.Images); err != nil {
+			return fmt.Errorf("check latest tags: %w", err)
+		}
+
+		imagesToCheck := manifest.Images
+		if viper.GetDuration("since") > 0 {
+			imagesToCheck, err = getChangedSourceImages(ctx, client, client.Logger, manifest.Images, viper.GetDuration("since"), time.Now())
+			if err != nil {
+				return fmt.Errorf("get changed images: %w", err)
+			}
 		}
 
-		for _, image := range manifest.Images {
-			imagesToCheck = append(imagesToCheck, image.String())
+		results, err = checkTargetSync(ctx, client, imagesToCheck, viper.GetBool("fail-fast"))
+		if err != nil {
+			checkErr = fmt.Errorf("check target sync: %w", err)
 		}
+	} else {
+		var imagesToCheck []string
+		var auths sourceAuths
+		if len(viper.GetStringSlice("images")) > 0 {
+			imagesToCheck = viper.GetStringSlice("images")
+		} else {
+			manifest, err := GetManifest(manifestPath)
+			if err != nil {
+				return getCheckManifestError(os.Stdout, manifestPath, format, err)
+			}
+			manifestImages = manifest.Images
+
+			if err := checkLatestTags(os.Stdout, client.Logger, format, manifest.Images); err != nil {
+				return fmt.Errorf("check latest tags: %w", err)
+			}
+
+			for _, image := range manifest.Images {
+				imagesToCheck = append(imagesToCheck, image.String())
+			}
+
+			auths, err = getSourceAuths(ctx, manifest.Images)
+			if err != nil {
+				return fmt.Errorf("get source auths: %w", err)
+			}
+		}
+
+		if viper.GetDuration("since") > 0 {
+			imagesToCheck = getChangedImages(ctx, client, client.Logger, imagesToCheck, auths, viper.GetDuration("since"), time.Now())
+		}
+
+		if viper.GetBool("source-only") {
+			results, err = checkSourceAvailability(ctx, client, imagesToCheck, auths, viper.GetBool("fail-fast"))
+			if err != nil {
+				checkErr = fmt.Errorf("check source availability: %w", err)
+			}
+		} else {
+			results, err = checkNewerVersions(ctx, client, imagesToCheck, auths)
+			if err != nil {
+				return fmt.Errorf("check newer versions: %w", err)
+			}
+		}
+	}
+
+	setResultPositions(results, manifestImages)
+
+	var imagesErr *checkError
+	if errors.As(checkErr, &imagesErr) {
+		imagesErr.setPositions(results)
 	}
 
+	if err := writeCheckResults(os.Stdout, results, format); err != nil {
+		return fmt.Errorf("write results: %w", err)
+	}
+
+	return checkErr
+}
+
+// getCheckManifestError returns the error of getting the manifest. When the results are written as
+// GitHub annotations, the problems found when validating the manifest are also written as annotations.
+func getCheckManifestError(output io.Writer, manifestPath string, format string, err error) error {
+	var validationErr ValidationError
+	if format == githubOutput && errors.As(err, &validationErr) {
+		manifestFile := manifestPath
+		if manifestPath != stdinManifestPath {
+			manifestFile = getManifestLocation(manifestPath)
+		}
+
+		for _, problem := range validationErr.Problems {
+			if err := writeGithubAnnotation(output, "error", manifestPosition{file: manifestFile}, problem); err != nil {
+				return fmt.Errorf("write annotation: %w", err)
+			}
+		}
+	}
+
+	return fmt.Errorf("get manifest: %w", err)
+}
+
+// checkLatestTags warns about each image of the manifest that is only pinned to the latest tag, or
+// returns a ValidationError with the images when the no-latest flag is set. The images are written as
+// annotations when the results are written as GitHub annotations, and are logged otherwise.
+func checkLatestTags(output io.Writer, logger *log.Logger, format string, images []SourceImage) error {
+	level := "warning"
+	if viper.GetBool("no-latest") {
+		level = "error"
+	}
+
+	var problems []string
+	for i, image := range images {
+		if !image.usesLatestTag() {
+			continue
+		}
+
+		problem := getLatestTagProblem(getImageName(i, image))
+		problems = append(problems, problem)
+
+		switch {
+		case format == githubOutput:
+			if err := writeGithubAnnotation(output, level, image.position, getLatestTagProblem(image.String())); err != nil {
+				return fmt.Errorf("write annotation: %w", err)
+			}
+
+		case level == "warning":
+			newImageLogEntry(logger, "check", image.String()).Warnf("[CHECK] %s", problem)
+		}
+	}
+
+	if level == "error" && len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// setResultPositions sets the position of each result to the position of its source image in the manifest
+func setResultPositions(results []checkResult, images []SourceImage) {
+	positions := make(map[string]manifestPosition)
+	for _, image := range images {
+		positions[image.String()] = image.position
+	}
+
+	for i, result := range results {
+		source := result.Source
+		if source == "" {
+			source = result.Image
+		}
+
+		results[i].position = positions[source]
+	}
+}
+
+func writeCheckResults(output io.Writer, results []checkResult, format string) error {
+	if results == nil {
+		results = []checkResult{}
+	}
+
+	if format == githubOutput {
+		return writeGithubAnnotations(output, results)
+	}
+
+	// Values that are not set are written as a dash, so that every row has the same columns
+	orDash := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+
+		return value
+	}
+
+	writeTable := func(output io.Writer) error {
+		writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "IMAGE\tSTATUS\tSOURCE\tNEWER VERSIONS")
+		for _, result := range results {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", result.Image, result.Status, orDash(result.Source), orDash(strings.Join(result.NewerVersions, ", ")))
+		}
+
+		if err := writer.Flush(); err != nil {
+			return fmt.Errorf("flush: %w", err)
+		}
+
+		return nil
+	}
+
+	return writeOutput(output, format, results, writeTable)
+}
+
+// writeGithubAnnotations writes an annotation for each image that failed the check, and a
+// warning for each image that has newer versions. Images without problems are not written.
+func writeGithubAnnotations(output io.Writer, results []checkResult) error {
+	for _, result := range results {
+		var level, message string
+		switch result.Status {
+		case "up to date", string(docker.ImageAvailable), string(imageInSync):
+			continue
+
+		case "outdated":
+			level = "warning"
+			message = fmt.Sprintf("New versions for %s found: %s", result.Image, strings.Join(result.NewerVersions, ", "))
+
+		case "invalid version":
+			level = "warning"
+			message = fmt.Sprintf("Image %s has an invalid version", result.Image)
+
+		default:
+			level = "error"
+			message = fmt.Sprintf("Image %s is %s", result.Image, result.Status)
+			if result.Source != "" {
+				message = fmt.Sprintf("Image %s is %s (%s)", result.Image, result.Status, result.Source)
+			}
+		}
+
+		if err := writeGithubAnnotation(output, level, result.position, message); err != nil {
+			return fmt.Errorf("write annotation: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeGithubAnnotation writes a workflow command (e.g. ::error file=.images.yaml,line=3::message)
+// with the message escaped, so that it is shown as an annotation of the file and line when known
+func writeGithubAnnotation(output io.Writer, level string, position manifestPosition, message string) error {
+	var properties []string
+	if position.file != "" {
+		properties = append(properties, "file="+escapeAnnotationProperty(position.file))
+	}
+
+	if position.line > 0 {
+		properties = append(properties, fmt.Sprintf("line=%v", position.line))
+	}
+
+	command := "::" + level
+	if len(properties) > 0 {
+		command = command + " " + strings.Join(properties, ",")
+	}
+
+	if _, err := fmt.Fprintf(output, "%s::%s\n", command, escapeAnnotationMessage(message)); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+type createdTimeGetter interface {
+	GetCreatedAtRemote(ctx context.Context, image string, encodedAuth string) (time.Time, error)
+}
+
+// getChangedImages returns the images that were created at their registry within the duration before now.
+// Images whose creation time can not be found, such as when the registry does not return the config of
+// the image or the image has no creation time, are kept so that they are still checked.
+func getChangedImages(ctx context.Context, getter createdTimeGetter, logger *log.Logger, images []string, auths sourceAuths, since time.Duration, now time.Time) []string {
+	changedSince := now.Add(-since)
+
+	var changedImages []string
+	for _, image := range images {
+		created, err := getCreatedAtRemote(ctx, getter, image, auths)
+		if err != nil {
+			newImageLogEntry(logger, "check", image).Debugf("[CHECK] Unable to find when %s was created, checking: %s", image, err)
+			changedImages = append(changedImages, image)
+			continue
+		}
+
+		if created.IsZero() {
+			newImageLogEntry(logger, "check", image).Debugf("[CHECK] Image %s has no creation time, checking", image)
+			changedImages = append(changedImages, image)
+			continue
+		}
+
+		if created.Before(changedSince) {
+			newImageLogEntry(logger, "check", image).Printf("[CHECK] Image %s has not changed since %s, skipping", image, changedSince.Format(time.RFC3339))
+			continue
+		}
+
+		changedImages = append(changedImages, image)
+	}
+
+	return changedImages
+}
+
+// getCreatedAtRemote returns the time the image was created at its registry, using the auth of the image
+func getCreatedAtRemote(ctx context.Context, getter createdTimeGetter, image string, auths sourceAuths) (time.Time, error) {
+	auth, err := auths.get(ctx, image)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get auth: %w", err)
+	}
+
+	return getter.GetCreatedAtRemote(ctx, image, auth)
+}
+
+// getChangedSourceImages returns the images whose source image was created within the duration before now
+func getChangedSourceImages(ctx context.Context, getter createdTimeGetter, logger *log.Logger, images []SourceImage, since time.Duration, now time.Time) ([]SourceImage, error) {
+	auths, err := getSourceAuths(ctx, images)
+	if err != nil {
+		return nil, fmt.Errorf("get source auths: %w", err)
+	}
+
+	var sources []string
+	for _, image := range images {
+		sources = append(sources, image.String())
+	}
+
+	changedSources := make(map[string]bool)
+	for _, source := range getChangedImages(ctx, getter, logger, sources, auths, since, now) {
+		changedSources[source] = true
+	}
+
+	var changedImages []SourceImage
+	for _, image := range images {
+		if changedSources[image.String()] {
+			changedImages = append(changedImages, image)
+		}
+	}
+
+	return changedImages, nil
+}
+
+func escapeAnnotationMessage(message string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(message)
+}
+
+func escapeAnnotationProperty(property string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(property)
+}
+
+func checkNewerVersions(ctx context.Context, client docker.Client, imagesToCheck []string, auths sourceAuths) ([]checkResult, error) {
 	var images []docker.RegistryPath
 	for _, image := range imagesToCheck {
 		images = append(images, docker.RegistryPath(image))
 	}
 
+	var results []checkResult
 	for _, image := range images {
 		if image.Tag() == "" {
 			continue
@@ -69,31 +490,160 @@ func runCheckCommand(ctx context.Context, logger *log.Logger, manifestPath strin
 
 		imageVersion, err := version.NewVersion(image.Tag())
 		if err != nil {
-			client.Logger.Printf("[CHECK] Image %s has an invalid version. Skipping ...", image)
+			newImageLogEntry(client.Logger, "check", string(image)).Printf("[CHECK] Image %s has an invalid version. Skipping ...", image)
+			results = append(results, checkResult{Image: string(image), Status: "invalid version"})
 			continue
 		}
 
-		tags, err := client.GetTagsForRepo(ctx, image.Host(), image.Repository())
+		auth, err := auths.get(ctx, string(image))
 		if err != nil {
-			return fmt.Errorf("get tags: %w", err)
+			return nil, fmt.Errorf("get auth: %w", err)
+		}
+
+		tags, err := client.GetTagsForRepo(ctx, image.Host(), image.Repository(), auth)
+		if err != nil {
+			return nil, fmt.Errorf("get tags: %w", err)
 		}
 
 		tags = filterTags(tags)
 
 		newerVersions, err := getNewerVersions(imageVersion, tags)
 		if err != nil {
-			return fmt.Errorf("getting newer version: %w", err)
+			return nil, fmt.Errorf("getting newer version: %w", err)
 		}
 
 		if len(newerVersions) == 0 {
-			client.Logger.Printf("[CHECK] Image %s is up to date!", image)
+			newImageLogEntry(client.Logger, "check", string(image)).Printf("[CHECK] Image %s is up to date!", image)
+			results = append(results, checkResult{Image: string(image), Status: "up to date"})
 			continue
 		}
 
-		client.Logger.Printf("[CHECK] New versions for %v found: %v", image, newerVersions)
+		newImageLogEntry(client.Logger, "check", string(image)).Printf("[CHECK] New versions for %v found: %v", image, newerVersions)
+		results = append(results, checkResult{Image: string(image), Status: "outdated", NewerVersions: newerVersions})
 	}
 
-	return nil
+	return results, nil
+}
+
+// checkSourceAvailability checks that every source image can be pulled. When failFast is true,
+// the check stops at the first image that can not be pulled.
+func checkSourceAvailability(ctx context.Context, client docker.Client, images []string, auths sourceAuths, failFast bool) ([]checkResult, error) {
+	var results []checkResult
+	unreachableImages := checkError{description: "unreachable source images"}
+	for _, image := range images {
+		auth, err := auths.get(ctx, image)
+		if err != nil {
+			return nil, fmt.Errorf("get auth: %w", err)
+		}
+
+		availability, err := client.GetImageAvailabilityAtRemote(ctx, image, auth)
+		if err != nil {
+			return nil, fmt.Errorf("get image availability: %w", err)
+		}
+
+		results = append(results, checkResult{Image: image, Status: string(availability)})
+
+		if availability != docker.ImageAvailable {
+			newImageLogEntry(client.Logger, "check", image).Printf("[CHECK] Image %s is %s", image, availability)
+			unreachableImages.add(image, string(availability))
+			if failFast {
+				break
+			}
+
+			continue
+		}
+
+		newImageLogEntry(client.Logger, "check", image).Printf("[CHECK] Image %s is available", image)
+	}
+
+	if len(unreachableImages.problems) > 0 {
+		return results, &unreachableImages
+	}
+
+	return results, nil
+}
+
+type imageSyncStatus string
+
+const (
+	imageInSync           imageSyncStatus = "in sync"
+	imageMissingAtSource  imageSyncStatus = "missing at source"
+	imageMissingAtTarget  imageSyncStatus = "missing at target"
+	imageDigestMismatched imageSyncStatus = "digest mismatched"
+)
+
+// getImageSyncStatus compares the digest of the target image with the digest of its source image
+func getImageSyncStatus(ctx context.Context, client docker.Client, image SourceImage) (imageSyncStatus, error) {
+	sourceAuth, err := getEncodedSourceAuth(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("get source auth: %w", err)
+	}
+
+	targetAuth, err := getEncodedTargetAuth(ctx, image.Target)
+	if err != nil {
+		return "", fmt.Errorf("get target auth: %w", err)
+	}
+
+	targetDigests, err := client.GetDigestsAtRemote(ctx, image.TargetImage(), targetAuth)
+	if err != nil {
+		return "", fmt.Errorf("get target digest: %w", err)
+	}
+
+	if len(targetDigests) == 0 {
+		return imageMissingAtTarget, nil
+	}
+
+	sourceDigests, err := client.GetDigestsAtRemote(ctx, image.String(), sourceAuth)
+	if err != nil {
+		return "", fmt.Errorf("get source digest: %w", err)
+	}
+
+	if len(sourceDigests) == 0 {
+		return imageMissingAtSource, nil
+	}
+
+	// Images pushed through Docker only include the platform specific image of a multi-arch
+	// source image, so the target digest can match either the source manifest list or one of its images.
+	if contains(sourceDigests, targetDigests[0]) {
+		return imageInSync, nil
+	}
+
+	// Multi-arch images pushed for only some platforms have a different manifest list
+	// at the target, which is in sync when each of its images is in the source manifest list.
+	if len(targetDigests) > 1 && containsAll(sourceDigests, targetDigests[1:]) {
+		return imageInSync, nil
+	}
+
+	return imageDigestMismatched, nil
+}
+
+// checkTargetSync checks that every target image matches its source image. When failFast is true,
+// the check stops at the first target image that is out of sync.
+func checkTargetSync(ctx context.Context, client docker.Client, images []SourceImage, failFast bool) ([]checkResult, error) {
+	var results []checkResult
+	outOfSyncImages := checkError{description: "target images out of sync"}
+	for _, image := range images {
+		syncStatus, err := getImageSyncStatus(ctx, client, image)
+		if err != nil {
+			return nil, fmt.Errorf("get sync status: %w", err)
+		}
+
+		newImageLogEntry(client.Logger, "check", image.TargetImage()).Printf("[CHECK] Image %s is %s (%s)", image.TargetImage(), syncStatus, image.String())
+		results = append(results, checkResult{Image: image.TargetImage(), Source: image.String(), Status: string(syncStatus)})
+
+		if syncStatus != imageInSync {
+			outOfSyncImages.add(image.TargetImage(), string(syncStatus))
+			if failFast {
+				break
+			}
+		}
+	}
+
+	if len(outOfSyncImages.problems) > 0 {
+		return results, &outOfSyncImages
+	}
+
+	return results, nil
 }
 
 func getNewerVersions(currentVersion *version.Version, foundTags []string) ([]string, error) {
@@ -144,6 +694,16 @@ func filterTags(tags []string) []string {
 	return filteredTags
 }
 
+func containsAll(items []string, subset []string) bool {
+	for _, item := range subset {
+		if !contains(items, item) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func containsSubstring(items []string, item string) bool {
 	for _, currentItem := range items {
 		if strings.Contains(item, currentItem) {
@@ -1,10 +1,32 @@
 package commands
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/hashicorp/go-version"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 )
 
 func TestFilterTags(t *testing.T) {
@@ -42,3 +64,494 @@ func TestNewerVersions(t *testing.T) {
 		t.Errorf("unexpected filtering of tags. expected %v actual %v", expected, actual)
 	}
 }
+
+func TestCheckSourceAvailability(t *testing.T) {
+	registryServer := newTestRegistry()
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	imageReference, err := name.ParseReference(registryHost + "/app:v1.0.0")
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(imageReference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	unauthorizedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorizedServer.Close()
+
+	unauthorizedHost := strings.TrimPrefix(unauthorizedServer.URL, "http://")
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := docker.Client{Logger: logger}
+
+	if _, err := checkSourceAvailability(context.Background(), client, []string{registryHost + "/app:v1.0.0"}, nil, false); err != nil {
+		t.Errorf("expected available image to pass check, actual %v", err)
+	}
+
+	images := []string{
+		registryHost + "/app:v1.0.0",
+		registryHost + "/app:v2.0.0",
+		unauthorizedHost + "/private:v1.0.0",
+	}
+
+	_, err = checkSourceAvailability(context.Background(), client, images, nil, false)
+	if err == nil {
+		t.Fatal("expected error for unreachable images")
+	}
+
+	expectedImages := []string{
+		registryHost + "/app:v2.0.0 (missing)",
+		unauthorizedHost + "/private:v1.0.0 (unauthorized)",
+	}
+
+	for _, expectedImage := range expectedImages {
+		if !strings.Contains(err.Error(), expectedImage) {
+			t.Errorf("expected error to contain %s, actual %v", expectedImage, err)
+		}
+	}
+
+	testCases := []struct {
+		failFast         bool
+		expectedResults  int
+		expectedProblems []imageProblem
+	}{
+		{
+			failFast:        false,
+			expectedResults: 3,
+			expectedProblems: []imageProblem{
+				{Image: registryHost + "/app:v2.0.0", Problem: "missing"},
+				{Image: unauthorizedHost + "/private:v1.0.0", Problem: "unauthorized"},
+			},
+		},
+		{
+			failFast:        true,
+			expectedResults: 2,
+			expectedProblems: []imageProblem{
+				{Image: registryHost + "/app:v2.0.0", Problem: "missing"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		results, err := checkSourceAvailability(context.Background(), client, images, nil, testCase.failFast)
+
+		var actual *checkError
+		if !errors.As(err, &actual) {
+			t.Fatalf("expected a check error when fail fast is %v, actual %v", testCase.failFast, err)
+		}
+
+		if !reflect.DeepEqual(actual.problems, testCase.expectedProblems) {
+			t.Errorf("expected problems %v when fail fast is %v, actual %v", testCase.expectedProblems, testCase.failFast, actual.problems)
+		}
+
+		if len(results) != testCase.expectedResults {
+			t.Errorf("expected %v results when fail fast is %v, actual %v", testCase.expectedResults, testCase.failFast, len(results))
+		}
+	}
+}
+
+func TestCheckTargetSync_FailFast(t *testing.T) {
+	registryServer := newTestRegistry()
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	for _, path := range []string{"/source/app:v1.0.0", "/source/missing:v1.0.0", "/source/other:v1.0.0", "/target/app:v1.0.0"} {
+		imageReference, err := name.ParseReference(registryHost + path)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		if err := remote.Write(imageReference, image); err != nil {
+			t.Fatal("write image:", err)
+		}
+	}
+
+	var images []SourceImage
+	for _, repository := range []string{"app", "missing", "other"} {
+		images = append(images, SourceImage{
+			Host:       registryHost + "/source",
+			Repository: repository,
+			Tag:        "v1.0.0",
+			Target:     Target{Host: registryHost, Repository: "target"},
+		})
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := docker.Client{Logger: logger}
+
+	testCases := []struct {
+		failFast         bool
+		expectedProblems []imageProblem
+	}{
+		{
+			failFast: false,
+			expectedProblems: []imageProblem{
+				{Image: registryHost + "/target/missing:v1.0.0", Problem: string(imageMissingAtTarget)},
+				{Image: registryHost + "/target/other:v1.0.0", Problem: string(imageMissingAtTarget)},
+			},
+		},
+		{
+			failFast: true,
+			expectedProblems: []imageProblem{
+				{Image: registryHost + "/target/missing:v1.0.0", Problem: string(imageMissingAtTarget)},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := checkTargetSync(context.Background(), client, images, testCase.failFast)
+
+		var actual *checkError
+		if !errors.As(err, &actual) {
+			t.Fatalf("expected a check error when fail fast is %v, actual %v", testCase.failFast, err)
+		}
+
+		if !reflect.DeepEqual(actual.problems, testCase.expectedProblems) {
+			t.Errorf("expected problems %v when fail fast is %v, actual %v", testCase.expectedProblems, testCase.failFast, actual.problems)
+		}
+	}
+}
+
+func TestGetImageSyncStatus(t *testing.T) {
+	registryServer := newTestRegistry()
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+
+	sourceImage, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	overwrittenImage, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	writeImages := map[string]v1.Image{
+		"/source/app:v1.0.0":         sourceImage,
+		"/source/overwritten:v1.0.0": sourceImage,
+		"/source/missing:v1.0.0":     sourceImage,
+		"/target/app:v1.0.0":         sourceImage,
+		"/target/overwritten:v1.0.0": overwrittenImage,
+		"/target/removed:v1.0.0":     sourceImage,
+	}
+
+	for path, image := range writeImages {
+		imageReference, err := name.ParseReference(registryHost + path)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		if err := remote.Write(imageReference, image); err != nil {
+			t.Fatal("write image:", err)
+		}
+	}
+
+	testCases := []struct {
+		repository         string
+		expectedSyncStatus imageSyncStatus
+	}{
+		{
+			repository:         "app",
+			expectedSyncStatus: imageInSync,
+		},
+		{
+			repository:         "overwritten",
+			expectedSyncStatus: imageDigestMismatched,
+		},
+		{
+			repository:         "missing",
+			expectedSyncStatus: imageMissingAtTarget,
+		},
+		{
+			repository:         "removed",
+			expectedSyncStatus: imageMissingAtSource,
+		},
+	}
+
+	for _, testCase := range testCases {
+		image := SourceImage{
+			Host:       registryHost + "/source",
+			Repository: testCase.repository,
+			Tag:        "v1.0.0",
+			Target: Target{
+				Host:       registryHost,
+				Repository: "target",
+			},
+		}
+
+		syncStatus, err := getImageSyncStatus(context.Background(), docker.Client{}, image)
+		if err != nil {
+			t.Fatal("get image sync status:", err)
+		}
+
+		if syncStatus != testCase.expectedSyncStatus {
+			t.Errorf("expected sync status to be %s for %s, actual %s", testCase.expectedSyncStatus, testCase.repository, syncStatus)
+		}
+	}
+}
+
+func TestGetImageSyncStatus_FilteredImageIndex(t *testing.T) {
+	registryServer := newTestRegistry()
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+
+	var addenda []mutate.IndexAddendum
+	for _, architecture := range []string{"amd64", "arm64"} {
+		platformImage, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		addenda = append(addenda, mutate.IndexAddendum{
+			Add:        platformImage,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: architecture}},
+		})
+	}
+
+	writeIndexes := map[string]v1.ImageIndex{
+		"/source/app:v1.0.0": mutate.AppendManifests(empty.Index, addenda...),
+		"/target/app:v1.0.0": mutate.AppendManifests(empty.Index, addenda[1]),
+	}
+
+	for path, imageIndex := range writeIndexes {
+		imageReference, err := name.ParseReference(registryHost + path)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		if err := remote.WriteIndex(imageReference, imageIndex); err != nil {
+			t.Fatal("write index:", err)
+		}
+	}
+
+	image := SourceImage{
+		Host:       registryHost + "/source",
+		Repository: "app",
+		Tag:        "v1.0.0",
+		Target:     Target{Host: registryHost, Repository: "target"},
+	}
+
+	syncStatus, err := getImageSyncStatus(context.Background(), docker.Client{}, image)
+	if err != nil {
+		t.Fatal("get image sync status:", err)
+	}
+
+	if syncStatus != imageInSync {
+		t.Errorf("expected sync status to be %s, actual %s", imageInSync, syncStatus)
+	}
+}
+
+func TestWriteCheckResults_GitHub(t *testing.T) {
+	manifestPath := writeTestManifest(t, `target:
+  host: mycompany.com
+sources:
+- repository: busybox
+  tag: 1.30.0
+- repository: nginx
+  tag: 1.19.0
+- repository: coreos/etcd
+  host: quay.io
+  tag: v3.4.13
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	results := []checkResult{
+		{Image: "busybox:1.30.0", Status: "outdated", NewerVersions: []string{"1.31.0", "1.32.0"}},
+		{Image: "mycompany.com/nginx:1.19.0", Source: "nginx:1.19.0", Status: string(imageDigestMismatched)},
+		{Image: "quay.io/coreos/etcd:v3.4.13", Status: string(docker.ImageAvailable)},
+		{Image: "other.com/app:1.0.0", Status: string(docker.ImageMissing)},
+	}
+	setResultPositions(results, manifest.Images)
+
+	var output bytes.Buffer
+	if err := writeCheckResults(&output, results, githubOutput); err != nil {
+		t.Fatal("write check results:", err)
+	}
+
+	expected := []string{
+		fmt.Sprintf("::warning file=%s,line=4::New versions for busybox:1.30.0 found: 1.31.0, 1.32.0", escapeAnnotationProperty(manifestPath)),
+		fmt.Sprintf("::error file=%s,line=6::Image mycompany.com/nginx:1.19.0 is digest mismatched (nginx:1.19.0)", escapeAnnotationProperty(manifestPath)),
+		"::error::Image other.com/app:1.0.0 is " + string(docker.ImageMissing),
+	}
+
+	actual := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected annotations %v, actual %v", expected, actual)
+	}
+
+	annotation := regexp.MustCompile(`^::(error|warning)( file=[^,:]+(,line=[0-9]+)?)?::.+$`)
+	for _, line := range actual {
+		if !annotation.MatchString(line) {
+			t.Errorf("expected %q to be a workflow command", line)
+		}
+	}
+}
+
+func TestCheckError_SetPositions(t *testing.T) {
+	results := []checkResult{
+		{Image: "mycompany.com/busybox:1.30.0", Status: string(imageInSync), position: manifestPosition{file: ".images.yaml", line: 4}},
+		{Image: "mycompany.com/nginx:1.19.0", Status: string(imageMissingAtTarget), position: manifestPosition{file: ".images.yaml", line: 6}},
+		{Image: "other.com/app:1.0.0", Status: string(imageMissingAtTarget)},
+	}
+
+	outOfSyncImages := checkError{description: "target images out of sync"}
+	outOfSyncImages.add("mycompany.com/nginx:1.19.0", string(imageMissingAtTarget))
+	outOfSyncImages.add("other.com/app:1.0.0", string(imageMissingAtTarget))
+	outOfSyncImages.setPositions(results)
+
+	const expected = "target images out of sync: mycompany.com/nginx:1.19.0 (missing at target) at .images.yaml:6, other.com/app:1.0.0 (missing at target)"
+	if outOfSyncImages.Error() != expected {
+		t.Errorf("expected error %q, actual %q", expected, outOfSyncImages.Error())
+	}
+}
+
+// fakeCreatedTimeGetter returns the creation time of each image, or an error for images without one,
+// and records the auth that each image was requested with
+type fakeCreatedTimeGetter struct {
+	created map[string]time.Time
+	auths   map[string]string
+}
+
+func (f fakeCreatedTimeGetter) GetCreatedAtRemote(ctx context.Context, image string, encodedAuth string) (time.Time, error) {
+	if f.auths != nil {
+		f.auths[image] = encodedAuth
+	}
+
+	created, exists := f.created[image]
+	if !exists {
+		return time.Time{}, errors.New("manifest unknown")
+	}
+
+	return created, nil
+}
+
+func TestGetChangedImages(t *testing.T) {
+	now := time.Date(2020, time.October, 1, 12, 0, 0, 0, time.UTC)
+	getter := fakeCreatedTimeGetter{
+		created: map[string]time.Time{
+			"busybox:1.32.0":        now.Add(-time.Hour),
+			"nginx:1.19.0":          now.Add(-48 * time.Hour),
+			"coreos/etcd:v3.4.13":   now.Add(-24 * time.Hour),
+			"distroless/base:1.0.0": {},
+		},
+	}
+
+	images := []string{"busybox:1.32.0", "nginx:1.19.0", "coreos/etcd:v3.4.13", "distroless/base:1.0.0", "private/app:1.0.0"}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	actual := getChangedImages(context.Background(), getter, logger, images, nil, 24*time.Hour, now)
+
+	// Images without a creation time, or whose creation time could not be found, are still checked
+	expected := []string{"busybox:1.32.0", "coreos/etcd:v3.4.13", "distroless/base:1.0.0", "private/app:1.0.0"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected changed images %v, actual %v", expected, actual)
+	}
+}
+
+func TestGetChangedSourceImages(t *testing.T) {
+	now := time.Date(2020, time.October, 1, 12, 0, 0, 0, time.UTC)
+	getter := fakeCreatedTimeGetter{
+		created: map[string]time.Time{
+			"busybox:1.32.0": now.Add(-time.Hour),
+			"nginx:1.19.0":   now.Add(-48 * time.Hour),
+		},
+		auths: make(map[string]string),
+	}
+
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "mycompany.com"}, Auth: Auth{Username: "user", Password: "pass"}},
+		{Repository: "nginx", Tag: "1.19.0", Target: Target{Host: "mycompany.com"}},
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	actual, err := getChangedSourceImages(context.Background(), getter, logger, images, 24*time.Hour, now)
+	if err != nil {
+		t.Fatal("get changed source images:", err)
+	}
+
+	if !reflect.DeepEqual(actual, images[:1]) {
+		t.Errorf("expected changed images %v, actual %v", images[:1], actual)
+	}
+
+	// The creation time of private images is found with the auth of the image in the manifest
+	expectedAuth, err := docker.GetEncodedBasicAuth("user", "pass")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	if getter.auths["busybox:1.32.0"] != expectedAuth {
+		t.Errorf("expected the auth of the image to be used, actual %q", getter.auths["busybox:1.32.0"])
+	}
+}
+
+func TestCheckLatestTags(t *testing.T) {
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Repository: "nginx", Tag: "latest", position: manifestPosition{file: ".images.yaml", line: 6}},
+		{Repository: "nginx", Digest: "sha256:123"},
+	}
+
+	var logs bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&logs)
+
+	var output bytes.Buffer
+	if err := checkLatestTags(&output, logger, "table", images); err != nil {
+		t.Fatal("expected the latest tag to only be a warning:", err)
+	}
+
+	if !strings.Contains(logs.String(), "sources[1] (nginx:latest) at .images.yaml:6: only pinned to the latest tag") {
+		t.Errorf("expected a warning for nginx:latest, actual %s", logs.String())
+	}
+
+	if err := checkLatestTags(&output, logger, githubOutput, images); err != nil {
+		t.Fatal("expected the latest tag to only be a warning:", err)
+	}
+
+	expected := "::warning file=.images.yaml,line=6::nginx:latest: only pinned to the latest tag, which changes when the source image is updated. Pin the image to a digest to sync the same image every time\n"
+	if output.String() != expected {
+		t.Errorf("expected annotation %s, actual %s", expected, output.String())
+	}
+
+	viper.Set("no-latest", true)
+	defer viper.Set("no-latest", false)
+
+	err := checkLatestTags(&output, logger, "table", images)
+
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Problems) != 1 {
+		t.Errorf("expected a validation error for nginx:latest, actual %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var progressFlags = []string{"quiet", "verbose"}
+
+var clientFlags = []string{"retry-attempts", "retry-delay", "retry-backoff", "retry-max-delay", "retry-jitter", "timeout", "insecure-registry", "ca-cert", "client-cert", "client-key", "registry-mirror", "proxy", "no-proxy"}
+
+func addClientFlags(cmd *cobra.Command) {
+	defaultRetryPolicy := docker.DefaultRetryPolicy()
+
+	cmd.Flags().Uint("retry-attempts", defaultRetryPolicy.Attempts, "The number of times to attempt an image operation before failing")
+	cmd.Flags().Duration("retry-delay", defaultRetryPolicy.Delay, "The delay between attempts of an image operation")
+	cmd.Flags().String("retry-backoff", string(defaultRetryPolicy.Backoff), "The backoff strategy between attempts (fixed or exponential)")
+	cmd.Flags().Duration("retry-max-delay", defaultRetryPolicy.MaxDelay, "The maximum delay between attempts when using the exponential backoff. Not capped when not set")
+	cmd.Flags().Duration("retry-jitter", defaultRetryPolicy.MaxJitter, "The maximum random duration added to the delay between attempts")
+	cmd.Flags().Duration("timeout", 0, "The maximum amount of time an image operation can take (e.g. 10m). No timeout when not set")
+	cmd.Flags().StringSlice("insecure-registry", []string{}, "The registries (e.g. registry.lan:5000) that are allowed to use plain HTTP and whose TLS certificates are not verified. A registry without a port matches every port")
+	cmd.Flags().StringSlice("ca-cert", []string{}, "The paths to PEM files of certificate authorities to trust when connecting to registries, in addition to the system certificates. Defaults to the paths in SINKER_CA_CERT")
+	cmd.Flags().StringSlice("client-cert", []string{}, "The PEM client certificate to present to a registry for mutual TLS, as registry=path (e.g. registry.lan:5000=client.crt)")
+	cmd.Flags().StringSlice("client-key", []string{}, "The PEM private key of the client certificate of a registry, as registry=path (e.g. registry.lan:5000=client.key)")
+	cmd.Flags().StringSlice("registry-mirror", []string{}, "The mirror to pull the images of a registry through, such as a pull-through cache, as registry=mirror (e.g. docker.io=mirror.internal). Images are still pushed to their target")
+	cmd.Flags().String("proxy", "", "The proxy (e.g. http://proxy.internal:3128) that connections to the source and target registries are made through. Defaults to HTTPS_PROXY and HTTP_PROXY")
+	cmd.Flags().StringSlice("no-proxy", []string{}, "The hosts, domains (e.g. .internal), and IP ranges that are connected to without the proxy. Defaults to NO_PROXY")
+}
+
+// addRateLimitFlag adds the rate-limit flag to the commands that transfer images between registries.
+// Commands that only transfer images through the Docker daemon do not have it, as the daemon is not limited.
+func addRateLimitFlag(cmd *cobra.Command) {
+	cmd.Flags().String("rate-limit", "", "The maximum rate (e.g. 10MB/s) that images are transferred to and from registries at, across every image transferred at the same time. Not limited when not set")
+}
+
+func addProgressFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("quiet", "q", false, "Only print whether each image succeeded or failed, without the progress of each image")
+	cmd.Flags().BoolP("verbose", "v", false, "Print every status of each image, including the progress details")
+}
+
+func bindProgressFlags(cmd *cobra.Command) error {
+	for _, flag := range progressFlags {
+		if err := viper.BindPFlag(flag, cmd.Flags().Lookup(flag)); err != nil {
+			return fmt.Errorf("bind %s flag: %w", flag, err)
+		}
+	}
+
+	if viper.GetBool("quiet") && viper.GetBool("verbose") {
+		return errors.New("the quiet and verbose flags cannot be used together")
+	}
+
+	return nil
+}
+
+// getClientLogger returns the logger given to the Docker client. In quiet mode, the level
+// of the logger is raised so that the progress of each image is not logged, but errors are.
+func getClientLogger(logger *log.Logger) *log.Logger {
+	if !viper.GetBool("quiet") {
+		return logger
+	}
+
+	clientLogger := log.New()
+	clientLogger.SetOutput(logger.Out)
+	clientLogger.SetFormatter(logger.Formatter)
+	clientLogger.SetLevel(log.WarnLevel)
+
+	// A log level that is already quieter than warnings is kept
+	if logger.GetLevel() < log.WarnLevel {
+		clientLogger.SetLevel(logger.GetLevel())
+	}
+
+	return clientLogger
+}
+
+func bindClientFlags(cmd *cobra.Command) error {
+	for _, flag := range clientFlags {
+		if err := viper.BindPFlag(flag, cmd.Flags().Lookup(flag)); err != nil {
+			return fmt.Errorf("bind %s flag: %w", flag, err)
+		}
+	}
+
+	if rateLimitFlag := cmd.Flags().Lookup("rate-limit"); rateLimitFlag != nil {
+		if err := viper.BindPFlag("rate-limit", rateLimitFlag); err != nil {
+			return fmt.Errorf("bind rate-limit flag: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func getClientOptions() ([]docker.ClientOption, error) {
+	retryPolicy := docker.RetryPolicy{
+		Attempts:  viper.GetUint("retry-attempts"),
+		Delay:     viper.GetDuration("retry-delay"),
+		Backoff:   docker.RetryBackoff(viper.GetString("retry-backoff")),
+		MaxDelay:  viper.GetDuration("retry-max-delay"),
+		MaxJitter: viper.GetDuration("retry-jitter"),
+	}
+
+	options := []docker.ClientOption{
+		docker.WithRetryPolicy(retryPolicy),
+		docker.WithTimeout(viper.GetDuration("timeout")),
+		docker.WithVerbose(viper.GetBool("verbose")),
+		docker.WithInsecureRegistries(viper.GetStringSlice("insecure-registry")),
+		docker.WithCACertificates(getCACertificates()),
+		docker.WithProxy(viper.GetString("proxy"), viper.GetStringSlice("no-proxy")),
+	}
+
+	clientCertificates, err := getClientCertificates(viper.GetStringSlice("client-cert"), viper.GetStringSlice("client-key"))
+	if err != nil {
+		return nil, fmt.Errorf("get client certificates: %w", err)
+	}
+
+	if len(clientCertificates) > 0 {
+		options = append(options, docker.WithClientCertificates(clientCertificates))
+	}
+
+	if viper.GetString("rate-limit") != "" {
+		bandwidthLimit, err := parseRate(viper.GetString("rate-limit"))
+		if err != nil {
+			return nil, fmt.Errorf("parse rate-limit: %w", err)
+		}
+
+		options = append(options, docker.WithBandwidthLimit(bandwidthLimit))
+	}
+
+	registryMirrors, err := parseRegistryMirrors(viper.GetStringSlice("registry-mirror"))
+	if err != nil {
+		return nil, fmt.Errorf("parse registry-mirror: %w", err)
+	}
+
+	if len(registryMirrors) > 0 {
+		options = append(options, docker.WithRegistryMirrors(registryMirrors))
+	}
+
+	return options, nil
+}
+
+// parseRate parses a rate in bytes per second in decimal units (e.g. 10MB/s), the same units as parseSize
+func parseRate(rate string) (int64, error) {
+	bytesPerSecond, err := parseSize(strings.TrimSuffix(strings.TrimSpace(rate), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("parse size: %w", err)
+	}
+
+	if bytesPerSecond <= 0 {
+		return 0, fmt.Errorf("rate %s must be greater than zero", rate)
+	}
+
+	return bytesPerSecond, nil
+}
+
+// getCACertificates returns the paths to the certificate authorities from the ca-cert flag,
+// or from the SINKER_CA_CERT environment variable when the flag is not set
+func getCACertificates() []string {
+	if caCertificates := viper.GetStringSlice("ca-cert"); len(caCertificates) > 0 {
+		return caCertificates
+	}
+
+	return filepath.SplitList(os.Getenv("SINKER_CA_CERT"))
+}
+
+// getClientCertificates returns the client certificate of each registry from
+// the registry=path values of the client-cert and client-key flags
+func getClientCertificates(certificates []string, keys []string) ([]docker.ClientCertificate, error) {
+	certificateFiles, err := parseRegistryPaths(certificates)
+	if err != nil {
+		return nil, fmt.Errorf("parse client-cert: %w", err)
+	}
+
+	keyFiles, err := parseRegistryPaths(keys)
+	if err != nil {
+		return nil, fmt.Errorf("parse client-key: %w", err)
+	}
+
+	for registry := range keyFiles {
+		if _, exists := certificateFiles[registry]; !exists {
+			return nil, fmt.Errorf("client key for %s has no client certificate", registry)
+		}
+	}
+
+	var clientCertificates []docker.ClientCertificate
+	for _, certificate := range certificates {
+		registry := strings.SplitN(certificate, "=", 2)[0]
+		keyFile, exists := keyFiles[registry]
+		if !exists {
+			return nil, fmt.Errorf("client certificate for %s has no client key", registry)
+		}
+
+		clientCertificate := docker.ClientCertificate{
+			Registry:        registry,
+			CertificateFile: certificateFiles[registry],
+			KeyFile:         keyFile,
+		}
+
+		clientCertificates = append(clientCertificates, clientCertificate)
+	}
+
+	return clientCertificates, nil
+}
+
+func parseRegistryPaths(values []string) (map[string]string, error) {
+	registryPaths := make(map[string]string)
+	for _, value := range values {
+		registryPath := strings.SplitN(value, "=", 2)
+		if len(registryPath) != 2 || registryPath[0] == "" || registryPath[1] == "" {
+			return nil, fmt.Errorf("%q must be in the format registry=path", value)
+		}
+
+		if _, exists := registryPaths[registryPath[0]]; exists {
+			return nil, fmt.Errorf("registry %s is given more than once", registryPath[0])
+		}
+
+		registryPaths[registryPath[0]] = registryPath[1]
+	}
+
+	return registryPaths, nil
+}
+
+// parseRegistryMirrors returns the mirror of each registry from the registry=mirror values
+// of the registry-mirror flag. The registries and mirrors are hosts, so they can not include a path.
+func parseRegistryMirrors(values []string) (map[string]string, error) {
+	registryMirrors := make(map[string]string)
+	for _, value := range values {
+		registryMirror := strings.SplitN(value, "=", 2)
+		if len(registryMirror) != 2 || registryMirror[0] == "" || registryMirror[1] == "" {
+			return nil, fmt.Errorf("%q must be in the format registry=mirror", value)
+		}
+
+		if strings.Contains(registryMirror[0], "/") || strings.Contains(registryMirror[1], "/") {
+			return nil, fmt.Errorf("%q must only include the hosts of the registry and mirror", value)
+		}
+
+		if _, exists := registryMirrors[registryMirror[0]]; exists {
+			return nil, fmt.Errorf("registry %s is given more than once", registryMirror[0])
+		}
+
+		registryMirrors[registryMirror[0]] = registryMirror[1]
+	}
+
+	return registryMirrors, nil
+}
@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestGetClientLogger_Quiet(t *testing.T) {
+	var output bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&output)
+
+	viper.Set("quiet", true)
+	defer viper.Set("quiet", false)
+
+	clientLogger := getClientLogger(logger)
+	clientLogger.Printf("[PULL] busybox:latest (Processing, 50%% complete)")
+	clientLogger.Errorf("[PULL] busybox:latest failed")
+	logger.Printf("[PULL] All images have been pulled!")
+
+	if strings.Contains(output.String(), "Processing") {
+		t.Errorf("expected progress to not be logged in quiet mode, actual %s", output.String())
+	}
+
+	if !strings.Contains(output.String(), "busybox:latest failed") {
+		t.Errorf("expected errors to be logged in quiet mode, actual %s", output.String())
+	}
+
+	if !strings.Contains(output.String(), "All images have been pulled!") {
+		t.Errorf("expected the command logger to not be quiet, actual %s", output.String())
+	}
+}
+
+func TestBindProgressFlags_QuietAndVerbose(t *testing.T) {
+	cmd := cobra.Command{}
+	addProgressFlags(&cmd)
+
+	viper.Set("quiet", true)
+	defer viper.Set("quiet", false)
+
+	viper.Set("verbose", true)
+	defer viper.Set("verbose", false)
+
+	if err := bindProgressFlags(&cmd); err == nil {
+		t.Error("expected an error when both quiet and verbose are set")
+	}
+}
+
+func TestAddRateLimitFlag(t *testing.T) {
+	testCases := []struct {
+		cmd              *cobra.Command
+		expectedRateFlag bool
+	}{
+		{cmd: newPushCommand(context.Background(), log.New()), expectedRateFlag: true},
+		{cmd: newSyncCommand(context.Background(), log.New()), expectedRateFlag: true},
+		{cmd: newPullCommand(context.Background(), log.New()), expectedRateFlag: false},
+		{cmd: newExportCommand(context.Background(), log.New()), expectedRateFlag: false},
+		{cmd: newImportCommand(context.Background(), log.New()), expectedRateFlag: false},
+	}
+
+	for _, testCase := range testCases {
+		if actual := testCase.cmd.Flags().Lookup("rate-limit") != nil; actual != testCase.expectedRateFlag {
+			t.Errorf("expected the rate-limit flag of %s to exist to be %v, actual %v", testCase.cmd.Name(), testCase.expectedRateFlag, actual)
+		}
+	}
+}
+
+func TestBindClientFlags_NoRateLimit(t *testing.T) {
+	defer viper.Reset()
+
+	cmd := cobra.Command{}
+	addClientFlags(&cmd)
+
+	if err := bindClientFlags(&cmd); err != nil {
+		t.Fatal("bind client flags:", err)
+	}
+
+	if _, err := getClientOptions(); err != nil {
+		t.Error("get client options:", err)
+	}
+}
+
+func TestGetClientCertificates(t *testing.T) {
+	testCases := []struct {
+		certificates  []string
+		keys          []string
+		expected      int
+		expectedError string
+	}{
+		{certificates: []string{"registry.lan:5000=client.crt"}, keys: []string{"registry.lan:5000=client.key"}, expected: 1},
+		{certificates: []string{"registry.lan=client.crt", "other.lan=other.crt"}, keys: []string{"other.lan=other.key", "registry.lan=client.key"}, expected: 2},
+		{certificates: []string{"registry.lan=client.crt"}, expectedError: "has no client key"},
+		{keys: []string{"registry.lan=client.key"}, expectedError: "has no client certificate"},
+		{certificates: []string{"client.crt"}, keys: []string{"registry.lan=client.key"}, expectedError: "must be in the format registry=path"},
+		{certificates: []string{"registry.lan=client.crt", "registry.lan=other.crt"}, keys: []string{"registry.lan=client.key"}, expectedError: "more than once"},
+	}
+
+	for _, testCase := range testCases {
+		clientCertificates, err := getClientCertificates(testCase.certificates, testCase.keys)
+		if testCase.expectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Errorf("expected error to contain %q, actual %v", testCase.expectedError, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal("get client certificates:", err)
+		}
+
+		if len(clientCertificates) != testCase.expected {
+			t.Errorf("expected %d client certificates, actual %d", testCase.expected, len(clientCertificates))
+		}
+
+		for _, clientCertificate := range clientCertificates {
+			if strings.TrimSuffix(clientCertificate.CertificateFile, ".crt") != strings.TrimSuffix(clientCertificate.KeyFile, ".key") {
+				t.Errorf("expected certificate %s to be paired with its key, actual %s", clientCertificate.CertificateFile, clientCertificate.KeyFile)
+			}
+		}
+	}
+}
+
+func TestParseRegistryMirrors(t *testing.T) {
+	testCases := []struct {
+		values        []string
+		expected      map[string]string
+		expectedError string
+	}{
+		{values: []string{"docker.io=mirror.internal"}, expected: map[string]string{"docker.io": "mirror.internal"}},
+		{values: []string{"docker.io=mirror.internal", "quay.io=mirror.internal:5000"}, expected: map[string]string{"docker.io": "mirror.internal", "quay.io": "mirror.internal:5000"}},
+		{values: []string{"mirror.internal"}, expectedError: "must be in the format registry=mirror"},
+		{values: []string{"docker.io=mirror.internal/dockerhub"}, expectedError: "must only include the hosts"},
+		{values: []string{"docker.io=mirror.internal", "docker.io=other.internal"}, expectedError: "more than once"},
+	}
+
+	for _, testCase := range testCases {
+		registryMirrors, err := parseRegistryMirrors(testCase.values)
+		if testCase.expectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Errorf("expected error to contain %q, actual %v", testCase.expectedError, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal("parse registry mirrors:", err)
+		}
+
+		if !reflect.DeepEqual(registryMirrors, testCase.expected) {
+			t.Errorf("expected registry mirrors %v, actual %v", testCase.expected, registryMirrors)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	testCases := []struct {
+		rate          string
+		expectedBytes int64
+		isValid       bool
+	}{
+		{rate: "10MB/s", expectedBytes: 10000000, isValid: true},
+		{rate: "500 kB/s", expectedBytes: 500000, isValid: true},
+		{rate: "1GB", expectedBytes: 1000000000, isValid: true},
+		{rate: "0MB/s", isValid: false},
+		{rate: "fast", isValid: false},
+	}
+
+	for _, testCase := range testCases {
+		bytesPerSecond, err := parseRate(testCase.rate)
+		if testCase.isValid && err != nil {
+			t.Errorf("expected rate %s to be valid, actual %v", testCase.rate, err)
+			continue
+		}
+
+		if !testCase.isValid {
+			if err == nil {
+				t.Errorf("expected rate %s to be invalid", testCase.rate)
+			}
+			continue
+		}
+
+		if bytesPerSecond != testCase.expectedBytes {
+			t.Errorf("expected rate %s to be %d bytes per second, actual %d", testCase.rate, testCase.expectedBytes, bytesPerSecond)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+func newCompletionCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:       "completion <bash|zsh|fish|powershell>",
+		Short:     "Print the shell completion script of sinker",
+		Long:      "Print the shell completion script of sinker, which completes the commands and flags, and the images of the manifest for the include and exclude flags (e.g. source <(sinker completion bash))",
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: completionShells,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runCompletionCommand(os.Stdout, cmd.Root(), args[0]); err != nil {
+				return fmt.Errorf("completion: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	return &cmd
+}
+
+func runCompletionCommand(output io.Writer, root *cobra.Command, shell string) error {
+	var err error
+	switch shell {
+	case "bash":
+		err = root.GenBashCompletion(output)
+	case "zsh":
+		err = root.GenZshCompletion(output)
+	case "fish":
+		err = root.GenFishCompletion(output, true)
+	case "powershell":
+		err = root.GenPowerShellCompletion(output)
+	default:
+		return fmt.Errorf("unsupported shell %s, must be one of %s", shell, strings.Join(completionShells, ", "))
+	}
+
+	if err != nil {
+		return fmt.Errorf("generate %s completion: %w", shell, err)
+	}
+
+	return nil
+}
+
+// completeImageNames completes the source images of the manifest that start with the text to complete.
+// As the include and exclude flags take comma-separated patterns, only the text after the last comma is
+// completed, and the patterns before it are kept. The manifest is not validated, so that its images are
+// still completed while it is being edited. Nothing is completed when the manifest cannot be read, as
+// completions cannot report errors, and when the manifest is read from stdin, which would wait for input.
+func completeImageNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	manifestPath := viper.GetString("manifest")
+	if manifestPath == stdinManifestPath {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	manifest, err := loadManifest(manifestPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completed string
+	if index := strings.LastIndex(toComplete, ","); index >= 0 {
+		completed, toComplete = toComplete[:index+1], toComplete[index+1:]
+	}
+
+	var completions []string
+	imageNames := make(map[string]bool)
+	for _, image := range manifest.Images {
+		imageName := image.String()
+		if image.Repository == "" || imageNames[imageName] || !strings.HasPrefix(imageName, toComplete) {
+			continue
+		}
+
+		imageNames[imageName] = true
+		completions = append(completions, completed+imageName)
+	}
+	sort.Strings(completions)
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
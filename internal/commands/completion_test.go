@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestRunCompletionCommand(t *testing.T) {
+	root := cobra.Command{Use: "sinker"}
+	root.AddCommand(newCompletionCommand())
+
+	for _, shell := range completionShells {
+		var output bytes.Buffer
+		if err := runCompletionCommand(&output, &root, shell); err != nil {
+			t.Fatalf("run %s completion: %v", shell, err)
+		}
+
+		if !strings.Contains(output.String(), "sinker") {
+			t.Errorf("expected the %s completion to complete sinker, actual %s", shell, output.String())
+		}
+	}
+
+	if err := runCompletionCommand(ioutil.Discard, &root, "tcsh"); err == nil {
+		t.Error("expected an error for an unsupported shell")
+	}
+}
+
+func TestCompleteImageNames(t *testing.T) {
+	const manifest = `
+target:
+  host: mycompany.com
+sources:
+- repository: busybox
+  tag: 1.32.0
+- repository: coreos/prometheus-operator
+  host: quay.io
+  tag: v0.40.0
+- repository: busybox
+  tag: 1.31.0
+`
+
+	manifestPath := writeTestManifest(t, manifest)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	defer viper.Reset()
+
+	root := cobra.Command{Use: "sinker"}
+	addManifestFlag(&root)
+	root.AddCommand(newPullCommand(context.Background(), logger))
+
+	var output bytes.Buffer
+	root.SetOut(&output)
+	root.SetArgs([]string{cobra.ShellCompNoDescRequestCmd, "pull", "source", "--manifest", manifestPath, "--include", "busy"})
+
+	if err := root.Execute(); err != nil {
+		t.Fatal("execute completion:", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	expected := []string{"busybox:1.31.0", "busybox:1.32.0", ":4"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected completions %v, actual %v", expected, lines)
+	}
+}
+
+func TestCompleteImageNames_Patterns(t *testing.T) {
+	const manifest = `
+target:
+  host: mycompany.com
+sources:
+- repository: busybox
+  tag: 1.32.0
+- repository: coreos/prometheus-operator
+  host: quay.io
+  tag: v0.40.0
+- repository: busybox
+  tag: 1.32.0
+  target:
+    host: other.com
+- tag: 1.0.0
+`
+
+	manifestPath := writeTestManifest(t, manifest)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	defer viper.Reset()
+	viper.Set("manifest", manifestPath)
+
+	testCases := []struct {
+		toComplete string
+		expected   []string
+	}{
+		{toComplete: "", expected: []string{"busybox:1.32.0", "quay.io/coreos/prometheus-operator:v0.40.0"}},
+		{toComplete: "quay.io/", expected: []string{"quay.io/coreos/prometheus-operator:v0.40.0"}},
+		{toComplete: "busybox:1.32.0,quay", expected: []string{"busybox:1.32.0,quay.io/coreos/prometheus-operator:v0.40.0"}},
+		{toComplete: "gcr.io/", expected: nil},
+	}
+
+	for _, testCase := range testCases {
+		actual, directive := completeImageNames(nil, nil, testCase.toComplete)
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected completions of %q to be %v, actual %v", testCase.toComplete, testCase.expected, actual)
+		}
+
+		if directive != cobra.ShellCompDirectiveNoFileComp {
+			t.Errorf("expected completions of %q to not complete files, actual directive %v", testCase.toComplete, directive)
+		}
+	}
+}
+
+func TestCompleteImageNames_InvalidManifest(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("manifest", filepath.Join("testdata", "missing.yaml"))
+
+	completions, directive := completeImageNames(nil, nil, "")
+	if len(completions) != 0 || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("expected no completions without file completion, actual %v with directive %v", completions, directive)
+	}
+}
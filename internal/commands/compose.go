@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/ghodss/yaml"
+)
+
+// composeFile is the part of a docker-compose file that describes the images of its services
+type composeFile struct {
+	Services map[string]composeService `json:"services"`
+}
+
+type composeService struct {
+	Image string `json:"image"`
+}
+
+// NewComposeManifest returns a new image manifest with the images of the services in the docker-compose file
+func NewComposeManifest(target string, path string) (Manifest, error) {
+	manifest := NewManifest(target)
+
+	foundImages, err := getImagesFromComposeFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("get from compose file: %w", err)
+	}
+
+	manifest.Images = foundImages
+
+	return manifest, nil
+}
+
+// getImagesFromComposeFile returns the images of the services in the docker-compose file, ordered
+// by the name of their service. Services which are only built, and have no image, are skipped.
+func getImagesFromComposeFile(path string) ([]SourceImage, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	var compose composeFile
+	if err := yaml.Unmarshal(contents, &compose); err != nil {
+		return nil, fmt.Errorf("unmarshal compose file: %w", err)
+	}
+
+	var serviceNames []string
+	for serviceName := range compose.Services {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+
+	var images []SourceImage
+	for _, serviceName := range serviceNames {
+		image := compose.Services[serviceName].Image
+		if image == "" {
+			continue
+		}
+
+		sourceImage := getComposeImage(image)
+		if containsImage(images, sourceImage) {
+			continue
+		}
+
+		images = append(images, sourceImage)
+	}
+
+	return images, nil
+}
+
+// getComposeImage returns the source image of the image of a service,
+// which uses the latest tag when the image has no tag or digest
+func getComposeImage(image string) SourceImage {
+	path := docker.RegistryPath(image)
+
+	sourceImage := SourceImage{
+		Host:       path.Host(),
+		Repository: path.Repository(),
+		Tag:        path.Tag(),
+		Digest:     path.Digest(),
+	}
+
+	if sourceImage.Tag == "" && sourceImage.Digest == "" {
+		sourceImage.Tag = "latest"
+	}
+
+	return sourceImage
+}
+
+func containsImage(images []SourceImage, image SourceImage) bool {
+	for _, currentImage := range images {
+		if currentImage.String() == image.String() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetImagesFromComposeFile(t *testing.T) {
+	images, err := getImagesFromComposeFile(filepath.Join("testdata", "docker-compose.yml"))
+	if err != nil {
+		t.Fatal("get images from compose file:", err)
+	}
+
+	expected := []SourceImage{
+		{Repository: "redis", Tag: "latest"},
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.13"},
+		{Repository: "prom/prometheus", Digest: "sha256:123"},
+		{Repository: "nginx", Tag: "1.19.0"},
+		{Host: "mycompany.com", Repository: "worker", Tag: "1.0.0"},
+	}
+
+	if len(images) != len(expected) {
+		t.Fatalf("expected %d images, actual %d: %v", len(expected), len(images), images)
+	}
+
+	for i, image := range images {
+		if image.String() != expected[i].String() {
+			t.Errorf("expected image %d to be %s, actual %s", i, expected[i].String(), image.String())
+		}
+	}
+}
+
+func TestGetComposeImage(t *testing.T) {
+	testCases := []struct {
+		image    string
+		expected SourceImage
+	}{
+		{image: "redis", expected: SourceImage{Repository: "redis", Tag: "latest"}},
+		{image: "localhost:5000/app", expected: SourceImage{Host: "localhost:5000", Repository: "app", Tag: "latest"}},
+		{image: "nginx:1.19.0", expected: SourceImage{Repository: "nginx", Tag: "1.19.0"}},
+		{image: "nginx@sha256:123", expected: SourceImage{Repository: "nginx", Digest: "sha256:123"}},
+	}
+
+	for _, testCase := range testCases {
+		actual := getComposeImage(testCase.image)
+		if actual.Host != testCase.expected.Host || actual.Repository != testCase.expected.Repository || actual.Tag != testCase.expected.Tag || actual.Digest != testCase.expected.Digest {
+			t.Errorf("expected %s to be %+v, actual %+v", testCase.image, testCase.expected, actual)
+		}
+	}
+}
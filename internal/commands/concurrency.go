@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/spf13/viper"
+)
+
+// imageErrors is a collection of errors for the images that failed
+type imageErrors []error
+
+func (e imageErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return fmt.Sprintf("%v image(s) failed: %s", len(e), strings.Join(messages, "; "))
+}
+
+// runConcurrently runs the operation for each index up to count using at most
+// maxConcurrent workers. Every operation is run to completion, even when others fail,
+// and the errors of all failed operations are returned together.
+func runConcurrently(maxConcurrent int, count int, operation func(index int) error) error {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+
+	indexes := make(chan int)
+	var errorsMutex sync.Mutex
+	var errs imageErrors
+
+	var workers sync.WaitGroup
+	for worker := 0; worker < maxConcurrent; worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+
+			for index := range indexes {
+				if err := operation(index); err != nil {
+					errorsMutex.Lock()
+					errs = append(errs, err)
+					errorsMutex.Unlock()
+				}
+			}
+		}()
+	}
+
+	for index := 0; index < count; index++ {
+		indexes <- index
+	}
+	close(indexes)
+
+	workers.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// hostLimiter limits the number of images that are processed at the same time for each registry host.
+// Hosts without a limit are only limited by the number of workers.
+type hostLimiter struct {
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(limits map[string]int) *hostLimiter {
+	slots := make(map[string]chan struct{})
+	for host, limit := range limits {
+		slots[normalizeRegistryHost(host)] = make(chan struct{}, limit)
+	}
+
+	return &hostLimiter{slots: slots}
+}
+
+// acquire waits until every host has a free slot and returns the function that releases the slots.
+// The slots are acquired in the order of the hosts, so that workers acquiring the same hosts
+// (e.g. a source and target host the other way around) can not wait on each other.
+func (l *hostLimiter) acquire(hosts ...string) func() {
+	if l == nil {
+		return func() {}
+	}
+
+	var limitedHosts []string
+	for _, host := range hosts {
+		host = normalizeRegistryHost(host)
+		if _, exists := l.slots[host]; exists && !contains(limitedHosts, host) {
+			limitedHosts = append(limitedHosts, host)
+		}
+	}
+	sort.Strings(limitedHosts)
+
+	for _, host := range limitedHosts {
+		l.slots[host] <- struct{}{}
+	}
+
+	return func() {
+		for _, host := range limitedHosts {
+			<-l.slots[host]
+		}
+	}
+}
+
+// getHostLimiter returns the limiter of the hosts given by the concurrency-per-host flag
+func getHostLimiter() (*hostLimiter, error) {
+	limits, err := parseHostLimits(viper.GetStringSlice("concurrency-per-host"))
+	if err != nil {
+		return nil, fmt.Errorf("parse concurrency-per-host: %w", err)
+	}
+
+	return newHostLimiter(limits), nil
+}
+
+// getImageHost returns the registry host of the image, where images without a host are on Docker Hub
+func getImageHost(image string) string {
+	return normalizeRegistryHost(docker.RegistryPath(image).Host())
+}
+
+// normalizeRegistryHost returns docker.io for each of the hosts of Docker Hub
+func normalizeRegistryHost(host string) string {
+	switch host {
+	case "", "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	default:
+		return host
+	}
+}
+
+// parseHostLimits returns the limit of each host from the host=limit values of the concurrency-per-host flag
+func parseHostLimits(values []string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, value := range values {
+		hostLimit := strings.SplitN(value, "=", 2)
+		if len(hostLimit) != 2 || hostLimit[0] == "" {
+			return nil, fmt.Errorf("%q must be in the format host=limit", value)
+		}
+
+		limit, err := strconv.Atoi(hostLimit[1])
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("limit of %s must be a positive number, actual %q", hostLimit[0], hostLimit[1])
+		}
+
+		host := normalizeRegistryHost(hostLimit[0])
+		if _, exists := limits[host]; exists {
+			return nil, fmt.Errorf("host %s is given more than once", hostLimit[0])
+		}
+
+		limits[host] = limit
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunConcurrently_MaxConcurrent(t *testing.T) {
+	const maxConcurrent = 3
+
+	var mutex sync.Mutex
+	var running int
+	var maxRunning int
+	var completed int
+
+	operation := func(index int) error {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		running--
+		completed++
+		mutex.Unlock()
+
+		return nil
+	}
+
+	if err := runConcurrently(maxConcurrent, 10, operation); err != nil {
+		t.Fatal("run concurrently:", err)
+	}
+
+	if maxRunning > maxConcurrent {
+		t.Errorf("expected at most %v concurrent operations, actual %v", maxConcurrent, maxRunning)
+	}
+
+	if completed != 10 {
+		t.Errorf("expected 10 completed operations, actual %v", completed)
+	}
+}
+
+func TestRunConcurrently_CollectsErrors(t *testing.T) {
+	var mutex sync.Mutex
+	var completed int
+
+	operation := func(index int) error {
+		mutex.Lock()
+		completed++
+		mutex.Unlock()
+
+		if index%2 == 0 {
+			return errors.New("failed")
+		}
+
+		return nil
+	}
+
+	err := runConcurrently(2, 6, operation)
+
+	var errs imageErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected image errors, actual %v", err)
+	}
+
+	if len(errs) != 3 {
+		t.Errorf("expected 3 errors, actual %v", len(errs))
+	}
+
+	if completed != 6 {
+		t.Errorf("expected all 6 operations to complete, actual %v", completed)
+	}
+}
+
+func TestHostLimiter_UnderLoad(t *testing.T) {
+	const maxConcurrent = 8
+	limits := map[string]int{"docker.io": 2, "quay.io": 3, "mycompany.com": 4}
+
+	// The source and target hosts of some images are the other way around, which
+	// would leave workers waiting on each other if the hosts were acquired in any order
+	images := []struct {
+		source string
+		target string
+	}{
+		{source: "busybox:1.32.0", target: "mycompany.com/busybox:1.32.0"},
+		{source: "quay.io/coreos/etcd:v3.4.13", target: "mycompany.com/coreos/etcd:v3.4.13"},
+		{source: "docker.io/library/nginx:1.19.0", target: "quay.io/mycompany/nginx:1.19.0"},
+		{source: "quay.io/prometheus/prometheus:v2.20.0", target: "index.docker.io/mycompany/prometheus:v2.20.0"},
+		{source: "gcr.io/distroless/base:latest", target: "gcr.io/mycompany/base:latest"},
+	}
+
+	var mutex sync.Mutex
+	running := make(map[string]int)
+	maxRunning := make(map[string]int)
+	var runningTotal int
+	var maxRunningTotal int
+
+	limiter := newHostLimiter(limits)
+	operation := func(index int) error {
+		image := images[index%len(images)]
+		hosts := []string{getImageHost(image.source)}
+		if targetHost := getImageHost(image.target); targetHost != hosts[0] {
+			hosts = append(hosts, targetHost)
+		}
+
+		release := limiter.acquire(hosts...)
+		defer release()
+
+		mutex.Lock()
+		runningTotal++
+		if runningTotal > maxRunningTotal {
+			maxRunningTotal = runningTotal
+		}
+		for _, host := range hosts {
+			running[host]++
+			if running[host] > maxRunning[host] {
+				maxRunning[host] = running[host]
+			}
+		}
+		mutex.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mutex.Lock()
+		runningTotal--
+		for _, host := range hosts {
+			running[host]--
+		}
+		mutex.Unlock()
+
+		return nil
+	}
+
+	if err := runConcurrently(maxConcurrent, 50, operation); err != nil {
+		t.Fatal("run concurrently:", err)
+	}
+
+	for host, limit := range limits {
+		if maxRunning[host] > limit {
+			t.Errorf("expected at most %v concurrent operations for %s, actual %v", limit, host, maxRunning[host])
+		}
+	}
+
+	if maxRunningTotal > maxConcurrent {
+		t.Errorf("expected at most %v concurrent operations, actual %v", maxConcurrent, maxRunningTotal)
+	}
+}
+
+func TestHostLimiter_Nil(t *testing.T) {
+	var limiter *hostLimiter
+
+	release := limiter.acquire("docker.io")
+	release()
+}
+
+func TestParseHostLimits(t *testing.T) {
+	testCases := []struct {
+		values        []string
+		expected      map[string]int
+		expectedError string
+	}{
+		{values: []string{"docker.io=2", "quay.io=5"}, expected: map[string]int{"docker.io": 2, "quay.io": 5}},
+		{values: []string{"index.docker.io=2"}, expected: map[string]int{"docker.io": 2}},
+		{values: []string{"docker.io"}, expectedError: "must be in the format host=limit"},
+		{values: []string{"docker.io=0"}, expectedError: "must be a positive number"},
+		{values: []string{"docker.io=two"}, expectedError: "must be a positive number"},
+		{values: []string{"docker.io=2", "index.docker.io=3"}, expectedError: "more than once"},
+	}
+
+	for _, testCase := range testCases {
+		limits, err := parseHostLimits(testCase.values)
+		if testCase.expectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Errorf("expected error to contain %q, actual %v", testCase.expectedError, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal("parse host limits:", err)
+		}
+
+		if !reflect.DeepEqual(limits, testCase.expected) {
+			t.Errorf("expected host limits %v, actual %v", testCase.expected, limits)
+		}
+	}
+}
@@ -1,14 +1,19 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
 
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-func newCreateCommand() *cobra.Command {
+func newCreateCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "create <source>",
 		Short: "Create a new image manifest",
@@ -18,13 +23,35 @@ func newCreateCommand() *cobra.Command {
 				return fmt.Errorf("bind target flag: %w", err)
 			}
 
+			if err := viper.BindPFlag("from-compose", cmd.Flags().Lookup("from-compose")); err != nil {
+				return fmt.Errorf("bind from-compose flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("from-kubernetes", cmd.Flags().Lookup("from-kubernetes")); err != nil {
+				return fmt.Errorf("bind from-kubernetes flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("include-digests", cmd.Flags().Lookup("include-digests")); err != nil {
+				return fmt.Errorf("bind include-digests flag: %w", err)
+			}
+
 			var path string
 			if len(args) > 0 {
 				path = args[0]
 			}
 
+			var resolver digestResolver
+			if viper.GetBool("include-digests") {
+				client, err := docker.NewClient(logger)
+				if err != nil {
+					return fmt.Errorf("create: new client: %w", err)
+				}
+
+				resolver = client
+			}
+
 			manifestPath := viper.GetString("manifest")
-			if err := runCreateCommand(path, manifestPath); err != nil {
+			if err := runCreateCommand(ctx, resolver, path, manifestPath); err != nil {
 				return fmt.Errorf("create: %w", err)
 			}
 
@@ -34,18 +61,42 @@ func newCreateCommand() *cobra.Command {
 
 	cmd.Flags().StringP("target", "t", "", "The target repository to sync images to (e.g. organization.com/repo)")
 	cmd.MarkFlagRequired("target")
+	cmd.Flags().String("from-compose", "", "Create the manifest from the images of the services in a docker-compose file (e.g. docker-compose.yml)")
+	cmd.Flags().Bool("include-digests", false, "Add the current digest of the tag of each source image to the manifest, the same as running update --pin-digests once the manifest is created")
+	cmd.Flags().String("from-kubernetes", "", "Create the manifest from the images of the Kubernetes manifests in a file or directory, the same as passing the source path")
 
 	return &cmd
 }
 
-func runCreateCommand(path string, manifestPath string) error {
-	if _, err := GetManifest(manifestPath); err == nil {
-		return errors.New("manifest file already exists")
+// runCreateCommand creates the manifest from the source path. When a resolver is given, the
+// digest that the tag of each source image currently refers to is added to the manifest.
+func runCreateCommand(ctx context.Context, resolver digestResolver, path string, manifestPath string) error {
+	if manifestPath != stdinManifestPath {
+		if _, err := os.Stat(getManifestLocation(manifestPath)); err == nil {
+			return errors.New("manifest file already exists")
+		}
+	}
+
+	if viper.GetString("from-kubernetes") != "" {
+		if path != "" {
+			return errors.New("a source path and the from-kubernetes flag cannot be used together")
+		}
+
+		path = viper.GetString("from-kubernetes")
+	}
+
+	if path != "" && viper.GetString("from-compose") != "" {
+		return errors.New("kubernetes manifests and the from-compose flag cannot be used together")
 	}
 
 	var err error
 	var manifest Manifest
-	if path == "" {
+	if viper.GetString("from-compose") != "" {
+		manifest, err = NewComposeManifest(viper.GetString("target"), viper.GetString("from-compose"))
+		if err != nil {
+			return fmt.Errorf("new manifest from compose: %w", err)
+		}
+	} else if path == "" {
 		manifest = NewManifest(viper.GetString("target"))
 	} else {
 		manifest, err = NewAutodetectManifest(viper.GetString("target"), path)
@@ -54,6 +105,19 @@ func runCreateCommand(path string, manifestPath string) error {
 		}
 	}
 
+	if resolver != nil {
+		for i, image := range manifest.Images {
+			if image.Tag == "" {
+				continue
+			}
+
+			manifest.Images[i].Digest, err = resolveDigest(ctx, resolver, image)
+			if err != nil {
+				return fmt.Errorf("resolve digest: %w", err)
+			}
+		}
+	}
+
 	if err := WriteManifest(manifest, manifestPath); err != nil {
 		return fmt.Errorf("writing manifest: %w", err)
 	}
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/viper"
+)
+
+func TestRunCreateCommand_IncludeDigests(t *testing.T) {
+	registryServer := newTestRegistry()
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	imageReference, err := name.ParseReference(registryHost + "/app:v1.0.0")
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(imageReference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	digest, err := image.Digest()
+	if err != nil {
+		t.Fatal("image digest:", err)
+	}
+
+	sourceDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(sourceDir)
+
+	compose := `services:
+  app:
+    image: ` + registryHost + `/app:v1.0.0
+`
+	composePath := filepath.Join(sourceDir, "docker-compose.yml")
+	if err := ioutil.WriteFile(composePath, []byte(compose), os.ModePerm); err != nil {
+		t.Fatal("write compose file:", err)
+	}
+
+	manifestDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(manifestDir)
+
+	viper.Set("target", "target.com")
+	defer viper.Set("target", "")
+
+	viper.Set("from-compose", composePath)
+	defer viper.Set("from-compose", "")
+
+	if err := runCreateCommand(context.Background(), docker.Client{}, "", manifestDir); err != nil {
+		t.Fatal("create manifest:", err)
+	}
+
+	manifest, err := GetManifest(manifestDir)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	if len(manifest.Images) != 1 {
+		t.Fatalf("expected 1 image in the manifest, actual %v", len(manifest.Images))
+	}
+
+	if manifest.Images[0].Digest != digest.String() {
+		t.Errorf("expected digest to be %s, actual %s", digest, manifest.Images[0].Digest)
+	}
+
+	if manifest.Images[0].Tag != "v1.0.0" {
+		t.Errorf("expected the tag to be kept, actual %s", manifest.Images[0].Tag)
+	}
+}
+
+func TestRunCreateCommand_InvalidManifestExists(t *testing.T) {
+	const contents = "target:\n  host: mycompany.com\nimages:\n- repository: \"\"\n"
+
+	manifestPath := writeTestManifest(t, contents)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	viper.Set("target", "target.com")
+	defer viper.Set("target", "")
+
+	if err := runCreateCommand(context.Background(), nil, "", manifestPath); err == nil {
+		t.Fatal("expected an error when the manifest already exists, even if it is invalid")
+	}
+
+	actual, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal("read manifest:", err)
+	}
+
+	if string(actual) != contents {
+		t.Errorf("expected the existing manifest to not be overwritten, actual %s", actual)
+	}
+}
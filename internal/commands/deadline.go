@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// withDeadline returns a context that is cancelled once the deadline has elapsed.
+// When there is no deadline, the context is only cancelled with the parent context.
+func withDeadline(ctx context.Context, deadline time.Duration) (context.Context, context.CancelFunc) {
+	if deadline <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, deadline)
+}
+
+// skipAfterCancel records the image as skipped in the summary, and returns true, when the deadline
+// of the run was exceeded, or the run was interrupted, before the image could be pulled or pushed
+func skipAfterCancel(ctx context.Context, summary *syncSummary, image string) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+
+	summary.skip(image)
+	return true
+}
+
+// checkCancelled returns an error with the number of skipped images when the deadline of the run was
+// exceeded or the run was interrupted, and otherwise returns the error of the images that were pulled or pushed
+func checkCancelled(ctx context.Context, summary *syncSummary, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+
+	if err == nil {
+		return fmt.Errorf("%v image(s) skipped: %w", summary.skippedImages(), ctx.Err())
+	}
+
+	return fmt.Errorf("%v image(s) skipped: %w: %s", summary.skippedImages(), ctx.Err(), err)
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type slowPuller struct {
+	delay time.Duration
+}
+
+func (s slowPuller) PullImageAndWait(ctx context.Context, image string, auth string) error {
+	select {
+	case <-time.After(s.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	ctx, cancel := withDeadline(context.Background(), 0)
+	defer cancel()
+
+	if _, exists := ctx.Deadline(); exists {
+		t.Error("expected no deadline when the deadline is not set")
+	}
+
+	deadlineCtx, deadlineCancel := withDeadline(context.Background(), time.Hour)
+	defer deadlineCancel()
+
+	if _, exists := deadlineCtx.Deadline(); !exists {
+		t.Error("expected a deadline when the deadline is set")
+	}
+}
+
+func TestPullImages_Deadline(t *testing.T) {
+	imagesToPull := make(map[string]string)
+	for i := 0; i < 5; i++ {
+		imagesToPull[fmt.Sprintf("busybox:1.%v.0", i)] = ""
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	ctx, cancel := withDeadline(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	summary := newSyncSummary()
+	start := time.Now()
+	err := pullImages(ctx, logger, slowPuller{delay: time.Minute}, nil, summary, imagesToPull, 1, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to be exceeded, actual %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the in-flight pull to be cancelled at the deadline, actual %s elapsed", elapsed)
+	}
+
+	if summary.succeeded != 0 || summary.failed != 1 || summary.skipped != 4 {
+		t.Errorf("expected summary of 0 succeeded, 1 failed, and 4 skipped, actual %v succeeded, %v failed, and %v skipped", summary.succeeded, summary.failed, summary.skipped)
+	}
+}
@@ -2,9 +2,15 @@ package commands
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path"
+	"syscall"
+
+	"github.com/plexsystems/sinker/internal/version"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -17,27 +23,131 @@ func NewDefaultCommand() *cobra.Command {
 		Use:     path.Base(os.Args[0]),
 		Short:   "sinker",
 		Long:    "A tool to sync container images to another container registry",
-		Version: "0.10.0",
+		Version: version.Version,
 	}
 
-	cmd.PersistentFlags().StringP("manifest", "m", "", "Path where the manifest file is (defaults to .images.yaml in the current directory)")
-	viper.BindPFlag("manifest", cmd.PersistentFlags().Lookup("manifest"))
-
-	ctx := context.Background()
+	addManifestFlag(&cmd)
+	addTmpDirFlag(&cmd)
 
 	logrusLogger := logrus.New()
 	logrusLogger.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: false,
+		DisableColors: !isTerminal(logrusLogger.Out),
 	})
 
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Cancel any in-flight image operations when interrupted or terminated, so that they
+	// unwind and the summary of the images that completed is printed. Subsequent
+	// signals are no longer handled so that they terminate immediately.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		received := <-signals
+		signal.Stop(signals)
+		logrusLogger.Warnf("[INFO] Received %s, waiting for the images in progress to stop ...", received)
+		cancel()
+	}()
+
+	cmd.PersistentFlags().String("log-format", textLogFormat, "The format of the logs (text, json)")
+	viper.BindPFlag("log-format", cmd.PersistentFlags().Lookup("log-format"))
+
+	cmd.PersistentFlags().String("log-level", "info", "The minimum level of the logs (debug, info, warn, error)")
+	viper.BindPFlag("log-level", cmd.PersistentFlags().Lookup("log-level"))
+
+	cmd.PersistentFlags().Bool("no-color", false, "Disable the colors of text logs, which are only colored when written to a terminal")
+	viper.BindPFlag("no-color", cmd.PersistentFlags().Lookup("no-color"))
+
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := configureLogger(logrusLogger, viper.GetString("log-format"), viper.GetString("log-level"), viper.GetBool("no-color")); err != nil {
+			return fmt.Errorf("configure logger: %w", err)
+		}
+
+		if err := validateTmpDir(viper.GetString("tmp-dir")); err != nil {
+			return fmt.Errorf("validate tmp dir: %w", err)
+		}
+
+		return nil
+	}
+
 	log.SetOutput(logrusLogger.Writer())
 
-	cmd.AddCommand(newCreateCommand())
-	cmd.AddCommand(newUpdateCommand())
-	cmd.AddCommand(newListCommand())
+	cmd.AddCommand(newCreateCommand(ctx, logrusLogger))
+	cmd.AddCommand(newUpdateCommand(ctx, logrusLogger))
+	cmd.AddCommand(newListCommand(ctx, logrusLogger))
 	cmd.AddCommand(newPullCommand(ctx, logrusLogger))
 	cmd.AddCommand(newPushCommand(ctx, logrusLogger))
+	cmd.AddCommand(newSyncCommand(ctx, logrusLogger))
 	cmd.AddCommand(newCheckCommand(ctx, logrusLogger))
+	cmd.AddCommand(newLsTagsCommand(ctx, logrusLogger))
+	cmd.AddCommand(newInspectCommand(ctx, logrusLogger))
+	cmd.AddCommand(newLoginCommand(ctx, logrusLogger))
+	cmd.AddCommand(newDiffCommand())
+	cmd.AddCommand(newValidateCommand())
+	cmd.AddCommand(newSchemaCommand())
+	cmd.AddCommand(newPruneCommand(ctx, logrusLogger))
+	cmd.AddCommand(newExportCommand(ctx, logrusLogger))
+	cmd.AddCommand(newImportCommand(ctx, logrusLogger))
+	cmd.AddCommand(newVersionCommand())
+	cmd.AddCommand(newCompletionCommand())
+
+	for _, subcommand := range cmd.Commands() {
+		wrapInterruptedError(ctx, subcommand)
+	}
 
 	return &cmd
 }
+
+// addManifestFlag adds the manifest flag, which falls back to the path in
+// the SINKER_MANIFEST environment variable when the flag is not set
+func addManifestFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringP("manifest", "m", "", "Path where the manifest file is (defaults to SINKER_MANIFEST, or .images.yaml in the current directory). Use - to read from stdin and write to stdout, or a comma-separated list of files or a directory of files to merge multiple manifests")
+	viper.BindPFlag("manifest", cmd.PersistentFlags().Lookup("manifest"))
+	viper.BindEnv("manifest", "SINKER_MANIFEST")
+}
+
+// addDeprecatedAlias adds a hidden flag with the old name of the flag that sets the same value as the flag, so
+// that existing scripts that use the old name keep working, while a deprecation warning is printed when they do
+func addDeprecatedAlias(cmd *cobra.Command, alias string, name string) {
+	flag := cmd.Flags().Lookup(name)
+	cmd.Flags().Var(flag.Value, alias, flag.Usage)
+	cmd.Flags().Lookup(alias).NoOptDefVal = flag.NoOptDefVal
+	cmd.Flags().MarkDeprecated(alias, "use --"+name+" instead")
+}
+
+// InterruptedExitCode is the exit code of a command that was interrupted or terminated by a signal
+const InterruptedExitCode = 130
+
+// ErrInterrupted is returned by a command that was stopped by SIGINT or SIGTERM before it completed
+var ErrInterrupted = errors.New("interrupted")
+
+// wrapInterruptedError wraps the error of the command, and of its subcommands, with
+// ErrInterrupted when the context was cancelled by a signal while the command was running
+func wrapInterruptedError(ctx context.Context, cmd *cobra.Command) {
+	for _, subcommand := range cmd.Commands() {
+		wrapInterruptedError(ctx, subcommand)
+	}
+
+	if cmd.RunE == nil {
+		return
+	}
+
+	runE := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		err := runE(cmd, args)
+		if err != nil && ctx.Err() != nil {
+			return fmt.Errorf("%w: %s", ErrInterrupted, err)
+		}
+
+		return err
+	}
+}
+
+// ExitCode returns the exit code of the error returned by a command
+func ExitCode(err error) int {
+	if errors.Is(err, ErrInterrupted) {
+		return InterruptedExitCode
+	}
+
+	return 1
+}
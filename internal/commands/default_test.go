@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestAddManifestFlag(t *testing.T) {
+	defer viper.Reset()
+	defer os.Unsetenv("SINKER_MANIFEST")
+
+	testCases := []struct {
+		flag     string
+		env      string
+		expected string
+	}{
+		{expected: ".images.yaml"},
+		{env: "config/sinker.yaml", expected: "config/sinker.yaml"},
+		{flag: "other/sinker.yaml", env: "config/sinker.yaml", expected: "other/sinker.yaml"},
+		{flag: "other", expected: "other/.images.yaml"},
+	}
+
+	for _, testCase := range testCases {
+		os.Setenv("SINKER_MANIFEST", testCase.env)
+		if testCase.env == "" {
+			os.Unsetenv("SINKER_MANIFEST")
+		}
+
+		cmd := cobra.Command{}
+		addManifestFlag(&cmd)
+
+		if testCase.flag != "" {
+			if err := cmd.PersistentFlags().Set("manifest", testCase.flag); err != nil {
+				t.Fatal("set manifest flag:", err)
+			}
+		}
+
+		actual := getManifestLocation(viper.GetString("manifest"))
+		if actual != testCase.expected {
+			t.Errorf("expected manifest location %s with flag %q and env %q, actual %s", testCase.expected, testCase.flag, testCase.env, actual)
+		}
+	}
+}
+
+func TestWrapInterruptedError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	parent := cobra.Command{Use: "list"}
+	cmd := cobra.Command{
+		Use: "source",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cancel()
+			return fmt.Errorf("push images: %w", ctx.Err())
+		},
+	}
+	parent.AddCommand(&cmd)
+
+	wrapInterruptedError(ctx, &parent)
+
+	err := cmd.RunE(&cmd, nil)
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected an interrupted error, actual %v", err)
+	}
+
+	if ExitCode(err) != InterruptedExitCode {
+		t.Errorf("expected exit code %v, actual %v", InterruptedExitCode, ExitCode(err))
+	}
+
+	if ExitCode(errors.New("push images: failed")) != 1 {
+		t.Errorf("expected exit code 1 for other errors, actual %v", ExitCode(errors.New("failed")))
+	}
+}
+
+func TestAddDeprecatedAlias(t *testing.T) {
+	defer viper.Reset()
+
+	testCases := []struct {
+		args     []string
+		expected bool
+	}{
+		{args: []string{}, expected: false},
+		{args: []string{"--dry-run"}, expected: true},
+		{args: []string{"--dryrun"}, expected: true},
+	}
+
+	for _, testCase := range testCases {
+		viper.Reset()
+
+		cmd := cobra.Command{
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run"))
+			},
+		}
+		cmd.SetOut(ioutil.Discard)
+		cmd.SetErr(ioutil.Discard)
+		cmd.Flags().Bool("dry-run", false, "")
+		addDeprecatedAlias(&cmd, "dryrun", "dry-run")
+
+		cmd.SetArgs(testCase.args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatal("execute:", err)
+		}
+
+		if actual := viper.GetBool("dry-run"); actual != testCase.expected {
+			t.Errorf("expected dry-run to be %v with args %v, actual %v", testCase.expected, testCase.args, actual)
+		}
+
+		if !cmd.Flags().Lookup("dryrun").Hidden {
+			t.Error("expected the deprecated alias to be hidden")
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newDiffCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "diff <manifest> <manifest>",
+		Short: "Show the images that were added, removed, or changed between two image manifests",
+		Args:  cobra.ExactArgs(2),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			if err := runDiffCommand(args[0], args[1]); err != nil {
+				return fmt.Errorf("diff: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	addOutputFlag(&cmd)
+
+	return &cmd
+}
+
+func runDiffCommand(fromPath string, toPath string) error {
+	if err := validateOutputFormat(viper.GetString("output")); err != nil {
+		return fmt.Errorf("validate output: %w", err)
+	}
+
+	fromManifest, err := GetManifest(fromPath)
+	if err != nil {
+		return fmt.Errorf("get manifest %s: %w", fromPath, err)
+	}
+
+	toManifest, err := GetManifest(toPath)
+	if err != nil {
+		return fmt.Errorf("get manifest %s: %w", toPath, err)
+	}
+
+	diff := getManifestDiff(fromManifest, toManifest)
+	if err := writeManifestDiff(os.Stdout, diff, viper.GetString("output")); err != nil {
+		return fmt.Errorf("write diff: %w", err)
+	}
+
+	if diff.HasChanges() {
+		return errors.New("manifests differ")
+	}
+
+	return nil
+}
+
+func writeManifestDiff(output io.Writer, diff manifestDiff, format string) error {
+	writeTable := func(output io.Writer) error {
+		for _, image := range diff.Added {
+			if _, err := fmt.Fprintf(output, "+ %s\n", image); err != nil {
+				return fmt.Errorf("writing added image: %w", err)
+			}
+		}
+
+		for _, image := range diff.Removed {
+			if _, err := fmt.Fprintf(output, "- %s\n", image); err != nil {
+				return fmt.Errorf("writing removed image: %w", err)
+			}
+		}
+
+		for _, change := range diff.Changed {
+			if _, err := fmt.Fprintf(output, "~ %s (%s -> %s)\n", change.Repository, change.From, change.To); err != nil {
+				return fmt.Errorf("writing changed image: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	return writeOutput(output, format, diff, writeTable)
+}
+
+// manifestDiff is the difference between the images of two manifests
+type manifestDiff struct {
+	Added   []string      `json:"added,omitempty"`
+	Removed []string      `json:"removed,omitempty"`
+	Changed []imageChange `json:"changed,omitempty"`
+}
+
+// imageChange is an image whose tag, tag patterns, or digest changed between two manifests
+type imageChange struct {
+	Repository string `json:"repository"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+}
+
+// HasChanges returns true if any images were added, removed, or changed
+func (m manifestDiff) HasChanges() bool {
+	return len(m.Added) > 0 || len(m.Removed) > 0 || len(m.Changed) > 0
+}
+
+// getManifestDiff compares the images of two manifests by their fully qualified repository,
+// regardless of the order they appear in or how their references are written
+func getManifestDiff(from Manifest, to Manifest) manifestDiff {
+	fromVersions := getVersionsByRepository(from.Images)
+	toVersions := getVersionsByRepository(to.Images)
+
+	var diff manifestDiff
+	for repository, versions := range fromVersions {
+		if _, exists := toVersions[repository]; !exists {
+			for _, version := range versions {
+				diff.Removed = append(diff.Removed, repository+version)
+			}
+		}
+	}
+
+	for repository, versions := range toVersions {
+		currentVersions, exists := fromVersions[repository]
+		if !exists {
+			for _, version := range versions {
+				diff.Added = append(diff.Added, repository+version)
+			}
+			continue
+		}
+
+		if len(currentVersions) == 1 && len(versions) == 1 {
+			if currentVersions[0] != versions[0] {
+				diff.Changed = append(diff.Changed, imageChange{
+					Repository: repository,
+					From:       currentVersions[0],
+					To:         versions[0],
+				})
+			}
+			continue
+		}
+
+		// When a repository is listed more than once, there is no way to know
+		// which versions were changed, so they are reported as added and removed.
+		for _, version := range versions {
+			if !contains(currentVersions, version) {
+				diff.Added = append(diff.Added, repository+version)
+			}
+		}
+
+		for _, currentVersion := range currentVersions {
+			if !contains(versions, currentVersion) {
+				diff.Removed = append(diff.Removed, repository+currentVersion)
+			}
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Repository < diff.Changed[j].Repository
+	})
+
+	return diff
+}
+
+func getVersionsByRepository(images []SourceImage) map[string][]string {
+	versionsByRepository := make(map[string][]string)
+	for _, image := range images {
+		path := docker.RegistryPath(docker.RegistryPath(image.String()).Reference())
+		repository := path.Host() + "/" + path.Repository()
+
+		var version string
+		if image.Tag != "" {
+			version = ":" + image.Tag
+		}
+
+		// An image without a tag or digest refers to the latest tag, the same as prune compares it
+		if image.Tag == "" && image.Digest == "" {
+			version = ":latest"
+		}
+
+		if image.Digest != "" {
+			version = version + "@" + image.Digest
+		}
+
+		// The tag patterns are sorted, so that the same patterns in a different order are not a change
+		if len(image.Tags) > 0 {
+			tags := append([]string{}, image.Tags...)
+			sort.Strings(tags)
+			version = ":" + strings.Join(tags, ",")
+		}
+
+		versionsByRepository[repository] = append(versionsByRepository[repository], version)
+	}
+
+	return versionsByRepository
+}
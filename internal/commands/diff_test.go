@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetManifestDiff(t *testing.T) {
+	from := Manifest{
+		Images: []SourceImage{
+			{Repository: "busybox", Tag: "1.30.0"},
+			{Repository: "coreos/prometheus-operator", Host: "quay.io", Tag: "v0.40.0"},
+			{Repository: "jimmidyson/configmap-reload", Tag: "v0.3.0"},
+			{Repository: "nginx", Digest: "sha256:123"},
+		},
+	}
+
+	to := Manifest{
+		Images: []SourceImage{
+			{Repository: "nginx", Host: "docker.io", Digest: "sha256:456"},
+			{Repository: "library/busybox", Host: "docker.io", Tag: "1.30.0"},
+			{Repository: "coreos/prometheus-operator", Host: "quay.io", Tag: "v0.41.0"},
+			{Repository: "coreos/prometheus-config-reloader", Host: "quay.io", Tag: "v0.40.0"},
+		},
+	}
+
+	actual := getManifestDiff(from, to)
+
+	expected := manifestDiff{
+		Added:   []string{"quay.io/coreos/prometheus-config-reloader:v0.40.0"},
+		Removed: []string{"docker.io/jimmidyson/configmap-reload:v0.3.0"},
+		Changed: []imageChange{
+			{Repository: "docker.io/library/nginx", From: "@sha256:123", To: "@sha256:456"},
+			{Repository: "quay.io/coreos/prometheus-operator", From: ":v0.40.0", To: ":v0.41.0"},
+		},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected manifest diff. expected %v actual %v", expected, actual)
+	}
+}
+
+func TestGetManifestDiff_Tags(t *testing.T) {
+	testCases := []struct {
+		from     []string
+		to       []string
+		expected []imageChange
+	}{
+		{
+			from:     []string{"v1.*"},
+			to:       []string{"v2.*"},
+			expected: []imageChange{{Repository: "docker.io/library/busybox", From: ":v1.*", To: ":v2.*"}},
+		},
+		{
+			from:     []string{"v1.*", "v2.*"},
+			to:       []string{"v2.*", "v1.*"},
+			expected: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		from := Manifest{Images: []SourceImage{{Repository: "busybox", Tags: testCase.from}}}
+		to := Manifest{Images: []SourceImage{{Repository: "busybox", Tags: testCase.to}}}
+
+		if actual := getManifestDiff(from, to); !reflect.DeepEqual(actual.Changed, testCase.expected) {
+			t.Errorf("expected changes from %v to %v to be %v, actual %v", testCase.from, testCase.to, testCase.expected, actual.Changed)
+		}
+	}
+}
+
+func TestGetManifestDiff_NoChanges(t *testing.T) {
+	from := Manifest{
+		Images: []SourceImage{
+			{Repository: "busybox", Tag: "1.30.0"},
+			{Repository: "nginx", Tag: "1.19.0"},
+			{Repository: "alpine"},
+		},
+	}
+
+	to := Manifest{
+		Images: []SourceImage{
+			{Repository: "nginx", Tag: "1.19.0"},
+			{Repository: "busybox", Tag: "1.30.0"},
+			{Repository: "library/alpine", Host: "docker.io", Tag: "latest"},
+		},
+	}
+
+	if diff := getManifestDiff(from, to); diff.HasChanges() {
+		t.Errorf("expected no changes, actual %v", diff)
+	}
+}
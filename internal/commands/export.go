@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newExportCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "export",
+		Short: "Export the source images in the manifest to a tarball that can be loaded with docker load",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-concurrent", cmd.Flags().Lookup("max-concurrent")); err != nil {
+				return fmt.Errorf("bind max-concurrent flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("platform", cmd.Flags().Lookup("platform")); err != nil {
+				return fmt.Errorf("bind platform flag: %w", err)
+			}
+
+			if err := bindFilterFlags(cmd); err != nil {
+				return fmt.Errorf("bind filter flags: %w", err)
+			}
+
+			if err := bindProgressFlags(cmd); err != nil {
+				return fmt.Errorf("bind progress flags: %w", err)
+			}
+
+			if err := bindClientFlags(cmd); err != nil {
+				return fmt.Errorf("bind client flags: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runExportCommand(ctx, logger, manifestPath, viper.GetString("output")); err != nil {
+				return fmt.Errorf("export: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "", "The path of the tarball to write the images to (e.g. images.tar)")
+	cmd.MarkFlagRequired("output")
+	cmd.Flags().Int("max-concurrent", 1, "The maximum number of images to pull at the same time")
+	cmd.Flags().String("platform", "", "The platform of the images to export (e.g. linux/arm64). Defaults to the platform of the Docker daemon")
+	addFilterFlags(&cmd)
+	addProgressFlags(&cmd)
+	addClientFlags(&cmd)
+
+	return &cmd
+}
+
+func runExportCommand(ctx context.Context, logger *log.Logger, manifestPath string, outputPath string) error {
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+
+	if len(manifest.Images) == 0 {
+		return errors.New("no images found in the image manifest")
+	}
+
+	if hasImageFilters() {
+		manifest.Images, err = filterImages(manifest.Images, viper.GetStringSlice("include"), viper.GetStringSlice("exclude"))
+		if err != nil {
+			return fmt.Errorf("filter images: %w", err)
+		}
+
+		if len(manifest.Images) == 0 {
+			return errors.New("no images in the image manifest match the include and exclude patterns")
+		}
+	}
+
+	var platforms []docker.Platform
+	if viper.GetString("platform") != "" {
+		platforms, err = getPlatforms([]string{viper.GetString("platform")})
+		if err != nil {
+			return fmt.Errorf("get platforms: %w", err)
+		}
+	}
+
+	summary := newSyncSummary()
+	clientOptions, err := getClientOptions()
+	if err != nil {
+		return fmt.Errorf("get client options: %w", err)
+	}
+
+	clientOptions = append(clientOptions, docker.WithTransferStats(summary.stats))
+	if len(platforms) > 0 {
+		clientOptions = append(clientOptions, docker.WithPlatform(platforms[0]))
+	}
+
+	client, err := docker.NewClient(getClientLogger(logger), clientOptions...)
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	images, err := expandImageTags(ctx, client, manifest.Images)
+	if err != nil {
+		return fmt.Errorf("expand image tags: %w", err)
+	}
+
+	imagesToPull, err := getImagesToPull(ctx, client, images, "source", platforms)
+	if err != nil {
+		return fmt.Errorf("get images to pull: %w", err)
+	}
+
+	err = pullImages(ctx, logger, client, nil, summary, imagesToPull, viper.GetInt("max-concurrent"), nil)
+
+	if err := summary.write(os.Stdout); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("pull images: %w", err)
+	}
+
+	exportImages, err := tagArchiveImages(ctx, client, images)
+	if err != nil {
+		return fmt.Errorf("tag archive images: %w", err)
+	}
+
+	if err := exportImageArchive(ctx, client, exportImages, outputPath); err != nil {
+		return fmt.Errorf("export image archive: %w", err)
+	}
+
+	logger.Printf("[EXPORT] All images have been exported to %s!", outputPath)
+
+	return nil
+}
+
+type imageTagger interface {
+	TagImage(ctx context.Context, source string, target string) error
+}
+
+// tagArchiveImages tags each pulled image as the reference that it is saved as in the archive,
+// and returns the references. Images pinned to a digest are pulled without their tag, and the
+// archive only records the tags of images, so they are tagged before they are saved.
+func tagArchiveImages(ctx context.Context, tagger imageTagger, images []SourceImage) ([]string, error) {
+	var archiveImages []string
+	for _, image := range images {
+		archiveImage := getArchiveReference(image)
+		if archiveImage != image.String() {
+			if err := tagger.TagImage(ctx, image.String(), archiveImage); err != nil {
+				return nil, fmt.Errorf("tag %s: %w", image, err)
+			}
+		}
+
+		archiveImages = append(archiveImages, archiveImage)
+	}
+
+	return archiveImages, nil
+}
+
+type imageSaver interface {
+	SaveImages(ctx context.Context, images []string, output io.Writer) error
+}
+
+// exportImageArchive writes the images to a tarball at the output path.
+// The tarball is staged in the tmp dir and only moved to the output path once
+// every image has been written to it, so that an incomplete tarball is not
+// mistaken for a complete export.
+func exportImageArchive(ctx context.Context, saver imageSaver, images []string, outputPath string) error {
+	staging, err := createStagingFile(outputPath)
+	if err != nil {
+		return fmt.Errorf("create staging file: %w", err)
+	}
+	defer os.Remove(staging.Name())
+
+	if err := saver.SaveImages(ctx, images, staging); err != nil {
+		staging.Close()
+		return fmt.Errorf("save images: %w", err)
+	}
+
+	if err := staging.Close(); err != nil {
+		return fmt.Errorf("close staging file: %w", err)
+	}
+
+	if err := os.Chmod(staging.Name(), 0644); err != nil {
+		return fmt.Errorf("chmod staging file: %w", err)
+	}
+
+	if err := moveStagingFile(staging.Name(), outputPath); err != nil {
+		return fmt.Errorf("move staging file: %w", err)
+	}
+
+	return nil
+}
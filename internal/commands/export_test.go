@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+type fakeImageSaver struct {
+	contents string
+	err      error
+}
+
+func (f fakeImageSaver) SaveImages(ctx context.Context, images []string, output io.Writer) error {
+	if _, err := io.WriteString(output, f.contents); err != nil {
+		return err
+	}
+
+	return f.err
+}
+
+// stagingImageSaver records the path of the file that the images are saved to
+type stagingImageSaver struct {
+	path string
+}
+
+func (s *stagingImageSaver) SaveImages(ctx context.Context, images []string, output io.Writer) error {
+	s.path = output.(*os.File).Name()
+	_, err := io.WriteString(output, "archive")
+	return err
+}
+
+func TestExportImageArchive(t *testing.T) {
+	outputDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	outputPath := filepath.Join(outputDir, "images.tar")
+	if err := exportImageArchive(context.Background(), fakeImageSaver{contents: "archive"}, []string{"busybox:1.32.0"}, outputPath); err != nil {
+		t.Fatal("export image archive:", err)
+	}
+
+	contents, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal("read archive:", err)
+	}
+
+	if string(contents) != "archive" {
+		t.Errorf("expected archive to be written, actual %s", contents)
+	}
+}
+
+func TestExportImageArchive_RemovedOnError(t *testing.T) {
+	outputDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	outputPath := filepath.Join(outputDir, "images.tar")
+	saver := fakeImageSaver{contents: "partial", err: errors.New("connection reset")}
+	if err := exportImageArchive(context.Background(), saver, []string{"busybox:1.32.0"}, outputPath); err == nil {
+		t.Fatal("expected an error when the images could not be saved")
+	}
+
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("expected incomplete archive to be removed, actual %v", err)
+	}
+}
+
+func TestExportImageArchive_StagedInTmpDir(t *testing.T) {
+	outputDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	tmpDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	viper.Set("tmp-dir", tmpDir)
+	defer viper.Set("tmp-dir", "")
+
+	saver := stagingImageSaver{}
+	outputPath := filepath.Join(outputDir, "images.tar")
+	if err := exportImageArchive(context.Background(), &saver, []string{"busybox:1.32.0"}, outputPath); err != nil {
+		t.Fatal("export image archive:", err)
+	}
+
+	if filepath.Dir(saver.path) != tmpDir {
+		t.Errorf("expected the archive to be staged in %s, actual %s", tmpDir, saver.path)
+	}
+
+	if _, err := os.Stat(saver.path); !os.IsNotExist(err) {
+		t.Errorf("expected the staging file to be removed, actual %v", err)
+	}
+
+	contents, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal("read archive:", err)
+	}
+
+	if string(contents) != "archive" {
+		t.Errorf("expected archive to be moved to the output path, actual %s", contents)
+	}
+}
+
+type fakeImageTagger struct {
+	tags map[string]string
+}
+
+func (f *fakeImageTagger) TagImage(ctx context.Context, source string, target string) error {
+	f.tags[source] = target
+	return nil
+}
+
+func TestTagArchiveImages(t *testing.T) {
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Repository: "nginx", Tag: "1.19.0", Digest: "sha256:119"},
+		{Host: "quay.io", Repository: "coreos/flannel", Digest: "sha256:0134"},
+	}
+
+	tagger := &fakeImageTagger{tags: make(map[string]string)}
+	archiveImages, err := tagArchiveImages(context.Background(), tagger, images)
+	if err != nil {
+		t.Fatal("tag archive images:", err)
+	}
+
+	expectedImages := []string{"busybox:1.32.0", "nginx:1.19.0", "quay.io/coreos/flannel:0134"}
+	if !reflect.DeepEqual(archiveImages, expectedImages) {
+		t.Errorf("expected archive images to be %v, actual %v", expectedImages, archiveImages)
+	}
+
+	// Only the images pinned to a digest are pulled without the tag that they are saved as
+	expectedTags := map[string]string{
+		"nginx:1.19.0@sha256:119":            "nginx:1.19.0",
+		"quay.io/coreos/flannel@sha256:0134": "quay.io/coreos/flannel:0134",
+	}
+	if !reflect.DeepEqual(tagger.tags, expectedTags) {
+		t.Errorf("expected tags to be %v, actual %v", expectedTags, tagger.tags)
+	}
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var filterFlags = []string{"include", "exclude", "require-match"}
+
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("include", []string{}, "Only the images with a source matching one of the patterns (e.g. quay.io/coreos/*)")
+	cmd.Flags().StringSlice("exclude", []string{}, "Skip the images with a source matching one of the patterns (e.g. busybox:*)")
+	cmd.Flags().Bool("require-match", false, "Return an error when the include and exclude patterns match no images")
+
+	cmd.RegisterFlagCompletionFunc("include", completeImageNames)
+	cmd.RegisterFlagCompletionFunc("exclude", completeImageNames)
+}
+
+func bindFilterFlags(cmd *cobra.Command) error {
+	for _, flag := range filterFlags {
+		if err := viper.BindPFlag(flag, cmd.Flags().Lookup(flag)); err != nil {
+			return fmt.Errorf("bind %s flag: %w", flag, err)
+		}
+	}
+
+	return nil
+}
+
+// hasImageFilters returns true when the images are filtered by include or exclude patterns
+func hasImageFilters() bool {
+	return len(viper.GetStringSlice("include")) > 0 || len(viper.GetStringSlice("exclude")) > 0
+}
+
+// filterImages returns the images with a source that matches any of the include patterns,
+// or every image when there are no include patterns, and does not match any of the exclude patterns
+func filterImages(images []SourceImage, include []string, exclude []string) ([]SourceImage, error) {
+	var filteredImages []SourceImage
+	for _, image := range images {
+		included := len(include) == 0
+		if !included {
+			matches, err := matchesAnyPattern(image.String(), include)
+			if err != nil {
+				return nil, fmt.Errorf("match include: %w", err)
+			}
+
+			included = matches
+		}
+
+		excluded, err := matchesAnyPattern(image.String(), exclude)
+		if err != nil {
+			return nil, fmt.Errorf("match exclude: %w", err)
+		}
+
+		if included && !excluded {
+			filteredImages = append(filteredImages, image)
+		}
+	}
+
+	return filteredImages, nil
+}
+
+func matchesAnyPattern(source string, patterns []string) (bool, error) {
+	for _, pattern := range patterns {
+		matches, err := path.Match(pattern, source)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+
+		if matches {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func TestFilterImages(t *testing.T) {
+	images := []SourceImage{
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-config-reloader", Tag: "v0.40.0"},
+		{Repository: "jimmidyson/configmap-reload", Tag: "v0.3.0"},
+		{Repository: "busybox", Tag: "1.32.0"},
+	}
+
+	testCases := []struct {
+		name           string
+		include        []string
+		exclude        []string
+		expectedImages []string
+	}{
+		{
+			name:           "no filters",
+			expectedImages: []string{"quay.io/coreos/prometheus-operator:v0.40.0", "quay.io/coreos/prometheus-config-reloader:v0.40.0", "jimmidyson/configmap-reload:v0.3.0", "busybox:1.32.0"},
+		},
+		{
+			name:           "include only",
+			include:        []string{"quay.io/coreos/*", "busybox:*"},
+			expectedImages: []string{"quay.io/coreos/prometheus-operator:v0.40.0", "quay.io/coreos/prometheus-config-reloader:v0.40.0", "busybox:1.32.0"},
+		},
+		{
+			name:           "exclude only",
+			exclude:        []string{"quay.io/coreos/prometheus-*"},
+			expectedImages: []string{"jimmidyson/configmap-reload:v0.3.0", "busybox:1.32.0"},
+		},
+		{
+			name:           "include and exclude",
+			include:        []string{"quay.io/coreos/*", "jimmidyson/*"},
+			exclude:        []string{"quay.io/coreos/*-reloader:*", "jimmidyson/*"},
+			expectedImages: []string{"quay.io/coreos/prometheus-operator:v0.40.0"},
+		},
+		{
+			name:    "include and exclude of the same image",
+			include: []string{"busybox:1.32.0"},
+			exclude: []string{"busybox:*"},
+		},
+		{
+			name:    "no matches",
+			include: []string{"gcr.io/*"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		filteredImages, err := filterImages(images, testCase.include, testCase.exclude)
+		if err != nil {
+			t.Fatal("filter images:", err)
+		}
+
+		var actualImages []string
+		for _, image := range filteredImages {
+			actualImages = append(actualImages, image.String())
+		}
+
+		if !reflect.DeepEqual(actualImages, testCase.expectedImages) {
+			t.Errorf("expected %s to return %v, actual %v", testCase.name, testCase.expectedImages, actualImages)
+		}
+	}
+
+	if _, err := filterImages(images, []string{"["}, nil); err == nil {
+		t.Error("expected an invalid pattern to return an error")
+	}
+}
+
+func TestRunPushCommand_NoFilterMatches(t *testing.T) {
+	manifestPath := writeTestManifest(t, dryRunManifest)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+	defer withoutDocker(t)()
+
+	viper.Set("include", []string{"gcr.io/*"})
+	defer viper.Set("include", []string{})
+
+	var output bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&output)
+
+	if err := runPushCommand(context.Background(), logger, manifestPath); err != nil {
+		t.Fatal("expected no matches to not return an error:", err)
+	}
+
+	if !strings.Contains(output.String(), "No images in the image manifest match") {
+		t.Errorf("expected a warning when no images match, actual %s", output.String())
+	}
+
+	viper.Set("require-match", true)
+	defer viper.Set("require-match", false)
+
+	if err := runPushCommand(context.Background(), logger, manifestPath); err == nil {
+		t.Error("expected no matches to return an error when a match is required")
+	}
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/plexsystems/sinker/internal/docker"
+)
+
+// imageFormatter writes the target images of the manifest in the format used by another tool
+type imageFormatter func(output io.Writer, images []SourceImage) error
+
+// imageFormatters are the formats of the list command, by the name used with the format flag
+var imageFormatters = map[string]imageFormatter{
+	"helm":      writeHelmValues,
+	"kustomize": writeKustomizeImages,
+}
+
+func getImageFormatter(format string) (imageFormatter, error) {
+	formatter, exists := imageFormatters[format]
+	if !exists {
+		return nil, fmt.Errorf("unknown format %q (available formats: %s)", format, strings.Join(getImageFormats(), ", "))
+	}
+
+	return formatter, nil
+}
+
+func getImageFormats() []string {
+	var formats []string
+	for format := range imageFormatters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// helmValues is an image map in a Helm values file
+type helmValues struct {
+	Images map[string]helmImage `json:"images"`
+}
+
+type helmImage struct {
+	Registry   string `json:"registry,omitempty"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag,omitempty"`
+}
+
+// writeHelmValues writes the target images as a Helm values image map, keyed by the name
+// of each image. Images that share a name are keyed by their name and tag instead.
+func writeHelmValues(output io.Writer, images []SourceImage) error {
+	names := make(map[string]int)
+	for _, image := range images {
+		names[path.Base(image.Repository)]++
+	}
+
+	values := helmValues{
+		Images: make(map[string]helmImage),
+	}
+
+	for _, image := range images {
+		target := docker.RegistryPath(image.TargetImage())
+
+		key := path.Base(image.Repository)
+		if names[key] > 1 && target.Tag() != "" {
+			key = key + "-" + target.Tag()
+		}
+
+		values.Images[key] = helmImage{
+			Registry:   target.Host(),
+			Repository: target.Repository(),
+			Tag:        target.Tag(),
+		}
+	}
+
+	return writeFormattedYAML(output, values)
+}
+
+// kustomizeImages is the images list of a kustomization
+type kustomizeImages struct {
+	Images []kustomizeImage `json:"images"`
+}
+
+type kustomizeImage struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName"`
+	NewTag  string `json:"newTag,omitempty"`
+}
+
+// writeKustomizeImages writes the target images as the images list of a kustomization, which
+// replaces each source image with its target image. Kustomize replaces images by their name
+// only, so images that share a name are listed once and keep the tag of the source image.
+func writeKustomizeImages(output io.Writer, images []SourceImage) error {
+	kustomization := kustomizeImages{
+		Images: []kustomizeImage{},
+	}
+
+	indexes := make(map[string]int)
+	for _, image := range images {
+		target := docker.RegistryPath(image.TargetImage())
+		name := strings.TrimLeft(image.Host+"/"+image.Repository, "/")
+
+		index, exists := indexes[name]
+		if exists {
+			kustomization.Images[index].NewTag = ""
+			continue
+		}
+
+		currentImage := kustomizeImage{
+			Name:    name,
+			NewName: strings.TrimLeft(target.Host()+"/"+target.Repository(), "/"),
+			NewTag:  target.Tag(),
+		}
+
+		indexes[name] = len(kustomization.Images)
+		kustomization.Images = append(kustomization.Images, currentImage)
+	}
+
+	return writeFormattedYAML(output, kustomization)
+}
+
+func writeFormattedYAML(output io.Writer, result interface{}) error {
+	contents, err := yaml.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("marshal yaml: %w", err)
+	}
+
+	if _, err := output.Write(contents); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageFormatters_Golden(t *testing.T) {
+	images := []SourceImage{
+		{
+			Host:       "quay.io",
+			Repository: "coreos/prometheus-operator",
+			Tag:        "v0.40.0",
+			Target:     Target{Host: "mycompany.com", Repository: "myrepo"},
+		},
+		{
+			Repository: "nginx",
+			Digest:     "sha256:123",
+			Target:     Target{Host: "mycompany.com", Repository: "myrepo"},
+		},
+		{
+			Repository: "busybox",
+			Tag:        "1.31.0",
+			Target:     Target{Host: "localhost:5000"},
+		},
+		{
+			Repository: "busybox",
+			Tag:        "1.32.0",
+			Target:     Target{Host: "localhost:5000"},
+		},
+	}
+
+	for _, format := range getImageFormats() {
+		formatter, err := getImageFormatter(format)
+		if err != nil {
+			t.Fatal("get formatter:", err)
+		}
+
+		var output bytes.Buffer
+		if err := formatter(&output, images); err != nil {
+			t.Fatal("write format:", err)
+		}
+
+		goldenPath := filepath.Join("testdata", "list."+format+".golden")
+		if *update {
+			if err := ioutil.WriteFile(goldenPath, output.Bytes(), 0644); err != nil {
+				t.Fatal("write golden file:", err)
+			}
+		}
+
+		expected, err := ioutil.ReadFile(goldenPath)
+		if err != nil {
+			t.Fatal("read golden file:", err)
+		}
+
+		if output.String() != string(expected) {
+			t.Errorf("expected %s output to be %s, actual %s", format, expected, output.String())
+		}
+	}
+}
+
+func TestGetImageFormatter_UnknownFormat(t *testing.T) {
+	if _, err := getImageFormatter("xml"); err == nil {
+		t.Error("expected unknown format to return an error")
+	}
+}
@@ -0,0 +1,257 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newImportCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "import <file>",
+		Short: "Load the images in a tarball created with export (or docker save) and push them to the target repository",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("max-concurrent", cmd.Flags().Lookup("max-concurrent")); err != nil {
+				return fmt.Errorf("bind max-concurrent flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
+				return fmt.Errorf("bind target flag: %w", err)
+			}
+
+			if err := bindProgressFlags(cmd); err != nil {
+				return fmt.Errorf("bind progress flags: %w", err)
+			}
+
+			if err := bindClientFlags(cmd); err != nil {
+				return fmt.Errorf("bind client flags: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runImportCommand(ctx, logger, manifestPath, args[0]); err != nil {
+				return fmt.Errorf("import: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Int("max-concurrent", 1, "The maximum number of images to push at the same time")
+	cmd.Flags().String("target", "", "The target (e.g. host/repository) to push every image to, instead of the target in the manifest")
+	addProgressFlags(&cmd)
+	addClientFlags(&cmd)
+
+	return &cmd
+}
+
+func runImportCommand(ctx context.Context, logger *log.Logger, manifestPath string, archivePath string) error {
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+
+	if len(manifest.Images) == 0 {
+		return errors.New("no images found in the image manifest")
+	}
+
+	if viper.GetString("target") != "" {
+		manifest = manifest.WithTarget(viper.GetString("target"))
+	}
+
+	archiveTags, err := getImageArchiveTags(archivePath)
+	if err != nil {
+		return fmt.Errorf("get image archive tags: %w", err)
+	}
+
+	importImages, unmatchedTags, err := getImportImages(archiveTags, manifest.Images)
+	if err != nil {
+		return fmt.Errorf("get import images: %w", err)
+	}
+
+	for _, tag := range unmatchedTags {
+		newImageLogEntry(logger, "import", tag).Warnf("[IMPORT] Image %s in the archive is not in the image manifest, skipping", tag)
+	}
+
+	if len(importImages) == 0 {
+		return errors.New("no images in the archive are in the image manifest")
+	}
+
+	summary := newSyncSummary()
+	clientOptions, err := getClientOptions()
+	if err != nil {
+		return fmt.Errorf("get client options: %w", err)
+	}
+
+	clientOptions = append(clientOptions, docker.WithTransferStats(summary.stats))
+	client, err := docker.NewClient(getClientLogger(logger), clientOptions...)
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer archive.Close()
+
+	logger.Printf("[IMPORT] Loading images from %s ...", archivePath)
+	if err := client.LoadImages(ctx, archive); err != nil {
+		return fmt.Errorf("load images: %w", err)
+	}
+
+	err = runConcurrently(viper.GetInt("max-concurrent"), len(importImages), func(index int) error {
+		importImage := importImages[index]
+		if skipAfterCancel(ctx, summary, importImage.Image.TargetImage()) {
+			return nil
+		}
+
+		err := pushArchiveImage(ctx, client, importImage)
+		summary.record(importImage.Image.TargetImage(), err)
+		if err != nil {
+			newImageLogEntry(logger, "import", importImage.Image.TargetImage()).Errorf("[IMPORT] %s failed: %s", importImage.Image.TargetImage(), err)
+			return fmt.Errorf("%s: %w", importImage.Image.TargetImage(), err)
+		}
+
+		newImageLogEntry(logger, "import", importImage.Image.TargetImage()).Printf("[IMPORT] %s complete.", importImage.Image.TargetImage())
+
+		return nil
+	})
+	err = checkCancelled(ctx, summary, err)
+
+	if err := summary.write(os.Stdout); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("push images: %w", err)
+	}
+
+	logger.Printf("[IMPORT] All images have been imported!")
+
+	return nil
+}
+
+func getImageArchiveTags(archivePath string) ([]string, error) {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer archive.Close()
+
+	return docker.GetImageArchiveTags(archive)
+}
+
+// importImage is an image loaded from an archive and the image in the manifest that it is pushed as
+type importImage struct {
+	ArchiveTag string
+	Image      SourceImage
+}
+
+// getImportImages returns the image in the manifest of each tag in the archive, along with the tags
+// that are not in the manifest. Tags are matched by their fully qualified reference, so that
+// busybox:1.32.0 matches docker.io/library/busybox:1.32.0, and images with tags patterns
+// match every tag of their repository that matches one of the patterns.
+func getImportImages(archiveTags []string, images []SourceImage) ([]importImage, []string, error) {
+	var importImages []importImage
+	var unmatchedTags []string
+	for _, archiveTag := range archiveTags {
+		image, matched, err := findArchiveImage(archiveTag, images)
+		if err != nil {
+			return nil, nil, fmt.Errorf("find image of %s: %w", archiveTag, err)
+		}
+
+		if !matched {
+			unmatchedTags = append(unmatchedTags, archiveTag)
+			continue
+		}
+
+		importImages = append(importImages, importImage{ArchiveTag: archiveTag, Image: image})
+	}
+
+	return importImages, unmatchedTags, nil
+}
+
+func findArchiveImage(archiveTag string, images []SourceImage) (SourceImage, bool, error) {
+	archivePath := docker.RegistryPath(archiveTag)
+	for _, image := range images {
+		if len(image.Tags) == 0 {
+			if docker.RegistryPath(getArchiveReference(image)).Reference() == archivePath.Reference() {
+				return image, true, nil
+			}
+
+			continue
+		}
+
+		if getRepositoryReference(image.String()) != getRepositoryReference(archiveTag) {
+			continue
+		}
+
+		for _, pattern := range image.Tags {
+			matched, err := path.Match(pattern, archivePath.Tag())
+			if err != nil {
+				return SourceImage{}, false, fmt.Errorf("match pattern %s: %w", pattern, err)
+			}
+
+			if matched {
+				image.Tag = archivePath.Tag()
+				image.Tags = nil
+
+				return image, true, nil
+			}
+		}
+	}
+
+	return SourceImage{}, false, nil
+}
+
+// getArchiveReference returns the reference that the image is saved as in an archive. Archives only
+// record the tags of images, so the digest of an image is not part of its reference, and images that
+// are pinned to only a digest are tagged with the digest, without its algorithm, as they are in the target.
+func getArchiveReference(image SourceImage) string {
+	tag := image.Tag
+	if tag == "" {
+		tag = strings.ReplaceAll(image.Digest, "sha256:", "")
+	}
+
+	if tag == "" {
+		tag = "latest"
+	}
+
+	return SourceImage{Host: image.Host, Repository: image.Repository, Tag: tag}.String()
+}
+
+// getRepositoryReference returns the fully qualified repository of the image, without its tag or digest
+func getRepositoryReference(image string) string {
+	imagePath := docker.RegistryPath(image)
+	repository := strings.TrimLeft(imagePath.Host()+"/"+imagePath.Repository(), "/")
+
+	return docker.RegistryPath(repository).Reference()
+}
+
+func pushArchiveImage(ctx context.Context, client docker.Client, image importImage) error {
+	targetAuth, err := getEncodedTargetAuth(ctx, image.Image.Target)
+	if err != nil {
+		return fmt.Errorf("get target auth: %w", err)
+	}
+
+	if err := client.DockerClient.ImageTag(ctx, image.ArchiveTag, image.Image.TargetImage()); err != nil {
+		return fmt.Errorf("tagging image: %w", err)
+	}
+
+	if err := client.PushImageAndWait(ctx, image.Image.TargetImage(), targetAuth); err != nil {
+		return fmt.Errorf("pushing image to target: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestGetImportImages(t *testing.T) {
+	target := Target{Host: "mycompany.com", Repository: "myrepo"}
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0", Target: target},
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.13", Target: target},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tags: []string{"v0.4*"}, Target: target},
+		{Repository: "jimmidyson/configmap-reload", Tag: "v0.3.0", Target: Target{Host: "other.com"}},
+		{Repository: "nginx", Tag: "1.19.0", Digest: "sha256:119", Target: target},
+		{Host: "quay.io", Repository: "coreos/flannel", Digest: "sha256:0134", Target: target},
+	}
+
+	testCases := []struct {
+		archiveTag     string
+		expectedTarget string
+	}{
+		{archiveTag: "busybox:1.32.0", expectedTarget: "mycompany.com/myrepo/busybox:1.32.0"},
+		{archiveTag: "docker.io/library/busybox:1.32.0", expectedTarget: "mycompany.com/myrepo/busybox:1.32.0"},
+		{archiveTag: "quay.io/coreos/etcd:v3.4.13", expectedTarget: "mycompany.com/myrepo/coreos/etcd:v3.4.13"},
+		{archiveTag: "quay.io/coreos/prometheus-operator:v0.40.0", expectedTarget: "mycompany.com/myrepo/coreos/prometheus-operator:v0.40.0"},
+		{archiveTag: "jimmidyson/configmap-reload:v0.3.0", expectedTarget: "other.com/jimmidyson/configmap-reload:v0.3.0"},
+		{archiveTag: "nginx:1.19.0", expectedTarget: "mycompany.com/myrepo/nginx:1.19.0"},
+		{archiveTag: "quay.io/coreos/flannel:0134", expectedTarget: "mycompany.com/myrepo/coreos/flannel:0134"},
+		{archiveTag: "busybox:1.31.0"},
+		{archiveTag: "quay.io/coreos/prometheus-operator:v0.39.0"},
+		{archiveTag: "coreos/etcd:v3.4.13"},
+	}
+
+	for _, testCase := range testCases {
+		importImages, unmatchedTags, err := getImportImages([]string{testCase.archiveTag}, images)
+		if err != nil {
+			t.Fatal("get import images:", err)
+		}
+
+		if testCase.expectedTarget == "" {
+			if len(importImages) != 0 || len(unmatchedTags) != 1 {
+				t.Errorf("expected %s to not match an image, actual %v", testCase.archiveTag, importImages)
+			}
+			continue
+		}
+
+		if len(importImages) != 1 {
+			t.Fatalf("expected %s to match an image, actual %v", testCase.archiveTag, unmatchedTags)
+		}
+
+		if importImages[0].Image.TargetImage() != testCase.expectedTarget {
+			t.Errorf("expected %s to be pushed as %s, actual %s", testCase.archiveTag, testCase.expectedTarget, importImages[0].Image.TargetImage())
+		}
+
+		if importImages[0].ArchiveTag != testCase.archiveTag {
+			t.Errorf("expected archive tag to be %s, actual %s", testCase.archiveTag, importImages[0].ArchiveTag)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newInspectCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "inspect <image>",
+		Short: "Print the details of the manifest of an image in its registry",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			if err := bindClientFlags(cmd); err != nil {
+				return fmt.Errorf("bind client flags: %w", err)
+			}
+
+			if err := validateOutputFormat(viper.GetString("output")); err != nil {
+				return fmt.Errorf("validate output format: %w", err)
+			}
+
+			clientOptions, err := getClientOptions()
+			if err != nil {
+				return fmt.Errorf("get client options: %w", err)
+			}
+
+			client, err := docker.NewClient(logger, clientOptions...)
+			if err != nil {
+				return fmt.Errorf("new client: %w", err)
+			}
+
+			if err := runInspectCommand(ctx, client, os.Stdout, args[0], viper.GetString("output")); err != nil {
+				return fmt.Errorf("inspect: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	addOutputFlag(&cmd)
+	addClientFlags(&cmd)
+
+	return &cmd
+}
+
+type imageInspector interface {
+	InspectImageAtRemote(ctx context.Context, image string, auth string) (docker.ImageDetails, error)
+}
+
+// inspectResult is the JSON and YAML output of the inspect command
+type inspectResult struct {
+	Image     string            `json:"image"`
+	Digest    string            `json:"digest"`
+	MediaType string            `json:"mediaType"`
+	Layers    int               `json:"layers"`
+	Size      int64             `json:"size"`
+	Platforms []inspectPlatform `json:"platforms,omitempty"`
+}
+
+// inspectPlatform is the image of a platform of a manifest list in the output of the inspect command
+type inspectPlatform struct {
+	Platform string `json:"platform"`
+	Digest   string `json:"digest"`
+	Layers   int    `json:"layers"`
+	Size     int64  `json:"size"`
+}
+
+// runInspectCommand writes the details of the image, using the credentials found for its registry host.
+// Images without a tag or digest are the latest tag, as they are when pulled.
+func runInspectCommand(ctx context.Context, inspector imageInspector, output io.Writer, image string, format string) error {
+	auth, err := getEncodedAuth(ctx, Auth{}, docker.RegistryPath(image).Host())
+	if err != nil {
+		return fmt.Errorf("get auth: %w", err)
+	}
+
+	details, err := inspector.InspectImageAtRemote(ctx, image, auth)
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", image, err)
+	}
+
+	result := inspectResult{
+		Image:     image,
+		Digest:    details.Digest,
+		MediaType: details.MediaType,
+		Layers:    details.Layers,
+		Size:      details.Size,
+	}
+
+	for _, platform := range details.Platforms {
+		platformName := platform.Platform.String()
+		if platform.Platform.OS == "" {
+			platformName = "unknown"
+		}
+
+		result.Platforms = append(result.Platforms, inspectPlatform{
+			Platform: platformName,
+			Digest:   platform.Digest,
+			Layers:   platform.Layers,
+			Size:     platform.Size,
+		})
+	}
+
+	writeTable := func(output io.Writer) error {
+		if _, err := fmt.Fprintf(output, "Image: %s\nDigest: %s\nMedia type: %s\nLayers: %d\nSize: %s\n", result.Image, result.Digest, result.MediaType, result.Layers, formatSize(result.Size)); err != nil {
+			return fmt.Errorf("writing image: %w", err)
+		}
+
+		if len(result.Platforms) == 0 {
+			return nil
+		}
+
+		if _, err := fmt.Fprintln(output, "Platforms:"); err != nil {
+			return fmt.Errorf("writing platforms: %w", err)
+		}
+
+		for _, platform := range result.Platforms {
+			if _, err := fmt.Fprintf(output, "  %s %s (%d layers, %s)\n", platform.Platform, platform.Digest, platform.Layers, formatSize(platform.Size)); err != nil {
+				return fmt.Errorf("writing platform: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	return writeOutput(output, format, result, writeTable)
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+)
+
+type fakeImageInspector struct {
+	details docker.ImageDetails
+}
+
+func (f fakeImageInspector) InspectImageAtRemote(ctx context.Context, image string, auth string) (docker.ImageDetails, error) {
+	return f.details, nil
+}
+
+func TestRunInspectCommand(t *testing.T) {
+	image := docker.ImageDetails{
+		Digest:    "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		MediaType: "application/vnd.docker.distribution.manifest.v2+json",
+		Layers:    3,
+		Size:      2500000,
+	}
+
+	manifestList := docker.ImageDetails{
+		Digest:    "sha256:2222222222222222222222222222222222222222222222222222222222222222",
+		MediaType: "application/vnd.docker.distribution.manifest.list.v2+json",
+		Layers:    4,
+		Size:      4000000,
+		Platforms: []docker.PlatformDetails{
+			{Platform: docker.Platform{OS: "linux", Architecture: "amd64"}, Digest: "sha256:3333333333333333333333333333333333333333333333333333333333333333", Layers: 2, Size: 2000000},
+			{Platform: docker.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}, Digest: "sha256:4444444444444444444444444444444444444444444444444444444444444444", Layers: 2, Size: 2000000},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		details        docker.ImageDetails
+		format         string
+		expectedOutput string
+	}{
+		{
+			name:    "image table",
+			details: image,
+			format:  tableOutput,
+			expectedOutput: `Image: mycompany.com/app:v1.0.0
+Digest: sha256:1111111111111111111111111111111111111111111111111111111111111111
+Media type: application/vnd.docker.distribution.manifest.v2+json
+Layers: 3
+Size: 2.5 MB
+`,
+		},
+		{
+			name:    "manifest list table",
+			details: manifestList,
+			format:  tableOutput,
+			expectedOutput: `Image: mycompany.com/app:v1.0.0
+Digest: sha256:2222222222222222222222222222222222222222222222222222222222222222
+Media type: application/vnd.docker.distribution.manifest.list.v2+json
+Layers: 4
+Size: 4.0 MB
+Platforms:
+  linux/amd64 sha256:3333333333333333333333333333333333333333333333333333333333333333 (2 layers, 2.0 MB)
+  linux/arm64/v8 sha256:4444444444444444444444444444444444444444444444444444444444444444 (2 layers, 2.0 MB)
+`,
+		},
+		{
+			name:    "image json",
+			details: image,
+			format:  jsonOutput,
+			expectedOutput: `{
+  "image": "mycompany.com/app:v1.0.0",
+  "digest": "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+  "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+  "layers": 3,
+  "size": 2500000
+}
+`,
+		},
+		{
+			name:    "manifest list json",
+			details: manifestList,
+			format:  jsonOutput,
+			expectedOutput: `{
+  "image": "mycompany.com/app:v1.0.0",
+  "digest": "sha256:2222222222222222222222222222222222222222222222222222222222222222",
+  "mediaType": "application/vnd.docker.distribution.manifest.list.v2+json",
+  "layers": 4,
+  "size": 4000000,
+  "platforms": [
+    {
+      "platform": "linux/amd64",
+      "digest": "sha256:3333333333333333333333333333333333333333333333333333333333333333",
+      "layers": 2,
+      "size": 2000000
+    },
+    {
+      "platform": "linux/arm64/v8",
+      "digest": "sha256:4444444444444444444444444444444444444444444444444444444444444444",
+      "layers": 2,
+      "size": 2000000
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			if err := runInspectCommand(context.Background(), fakeImageInspector{details: testCase.details}, &output, "mycompany.com/app:v1.0.0", testCase.format); err != nil {
+				t.Fatal("run inspect command:", err)
+			}
+
+			if output.String() != testCase.expectedOutput {
+				t.Errorf("expected output %s, actual %s", testCase.expectedOutput, output.String())
+			}
+		})
+	}
+}
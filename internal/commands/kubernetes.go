@@ -13,6 +13,7 @@ import (
 
 	promv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	kubeyaml "github.com/ghodss/yaml"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -178,6 +179,24 @@ func getImagesFromYamlFile(yamlFile []byte) ([]string, error) {
 		return images, nil
 	}
 
+	if typeMeta.Kind == "Pod" {
+		var pod corev1.Pod
+		if err := kubeyaml.Unmarshal(yamlFile, &pod); err != nil {
+			return nil, fmt.Errorf("unmarshal pod: %w", err)
+		}
+
+		return getImagesFromPodSpec(pod.Spec), nil
+	}
+
+	if typeMeta.Kind == "CronJob" {
+		var cronJob batchv1beta1.CronJob
+		if err := kubeyaml.Unmarshal(yamlFile, &cronJob); err != nil {
+			return nil, fmt.Errorf("unmarshal cronjob: %w", err)
+		}
+
+		return getImagesFromPodSpec(cronJob.Spec.JobTemplate.Spec.Template.Spec), nil
+	}
+
 	type BaseSpec struct {
 		Template corev1.PodTemplateSpec `json:"template" protobuf:"bytes,3,opt,name=template"`
 	}
@@ -191,12 +210,19 @@ func getImagesFromYamlFile(yamlFile []byte) ([]string, error) {
 		return []string{}, nil
 	}
 
-	images = append(images, getImagesFromContainers(contents.Spec.Template.Spec.InitContainers)...)
-	images = append(images, getImagesFromContainers(contents.Spec.Template.Spec.Containers)...)
+	images = append(images, getImagesFromPodSpec(contents.Spec.Template.Spec)...)
 
 	return images, nil
 }
 
+func getImagesFromPodSpec(podSpec corev1.PodSpec) []string {
+	var images []string
+	images = append(images, getImagesFromContainers(podSpec.InitContainers)...)
+	images = append(images, getImagesFromContainers(podSpec.Containers)...)
+
+	return images
+}
+
 func getPrometheusImages(yamlFile []byte) ([]string, error) {
 	var images []string
 	var prometheus promv1.Prometheus
@@ -1,6 +1,10 @@
 package commands
 
-import "testing"
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 func TestGetSourceHostFromRepository(t *testing.T) {
 	testCases := []struct {
@@ -33,3 +37,28 @@ func TestGetSourceHostFromRepository(t *testing.T) {
 		}
 	}
 }
+
+func TestGetImagesFromKubernetesManifests(t *testing.T) {
+	images, err := getImagesFromKubernetesManifests(filepath.Join("testdata", "kubernetes"), Target{Host: "mycompany.com", Repository: "myrepo"})
+	if err != nil {
+		t.Fatal("get images from kubernetes manifests:", err)
+	}
+
+	expected := []string{
+		"busybox:1.32.0",
+		"alpine:3.12.0",
+		"alpine:3.11.0",
+		"quay.io/coreos/etcd:v3.4.13",
+		"nginx:1.19.0",
+		"postgres:13.0",
+	}
+
+	var actual []string
+	for _, image := range images {
+		actual = append(actual, image.String())
+	}
+
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected images to be %v, actual %v", expected, actual)
+	}
+}
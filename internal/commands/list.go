@@ -1,14 +1,22 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-func newListCommand() *cobra.Command {
+func newListCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 	cmd := cobra.Command{
 		Use:       "list <source|target>",
 		Short:     "List the images found in the image manifest",
@@ -20,13 +28,29 @@ func newListCommand() *cobra.Command {
 				return fmt.Errorf("bind output flag: %w", err)
 			}
 
+			if err := viper.BindPFlag("output-file", cmd.Flags().Lookup("output-file")); err != nil {
+				return fmt.Errorf("bind output-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
+				return fmt.Errorf("bind target flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("sizes", cmd.Flags().Lookup("sizes")); err != nil {
+				return fmt.Errorf("bind sizes flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("format", cmd.Flags().Lookup("format")); err != nil {
+				return fmt.Errorf("bind format flag: %w", err)
+			}
+
 			var location string
 			if len(args) > 0 {
 				location = args[0]
 			}
 
 			manifestPath := viper.GetString("manifest")
-			if err := runListCommand(location, manifestPath); err != nil {
+			if err := runListCommand(ctx, logger, location, manifestPath); err != nil {
 				return fmt.Errorf("list: %w", err)
 			}
 
@@ -34,47 +58,268 @@ func newListCommand() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringP("output", "o", "", "Output the images in the manifest to a file")
+	addOutputFlag(&cmd)
+	cmd.Flags().String("output-file", "", "Output the images in the manifest to a file")
+	cmd.Flags().Bool("target", false, "Print the resolved target image alongside each source image")
+	cmd.Flags().Bool("sizes", false, "Print the compressed size of each source image and the total size, as found in the source registry")
+	cmd.Flags().String("format", "", fmt.Sprintf("Print the target images in the format used by another tool (%s), instead of the output format", strings.Join(getImageFormats(), ", ")))
 
 	return &cmd
 }
 
-func runListCommand(location string, manifestPath string) error {
+// listImage is an image in the manifest as it appears in the JSON and YAML output of the list command
+type listImage struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Tag    string `json:"tag,omitempty"`
+	Digest string `json:"digest,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// listSizes is the JSON and YAML output of the list command when sizes are listed
+type listSizes struct {
+	Images    []listImage `json:"images"`
+	TotalSize int64       `json:"totalSize"`
+}
+
+// imageSizes are the sizes of each image in the manifest, in bytes. The total size
+// only includes layers that are shared between images once.
+type imageSizes struct {
+	Images []int64
+	Total  int64
+}
+
+func runListCommand(ctx context.Context, logger *log.Logger, location string, manifestPath string) error {
+	outputFormat, outputFile := getListOutput(logger)
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return fmt.Errorf("validate output: %w", err)
+	}
+
+	var formatter imageFormatter
+	if viper.GetString("format") != "" {
+		var err error
+		formatter, err = getImageFormatter(viper.GetString("format"))
+		if err != nil {
+			return fmt.Errorf("get formatter: %w", err)
+		}
+
+		if viper.GetBool("sizes") {
+			return errors.New("the format and sizes flags cannot be used together")
+		}
+	}
+
 	manifest, err := GetManifest(manifestPath)
 	if err != nil {
 		return fmt.Errorf("get manifest: %w", err)
 	}
 
-	var listImages []string
-	for _, image := range manifest.Images {
-		if location == "target" {
-			listImages = append(listImages, image.TargetImage())
-		} else {
-			listImages = append(listImages, image.String())
+	output := io.Writer(os.Stdout)
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("creating file: %w", err)
 		}
+		defer f.Close()
+
+		output = f
 	}
 
-	if viper.GetString("output") == "" {
-		for _, image := range listImages {
-			fmt.Println(image)
+	if formatter != nil {
+		if err := formatter(output, manifest.Images); err != nil {
+			return fmt.Errorf("write %s format: %w", viper.GetString("format"), err)
 		}
+
 		return nil
 	}
 
-	f, err := os.Create(viper.GetString("output"))
-	if err != nil {
-		return fmt.Errorf("creating file: %w", err)
+	if viper.GetBool("target") {
+		location = "mapping"
 	}
 
-	for _, value := range listImages {
-		if _, err := fmt.Fprintln(f, value); err != nil {
-			return fmt.Errorf("writing image to file: %w", err)
+	var sizes *imageSizes
+	if viper.GetBool("sizes") {
+		client, err := docker.NewClient(logger)
+		if err != nil {
+			return fmt.Errorf("new client: %w", err)
+		}
+
+		sizes, err = getImageSizes(ctx, client, manifest.Images)
+		if err != nil {
+			return fmt.Errorf("get image sizes: %w", err)
 		}
 	}
 
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("close: %w", err)
+	if err := writeImageList(output, manifest.Images, sizes, location, outputFormat); err != nil {
+		return fmt.Errorf("write image list: %w", err)
 	}
 
 	return nil
 }
+
+// getListOutput returns the output format and the path of the file that the images are written to. The output
+// flag of the list command used to be the path of the file, so a value that is not an output format is still
+// used as the path when the output-file flag is not set, with a warning, so that existing scripts keep working.
+func getListOutput(logger *log.Logger) (string, string) {
+	outputFormat := viper.GetString("output")
+	outputFile := viper.GetString("output-file")
+	if validateOutputFormat(outputFormat) == nil || outputFile != "" {
+		return outputFormat, outputFile
+	}
+
+	logger.Warnf("[LIST] Passing a file path to --output is deprecated, use --output-file %s instead", outputFormat)
+
+	return tableOutput, outputFormat
+}
+
+type layerSizer interface {
+	GetLayerSizesAtRemote(ctx context.Context, image string, auth string) (map[string]int64, error)
+}
+
+// getImageSizes returns the size of each source image from the sizes of its layers.
+// The sizes of layers are cached by their digest, so layers that are shared between
+// images are only counted once in the total and images are only requested once.
+func getImageSizes(ctx context.Context, sizer layerSizer, images []SourceImage) (*imageSizes, error) {
+	sizes := imageSizes{
+		Images: make([]int64, len(images)),
+	}
+
+	layerSizes := make(map[string]int64)
+	imageLayerSizes := make(map[string]map[string]int64)
+	for i, image := range images {
+		// The tags that match the patterns of an image are only known once they are expanded when pushing or pulling
+		if len(image.Tags) > 0 {
+			continue
+		}
+
+		currentLayerSizes, exists := imageLayerSizes[image.String()]
+		if !exists {
+			auth, err := getEncodedSourceAuth(ctx, image)
+			if err != nil {
+				return nil, fmt.Errorf("get source auth: %w", err)
+			}
+
+			currentLayerSizes, err = sizer.GetLayerSizesAtRemote(ctx, image.String(), auth)
+			if err != nil {
+				return nil, fmt.Errorf("get layer sizes of %s: %w", image.String(), err)
+			}
+
+			imageLayerSizes[image.String()] = currentLayerSizes
+		}
+
+		for digest, size := range currentLayerSizes {
+			sizes.Images[i] += size
+			layerSizes[digest] = size
+		}
+	}
+
+	for _, size := range layerSizes {
+		sizes.Total += size
+	}
+
+	return &sizes, nil
+}
+
+func writeImageList(output io.Writer, images []SourceImage, sizes *imageSizes, location string, format string) error {
+	listImages := []listImage{}
+	for i, image := range images {
+		currentImage := listImage{
+			Source: image.String(),
+			Target: image.TargetImage(),
+			Tag:    image.Tag,
+			Digest: image.Digest,
+		}
+
+		if sizes != nil {
+			currentImage.Size = sizes.Images[i]
+		}
+
+		listImages = append(listImages, currentImage)
+	}
+
+	writeTable := func(output io.Writer) error {
+		for _, image := range listImages {
+			var listImage string
+			switch location {
+			case "target":
+				listImage = image.Target
+			case "mapping":
+				listImage = image.Source + " -> " + image.Target
+			default:
+				listImage = image.Source
+			}
+
+			if sizes != nil {
+				listImage = fmt.Sprintf("%s (%s)", listImage, formatSize(image.Size))
+			}
+
+			if _, err := fmt.Fprintln(output, listImage); err != nil {
+				return fmt.Errorf("writing image: %w", err)
+			}
+		}
+
+		if sizes != nil {
+			if _, err := fmt.Fprintf(output, "Total: %s\n", formatSize(sizes.Total)); err != nil {
+				return fmt.Errorf("writing total: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	if sizes != nil {
+		result := listSizes{
+			Images:    listImages,
+			TotalSize: sizes.Total,
+		}
+
+		return writeOutput(output, format, result, writeTable)
+	}
+
+	return writeOutput(output, format, listImages, writeTable)
+}
+
+// formatSize formats a size in bytes using decimal units (e.g. 12.3 MB), the same as Docker
+func formatSize(size int64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+
+	value := float64(size)
+	unit := 0
+	for value >= 1000 && unit < len(units)-1 {
+		value /= 1000
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%v %s", size, units[unit])
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// parseSize parses a size in decimal units (e.g. 10GB or 500 MB), the same units as formatSize.
+// A size without a unit is in bytes.
+func parseSize(size string) (int64, error) {
+	units := map[string]float64{"": 1, "B": 1, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12}
+
+	trimmedSize := strings.TrimSpace(size)
+	unitIndex := strings.IndexFunc(trimmedSize, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+
+	value, unit := trimmedSize, ""
+	if unitIndex >= 0 {
+		value, unit = trimmedSize[:unitIndex], strings.ToUpper(strings.TrimSpace(trimmedSize[unitIndex:]))
+	}
+
+	multiplier, exists := units[unit]
+	if !exists {
+		return 0, fmt.Errorf("unknown unit %q in size %s", unit, size)
+	}
+
+	parsedValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse size %s: %w", size, err)
+	}
+
+	return int64(parsedValue * multiplier), nil
+}
@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func TestWriteImageList(t *testing.T) {
+	images := []SourceImage{
+		{
+			Host:       "quay.io",
+			Repository: "coreos/prometheus-operator",
+			Tag:        "v0.40.0",
+			Target:     Target{Host: "mycompany.com", Repository: "myrepo"},
+		},
+		{
+			Repository: "nginx",
+			Digest:     "sha256:123",
+			Target:     Target{Host: "mycompany.com", Repository: "myrepo"},
+		},
+	}
+
+	testCases := []struct {
+		location       string
+		format         string
+		expectedOutput string
+	}{
+		{
+			location:       "source",
+			format:         tableOutput,
+			expectedOutput: "quay.io/coreos/prometheus-operator:v0.40.0\nnginx@sha256:123\n",
+		},
+		{
+			location:       "target",
+			format:         tableOutput,
+			expectedOutput: "mycompany.com/myrepo/coreos/prometheus-operator:v0.40.0\nmycompany.com/myrepo/nginx:123\n",
+		},
+		{
+			location:       "mapping",
+			format:         tableOutput,
+			expectedOutput: "quay.io/coreos/prometheus-operator:v0.40.0 -> mycompany.com/myrepo/coreos/prometheus-operator:v0.40.0\nnginx@sha256:123 -> mycompany.com/myrepo/nginx:123\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		var output bytes.Buffer
+		if err := writeImageList(&output, images, nil, testCase.location, testCase.format); err != nil {
+			t.Fatal("write image list:", err)
+		}
+
+		if output.String() != testCase.expectedOutput {
+			t.Errorf("expected output to be %s, actual %s", testCase.expectedOutput, output.String())
+		}
+	}
+}
+
+type fakeLayerSizer struct {
+	layerSizes map[string]map[string]int64
+	requests   int
+}
+
+func (f *fakeLayerSizer) GetLayerSizesAtRemote(ctx context.Context, image string, auth string) (map[string]int64, error) {
+	f.requests++
+	return f.layerSizes[image], nil
+}
+
+func TestGetImageSizes(t *testing.T) {
+	images := []SourceImage{
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-config-reloader", Tag: "v0.40.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0", Target: Target{Host: "other.com"}},
+	}
+
+	sizer := fakeLayerSizer{
+		layerSizes: map[string]map[string]int64{
+			"quay.io/coreos/prometheus-operator:v0.40.0":        {"sha256:base": 100, "sha256:operator": 50},
+			"quay.io/coreos/prometheus-config-reloader:v0.40.0": {"sha256:base": 100, "sha256:reloader": 10},
+		},
+	}
+
+	sizes, err := getImageSizes(context.Background(), &sizer, images)
+	if err != nil {
+		t.Fatal("get image sizes:", err)
+	}
+
+	expectedSizes := []int64{150, 110, 150}
+	for i, expectedSize := range expectedSizes {
+		if sizes.Images[i] != expectedSize {
+			t.Errorf("expected size of %s to be %v, actual %v", images[i].String(), expectedSize, sizes.Images[i])
+		}
+	}
+
+	const expectedTotal = 160
+	if sizes.Total != expectedTotal {
+		t.Errorf("expected shared layers to be counted once in a total of %v, actual %v", expectedTotal, sizes.Total)
+	}
+
+	if sizer.requests != 2 {
+		t.Errorf("expected each image to be requested once, actual %v requests", sizer.requests)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	testCases := []struct {
+		size     int64
+		expected string
+	}{
+		{size: 0, expected: "0 B"},
+		{size: 999, expected: "999 B"},
+		{size: 1530, expected: "1.5 kB"},
+		{size: 52428800, expected: "52.4 MB"},
+		{size: 3 * 1000 * 1000 * 1000, expected: "3.0 GB"},
+	}
+
+	for _, testCase := range testCases {
+		if actual := formatSize(testCase.size); actual != testCase.expected {
+			t.Errorf("expected size %v to be formatted as %s, actual %s", testCase.size, testCase.expected, actual)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		size     string
+		expected int64
+	}{
+		{size: "1024", expected: 1024},
+		{size: "500B", expected: 500},
+		{size: "1.5kB", expected: 1500},
+		{size: "512 MB", expected: 512 * 1000 * 1000},
+		{size: "10GB", expected: 10 * 1000 * 1000 * 1000},
+		{size: "1tb", expected: 1000 * 1000 * 1000 * 1000},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := parseSize(testCase.size)
+		if err != nil {
+			t.Fatal("parse size:", err)
+		}
+
+		if actual != testCase.expected {
+			t.Errorf("expected size %s to be parsed as %v, actual %v", testCase.size, testCase.expected, actual)
+		}
+	}
+
+	for _, size := range []string{"10XB", "GB", "1.2.3MB"} {
+		if _, err := parseSize(size); err == nil {
+			t.Errorf("expected size %s to return an error", size)
+		}
+	}
+}
+
+func TestGetListOutput(t *testing.T) {
+	defer viper.Reset()
+
+	testCases := []struct {
+		output         string
+		outputFile     string
+		expectedFormat string
+		expectedFile   string
+	}{
+		{output: "table", expectedFormat: "table"},
+		{output: "json", outputFile: "images.json", expectedFormat: "json", expectedFile: "images.json"},
+		{output: "images.txt", expectedFormat: "table", expectedFile: "images.txt"},
+		{output: "images.txt", outputFile: "other.txt", expectedFormat: "images.txt", expectedFile: "other.txt"},
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	for _, testCase := range testCases {
+		viper.Set("output", testCase.output)
+		viper.Set("output-file", testCase.outputFile)
+
+		actualFormat, actualFile := getListOutput(logger)
+		if actualFormat != testCase.expectedFormat || actualFile != testCase.expectedFile {
+			t.Errorf("expected format %q and file %q for output %q and output file %q, actual format %q and file %q", testCase.expectedFormat, testCase.expectedFile, testCase.output, testCase.outputFile, actualFormat, actualFile)
+		}
+	}
+}
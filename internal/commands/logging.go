@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	textLogFormat = "text"
+	jsonLogFormat = "json"
+)
+
+// configureLogger sets the formatter and level of the logger that is used by every command.
+// JSON logs include the fields of each log line (e.g. the image and command) as JSON fields.
+// Text logs are only colored when they are written to a terminal and colors are not disabled.
+func configureLogger(logger *logrus.Logger, format string, level string, noColor bool) error {
+	switch format {
+	case textLogFormat:
+		logger.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp: false,
+			DisableColors: noColor || !isTerminal(logger.Out),
+		})
+
+	case jsonLogFormat:
+		logger.SetFormatter(&logrus.JSONFormatter{})
+
+	default:
+		return fmt.Errorf("unknown log format %q (text, json)", format)
+	}
+
+	logLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("parse log level: %w", err)
+	}
+
+	logger.SetLevel(logLevel)
+
+	return nil
+}
+
+// isTerminal returns true when the output is a terminal, rather than a file or pipe
+func isTerminal(output io.Writer) bool {
+	file, ok := output.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// newImageLogEntry returns a log entry with the command (e.g. push) and image as fields
+func newImageLogEntry(logger *logrus.Logger, command string, image string) *logrus.Entry {
+	return logger.WithFields(logrus.Fields{
+		"command": strings.ToLower(command),
+		"image":   image,
+	})
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestConfigureLogger_JSON(t *testing.T) {
+	var output bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&output)
+
+	if err := configureLogger(logger, jsonLogFormat, "warn", false); err != nil {
+		t.Fatal("configure logger:", err)
+	}
+
+	newImageLogEntry(logger, "PUSH", "busybox:1.32.0").Printf("[PUSH] busybox:1.32.0 complete.")
+	newImageLogEntry(logger, "PUSH", "busybox:1.32.0").Errorf("[PUSH] busybox:1.32.0 failed")
+
+	var entry map[string]string
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single JSON log line below the warn level, actual %s", output.String())
+	}
+
+	if entry["command"] != "push" || entry["image"] != "busybox:1.32.0" {
+		t.Errorf("expected the command and image to be fields, actual %v", entry)
+	}
+
+	if entry["level"] != "error" {
+		t.Errorf("expected level to be error, actual %s", entry["level"])
+	}
+}
+
+func TestConfigureLogger_Invalid(t *testing.T) {
+	testCases := []struct {
+		format string
+		level  string
+	}{
+		{format: "xml", level: "info"},
+		{format: textLogFormat, level: "loud"},
+	}
+
+	for _, testCase := range testCases {
+		if err := configureLogger(logrus.New(), testCase.format, testCase.level, false); err == nil {
+			t.Errorf("expected an error for format %s and level %s", testCase.format, testCase.level)
+		}
+	}
+}
+
+func TestConfigureLogger_NoColorWhenNotTerminal(t *testing.T) {
+	logFile, err := ioutil.TempFile("", "sinker")
+	if err != nil {
+		t.Fatal("temp file:", err)
+	}
+	defer os.Remove(logFile.Name())
+	defer logFile.Close()
+
+	var buffer bytes.Buffer
+	for _, output := range []io.Writer{&buffer, logFile} {
+		logger := logrus.New()
+		logger.SetOutput(output)
+
+		if err := configureLogger(logger, textLogFormat, "info", false); err != nil {
+			t.Fatal("configure logger:", err)
+		}
+
+		newImageLogEntry(logger, "PUSH", "busybox:1.32.0").Errorf("[PUSH] busybox:1.32.0 failed")
+	}
+
+	contents, err := ioutil.ReadFile(logFile.Name())
+	if err != nil {
+		t.Fatal("read log file:", err)
+	}
+
+	for _, output := range []string{buffer.String(), string(contents)} {
+		if !strings.Contains(output, "busybox:1.32.0 failed") {
+			t.Errorf("expected the log line to be written, actual %q", output)
+		}
+
+		if strings.Contains(output, "\x1b[") {
+			t.Errorf("expected no escape codes when not writing to a terminal, actual %q", output)
+		}
+	}
+}
+
+func TestConfigureLogger_NoColor(t *testing.T) {
+	logger := logrus.New()
+
+	if err := configureLogger(logger, textLogFormat, "info", true); err != nil {
+		t.Fatal("configure logger:", err)
+	}
+
+	formatter, ok := logger.Formatter.(*logrus.TextFormatter)
+	if !ok {
+		t.Fatalf("expected a text formatter, actual %T", logger.Formatter)
+	}
+
+	if !formatter.DisableColors {
+		t.Error("expected colors to be disabled with no-color")
+	}
+}
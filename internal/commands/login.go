@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/docker/docker/api/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newLoginCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "login <registry>",
+		Short: "Log in to a registry and save the credentials for pushing and pulling",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := []string{"username", "password", "password-stdin"}
+			for _, flag := range flags {
+				if err := viper.BindPFlag(flag, cmd.Flags().Lookup(flag)); err != nil {
+					return fmt.Errorf("bind %s flag: %w", flag, err)
+				}
+			}
+
+			host := args[0]
+			if err := runLoginCommand(ctx, logger, host, os.Stdin); err != nil {
+				return fmt.Errorf("login: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("username", "u", "", "The username to log in with")
+	cmd.Flags().StringP("password", "p", "", "The password to log in with")
+	cmd.Flags().Bool("password-stdin", false, "Read the password from stdin")
+	cmd.MarkFlagRequired("username")
+
+	return &cmd
+}
+
+func runLoginCommand(ctx context.Context, logger *log.Logger, host string, input io.Reader) error {
+	password, err := getLoginPassword(viper.GetString("password"), viper.GetBool("password-stdin"), input)
+	if err != nil {
+		return fmt.Errorf("get password: %w", err)
+	}
+
+	client, err := docker.NewClient(logger)
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	authHost := getAuthHostFromRegistryHost(host)
+	authConfig := types.AuthConfig{
+		Username:      viper.GetString("username"),
+		Password:      password,
+		ServerAddress: authHost,
+	}
+
+	if _, err := client.DockerClient.RegistryLogin(ctx, authConfig); err != nil {
+		return fmt.Errorf("registry login: %w", err)
+	}
+
+	if err := docker.SaveCredentials(authHost, authConfig.Username, authConfig.Password); err != nil {
+		return fmt.Errorf("save credentials: %w", err)
+	}
+
+	client.Logger.Printf("[LOGIN] Login to %s succeeded!", host)
+
+	return nil
+}
+
+func getLoginPassword(password string, passwordStdin bool, input io.Reader) (string, error) {
+	if password != "" && passwordStdin {
+		return "", errors.New("--password and --password-stdin are mutually exclusive")
+	}
+
+	if passwordStdin {
+		contents, err := ioutil.ReadAll(input)
+		if err != nil {
+			return "", fmt.Errorf("read stdin: %w", err)
+		}
+
+		password = strings.TrimRight(string(contents), "\r\n")
+	}
+
+	if password == "" {
+		return "", errors.New("password is required")
+	}
+
+	return password, nil
+}
@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetLoginPassword_Stdin(t *testing.T) {
+	const expected = "secret"
+
+	password, err := getLoginPassword("", true, strings.NewReader(expected+"\n"))
+	if err != nil {
+		t.Fatal("get login password:", err)
+	}
+
+	if password != expected {
+		t.Errorf("expected password to be %s, actual %s", expected, password)
+	}
+}
+
+func TestGetLoginPassword_BothSet(t *testing.T) {
+	if _, err := getLoginPassword("secret", true, strings.NewReader("secret")); err == nil {
+		t.Errorf("expected error when password and password-stdin are both set")
+	}
+}
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newLsTagsCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "ls-tags <repository>",
+		Short: "List the tags of a repository in its registry",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("limit", cmd.Flags().Lookup("limit")); err != nil {
+				return fmt.Errorf("bind limit flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("filter", cmd.Flags().Lookup("filter")); err != nil {
+				return fmt.Errorf("bind filter flag: %w", err)
+			}
+
+			if err := bindClientFlags(cmd); err != nil {
+				return fmt.Errorf("bind client flags: %w", err)
+			}
+
+			clientOptions, err := getClientOptions()
+			if err != nil {
+				return fmt.Errorf("get client options: %w", err)
+			}
+
+			client, err := docker.NewClient(logger, clientOptions...)
+			if err != nil {
+				return fmt.Errorf("new client: %w", err)
+			}
+
+			if err := runLsTagsCommand(ctx, client, os.Stdout, args[0]); err != nil {
+				return fmt.Errorf("ls-tags: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Int("limit", 0, "The maximum number of tags to list, in the order returned by the registry. Every tag is listed when not set")
+	cmd.Flags().String("filter", "", "Only list the tags that match the glob pattern (e.g. 1.32.*)")
+	addClientFlags(&cmd)
+
+	return &cmd
+}
+
+// runLsTagsCommand writes each tag of the repository that matches the filter, one per line.
+// Repositories without a host are Docker Hub repositories (e.g. busybox is docker.io/library/busybox).
+func runLsTagsCommand(ctx context.Context, lister tagLister, output io.Writer, repository string) error {
+	if viper.GetInt("limit") < 0 {
+		return errors.New("limit must not be negative")
+	}
+
+	repositoryPath := docker.RegistryPath(repository)
+	if repositoryPath.Tag() != "" || repositoryPath.Digest() != "" {
+		return fmt.Errorf("%s is not a repository, remove its tag or digest", repository)
+	}
+
+	// The reference expands Docker Hub repositories to their host and the library of official images
+	repositoryPath = docker.RegistryPath(repositoryPath.Reference())
+
+	auth, err := getEncodedAuth(ctx, Auth{}, repositoryPath.Host())
+	if err != nil {
+		return fmt.Errorf("get auth: %w", err)
+	}
+
+	tags, err := lister.GetTagsForRepo(ctx, repositoryPath.Host(), repositoryPath.Repository(), auth)
+	if err != nil {
+		return fmt.Errorf("get tags for %s: %w", repository, err)
+	}
+
+	if viper.GetString("filter") != "" {
+		tags, err = getMatchingTags(tags, []string{viper.GetString("filter")})
+		if err != nil {
+			return fmt.Errorf("filter tags: %w", err)
+		}
+	}
+
+	if limit := viper.GetInt("limit"); limit > 0 && len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	for _, tag := range tags {
+		fmt.Fprintln(output, tag)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/spf13/viper"
+)
+
+// newTestTagListRegistry returns a registry whose tag list of team/app is split into pages of two tags
+func newTestTagListRegistry(t *testing.T, tags []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			return
+		}
+
+		if r.URL.Path != "/v2/team/app/tags/list" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		start := 0
+		for i, tag := range tags {
+			if tag == r.URL.Query().Get("last") {
+				start = i + 1
+			}
+		}
+
+		end := start + 2
+		if end < len(tags) {
+			w.Header().Set("Link", `</v2/team/app/tags/list?n=2&last=`+tags[end-1]+`>; rel="next"`)
+		} else {
+			end = len(tags)
+		}
+
+		page := struct {
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		}{Name: "team/app", Tags: tags[start:end]}
+
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			t.Error("encode tags:", err)
+		}
+	}))
+}
+
+func TestRunLsTagsCommand(t *testing.T) {
+	registryServer := newTestTagListRegistry(t, []string{"1.31.0", "1.31.1", "1.32.0", "1.32.1", "latest"})
+	defer registryServer.Close()
+
+	repository := strings.TrimPrefix(registryServer.URL, "http://") + "/team/app"
+
+	testCases := []struct {
+		name         string
+		limit        int
+		filter       string
+		expectedTags string
+	}{
+		{
+			name:         "every page",
+			expectedTags: "1.31.0\n1.31.1\n1.32.0\n1.32.1\nlatest\n",
+		},
+		{
+			name:         "filter",
+			filter:       "1.32.*",
+			expectedTags: "1.32.0\n1.32.1\n",
+		},
+		{
+			name:         "limit",
+			limit:        3,
+			expectedTags: "1.31.0\n1.31.1\n1.32.0\n",
+		},
+		{
+			name:         "filter and limit",
+			limit:        1,
+			filter:       "1.32.*",
+			expectedTags: "1.32.0\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			viper.Set("limit", testCase.limit)
+			defer viper.Set("limit", 0)
+
+			viper.Set("filter", testCase.filter)
+			defer viper.Set("filter", "")
+
+			var output bytes.Buffer
+			if err := runLsTagsCommand(context.Background(), docker.Client{}, &output, repository); err != nil {
+				t.Fatal("run ls-tags command:", err)
+			}
+
+			if output.String() != testCase.expectedTags {
+				t.Errorf("expected tags %q, actual %q", testCase.expectedTags, output.String())
+			}
+		})
+	}
+}
+
+func TestRunLsTagsCommand_DockerHubRepository(t *testing.T) {
+	lister := fakeTagLister{
+		tags: map[string][]string{
+			"docker.io/library/busybox": {"1.32.0"},
+		},
+	}
+
+	var output bytes.Buffer
+	if err := runLsTagsCommand(context.Background(), lister, &output, "busybox"); err != nil {
+		t.Fatal("run ls-tags command:", err)
+	}
+
+	if output.String() != "1.32.0\n" {
+		t.Errorf("expected the tags of docker.io/library/busybox, actual %q", output.String())
+	}
+}
+
+func TestRunLsTagsCommand_NotRepository(t *testing.T) {
+	references := []string{"busybox:1.32.0", "mycompany.com/app@sha256:e1b0a0d4c4e2e470e5b5b4b2e11d1d3b1e5f0b1d2c3e4f5a6b7c8d9e0f1a2b3c"}
+	for _, reference := range references {
+		if err := runLsTagsCommand(context.Background(), fakeTagLister{}, &bytes.Buffer{}, reference); err == nil {
+			t.Errorf("expected an error for %s, which is not a repository", reference)
+		}
+	}
+}
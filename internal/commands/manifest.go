@@ -3,9 +3,14 @@ package commands
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/plexsystems/sinker/internal/docker"
@@ -17,6 +22,10 @@ import (
 type Auth struct {
 	Username string `yaml:"username,omitempty"`
 	Password string `yaml:"password,omitempty"`
+
+	// Token is the name of the environment variable that contains
+	// a Base64 encoded Docker auth token (username:password)
+	Token string `yaml:"token,omitempty"`
 }
 
 // Target is a target location for an image
@@ -24,6 +33,43 @@ type Target struct {
 	Host       string `yaml:"host,omitempty"`
 	Repository string `yaml:"repository,omitempty"`
 	Auth       Auth   `yaml:"auth,omitempty"`
+
+	// StripPrefix is the prefix (e.g. organization/team) that is removed
+	// from the repository of each source image before it is appended to the target
+	StripPrefix string `yaml:"stripPrefix,omitempty"`
+
+	// Rewrite are the rules that rewrite the repository of each source image
+	// before it is appended to the target. Only the first matching rule is applied.
+	Rewrite []RewriteRule `yaml:"rewrite,omitempty"`
+
+	// Flatten appends only the last path segment of the repository of each source image
+	// to the target (e.g. ghcr.io/myorg/prometheus-operator for coreos/prometheus-operator),
+	// once the strip prefix and rewrite rules have been applied
+	Flatten bool `yaml:"flatten,omitempty"`
+
+	// TagPrefix and TagSuffix are added to the tag of each source image (e.g. 1.25-staging)
+	// to form the tag of its target image. Images pinned to only a digest are not changed.
+	TagPrefix string `yaml:"tagPrefix,omitempty"`
+	TagSuffix string `yaml:"tagSuffix,omitempty"`
+}
+
+// RewriteRule rewrites the repositories that match the regular expression
+// (e.g. ^library/(.*)$) with the replacement, which can reference the groups
+// of the match (e.g. mirror/$1)
+type RewriteRule struct {
+	Match   string `yaml:"match"`
+	Replace string `yaml:"replace"`
+}
+
+// withNamingRules returns the target with the strip prefix, rewrite, flatten, and tag rules of the other target
+func (t Target) withNamingRules(other Target) Target {
+	t.StripPrefix = other.StripPrefix
+	t.Rewrite = other.Rewrite
+	t.Flatten = other.Flatten
+	t.TagPrefix = other.TagPrefix
+	t.TagSuffix = other.TagSuffix
+
+	return t
 }
 
 func (t Target) String() string {
@@ -49,15 +95,57 @@ type SourceImage struct {
 	Tag        string `yaml:"tag,omitempty"`
 	Digest     string `yaml:"digest,omitempty"`
 	Auth       Auth   `yaml:"auth,omitempty"`
+
+	// Tags are glob patterns (e.g. v1.*) of the tags in the source
+	// repository to sync, which are expanded when pushing or pulling
+	Tags []string `yaml:"tags,omitempty"`
+
+	// position is where the image is in the manifest file it was loaded from
+	position manifestPosition
+
+	// groupPrefix is the target prefix of the group that the image is in, when it is in a group
+	groupPrefix string
+}
+
+// SourceGroup is a group of source images that share the beginning of their target repository.
+// The target prefix is appended to the target repository of the manifest for each image of the
+// group, unless the image has its own target.
+type SourceGroup struct {
+	TargetPrefix string        `yaml:"targetPrefix"`
+	Images       []SourceImage `yaml:"sources"`
 }
 
-// String returns the source image including its tag
+// withDefaultTarget returns the image with the target, unless the image has its own target.
+// The target prefix of the group of the image is appended to the repository of the target.
+func (c SourceImage) withDefaultTarget(target Target) SourceImage {
+	if c.Target.Host != "" {
+		return c
+	}
+
+	c.Target = c.withGroupPrefix(target)
+
+	return c
+}
+
+// withGroupPrefix returns the target with the target prefix of the group of the image appended to its repository
+func (c SourceImage) withGroupPrefix(target Target) Target {
+	if prefix := strings.Trim(c.groupPrefix, "/"); prefix != "" {
+		target.Repository = strings.TrimLeft(path.Join(target.Repository, prefix), "/")
+	}
+
+	return target
+}
+
+// String returns the source image including its tag and digest
 func (c SourceImage) String() string {
 	var source string
 	if c.Tag != "" {
 		source = ":" + c.Tag
-	} else if c.Digest != "" {
-		source = "@" + c.Digest
+	}
+
+	// When both a tag and a digest are set, the digest is used to pull the image
+	if c.Digest != "" {
+		source = source + "@" + c.Digest
 	}
 
 	if c.Repository != "" {
@@ -75,53 +163,229 @@ func (c SourceImage) String() string {
 
 // TargetImage returns the target image includes its tag
 func (c SourceImage) TargetImage() string {
+	target, _ := c.targetImage()
+	return target
+}
+
+// targetImage returns the target image, and the target image before its repository was lowercased.
+// Repositories can only be lowercase, but can be named with uppercase letters in the manifest, such as
+// by tools that generate it, or by organizations and users of GHCR that have uppercase names.
+func (c SourceImage) targetImage() (string, string) {
 	var target string
-	if c.Tag != "" {
-		target = ":" + c.Tag
-	} else if c.Digest != "" {
-		target = strings.ReplaceAll(c.Digest, "sha256:", "")
-		target = ":" + target
+	if tag := c.targetTag(); tag != "" {
+		target = ":" + tag
 	}
 
-	if c.Repository != "" {
-		target = "/" + c.Repository + target
+	if repository := c.targetRepository(); repository != "" {
+		target = "/" + repository + target
 	}
 
 	if c.Target.Repository != "" {
 		target = "/" + c.Target.Repository + target
 	}
 
+	original := target
+	target = lowercaseRepository(target)
+
 	if c.Target.Host != "" {
 		target = "/" + c.Target.Host + target
+		original = "/" + c.Target.Host + original
 	}
 
-	target = strings.TrimLeft(target, "/")
+	return strings.TrimLeft(target, "/"), strings.TrimLeft(original, "/")
+}
 
-	return target
+// targetTag returns the tag of the target image, which is the tag of the source image with the tag
+// prefix and suffix of the target. Images pinned to only a digest are tagged with the digest, without
+// its algorithm, and without the tag prefix and suffix, so that the tag still identifies the digest.
+func (c SourceImage) targetTag() string {
+	if c.Tag != "" {
+		return c.Target.TagPrefix + c.Tag + c.Target.TagSuffix
+	}
+
+	return strings.ReplaceAll(c.Digest, "sha256:", "")
+}
+
+// usesLatestTag returns true when the source image is only pinned to the floating latest tag,
+// including images without a tag or digest, which are pulled as the latest tag
+func (c SourceImage) usesLatestTag() bool {
+	if c.Digest != "" || len(c.Tags) > 0 {
+		return false
+	}
+
+	return c.Tag == "" || c.Tag == "latest"
+}
+
+// targetRepository returns the repository of the source image that is appended to the target.
+// The strip prefix of the target is only removed when it matches whole path segments of the
+// repository, and never removes the entire repository. The rewrite rules are then applied to
+// the remaining repository, and only its last path segment is kept when the target is flattened.
+func (c SourceImage) targetRepository() string {
+	repository := c.Repository
+	prefix := strings.Trim(c.Target.StripPrefix, "/")
+	if prefix != "" && strings.HasPrefix(repository, prefix+"/") {
+		repository = strings.TrimPrefix(repository, prefix+"/")
+	}
+
+	repository = rewriteRepository(repository, c.Target.Rewrite)
+	if c.Target.Flatten && repository != "" {
+		return path.Base(repository)
+	}
+
+	return repository
+}
+
+// isGHCRHost returns true when the host is the GitHub Container Registry
+func isGHCRHost(host string) bool {
+	return strings.EqualFold(host, "ghcr.io")
+}
+
+// lowercaseRepository returns the repository of the image in lowercase, without changing its tag,
+// as tags are case sensitive
+func lowercaseRepository(image string) string {
+	if index := strings.LastIndex(image, ":"); index >= 0 && !strings.Contains(image[index:], "/") {
+		return strings.ToLower(image[:index]) + image[index:]
+	}
+
+	return strings.ToLower(image)
+}
+
+// rewriteRepository applies the first rule that matches the repository. Rules with an invalid
+// regular expression are skipped, as they are reported when the manifest is validated.
+func rewriteRepository(repository string, rules []RewriteRule) string {
+	for _, rule := range rules {
+		pattern, err := regexp.Compile(rule.Match)
+		if err != nil || !pattern.MatchString(repository) {
+			continue
+		}
+
+		return pattern.ReplaceAllString(repository, rule.Replace)
+	}
+
+	return repository
 }
 
 // Manifest is a collection of images to sync
 type Manifest struct {
-	Target Target        `yaml:"target"`
-	Images []SourceImage `yaml:"sources,omitempty"`
+	Version int           `yaml:"version,omitempty"`
+	Target  Target        `yaml:"target"`
+	Images  []SourceImage `yaml:"sources,omitempty"`
+
+	// Groups are the groups of source images that share a target prefix. They are flattened into
+	// the images of the manifest, after the images that are not in a group, when it is loaded.
+	Groups []SourceGroup `yaml:"groups,omitempty"`
 }
 
-// NewManifest returns a new image manifest
-func NewManifest(target string) Manifest {
-	targetPath := docker.RegistryPath(target)
+// getImage returns the image at the index of the images that the manifest syncs, which are the images
+// of the manifest followed by the images of each of its groups, or nil when the index is out of range
+func (m *Manifest) getImage(index int) *SourceImage {
+	if index < len(m.Images) {
+		return &m.Images[index]
+	}
 
-	manifestTarget := Target{
-		Host:       targetPath.Host(),
-		Repository: targetPath.Repository(),
+	index -= len(m.Images)
+	for i := range m.Groups {
+		if index < len(m.Groups[i].Images) {
+			return &m.Groups[i].Images[index]
+		}
+
+		index -= len(m.Groups[i].Images)
 	}
 
+	return nil
+}
+
+// flattenGroups returns the manifest with the images of each group appended to its images. Each
+// image keeps the target prefix of its group, which is applied when the target of the image is set.
+func (m Manifest) flattenGroups() Manifest {
+	if len(m.Groups) == 0 {
+		return m
+	}
+
+	images := append([]SourceImage{}, m.Images...)
+	for _, group := range m.Groups {
+		for _, image := range group.Images {
+			image.groupPrefix = group.TargetPrefix
+			images = append(images, image)
+		}
+	}
+
+	m.Images = images
+	m.Groups = nil
+
+	return m
+}
+
+// NewManifest returns a new image manifest
+func NewManifest(target string) Manifest {
 	manifest := Manifest{
-		Target: manifestTarget,
+		Version: currentManifestVersion,
+		Target:  parseTarget(target),
 	}
 
 	return manifest
 }
 
+// WithTarget returns the manifest with the target of every image, including images
+// with their own target, replaced by the given target (e.g. host/repository).
+// The auth of the replaced targets is not used, as it is for a different registry,
+// while their strip prefix, rewrite, and tag rules are kept, as they depend on the source images.
+// The target prefix of the group of each image is appended to the given target, so that the
+// images of different groups are still synced to different repositories.
+func (m Manifest) WithTarget(target string) Manifest {
+	m.Target = parseTarget(target).withNamingRules(m.Target)
+
+	images := make([]SourceImage, len(m.Images))
+	for i, image := range m.Images {
+		image.Target = image.withGroupPrefix(parseTarget(target).withNamingRules(image.Target))
+		images[i] = image
+	}
+	m.Images = images
+
+	return m
+}
+
+// WithTagAffixes returns the manifest with the tag prefix and suffix of the target of every image,
+// including images with their own target, replaced by the given prefix and suffix when they are set
+func (m Manifest) WithTagAffixes(prefix string, suffix string) Manifest {
+	withTagAffixes := func(target Target) Target {
+		if prefix != "" {
+			target.TagPrefix = prefix
+		}
+
+		if suffix != "" {
+			target.TagSuffix = suffix
+		}
+
+		return target
+	}
+
+	m.Target = withTagAffixes(m.Target)
+
+	images := make([]SourceImage, len(m.Images))
+	for i, image := range m.Images {
+		image.Target = withTagAffixes(image.Target)
+		images[i] = image
+	}
+	m.Images = images
+
+	return m
+}
+
+// parseTarget parses a target in the format host/repository, where a
+// target without a repository (e.g. localhost:5000) is only a host
+func parseTarget(target string) Target {
+	targetPath := docker.RegistryPath(target)
+	if !strings.Contains(target, "/") && docker.RegistryPath(target+"/").Host() != "" {
+		return Target{Host: target}
+	}
+
+	return Target{
+		Host:       targetPath.Host(),
+		Repository: targetPath.Repository(),
+	}
+}
+
 // NewAutodetectManifest returns a new image manifest with images found in the repository
 func NewAutodetectManifest(target string, path string) (Manifest, error) {
 	manifest := NewManifest(target)
@@ -136,28 +400,446 @@ func NewAutodetectManifest(target string, path string) (Manifest, error) {
 	return manifest, nil
 }
 
+// stdinManifestPath is the manifest path that reads and writes
+// the manifest using standard input and output
+const stdinManifestPath = "-"
+
+var (
+	manifestInput  io.Reader = os.Stdin
+	manifestOutput io.Writer = os.Stdout
+)
+
 // GetManifest returns the current manifest file in the working directory
 func GetManifest(path string) (Manifest, error) {
-	manifestLocation := getManifestLocation(path)
-	manifestContents, err := ioutil.ReadFile(manifestLocation)
+	manifest, err := loadManifest(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return Manifest{}, fmt.Errorf("validate manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// loadManifest reads the manifest at the given path without validating its images.
+// When the path is a comma-separated list of manifest files, or a directory of manifest
+// files, the manifests are merged into a single manifest.
+func loadManifest(path string) (Manifest, error) {
+	manifestPaths, err := getManifestPaths(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("get manifest paths: %w", err)
+	}
+
+	if len(manifestPaths) == 1 {
+		return loadManifestFile(manifestPaths[0])
+	}
+
+	var manifests []Manifest
+	for _, manifestPath := range manifestPaths {
+		manifest, err := loadManifestFile(manifestPath)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("load %s: %w", manifestPath, err)
+		}
+
+		manifests = append(manifests, manifest)
+	}
+
+	manifest, err := mergeManifests(manifestPaths, manifests)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("merge manifests: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// loadManifestFile reads a single manifest without validating its images
+func loadManifestFile(path string) (Manifest, error) {
+	manifest, err := decodeManifest(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	manifest = manifest.flattenGroups()
+
+	if err := expandManifestEnv(&manifest); err != nil {
+		return Manifest{}, fmt.Errorf("expand environment variables: %w", err)
+	}
+
+	for i := range manifest.Images {
+		manifest.Images[i] = manifest.Images[i].withDefaultTarget(manifest.Target)
+	}
+
+	return manifest, nil
+}
+
+// getManifestPaths returns the manifests at the given path, which is a comma-separated list of manifest files
+// or a single manifest. A directory is the .images.yaml file in the directory, or every YAML file in the
+// directory when it does not have a .images.yaml file.
+func getManifestPaths(path string) ([]string, error) {
+	if strings.Contains(path, ",") {
+		var manifestPaths []string
+		for _, manifestPath := range strings.Split(path, ",") {
+			if strings.TrimSpace(manifestPath) != "" {
+				manifestPaths = append(manifestPaths, strings.TrimSpace(manifestPath))
+			}
+		}
+
+		return manifestPaths, nil
+	}
+
+	if path == stdinManifestPath || !isManifestDirectory(path) {
+		return []string{path}, nil
+	}
+
+	var manifestPaths []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(path, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("glob: %w", err)
+		}
+
+		manifestPaths = append(manifestPaths, matches...)
+	}
+	sort.Strings(manifestPaths)
+
+	if len(manifestPaths) == 0 {
+		return nil, fmt.Errorf("no manifest files found in %s", path)
+	}
+
+	return manifestPaths, nil
+}
+
+// isManifestDirectory returns true when the path is a directory of manifest files,
+// rather than a directory with a single .images.yaml manifest
+func isManifestDirectory(path string) bool {
+	if path == "" {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+
+	if _, err := os.Stat(getManifestLocation(path)); err == nil {
+		return false
+	}
+
+	return true
+}
+
+// mergeManifests merges the manifests that were loaded from the given paths into a single manifest.
+// Each manifest either has no target, or the same target as every other manifest with a target,
+// which is used for the images of the manifests without a target.
+func mergeManifests(paths []string, manifests []Manifest) (Manifest, error) {
+	merged := Manifest{Version: currentManifestVersion}
+
+	var targetPath string
+	var conflicts []string
+	for i, manifest := range manifests {
+		if reflect.DeepEqual(manifest.Target, Target{}) {
+			continue
+		}
+
+		if targetPath == "" {
+			merged.Target = manifest.Target
+			targetPath = paths[i]
+			continue
+		}
+
+		if !reflect.DeepEqual(manifest.Target, merged.Target) {
+			conflicts = append(conflicts, fmt.Sprintf("target %s in %s conflicts with target %s in %s", manifest.Target, paths[i], merged.Target, targetPath))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return Manifest{}, fmt.Errorf("conflicting targets: %s", strings.Join(conflicts, "; "))
+	}
+
+	for _, manifest := range manifests {
+		for _, image := range manifest.Images {
+			merged.Images = append(merged.Images, image.withDefaultTarget(merged.Target))
+		}
+	}
+
+	return merged, nil
+}
+
+// decodeManifest reads the manifest at the given path as it was written, without
+// expanding its environment variables or applying the target to each of its images
+func decodeManifest(path string) (Manifest, error) {
+	manifestContents, err := readManifest(path)
 	if err != nil {
 		return Manifest{}, fmt.Errorf("reading manifest: %w", err)
 	}
 
+	manifestContents, err = migrateManifest(manifestContents)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("migrate manifest: %w", err)
+	}
+
+	// Unknown fields are rejected so that misspelled fields
+	// do not silently result in images not being synced.
 	var manifest Manifest
-	if err := yaml.Unmarshal(manifestContents, &manifest); err != nil {
+	if err := yaml.UnmarshalStrict(manifestContents, &manifest); err != nil {
 		return Manifest{}, fmt.Errorf("unmarshal current manifest: %w", err)
 	}
+	manifest.Version = currentManifestVersion
 
-	for i := range manifest.Images {
-		if manifest.Images[i].Target.Host == "" {
-			manifest.Images[i].Target = manifest.Target
+	manifestFile := path
+	if path != stdinManifestPath {
+		manifestFile = getManifestLocation(path)
+	}
+
+	imagePositions, err := getImagePositions(manifestFile, manifestContents)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("get image positions: %w", err)
+	}
+
+	for i, position := range imagePositions {
+		if image := manifest.getImage(i); image != nil {
+			image.position = position
 		}
 	}
 
 	return manifest, nil
 }
 
+// envReference matches ${ENV_VAR} and ${ENV_VAR:-default} references
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandManifestEnv expands the environment variable references
+// in the host, repository, and tag fields of the manifest
+func expandManifestEnv(manifest *Manifest) error {
+	fields := []*string{&manifest.Target.Host, &manifest.Target.Repository}
+	for i := range manifest.Images {
+		image := &manifest.Images[i]
+		fields = append(fields, &image.Host, &image.Repository, &image.Tag, &image.Target.Host, &image.Target.Repository)
+	}
+
+	for _, field := range fields {
+		expanded, err := expandEnv(*field)
+		if err != nil {
+			return err
+		}
+
+		*field = expanded
+	}
+
+	return nil
+}
+
+// expandEnv replaces every environment variable reference in the value with the
+// value of the environment variable. When the environment variable is not set or
+// is empty, the default is used if one was provided, otherwise an error is returned.
+func expandEnv(value string) (string, error) {
+	var missing []string
+	expanded := envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		match := envReference.FindStringSubmatch(reference)
+		if env := os.Getenv(match[1]); env != "" {
+			return env
+		}
+
+		if match[2] == "" {
+			missing = append(missing, match[1])
+			return reference
+		}
+
+		return match[3]
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
+
+// tagPrefixPattern and tagSuffixPattern match the prefixes and suffixes that form a valid tag
+// when added to a tag, as a tag can only start with a letter, digit, or underscore
+var (
+	tagPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+	tagSuffixPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// ValidationError is returned when a manifest contains one or more problems
+type ValidationError struct {
+	Problems []string
+}
+
+func (v ValidationError) Error() string {
+	return fmt.Sprintf("%v problem(s) found: %s", len(v.Problems), strings.Join(v.Problems, "; "))
+}
+
+// Validate returns a ValidationError that contains every problem found with the images in the manifest
+func (m Manifest) Validate() error {
+	var problems []string
+	sources := make(map[string]int)
+	for i, image := range m.Images {
+		name := getImageName(i, image)
+
+		if image.Repository == "" {
+			problems = append(problems, fmt.Sprintf("%s: repository is required", name))
+		}
+
+		if image.Target.Host == "" && image.Target.Repository == "" {
+			problems = append(problems, fmt.Sprintf("%s: target host or repository is required", name))
+		}
+
+		if image.Digest != "" && !strings.Contains(image.Digest, ":") {
+			problems = append(problems, fmt.Sprintf("%s: digest %s must include its algorithm (e.g. sha256:)", name, image.Digest))
+		}
+
+		if len(image.Tags) > 0 && (image.Tag != "" || image.Digest != "") {
+			problems = append(problems, fmt.Sprintf("%s: tags can not be used with a tag or digest", name))
+		}
+
+		for _, pattern := range image.Tags {
+			if _, err := path.Match(pattern, ""); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid tags pattern %s", name, pattern))
+			}
+		}
+
+		if image.Target.TagPrefix != "" && !tagPrefixPattern.MatchString(image.Target.TagPrefix) {
+			problems = append(problems, fmt.Sprintf("%s: invalid tag prefix %s, must start with a letter, digit, or underscore and only contain letters, digits, underscores, periods, and dashes", name, image.Target.TagPrefix))
+		}
+
+		if image.Target.TagSuffix != "" && !tagSuffixPattern.MatchString(image.Target.TagSuffix) {
+			problems = append(problems, fmt.Sprintf("%s: invalid tag suffix %s, must only contain letters, digits, underscores, periods, and dashes", name, image.Target.TagSuffix))
+		}
+
+		for _, rule := range image.Target.Rewrite {
+			if _, err := regexp.Compile(rule.Match); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid rewrite match %s: %s", name, rule.Match, err))
+			}
+		}
+
+		if image.Repository == "" {
+			continue
+		}
+
+		source := docker.RegistryPath(image.String()).Reference()
+		if len(image.Tags) > 0 {
+			source = source + ":" + strings.Join(image.Tags, ",")
+		}
+
+		if first, exists := sources[source]; exists {
+			problems = append(problems, fmt.Sprintf("%s: duplicate of %s", name, getImageName(first, m.Images[first])))
+			continue
+		}
+
+		sources[source] = i
+	}
+
+	problems = append(problems, m.getTargetCollisionProblems()...)
+
+	if len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// ValidateTargets returns a ValidationError that contains the images in the manifest that would overwrite
+// the target image of another image. The targets can change once the manifest has been validated, such
+// as with the target flag, so they are validated again before the images are pushed.
+func (m Manifest) ValidateTargets() error {
+	if problems := m.getTargetCollisionProblems(); len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// getTargetCollisionProblems returns the target collisions that are problems rather than warnings
+func (m Manifest) getTargetCollisionProblems() []string {
+	var problems []string
+	for _, collision := range m.getTargetCollisions() {
+		if collision.isError() {
+			problems = append(problems, collision.problem)
+		}
+	}
+
+	return problems
+}
+
+// Warnings returns the problems found with the images in the manifest that
+// are allowed, but almost always a mistake, such as different source images
+// that would be pushed to the same target image
+func (m Manifest) Warnings() []string {
+	var warnings []string
+	for _, collision := range m.getTargetCollisions() {
+		if !collision.isError() {
+			warnings = append(warnings, collision.problem)
+		}
+	}
+
+	return warnings
+}
+
+// targetCollision is an image in the manifest that would be pushed to the same
+// target image as an earlier image in the manifest with a different source image
+type targetCollision struct {
+	target  Target
+	problem string
+}
+
+// isError returns true when the collision is a problem rather than a warning. Flattened targets and
+// GHCR targets, whose repositories are usually flattened into an organization, would otherwise
+// silently overwrite the target image of one source image with another.
+func (t targetCollision) isError() bool {
+	return t.target.Flatten || isGHCRHost(t.target.Host)
+}
+
+// getTargetCollisions returns the images that would be pushed to the same target image as an earlier image
+func (m Manifest) getTargetCollisions() []targetCollision {
+	var collisions []targetCollision
+	targets := make(map[string]int)
+	for i, image := range m.Images {
+		if len(image.Tags) > 0 {
+			continue
+		}
+
+		target := docker.RegistryPath(image.TargetImage()).Reference()
+		first, exists := targets[target]
+		if !exists {
+			targets[target] = i
+			continue
+		}
+
+		firstSource := docker.RegistryPath(m.Images[first].String()).Reference()
+		if firstSource == docker.RegistryPath(image.String()).Reference() {
+			continue
+		}
+
+		collision := targetCollision{
+			target:  image.Target,
+			problem: fmt.Sprintf("%s: pushed to the same target %s as %s", getImageName(i, image), image.TargetImage(), getImageName(first, m.Images[first])),
+		}
+
+		collisions = append(collisions, collision)
+	}
+
+	return collisions
+}
+
+// getImageName returns the name of the image at the given position
+// in the manifest that is used when reporting problems
+func getImageName(index int, image SourceImage) string {
+	name := fmt.Sprintf("sources[%v]", index)
+	if image.String() != "" {
+		name = fmt.Sprintf("%s (%s)", name, image.String())
+	}
+
+	if image.position.String() != "" {
+		name = fmt.Sprintf("%s at %s", name, image.position)
+	}
+
+	return name
+}
+
+// WriteManifest writes the manifest to the given path
 func WriteManifest(manifest Manifest, path string) error {
 	imageManifestContents, err := yaml.Marshal(&manifest)
 	if err != nil {
@@ -165,14 +847,56 @@ func WriteManifest(manifest Manifest, path string) error {
 	}
 	imageManifestContents = bytes.ReplaceAll(imageManifestContents, []byte(`"`), []byte(""))
 
+	if err := writeManifest(imageManifestContents, path); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	return nil
+}
+
+func readManifest(path string) ([]byte, error) {
+	if err := validateSingleManifest(path); err != nil {
+		return nil, err
+	}
+
+	if path == stdinManifestPath {
+		return ioutil.ReadAll(manifestInput)
+	}
+
+	return ioutil.ReadFile(getManifestLocation(path))
+}
+
+func writeManifest(contents []byte, path string) error {
+	if strings.Contains(path, ",") {
+		return fmt.Errorf("%s contains multiple manifests, which can not be written", path)
+	}
+
+	if path == stdinManifestPath {
+		if _, err := manifestOutput.Write(contents); err != nil {
+			return fmt.Errorf("write to stdout: %w", err)
+		}
+
+		return nil
+	}
+
 	manifestLocation := getManifestLocation(path)
-	if err := ioutil.WriteFile(manifestLocation, imageManifestContents, os.ModePerm); err != nil {
+	if err := ioutil.WriteFile(manifestLocation, contents, os.ModePerm); err != nil {
 		return fmt.Errorf("creating file: %w", err)
 	}
 
 	return nil
 }
 
+// validateSingleManifest returns an error when the path is multiple manifests, which
+// can only be loaded, as it is ambiguous which of the manifests is read or written
+func validateSingleManifest(path string) error {
+	if strings.Contains(path, ",") || isManifestDirectory(path) {
+		return fmt.Errorf("%s contains multiple manifests, which can only be read as a merged manifest", path)
+	}
+
+	return nil
+}
+
 func getManifestLocation(path string) string {
 	const defaultManifestFileName = ".images.yaml"
 
@@ -1,6 +1,18 @@
 package commands
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+)
 
 func TestTarget_NoRepository_EmptyRepository(t *testing.T) {
 	const expected = "target.com"
@@ -195,3 +207,841 @@ func TestSourceImage_Digest(t *testing.T) {
 		t.Errorf("unexpected target string. expected %s, actual %s", image.TargetImage(), expectedTarget)
 	}
 }
+
+func TestSourceImage_TagAndDigest(t *testing.T) {
+	image := SourceImage{
+		Host:       "source.com",
+		Repository: "repo",
+		Tag:        "v1.0.0",
+		Digest:     "sha256:123",
+		Target:     Target{Host: "target.com"},
+	}
+
+	const expectedSource = "source.com/repo:v1.0.0@sha256:123"
+	if image.String() != expectedSource {
+		t.Errorf("expected source to be %s, actual %s", expectedSource, image.String())
+	}
+
+	const expectedTarget = "target.com/repo:v1.0.0"
+	if image.TargetImage() != expectedTarget {
+		t.Errorf("expected target to be %s, actual %s", expectedTarget, image.TargetImage())
+	}
+}
+
+func TestGetManifest_Stdin(t *testing.T) {
+	manifestInput = strings.NewReader(`
+target:
+  host: target.com
+sources:
+- repository: busybox
+  tag: 1.32.0
+`)
+	defer func() { manifestInput = os.Stdin }()
+
+	manifest, err := GetManifest("-")
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	if len(manifest.Images) != 1 {
+		t.Fatalf("expected 1 image, actual %v", len(manifest.Images))
+	}
+
+	const expectedTarget = "target.com/busybox:1.32.0"
+	if manifest.Images[0].TargetImage() != expectedTarget {
+		t.Errorf("expected target to be %s, actual %s", expectedTarget, manifest.Images[0].TargetImage())
+	}
+}
+
+func TestWriteManifest_Stdout(t *testing.T) {
+	var output bytes.Buffer
+	manifestOutput = &output
+	defer func() { manifestOutput = os.Stdout }()
+
+	manifest := NewManifest("target.com/repo")
+	if err := WriteManifest(manifest, "-"); err != nil {
+		t.Fatal("write manifest:", err)
+	}
+
+	if !strings.Contains(output.String(), "host: target.com") {
+		t.Errorf("expected manifest to be written to stdout, actual %s", output.String())
+	}
+}
+
+func TestGetManifest_UnknownField(t *testing.T) {
+	manifestPath := writeTestManifest(t, `
+target:
+  host: target.com
+sources:
+- repostory: busybox
+  tag: 1.32.0
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	_, err := GetManifest(manifestPath)
+	if err == nil {
+		t.Fatal("expected unknown field to return an error")
+	}
+
+	if !strings.Contains(err.Error(), "line 5: field repostory not found") {
+		t.Errorf("expected error to contain the line of the unknown field, actual %v", err)
+	}
+}
+
+func TestManifest_Validate(t *testing.T) {
+	target := Target{Host: "target.com"}
+
+	testCases := []struct {
+		image            SourceImage
+		expectedProblems []string
+	}{
+		{
+			image:            SourceImage{Repository: "busybox", Tag: "1.32.0", Target: target},
+			expectedProblems: nil,
+		},
+		{
+			image:            SourceImage{Repository: "busybox", Tag: "1.32.0", Target: Target{Repository: "plexsystems"}},
+			expectedProblems: nil,
+		},
+		{
+			image:            SourceImage{Host: "quay.io", Tag: "v1.0.0", Target: target},
+			expectedProblems: []string{"sources[0] (quay.io:v1.0.0): repository is required"},
+		},
+		{
+			image:            SourceImage{Repository: "busybox", Tag: "1.32.0"},
+			expectedProblems: []string{"sources[0] (busybox:1.32.0): target host or repository is required"},
+		},
+		{
+			image:            SourceImage{Repository: "busybox", Digest: "123", Target: target},
+			expectedProblems: []string{"sources[0] (busybox@123): digest 123 must include its algorithm (e.g. sha256:)"},
+		},
+		{
+			image:            SourceImage{Repository: "busybox", Tags: []string{"1.*"}, Target: target},
+			expectedProblems: nil,
+		},
+		{
+			image:            SourceImage{Repository: "busybox", Tag: "1.32.0", Tags: []string{"1.*"}, Target: target},
+			expectedProblems: []string{"sources[0] (busybox:1.32.0): tags can not be used with a tag or digest"},
+		},
+		{
+			image:            SourceImage{Repository: "busybox", Tags: []string{"1.[3"}, Target: target},
+			expectedProblems: []string{"sources[0] (busybox): invalid tags pattern 1.[3"},
+		},
+		{
+			image:            SourceImage{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "target.com", Rewrite: []RewriteRule{{Match: "(busybox", Replace: "mirror"}}}},
+			expectedProblems: []string{"sources[0] (busybox:1.32.0): invalid rewrite match (busybox: error parsing regexp: missing closing ): `(busybox`"},
+		},
+		{
+			image:            SourceImage{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "target.com", TagPrefix: "staging-", TagSuffix: "-staging"}},
+			expectedProblems: nil,
+		},
+		{
+			image: SourceImage{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "target.com", TagPrefix: ".staging", TagSuffix: "/staging"}},
+			expectedProblems: []string{
+				"sources[0] (busybox:1.32.0): invalid tag prefix .staging, must start with a letter, digit, or underscore and only contain letters, digits, underscores, periods, and dashes",
+				"sources[0] (busybox:1.32.0): invalid tag suffix /staging, must only contain letters, digits, underscores, periods, and dashes",
+			},
+		},
+		{
+			image: SourceImage{},
+			expectedProblems: []string{
+				"sources[0]: repository is required",
+				"sources[0]: target host or repository is required",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		manifest := Manifest{Images: []SourceImage{testCase.image}}
+
+		err := manifest.Validate()
+		if testCase.expectedProblems == nil {
+			if err != nil {
+				t.Errorf("expected %s to be valid, actual %v", testCase.image, err)
+			}
+			continue
+		}
+
+		var validationErr ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("expected validation error for %s, actual %v", testCase.image, err)
+		}
+
+		if !reflect.DeepEqual(validationErr.Problems, testCase.expectedProblems) {
+			t.Errorf("expected problems to be %v, actual %v", testCase.expectedProblems, validationErr.Problems)
+		}
+	}
+}
+
+func TestManifest_Validate_Duplicates(t *testing.T) {
+	target := Target{Host: "target.com"}
+
+	manifest := Manifest{
+		Images: []SourceImage{
+			{Repository: "busybox", Tag: "1.32.0", Target: target},
+			{Repository: "nginx", Tag: "1.19.0", Target: target},
+			{Repository: "library/busybox", Host: "docker.io", Tag: "1.32.0", Target: target},
+			{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "other.com"}},
+			{Repository: "busybox", Tag: "1.31.0", Target: target},
+		},
+	}
+
+	expected := []string{
+		"sources[2] (docker.io/library/busybox:1.32.0): duplicate of sources[0] (busybox:1.32.0)",
+		"sources[3] (busybox:1.32.0): duplicate of sources[0] (busybox:1.32.0)",
+	}
+
+	var validationErr ValidationError
+	if err := manifest.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, actual %v", err)
+	}
+
+	if !reflect.DeepEqual(validationErr.Problems, expected) {
+		t.Errorf("expected problems to be %v, actual %v", expected, validationErr.Problems)
+	}
+}
+
+func TestManifest_Warnings(t *testing.T) {
+	target := Target{Host: "target.com"}
+
+	manifest := Manifest{
+		Images: []SourceImage{
+			{Repository: "prometheus/prometheus", Tag: "v2.20.0", Target: target},
+			{Repository: "prometheus/prometheus", Host: "quay.io", Tag: "v2.20.0", Target: target},
+			{Repository: "prometheus/prometheus", Host: "quay.io", Tag: "v2.21.0", Target: target},
+			{Repository: "prometheus/prometheus", Host: "quay.io", Tag: "v2.20.0", Target: Target{Host: "other.com"}},
+		},
+	}
+
+	expected := []string{
+		"sources[1] (quay.io/prometheus/prometheus:v2.20.0): pushed to the same target target.com/prometheus/prometheus:v2.20.0 as sources[0] (prometheus/prometheus:v2.20.0)",
+	}
+
+	if actual := manifest.Warnings(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected warnings to be %v, actual %v", expected, actual)
+	}
+}
+
+func TestManifest_Validate_TargetCollisions(t *testing.T) {
+	testCases := []struct {
+		name             string
+		target           Target
+		expectedProblems int
+		expectedWarnings int
+	}{
+		{name: "other registry", target: Target{Host: "target.com", Repository: "myorg", Flatten: true}, expectedProblems: 1},
+		{name: "ghcr", target: Target{Host: "ghcr.io", Repository: "myorg", Flatten: true}, expectedProblems: 1},
+		{name: "ghcr without flatten", target: Target{Host: "ghcr.io", StripPrefix: "mirror", Rewrite: []RewriteRule{{Match: ".*", Replace: "busybox"}}}, expectedProblems: 1},
+		{name: "other registry without flatten", target: Target{Host: "target.com", Rewrite: []RewriteRule{{Match: ".*", Replace: "busybox"}}}, expectedWarnings: 1},
+	}
+
+	for _, testCase := range testCases {
+		manifest := Manifest{
+			Images: []SourceImage{
+				{Repository: "library/busybox", Tag: "1.32.0", Target: testCase.target},
+				{Host: "quay.io", Repository: "mirror/busybox", Tag: "1.32.0", Target: testCase.target},
+			},
+		}
+
+		var problems int
+		var validationErr ValidationError
+		if err := manifest.Validate(); errors.As(err, &validationErr) {
+			problems = len(validationErr.Problems)
+		}
+
+		if problems != testCase.expectedProblems {
+			t.Errorf("expected %v problems for %s, actual %v", testCase.expectedProblems, testCase.name, problems)
+		}
+
+		if err := manifest.ValidateTargets(); (err != nil) != (testCase.expectedProblems > 0) {
+			t.Errorf("expected target validation error for %s to be %v, actual %v", testCase.name, testCase.expectedProblems > 0, err)
+		}
+
+		if warnings := manifest.Warnings(); len(warnings) != testCase.expectedWarnings {
+			t.Errorf("expected %v warnings for %s, actual %v", testCase.expectedWarnings, testCase.name, warnings)
+		}
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	os.Setenv("SINKER_TEST_HOST", "prod.mycompany.com")
+	defer os.Unsetenv("SINKER_TEST_HOST")
+
+	os.Unsetenv("SINKER_TEST_UNSET")
+
+	testCases := []struct {
+		value         string
+		expected      string
+		expectedError bool
+	}{
+		{value: "mycompany.com", expected: "mycompany.com"},
+		{value: "${SINKER_TEST_HOST}", expected: "prod.mycompany.com"},
+		{value: "${SINKER_TEST_HOST}/myrepo", expected: "prod.mycompany.com/myrepo"},
+		{value: "${SINKER_TEST_HOST:-staging.mycompany.com}", expected: "prod.mycompany.com"},
+		{value: "${SINKER_TEST_UNSET:-staging.mycompany.com}", expected: "staging.mycompany.com"},
+		{value: "${SINKER_TEST_UNSET:-}", expected: ""},
+		{value: "${SINKER_TEST_UNSET}", expectedError: true},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := expandEnv(testCase.value)
+		if testCase.expectedError {
+			if err == nil {
+				t.Errorf("expected %s to return an error", testCase.value)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal("expand env:", err)
+		}
+
+		if actual != testCase.expected {
+			t.Errorf("expected %s to expand to %s, actual %s", testCase.value, testCase.expected, actual)
+		}
+	}
+}
+
+func TestGetManifest_ExpandsEnv(t *testing.T) {
+	os.Setenv("SINKER_TEST_HOST", "prod.mycompany.com")
+	defer os.Unsetenv("SINKER_TEST_HOST")
+
+	manifestPath := writeTestManifest(t, `
+target:
+  host: ${SINKER_TEST_HOST}
+  repository: ${SINKER_TEST_REPOSITORY:-myrepo}
+sources:
+- repository: busybox
+  tag: ${SINKER_TEST_TAG:-1.32.0}
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	const expected = "prod.mycompany.com/myrepo/busybox:1.32.0"
+	if manifest.Images[0].TargetImage() != expected {
+		t.Errorf("expected target image to be %s, actual %s", expected, manifest.Images[0].TargetImage())
+	}
+}
+
+func TestManifest_WithTarget(t *testing.T) {
+	manifest := Manifest{
+		Target: Target{Host: "target.com", Repository: "base", Auth: Auth{Username: "user", Password: "pass"}},
+		Images: []SourceImage{
+			{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "target.com", Repository: "base"}},
+			{Repository: "coreos/etcd", Tag: "v3.4.0", Target: Target{Host: "other.com", Repository: "mirror"}},
+		},
+	}
+
+	testCases := []struct {
+		target          string
+		expectedTargets []string
+	}{
+		{
+			target:          "throwaway.lan:5000/test",
+			expectedTargets: []string{"throwaway.lan:5000/test/busybox:1.32.0", "throwaway.lan:5000/test/coreos/etcd:v3.4.0"},
+		},
+		{
+			target:          "localhost:5000",
+			expectedTargets: []string{"localhost:5000/busybox:1.32.0", "localhost:5000/coreos/etcd:v3.4.0"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		overridden := manifest.WithTarget(testCase.target)
+
+		for i, expectedTarget := range testCase.expectedTargets {
+			if overridden.Images[i].TargetImage() != expectedTarget {
+				t.Errorf("expected target to be %s, actual %s", expectedTarget, overridden.Images[i].TargetImage())
+			}
+		}
+
+		if overridden.Target.Auth != (Auth{}) {
+			t.Errorf("expected the auth of the manifest target to not be used, actual %v", overridden.Target.Auth)
+		}
+	}
+
+	if manifest.Images[0].TargetImage() != "target.com/base/busybox:1.32.0" {
+		t.Errorf("expected the original manifest to be unchanged, actual %s", manifest.Images[0].TargetImage())
+	}
+}
+
+func TestSourceImage_StripPrefix(t *testing.T) {
+	image := SourceImage{
+		Host:       "quay.io",
+		Repository: "organization/team/app",
+		Tag:        "v1.0.0",
+	}
+
+	testCases := []struct {
+		stripPrefix        string
+		expectedTarget     string
+		expectedRepository string
+	}{
+		{stripPrefix: "", expectedTarget: "myregistry.com/mirror/organization/team/app:v1.0.0", expectedRepository: "mirror/organization/team/app"},
+		{stripPrefix: "organization", expectedTarget: "myregistry.com/mirror/team/app:v1.0.0", expectedRepository: "mirror/team/app"},
+		{stripPrefix: "organization/team/", expectedTarget: "myregistry.com/mirror/app:v1.0.0", expectedRepository: "mirror/app"},
+		{stripPrefix: "/organization/team", expectedTarget: "myregistry.com/mirror/app:v1.0.0", expectedRepository: "mirror/app"},
+		{stripPrefix: "org", expectedTarget: "myregistry.com/mirror/organization/team/app:v1.0.0", expectedRepository: "mirror/organization/team/app"},
+		{stripPrefix: "other", expectedTarget: "myregistry.com/mirror/organization/team/app:v1.0.0", expectedRepository: "mirror/organization/team/app"},
+		{stripPrefix: "organization/team/app", expectedTarget: "myregistry.com/mirror/organization/team/app:v1.0.0", expectedRepository: "mirror/organization/team/app"},
+	}
+
+	for _, testCase := range testCases {
+		image.Target = Target{Host: "myregistry.com", Repository: "mirror", StripPrefix: testCase.stripPrefix}
+
+		actual := image.TargetImage()
+		if actual != testCase.expectedTarget {
+			t.Errorf("expected target %s when stripping %q, actual %s", testCase.expectedTarget, testCase.stripPrefix, actual)
+		}
+
+		actualRepository := docker.RegistryPath(actual).Repository()
+		if actualRepository != testCase.expectedRepository {
+			t.Errorf("expected target repository %s when stripping %q, actual %s", testCase.expectedRepository, testCase.stripPrefix, actualRepository)
+		}
+	}
+}
+
+func TestGetManifest_StripPrefix(t *testing.T) {
+	manifestPath := writeTestManifest(t, `
+target:
+  host: myregistry.com
+  stripPrefix: organization/team
+sources:
+- repository: organization/team/app
+  host: quay.io
+  tag: v1.0.0
+- repository: organization/other/app
+  host: quay.io
+  tag: v1.0.0
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	expectedTargets := []string{"myregistry.com/app:v1.0.0", "myregistry.com/organization/other/app:v1.0.0"}
+	for i, expectedTarget := range expectedTargets {
+		if manifest.Images[i].TargetImage() != expectedTarget {
+			t.Errorf("expected target to be %s, actual %s", expectedTarget, manifest.Images[i].TargetImage())
+		}
+	}
+
+	overridden := manifest.WithTarget("other.com/mirror")
+	if overridden.Images[0].TargetImage() != "other.com/mirror/app:v1.0.0" {
+		t.Errorf("expected the strip prefix to be kept when the target is overridden, actual %s", overridden.Images[0].TargetImage())
+	}
+}
+
+func TestSourceImage_Rewrite(t *testing.T) {
+	rules := []RewriteRule{
+		{Match: "^library/(.*)$", Replace: "mirror/$1"},
+		{Match: "^library/nginx$", Replace: "web/nginx"},
+		{Match: "^([^/]+)/([^/]+)/(.*)$", Replace: "$1-$2-$3"},
+	}
+
+	testCases := []struct {
+		repository     string
+		stripPrefix    string
+		expectedTarget string
+	}{
+		{repository: "library/nginx", expectedTarget: "target.com/mirror/nginx:v1.0.0"},
+		{repository: "organization/team/app", expectedTarget: "target.com/organization-team-app:v1.0.0"},
+		{repository: "organization/team/app", stripPrefix: "organization", expectedTarget: "target.com/team/app:v1.0.0"},
+		{repository: "coreos/etcd", expectedTarget: "target.com/coreos/etcd:v1.0.0"},
+		{repository: "busybox", expectedTarget: "target.com/busybox:v1.0.0"},
+	}
+
+	for _, testCase := range testCases {
+		image := SourceImage{
+			Repository: testCase.repository,
+			Tag:        "v1.0.0",
+			Target:     Target{Host: "target.com", StripPrefix: testCase.stripPrefix, Rewrite: rules},
+		}
+
+		actual := image.TargetImage()
+		if actual != testCase.expectedTarget {
+			t.Errorf("expected target %s for %s, actual %s", testCase.expectedTarget, testCase.repository, actual)
+		}
+	}
+}
+
+func writeTestManifests(t *testing.T, manifests map[string]string) string {
+	manifestDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+
+	for name, contents := range manifests {
+		if err := ioutil.WriteFile(filepath.Join(manifestDir, name), []byte(contents), os.ModePerm); err != nil {
+			t.Fatal("write manifest:", err)
+		}
+	}
+
+	return manifestDir
+}
+
+func TestGetManifest_MergesManifests(t *testing.T) {
+	manifestDir := writeTestManifests(t, map[string]string{
+		"team-a.yaml": `
+target:
+  host: myregistry.com
+sources:
+- repository: team-a/app
+  host: quay.io
+  tag: v1.0.0
+`,
+		"team-b.yml": `
+sources:
+- repository: team-b/app
+  host: quay.io
+  tag: v2.0.0
+`,
+	})
+	defer os.RemoveAll(manifestDir)
+
+	testCases := []struct {
+		name string
+		path string
+	}{
+		{name: "directory", path: manifestDir},
+		{name: "comma-separated files", path: filepath.Join(manifestDir, "team-b.yml") + "," + filepath.Join(manifestDir, "team-a.yaml")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			manifest, err := GetManifest(testCase.path)
+			if err != nil {
+				t.Fatal("get manifest:", err)
+			}
+
+			if manifest.Target.String() != "myregistry.com" {
+				t.Errorf("expected the shared target to be myregistry.com, actual %s", manifest.Target)
+			}
+
+			var actual []string
+			for _, image := range manifest.Images {
+				actual = append(actual, image.TargetImage())
+			}
+			sort.Strings(actual)
+
+			expected := []string{"myregistry.com/team-a/app:v1.0.0", "myregistry.com/team-b/app:v2.0.0"}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("expected merged images %v, actual %v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetManifest_ConflictingTargets(t *testing.T) {
+	manifestDir := writeTestManifests(t, map[string]string{
+		"team-a.yaml": `
+target:
+  host: myregistry.com
+sources:
+- repository: team-a/app
+  host: quay.io
+`,
+		"team-b.yaml": `
+target:
+  host: myregistry.com
+sources:
+- repository: team-b/app
+  host: quay.io
+`,
+		"team-c.yaml": `
+target:
+  host: other.com
+  repository: mirror
+sources:
+- repository: team-c/app
+  host: quay.io
+`,
+	})
+	defer os.RemoveAll(manifestDir)
+
+	_, err := GetManifest(manifestDir)
+	if err == nil {
+		t.Fatal("expected an error for conflicting targets")
+	}
+
+	if !strings.Contains(err.Error(), "team-c.yaml") || !strings.Contains(err.Error(), "other.com/mirror") {
+		t.Errorf("expected the conflicting target of team-c.yaml to be reported, actual %s", err)
+	}
+
+	if strings.Contains(err.Error(), "team-b.yaml conflicts") {
+		t.Errorf("expected agreeing targets to not be reported, actual %s", err)
+	}
+}
+
+func TestWriteManifest_MultipleManifests(t *testing.T) {
+	if err := WriteManifest(NewManifest("myregistry.com"), "team-a.yaml,team-b.yaml"); err == nil {
+		t.Error("expected an error when writing to multiple manifests")
+	}
+}
+
+func TestGetManifest_ImagePositions(t *testing.T) {
+	manifestDir := writeTestManifests(t, map[string]string{
+		"team-a.yaml": `target:
+  host: myregistry.com
+sources:
+- repository: team-a/app
+  tag: v1.0.0
+-   repository: team-a/worker
+    tag: v1.0.0
+`,
+		"team-b.yaml": `sources:
+  - repository: team-b/app
+    tag: v2.0.0
+`,
+	})
+	defer os.RemoveAll(manifestDir)
+
+	manifest, err := GetManifest(manifestDir)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	expected := map[string]manifestPosition{
+		"team-a/app:v1.0.0":    {file: filepath.Join(manifestDir, "team-a.yaml"), line: 4, column: 3},
+		"team-a/worker:v1.0.0": {file: filepath.Join(manifestDir, "team-a.yaml"), line: 6, column: 5},
+		"team-b/app:v2.0.0":    {file: filepath.Join(manifestDir, "team-b.yaml"), line: 2, column: 5},
+	}
+
+	actual := make(map[string]manifestPosition)
+	for _, image := range manifest.Images {
+		actual[image.String()] = image.position
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected image positions %v, actual %v", expected, actual)
+	}
+}
+
+func TestSourceImage_TargetImage_GHCR(t *testing.T) {
+	testCases := []struct {
+		name     string
+		image    SourceImage
+		expected string
+	}{
+		{
+			name:     "organization",
+			image:    SourceImage{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0", Target: Target{Host: "ghcr.io", Repository: "myorg"}},
+			expected: "ghcr.io/myorg/coreos/prometheus-operator:v0.40.0",
+		},
+		{
+			name:     "flattened organization",
+			image:    SourceImage{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0", Target: Target{Host: "ghcr.io", Repository: "myorg", Flatten: true}},
+			expected: "ghcr.io/myorg/prometheus-operator:v0.40.0",
+		},
+		{
+			name:     "mixed case organization",
+			image:    SourceImage{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "ghcr.io", Repository: "MyOrg", Flatten: true}},
+			expected: "ghcr.io/myorg/busybox:1.32.0",
+		},
+		{
+			name:     "mixed case source",
+			image:    SourceImage{Host: "quay.io", Repository: "MyTeam/MyApp", Tag: "v1.0.0-RC1", Target: Target{Host: "ghcr.io", Repository: "myorg", Flatten: true}},
+			expected: "ghcr.io/myorg/myapp:v1.0.0-RC1",
+		},
+		{
+			name:     "strip prefix",
+			image:    SourceImage{Host: "quay.io", Repository: "coreos/kube-state-metrics/exporter", Tag: "v1.9.7", Target: Target{Host: "ghcr.io", Repository: "myorg", StripPrefix: "coreos"}},
+			expected: "ghcr.io/myorg/kube-state-metrics/exporter:v1.9.7",
+		},
+		{
+			name:     "flattened rewrite",
+			image:    SourceImage{Host: "quay.io", Repository: "coreos/kube-state-metrics/exporter", Tag: "v1.9.7", Target: Target{Host: "ghcr.io", Repository: "myorg", Flatten: true, Rewrite: []RewriteRule{{Match: "^coreos/(.*)/exporter$", Replace: "$1"}}}},
+			expected: "ghcr.io/myorg/kube-state-metrics:v1.9.7",
+		},
+		{
+			name:     "no organization",
+			image:    SourceImage{Repository: "MyOrg/busybox", Tag: "1.32.0", Target: Target{Host: "ghcr.io"}},
+			expected: "ghcr.io/myorg/busybox:1.32.0",
+		},
+		{
+			name:     "other registry",
+			image:    SourceImage{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0", Target: Target{Host: "mycompany.com", Repository: "Mirror"}},
+			expected: "mycompany.com/mirror/coreos/prometheus-operator:v0.40.0",
+		},
+	}
+
+	for _, testCase := range testCases {
+		if actual := testCase.image.TargetImage(); actual != testCase.expected {
+			t.Errorf("expected target image of %s to be %s, actual %s", testCase.name, testCase.expected, actual)
+		}
+	}
+}
+
+func TestSourceImage_TargetTag(t *testing.T) {
+	testCases := []struct {
+		image    SourceImage
+		expected string
+	}{
+		{image: SourceImage{Repository: "nginx", Tag: "1.25"}, expected: "1.25"},
+		{image: SourceImage{Repository: "nginx", Tag: "latest"}, expected: "latest"},
+		{image: SourceImage{Repository: "nginx", Tag: "1.25", Digest: "sha256:123"}, expected: "1.25"},
+		{image: SourceImage{Repository: "nginx", Digest: "sha256:123"}, expected: "123"},
+		{image: SourceImage{Repository: "nginx", Tag: "1.25", Target: Target{TagPrefix: "staging-"}}, expected: "staging-1.25"},
+		{image: SourceImage{Repository: "nginx", Tag: "1.25", Target: Target{TagSuffix: "-staging"}}, expected: "1.25-staging"},
+		{image: SourceImage{Repository: "nginx", Tag: "1.25", Target: Target{TagPrefix: "v", TagSuffix: "-staging"}}, expected: "v1.25-staging"},
+		{image: SourceImage{Repository: "nginx", Digest: "sha256:123", Target: Target{TagPrefix: "v", TagSuffix: "-staging"}}, expected: "123"},
+	}
+
+	for _, testCase := range testCases {
+		if actual := testCase.image.targetTag(); actual != testCase.expected {
+			t.Errorf("expected target tag of %s to be %s, actual %s", testCase.image, testCase.expected, actual)
+		}
+	}
+}
+
+func TestManifest_WithTagAffixes(t *testing.T) {
+	manifest := Manifest{
+		Target: Target{Host: "mycompany.com", TagSuffix: "-old"},
+		Images: []SourceImage{
+			{Repository: "nginx", Tag: "1.25", Target: Target{Host: "mycompany.com", TagSuffix: "-old"}},
+			{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "other.com", TagPrefix: "mirror-"}},
+		},
+	}
+
+	actual := manifest.WithTagAffixes("", "-staging")
+
+	if actual.Target.TagSuffix != "-staging" {
+		t.Errorf("expected manifest target tag suffix to be -staging, actual %s", actual.Target.TagSuffix)
+	}
+
+	expected := []string{"mycompany.com/nginx:1.25-staging", "other.com/busybox:mirror-1.32.0-staging"}
+	for i, image := range actual.Images {
+		if image.TargetImage() != expected[i] {
+			t.Errorf("expected target image %s, actual %s", expected[i], image.TargetImage())
+		}
+	}
+
+	if manifest.Images[0].TargetImage() != "mycompany.com/nginx:1.25-old" {
+		t.Errorf("expected original manifest to be unchanged, actual %s", manifest.Images[0].TargetImage())
+	}
+}
+
+func TestGetManifest_Groups(t *testing.T) {
+	manifestPath := writeTestManifest(t, `target:
+  host: mycompany.com
+  repository: myteam
+sources:
+- repository: busybox
+  tag: 1.32.0
+groups:
+- targetPrefix: monitoring
+  sources:
+  - repository: prometheus/prometheus
+    host: quay.io
+    tag: v2.22.0
+  - repository: grafana/grafana
+    tag: 7.3.0
+    target:
+      host: other.com
+      repository: dashboards
+- targetPrefix: /databases/
+  sources:
+  - repository: postgres
+    tag: "13.0"
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	if len(manifest.Groups) != 0 {
+		t.Errorf("expected the groups to be flattened, actual %v groups", len(manifest.Groups))
+	}
+
+	expected := []string{
+		"mycompany.com/myteam/busybox:1.32.0",
+		"mycompany.com/myteam/monitoring/prometheus/prometheus:v2.22.0",
+		"other.com/dashboards/grafana/grafana:7.3.0",
+		"mycompany.com/myteam/databases/postgres:13.0",
+	}
+
+	var actual []string
+	for _, image := range manifest.Images {
+		actual = append(actual, image.TargetImage())
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected target images %v, actual %v", expected, actual)
+	}
+
+	expectedLines := []int{5, 10, 13, 20}
+	for i, image := range manifest.Images {
+		if image.position.line != expectedLines[i] {
+			t.Errorf("expected %s to be at line %v, actual %v", image, expectedLines[i], image.position.line)
+		}
+	}
+}
+
+func TestGetManifest_GroupsWithTarget(t *testing.T) {
+	manifestPath := writeTestManifest(t, `target:
+  host: mycompany.com
+sources:
+- repository: team-a/nginx
+  tag: "1.25"
+groups:
+- targetPrefix: team-b
+  sources:
+  - repository: nginx
+    tag: "1.25"
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	expected := []string{
+		"other.io/mirror/team-a/nginx:1.25",
+		"other.io/mirror/team-b/nginx:1.25",
+	}
+
+	var actual []string
+	for _, image := range manifest.WithTarget("other.io/mirror").Images {
+		actual = append(actual, image.TargetImage())
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected the group prefix to be kept when the target is overridden, expected %v actual %v", expected, actual)
+	}
+}
+
+func TestGetManifest_GroupsMergedManifests(t *testing.T) {
+	manifestDir := writeTestManifests(t, map[string]string{
+		"team-a.yaml": `target:
+  host: myregistry.com
+sources:
+- repository: team-a/app
+  tag: v1.0.0
+`,
+		"team-b.yaml": `groups:
+- targetPrefix: team-b
+  sources:
+  - repository: app
+    tag: v2.0.0
+`,
+	})
+	defer os.RemoveAll(manifestDir)
+
+	manifest, err := GetManifest(manifestDir)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	if actual := manifest.Images[1].TargetImage(); actual != "myregistry.com/team-b/app:v2.0.0" {
+		t.Errorf("expected the group prefix to be appended to the merged target, actual %s", actual)
+	}
+}
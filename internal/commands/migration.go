@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// currentManifestVersion is the version of the manifest created by this version of sinker
+const currentManifestVersion = 1
+
+// manifestMigrations migrate the contents of a manifest from the version at their index to the next version.
+// Migrations operate on the contents of the manifest so that comments and the formatting of values are kept.
+var manifestMigrations = []func([]byte) ([]byte, error){
+	// Manifests created before the version field was introduced
+	// are otherwise the same as version 1 manifests.
+	func(contents []byte) ([]byte, error) {
+		return contents, nil
+	},
+}
+
+// versionField matches the top level version field of a manifest
+var versionField = regexp.MustCompile(`(?m)^version:.*$`)
+
+// migrateManifest migrates the contents of a manifest to the current manifest version.
+// Manifests without a version are assumed to be version 0. The version field itself is
+// not changed, so that errors found in the migrated contents refer to the original lines.
+func migrateManifest(contents []byte) ([]byte, error) {
+	var versionedManifest struct {
+		Version int `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(contents, &versionedManifest); err != nil {
+		return nil, fmt.Errorf("unmarshal manifest version: %w", err)
+	}
+
+	version := versionedManifest.Version
+	if version > currentManifestVersion {
+		return nil, fmt.Errorf("manifest version %v is newer than the supported version %v, this sinker is too old and must be upgraded", version, currentManifestVersion)
+	}
+
+	if version < 0 {
+		return nil, fmt.Errorf("invalid manifest version %v", version)
+	}
+
+	for ; version < currentManifestVersion; version++ {
+		migratedContents, err := manifestMigrations[version](contents)
+		if err != nil {
+			return nil, fmt.Errorf("migrate manifest from version %v: %w", version, err)
+		}
+
+		contents = migratedContents
+	}
+
+	return contents, nil
+}
+
+func setManifestVersion(contents []byte, version int) []byte {
+	versionLine := []byte(fmt.Sprintf("version: %v", version))
+	if versionField.Match(contents) {
+		return versionField.ReplaceAll(contents, versionLine)
+	}
+
+	return append(append(versionLine, '\n'), contents...)
+}
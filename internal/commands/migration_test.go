@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const unversionedManifest = `# Images for the monitoring stack
+target:
+  host: mycompany.com
+sources:
+- repository: busybox
+  tag: 1.30
+`
+
+func TestSetManifestVersion(t *testing.T) {
+	versionedContents := setManifestVersion([]byte(unversionedManifest), 1)
+
+	expected := "version: 1\n" + unversionedManifest
+	if string(versionedContents) != expected {
+		t.Errorf("expected versioned manifest to be %s, actual %s", expected, versionedContents)
+	}
+
+	if actual := setManifestVersion(versionedContents, 1); string(actual) != expected {
+		t.Errorf("expected existing version to be replaced, actual %s", actual)
+	}
+}
+
+func TestMigrateManifest_TooNew(t *testing.T) {
+	_, err := migrateManifest([]byte("version: 2\ntarget:\n  host: mycompany.com\n"))
+	if err == nil {
+		t.Fatal("expected newer manifest version to return an error")
+	}
+
+	if !strings.Contains(err.Error(), "this sinker is too old") {
+		t.Errorf("expected error to mention sinker is too old, actual %v", err)
+	}
+}
+
+func TestRunMigrateManifest_RoundTrip(t *testing.T) {
+	manifestPath := writeTestManifest(t, unversionedManifest)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	unversioned, err := GetManifest(manifestPath)
+	if err != nil {
+		t.Fatal("get unversioned manifest:", err)
+	}
+
+	if err := runMigrateManifest(manifestPath); err != nil {
+		t.Fatal("migrate manifest:", err)
+	}
+
+	migratedContents, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal("read migrated manifest:", err)
+	}
+
+	if !strings.HasPrefix(string(migratedContents), "version: 1\n") {
+		t.Errorf("expected migrated manifest to start with the version, actual %s", migratedContents)
+	}
+
+	migrated, err := GetManifest(manifestPath)
+	if err != nil {
+		t.Fatal("get migrated manifest:", err)
+	}
+
+	// The version is added as the first line, so only the positions of the images are expected to change
+	if !reflect.DeepEqual(withoutPositions(migrated), withoutPositions(unversioned)) {
+		t.Errorf("expected migrated manifest to be %v, actual %v", unversioned, migrated)
+	}
+
+	if migrated.Version != currentManifestVersion {
+		t.Errorf("expected manifest version to be %v, actual %v", currentManifestVersion, migrated.Version)
+	}
+
+	if migrated.Images[0].Tag != "1.30" {
+		t.Errorf("expected tag to be 1.30, actual %s", migrated.Images[0].Tag)
+	}
+}
+
+func withoutPositions(manifest Manifest) Manifest {
+	images := make([]SourceImage, len(manifest.Images))
+	for i, image := range manifest.Images {
+		image.position = manifestPosition{}
+		images[i] = image
+	}
+	manifest.Images = images
+
+	return manifest
+}
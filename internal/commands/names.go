@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// addStrictNamesFlag adds the strict-names flag, which fails when any target repository is lowercased
+func addStrictNamesFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("strict-names", false, "Fail when the target repository of any image has uppercase letters, rather than warning about it and pushing it to the lowercase repository")
+}
+
+// getUppercaseNameProblems returns a problem for each image with uppercase letters in its target
+// repository, which is lowercased when the image is pushed, as repositories can only be lowercase
+func getUppercaseNameProblems(images []SourceImage) []string {
+	var problems []string
+	for i, image := range images {
+		if image.Repository == "" {
+			continue
+		}
+
+		target, original := image.targetImage()
+		if target == original {
+			continue
+		}
+
+		problems = append(problems, getUppercaseNameProblem(getImageName(i, image), original, target))
+	}
+
+	return problems
+}
+
+func getUppercaseNameProblem(imageName string, original string, target string) string {
+	return fmt.Sprintf("%s: target %s has uppercase letters, which are not allowed in repositories, and is pushed as %s", imageName, original, target)
+}
+
+// checkTargetNames warns about each image with uppercase letters in its target repository,
+// or returns a ValidationError with the images when the strict-names flag is set
+func checkTargetNames(logger *log.Logger, command string, images []SourceImage, strict bool) error {
+	problems := getUppercaseNameProblems(images)
+	if strict && len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+
+	for _, problem := range problems {
+		logger.Warnf("[%s] %s", strings.ToUpper(command), problem)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestGetUppercaseNameProblems(t *testing.T) {
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "mycompany.com", Repository: "mirror"}},
+		{Repository: "MyTeam/MyApp", Host: "quay.io", Tag: "v1.0.0-RC1", Target: Target{Host: "mycompany.com", Repository: "mirror"}},
+		{Repository: "nginx", Tag: "1.25", Target: Target{Host: "MyCompany.com", Repository: "Mirror"}},
+		{Repository: "nginx", Tag: "1.25-RC1", Target: Target{Host: "mycompany.com"}},
+	}
+
+	expected := []string{
+		"sources[1] (quay.io/MyTeam/MyApp:v1.0.0-RC1): target mycompany.com/mirror/MyTeam/MyApp:v1.0.0-RC1 has uppercase letters, which are not allowed in repositories, and is pushed as mycompany.com/mirror/myteam/myapp:v1.0.0-RC1",
+		"sources[2] (nginx:1.25): target MyCompany.com/Mirror/nginx:1.25 has uppercase letters, which are not allowed in repositories, and is pushed as MyCompany.com/mirror/nginx:1.25",
+	}
+
+	actual := getUppercaseNameProblems(images)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected problems %v, actual %v", expected, actual)
+	}
+}
+
+func TestCheckTargetNames(t *testing.T) {
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "mycompany.com", Repository: "MyTeam"}},
+	}
+
+	var output bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&output)
+
+	if err := checkTargetNames(logger, "push", images, false); err != nil {
+		t.Fatal("expected the uppercase target to only be a warning:", err)
+	}
+
+	if !strings.Contains(output.String(), "[PUSH] sources[0] (busybox:1.32.0): target mycompany.com/MyTeam/busybox:1.32.0 has uppercase letters") {
+		t.Errorf("expected the uppercase target to be logged as a warning, actual %s", output.String())
+	}
+
+	output.Reset()
+	err := checkTargetNames(logger, "push", images, true)
+
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Problems) != 1 {
+		t.Fatalf("expected a validation error with the uppercase target, actual %v", err)
+	}
+
+	if output.Len() > 0 {
+		t.Errorf("expected nothing to be logged in strict mode, actual %s", output.String())
+	}
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+	yamlOutput  = "yaml"
+)
+
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", tableOutput, "Output format (table, json, yaml)")
+}
+
+func validateOutputFormat(format string) error {
+	switch format {
+	case tableOutput, jsonOutput, yamlOutput:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// writeOutput writes the result of a command in the given format.
+// The JSON and YAML output is marshalled from the json tags of the result,
+// while the table output is left to each command to format.
+func writeOutput(output io.Writer, format string, result interface{}, writeTable func(io.Writer) error) error {
+	var contents []byte
+	switch format {
+	case tableOutput:
+		return writeTable(output)
+
+	case jsonOutput:
+		jsonContents, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal json: %w", err)
+		}
+
+		contents = append(jsonContents, '\n')
+
+	case yamlOutput:
+		yamlContents, err := yaml.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("marshal yaml: %w", err)
+		}
+
+		contents = yamlContents
+
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	if _, err := output.Write(contents); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	return nil
+}
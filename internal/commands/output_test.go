@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestWriteOutput_Golden(t *testing.T) {
+	images := []SourceImage{
+		{
+			Host:       "quay.io",
+			Repository: "coreos/prometheus-operator",
+			Tag:        "v0.40.0",
+			Target:     Target{Host: "mycompany.com", Repository: "myrepo"},
+		},
+		{
+			Repository: "nginx",
+			Digest:     "sha256:123",
+			Target:     Target{Host: "mycompany.com", Repository: "myrepo"},
+		},
+	}
+
+	results := []checkResult{
+		{Image: "busybox:1.30.0", Status: "outdated", NewerVersions: []string{"1.31.0", "1.32.0"}},
+		{Image: "mycompany.com/myrepo/nginx:1.19.0", Source: "nginx:1.19.0", Status: string(imageDigestMismatched)},
+	}
+
+	diff := manifestDiff{
+		Added:   []string{"quay.io/coreos/prometheus-config-reloader:v0.40.0"},
+		Removed: []string{"docker.io/jimmidyson/configmap-reload:v0.3.0"},
+		Changed: []imageChange{
+			{Repository: "quay.io/coreos/prometheus-operator", From: ":v0.40.0", To: ":v0.41.0"},
+		},
+	}
+
+	writers := map[string]func(io.Writer, string) error{
+		"list": func(output io.Writer, format string) error {
+			return writeImageList(output, images, nil, "source", format)
+		},
+		"list-sizes": func(output io.Writer, format string) error {
+			return writeImageList(output, images, &imageSizes{Images: []int64{52428800, 1530}, Total: 52429100}, "source", format)
+		},
+		"check": func(output io.Writer, format string) error {
+			return writeCheckResults(output, results, format)
+		},
+		"diff": func(output io.Writer, format string) error {
+			return writeManifestDiff(output, diff, format)
+		},
+	}
+
+	for command, write := range writers {
+		for _, format := range []string{tableOutput, jsonOutput, yamlOutput} {
+			var output bytes.Buffer
+			if err := write(&output, format); err != nil {
+				t.Fatal("write output:", err)
+			}
+
+			goldenPath := filepath.Join("testdata", command+"."+format+".golden")
+			if *update {
+				if err := ioutil.WriteFile(goldenPath, output.Bytes(), 0644); err != nil {
+					t.Fatal("write golden file:", err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal("read golden file:", err)
+			}
+
+			if output.String() != string(expected) {
+				t.Errorf("expected %s %s output to be %s, actual %s", command, format, expected, output.String())
+			}
+		}
+	}
+}
+
+func TestWriteOutput_UnknownFormat(t *testing.T) {
+	if err := validateOutputFormat("xml"); err == nil {
+		t.Error("expected unknown output format to return an error")
+	}
+}
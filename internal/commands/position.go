@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// manifestPosition is the location of an image entry in a manifest file
+type manifestPosition struct {
+	file   string
+	line   int
+	column int
+}
+
+// String returns the position as file:line, or an empty string when the position is not known
+func (p manifestPosition) String() string {
+	if p.file == "" || p.line == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s:%v", p.file, p.line)
+}
+
+// getImagePositions returns the position of each entry of the sources of the manifest contents, followed
+// by the entries of the sources of each group, which are found by decoding the contents into YAML nodes
+// that retain their line and column
+func getImagePositions(file string, contents []byte) ([]manifestPosition, error) {
+	var document yamlv3.Node
+	if err := yamlv3.Unmarshal(contents, &document); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	if len(document.Content) == 0 || document.Content[0].Kind != yamlv3.MappingNode {
+		return nil, nil
+	}
+
+	root := document.Content[0]
+	positions := getSourcePositions(file, getMappingValue(root, "sources"))
+	if groups := getMappingValue(root, "groups"); groups != nil && groups.Kind == yamlv3.SequenceNode {
+		for _, group := range groups.Content {
+			if group.Kind == yamlv3.MappingNode {
+				positions = append(positions, getSourcePositions(file, getMappingValue(group, "sources"))...)
+			}
+		}
+	}
+
+	return positions, nil
+}
+
+// getSourcePositions returns the position of each entry of the sequence of sources
+func getSourcePositions(file string, sources *yamlv3.Node) []manifestPosition {
+	if sources == nil || sources.Kind != yamlv3.SequenceNode {
+		return nil
+	}
+
+	var positions []manifestPosition
+	for _, entry := range sources.Content {
+		positions = append(positions, manifestPosition{file: file, line: entry.Line, column: entry.Column})
+	}
+
+	return positions
+}
+
+// getMappingValue returns the value of the key of the mapping, or nil when the mapping does not have the key
+func getMappingValue(mapping *yamlv3.Node, key string) *yamlv3.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newPruneCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "prune",
+		Short: "Delete the images in the target repository that are not in the image manifest",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("confirm", cmd.Flags().Lookup("confirm")); err != nil {
+				return fmt.Errorf("bind confirm flag: %w", err)
+			}
+
+			if err := bindClientFlags(cmd); err != nil {
+				return fmt.Errorf("bind client flags: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runPruneCommand(ctx, logger, manifestPath); err != nil {
+				return fmt.Errorf("prune: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("confirm", false, "Delete the images. Without this flag, the images that would be deleted are only printed")
+	addClientFlags(&cmd)
+
+	return &cmd
+}
+
+type imagePruner interface {
+	tagLister
+	GetRepositoriesAtRemote(ctx context.Context, host string, encodedAuth string) ([]string, error)
+	GetDigestsAtRemote(ctx context.Context, image string, encodedAuth string) ([]string, error)
+	DeleteImageAtRemote(ctx context.Context, image string, encodedAuth string) error
+}
+
+func runPruneCommand(ctx context.Context, logger *log.Logger, manifestPath string) error {
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+
+	clientOptions, err := getClientOptions()
+	if err != nil {
+		return fmt.Errorf("get client options: %w", err)
+	}
+
+	client, err := docker.NewClient(logger, clientOptions...)
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	if err := pruneImages(ctx, logger, client, manifest.Images, viper.GetBool("confirm")); err != nil {
+		return fmt.Errorf("prune images: %w", err)
+	}
+
+	return nil
+}
+
+// pruneImages deletes the stale images in the target repositories of the
+// images, or only logs the images that would be deleted when not confirmed
+func pruneImages(ctx context.Context, logger *log.Logger, pruner imagePruner, images []SourceImage, confirm bool) error {
+	targetAuths, err := getTargetAuths(ctx, images)
+	if err != nil {
+		return fmt.Errorf("get target auths: %w", err)
+	}
+
+	staleImages, err := getStaleImages(ctx, logger, pruner, images, targetAuths)
+	if err != nil {
+		return fmt.Errorf("get stale images: %w", err)
+	}
+
+	if len(staleImages) == 0 {
+		logger.Printf("[PRUNE] No stale images found. 0 images deleted.")
+		return nil
+	}
+
+	for _, staleImage := range staleImages {
+		if !confirm {
+			newImageLogEntry(logger, "prune", staleImage).Printf("[PRUNE] Would delete %s", staleImage)
+			continue
+		}
+
+		if err := pruner.DeleteImageAtRemote(ctx, staleImage, targetAuths[docker.RegistryPath(staleImage).Host()]); err != nil {
+			return fmt.Errorf("delete %s: %w", staleImage, err)
+		}
+
+		newImageLogEntry(logger, "prune", staleImage).Printf("[PRUNE] Deleted %s", staleImage)
+	}
+
+	if !confirm {
+		logger.Printf("[PRUNE] Run with --confirm to delete %v image(s)", len(staleImages))
+	}
+
+	return nil
+}
+
+// getTargetAuths returns the encoded auth of the target of the images by the host of the target.
+// The auth of the first image of each host is used, as a registry is listed with a single auth.
+func getTargetAuths(ctx context.Context, images []SourceImage) (map[string]string, error) {
+	auths := make(map[string]string)
+	for _, image := range images {
+		if _, exists := auths[image.Target.Host]; exists {
+			continue
+		}
+
+		auth, err := getEncodedTargetAuth(ctx, image.Target)
+		if err != nil {
+			return nil, fmt.Errorf("get %s auth: %w", image.Target, err)
+		}
+
+		auths[image.Target.Host] = auth
+	}
+
+	return auths, nil
+}
+
+// getStaleImages returns the images found in the target repositories of the images
+// that are not referenced by any of the images. Only the repositories within the
+// target repository are considered, so images outside of it are never returned.
+// The registry of each target host is listed with the auth of the host in the target auths.
+func getStaleImages(ctx context.Context, logger *log.Logger, pruner imagePruner, images []SourceImage, targetAuths map[string]string) ([]string, error) {
+	images, err := expandImageTags(ctx, pruner, images)
+	if err != nil {
+		return nil, fmt.Errorf("expand image tags: %w", err)
+	}
+
+	referencedImages := make(map[string]bool)
+	namespaces := make(map[string][]string)
+	for _, image := range images {
+		if image.Target.Repository == "" {
+			return nil, fmt.Errorf("target %s of %s has no repository, the entire registry can not be pruned", image.Target, image.String())
+		}
+
+		referencedImages[getPruneReference(image.TargetImage())] = true
+
+		if !contains(namespaces[image.Target.Host], image.Target.Repository) {
+			namespaces[image.Target.Host] = append(namespaces[image.Target.Host], image.Target.Repository)
+		}
+	}
+
+	var hosts []string
+	for host := range namespaces {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var staleImages []string
+	for _, host := range hosts {
+		repositories, err := pruner.GetRepositoriesAtRemote(ctx, host, targetAuths[host])
+		if err != nil {
+			return nil, fmt.Errorf("get repositories of %s: %w", host, err)
+		}
+
+		for _, repository := range repositories {
+			if !isInNamespace(repository, namespaces[host]) {
+				continue
+			}
+
+			repositoryStaleImages, err := getStaleImagesInRepository(ctx, logger, pruner, host, repository, targetAuths[host], referencedImages)
+			if err != nil {
+				return nil, fmt.Errorf("get stale images of %s: %w", repository, err)
+			}
+
+			staleImages = append(staleImages, repositoryStaleImages...)
+		}
+	}
+
+	return staleImages, nil
+}
+
+func getStaleImagesInRepository(ctx context.Context, logger *log.Logger, pruner imagePruner, host string, repository string, encodedAuth string, referencedImages map[string]bool) ([]string, error) {
+	tags, err := pruner.GetTagsForRepo(ctx, host, repository, encodedAuth)
+	if err != nil {
+		return nil, fmt.Errorf("get tags: %w", err)
+	}
+
+	var candidates []string
+	var referencedDigests []string
+	for _, tag := range tags {
+		image := strings.TrimLeft(host+"/"+repository+":"+tag, "/")
+		if !referencedImages[getPruneReference(image)] {
+			candidates = append(candidates, image)
+			continue
+		}
+
+		digests, err := pruner.GetDigestsAtRemote(ctx, image, encodedAuth)
+		if err != nil {
+			return nil, fmt.Errorf("get digest of %s: %w", image, err)
+		}
+
+		referencedDigests = append(referencedDigests, digests...)
+	}
+
+	// Images are deleted by their digest, which would also delete
+	// any referenced image that shares the same digest.
+	var staleImages []string
+	for _, candidate := range candidates {
+		digests, err := pruner.GetDigestsAtRemote(ctx, candidate, encodedAuth)
+		if err != nil {
+			return nil, fmt.Errorf("get digest of %s: %w", candidate, err)
+		}
+
+		if len(digests) > 0 && contains(referencedDigests, digests[0]) {
+			newImageLogEntry(logger, "prune", candidate).Printf("[PRUNE] Image %s has the same digest as an image in the manifest, skipping", candidate)
+			continue
+		}
+
+		staleImages = append(staleImages, candidate)
+	}
+
+	return staleImages, nil
+}
+
+// getPruneReference returns the reference of the image that is compared
+// against the images found in the registry. An image without a tag or a
+// digest refers to the latest tag, which is how the registry lists it.
+func getPruneReference(image string) string {
+	path := docker.RegistryPath(image)
+	if path.Tag() == "" && path.Digest() == "" {
+		return path.Reference() + ":latest"
+	}
+
+	return path.Reference()
+}
+
+func isInNamespace(repository string, namespaces []string) bool {
+	for _, namespace := range namespaces {
+		if strings.HasPrefix(repository, namespace+"/") {
+			return true
+		}
+	}
+
+	return false
+}
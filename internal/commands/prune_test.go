@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type fakePruner struct {
+	repositories map[string][]string
+	tags         map[string][]string
+	digests      map[string]string
+	deleted      []string
+
+	// auths are the auths that each host was listed and deleted from with
+	auths map[string][]string
+}
+
+func (f *fakePruner) GetRepositoriesAtRemote(ctx context.Context, host string, encodedAuth string) ([]string, error) {
+	f.recordAuth(host, encodedAuth)
+	return f.repositories[host], nil
+}
+
+func (f *fakePruner) GetTagsForRepo(ctx context.Context, host string, repository string, encodedAuth string) ([]string, error) {
+	return f.tags[host+"/"+repository], nil
+}
+
+func (f *fakePruner) GetDigestsAtRemote(ctx context.Context, image string, encodedAuth string) ([]string, error) {
+	return []string{f.digests[image]}, nil
+}
+
+func (f *fakePruner) DeleteImageAtRemote(ctx context.Context, image string, encodedAuth string) error {
+	f.recordAuth(docker.RegistryPath(image).Host(), encodedAuth)
+	f.deleted = append(f.deleted, image)
+	return nil
+}
+
+func (f *fakePruner) recordAuth(host string, encodedAuth string) {
+	if f.auths == nil {
+		f.auths = make(map[string][]string)
+	}
+
+	f.auths[host] = append(f.auths[host], encodedAuth)
+}
+
+func newFakePruner() *fakePruner {
+	return &fakePruner{
+		repositories: map[string][]string{
+			"mycompany.com": {"myrepo/busybox", "myrepo/nginx", "myrepo-other/busybox", "other/nginx"},
+		},
+		tags: map[string][]string{
+			"mycompany.com/myrepo/busybox":       {"1.31.0", "1.32.0", "stable"},
+			"mycompany.com/myrepo/nginx":         {"1.19.0"},
+			"mycompany.com/myrepo-other/busybox": {"1.30.0"},
+			"mycompany.com/other/nginx":          {"1.18.0"},
+		},
+		digests: map[string]string{
+			"mycompany.com/myrepo/busybox:1.31.0": "sha256:131",
+			"mycompany.com/myrepo/busybox:1.32.0": "sha256:132",
+			"mycompany.com/myrepo/busybox:stable": "sha256:132",
+			"mycompany.com/myrepo/nginx:1.19.0":   "sha256:119",
+		},
+	}
+}
+
+func TestPruneImages(t *testing.T) {
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "mycompany.com", Repository: "myrepo"}},
+	}
+
+	pruner := newFakePruner()
+	if err := pruneImages(context.Background(), logger, pruner, images, false); err != nil {
+		t.Fatal("prune images:", err)
+	}
+
+	if len(pruner.deleted) > 0 {
+		t.Errorf("expected no images to be deleted without confirm, actual %v", pruner.deleted)
+	}
+
+	if err := pruneImages(context.Background(), logger, pruner, images, true); err != nil {
+		t.Fatal("prune images:", err)
+	}
+
+	expected := []string{"mycompany.com/myrepo/busybox:1.31.0", "mycompany.com/myrepo/nginx:1.19.0"}
+	if !reflect.DeepEqual(pruner.deleted, expected) {
+		t.Errorf("expected deleted images to be %v, actual %v", expected, pruner.deleted)
+	}
+}
+
+func TestPruneImages_NoTag(t *testing.T) {
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "mycompany.com", Repository: "myrepo"}},
+		{Repository: "nginx", Target: Target{Host: "mycompany.com", Repository: "myrepo"}},
+	}
+
+	pruner := newFakePruner()
+	pruner.tags["mycompany.com/myrepo/nginx"] = []string{"1.19.0", "latest"}
+	pruner.digests["mycompany.com/myrepo/nginx:latest"] = "sha256:latest"
+
+	if err := pruneImages(context.Background(), logger, pruner, images, true); err != nil {
+		t.Fatal("prune images:", err)
+	}
+
+	// An image without a tag refers to the latest tag, so only the other tag is stale
+	expected := []string{"mycompany.com/myrepo/busybox:1.31.0", "mycompany.com/myrepo/nginx:1.19.0"}
+	if !reflect.DeepEqual(pruner.deleted, expected) {
+		t.Errorf("expected deleted images to be %v, actual %v", expected, pruner.deleted)
+	}
+}
+
+func TestPruneImages_TargetAuth(t *testing.T) {
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "mycompany.com", Repository: "myrepo", Auth: Auth{Username: "user", Password: "pass"}}},
+	}
+
+	pruner := newFakePruner()
+	if err := pruneImages(context.Background(), logger, pruner, images, true); err != nil {
+		t.Fatal("prune images:", err)
+	}
+
+	expectedAuth, err := docker.GetEncodedBasicAuth("user", "pass")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	// The registry is listed once, and each of the two stale images is deleted with the auth of the target
+	expected := []string{expectedAuth, expectedAuth, expectedAuth}
+	if !reflect.DeepEqual(pruner.auths["mycompany.com"], expected) {
+		t.Errorf("expected the auth of the target to be used, actual %v", pruner.auths["mycompany.com"])
+	}
+}
+
+func TestPruneImages_NoTargetRepository(t *testing.T) {
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "mycompany.com"}},
+	}
+
+	pruner := newFakePruner()
+	if err := pruneImages(context.Background(), logger, pruner, images, true); err == nil {
+		t.Error("expected target without a repository to return an error")
+	}
+
+	if len(pruner.deleted) > 0 {
+		t.Errorf("expected no images to be deleted, actual %v", pruner.deleted)
+	}
+}
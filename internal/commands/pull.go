@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 
 	"github.com/plexsystems/sinker/internal/docker"
 
@@ -20,13 +21,72 @@ func newPullCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 		ValidArgs: []string{"source", "target"},
 
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run")); err != nil {
+				return fmt.Errorf("bind dry-run flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("concurrency-per-host", cmd.Flags().Lookup("concurrency-per-host")); err != nil {
+				return fmt.Errorf("bind concurrency-per-host flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-concurrent", cmd.Flags().Lookup("max-concurrent")); err != nil {
+				return fmt.Errorf("bind max-concurrent flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("metrics-file", cmd.Flags().Lookup("metrics-file")); err != nil {
+				return fmt.Errorf("bind metrics-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("platform", cmd.Flags().Lookup("platform")); err != nil {
+				return fmt.Errorf("bind platform flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
+				return fmt.Errorf("bind target flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("deadline", cmd.Flags().Lookup("deadline")); err != nil {
+				return fmt.Errorf("bind deadline flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("skip-missing", cmd.Flags().Lookup("skip-missing")); err != nil {
+				return fmt.Errorf("bind skip-missing flag: %w", err)
+			}
+
+			if err := bindVerifyFlags(cmd); err != nil {
+				return fmt.Errorf("bind verify flags: %w", err)
+			}
+
+			if err := bindTagAffixFlags(cmd); err != nil {
+				return fmt.Errorf("bind tag affix flags: %w", err)
+			}
+
+			if err := bindFilterFlags(cmd); err != nil {
+				return fmt.Errorf("bind filter flags: %w", err)
+			}
+
+			if err := viper.BindPFlag("output-manifest", cmd.Flags().Lookup("output-manifest")); err != nil {
+				return fmt.Errorf("bind output-manifest flag: %w", err)
+			}
+
+			if err := bindProgressFlags(cmd); err != nil {
+				return fmt.Errorf("bind progress flags: %w", err)
+			}
+
+			if err := bindClientFlags(cmd); err != nil {
+				return fmt.Errorf("bind client flags: %w", err)
+			}
+
 			var location string
 			if len(args) > 0 {
 				location = args[0]
 			}
 
+			runCtx, cancel := withDeadline(ctx, viper.GetDuration("deadline"))
+			defer cancel()
+
 			manifestPath := viper.GetString("manifest")
-			if err := runPullCommand(ctx, logger, location, manifestPath); err != nil {
+			if err := runPullCommand(runCtx, logger, location, manifestPath); err != nil {
 				return fmt.Errorf("pull: %w", err)
 			}
 
@@ -34,15 +94,25 @@ func newPullCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 		},
 	}
 
+	cmd.Flags().Bool("dry-run", false, "Print the images that would be pulled without contacting any registry")
+	cmd.Flags().Duration("deadline", 0, "The maximum amount of time the entire pull can take (e.g. 1h). When exceeded, in-flight images are cancelled and the remaining images are skipped. No deadline when not set")
+	cmd.Flags().Int("max-concurrent", 1, "The maximum number of images to pull at the same time")
+	cmd.Flags().StringSlice("concurrency-per-host", []string{}, "The maximum number of images to pull at the same time for a registry host, as host=limit (e.g. docker.io=2,quay.io=5). Both the source and target host of an image are limited, along with --max-concurrent")
+	cmd.Flags().String("metrics-file", "", "Write the number of images pulled and failed, and the bytes transferred, of each registry to a file in the Prometheus text format (e.g. for the node_exporter textfile collector)")
+	cmd.Flags().String("platform", "", "The platform of the images to pull (e.g. linux/arm64). Defaults to the platform of the Docker daemon")
+	cmd.Flags().String("target", "", "The target (e.g. host/repository) to pull every image from, instead of the target in the manifest")
+	cmd.Flags().Bool("skip-missing", false, "Skip the images that are missing at their registry with a warning, and report them in the summary, rather than failing the pull")
+	addVerifyFlags(&cmd)
+	addTagAffixFlags(&cmd)
+	addFilterFlags(&cmd)
+	addOutputManifestFlag(&cmd)
+	addProgressFlags(&cmd)
+	addClientFlags(&cmd)
+
 	return &cmd
 }
 
 func runPullCommand(ctx context.Context, logger *log.Logger, location string, manifestPath string) error {
-	client, err := docker.NewClient(logger)
-	if err != nil {
-		return fmt.Errorf("new client: %w", err)
-	}
-
 	manifest, err := GetManifest(manifestPath)
 	if err != nil {
 		return fmt.Errorf("get manifest: %w", err)
@@ -52,40 +122,221 @@ func runPullCommand(ctx context.Context, logger *log.Logger, location string, ma
 		return errors.New("no images found in the image manifest")
 	}
 
+	if viper.GetString("target") != "" {
+		manifest = manifest.WithTarget(viper.GetString("target"))
+	}
+
+	manifest, err = applyTagAffixFlags(manifest)
+	if err != nil {
+		return fmt.Errorf("apply tag affix flags: %w", err)
+	}
+
+	if hasImageFilters() {
+		manifest.Images, err = filterImages(manifest.Images, viper.GetStringSlice("include"), viper.GetStringSlice("exclude"))
+		if err != nil {
+			return fmt.Errorf("filter images: %w", err)
+		}
+
+		if len(manifest.Images) == 0 && viper.GetBool("require-match") {
+			return errors.New("no images in the image manifest match the include and exclude patterns")
+		}
+
+		if len(manifest.Images) == 0 {
+			logger.Warnf("[PULL] No images in the image manifest match the include and exclude patterns")
+			return nil
+		}
+	}
+
+	if err := writeResolvedManifest(manifest); err != nil {
+		return fmt.Errorf("write resolved manifest: %w", err)
+	}
+
+	var platforms []docker.Platform
+	if viper.GetString("platform") != "" {
+		platforms, err = getPlatforms([]string{viper.GetString("platform")})
+		if err != nil {
+			return fmt.Errorf("get platforms: %w", err)
+		}
+	}
+
+	verifier, err := getImageVerifier()
+	if err != nil {
+		return fmt.Errorf("get image verifier: %w", err)
+	}
+
+	limiter, err := getHostLimiter()
+	if err != nil {
+		return fmt.Errorf("get host limiter: %w", err)
+	}
+
+	if viper.GetBool("dry-run") {
+		for _, image := range manifest.Images {
+			if len(image.Tags) > 0 {
+				newImageLogEntry(logger, "pull", image.String()).Printf("[DRYRUN] Would pull the tags of %s matching %v", image.String(), image.Tags)
+				continue
+			}
+
+			imageToPull := image.String()
+			if location == "target" {
+				imageToPull = image.TargetImage()
+			}
+
+			entry := newImageLogEntry(logger, "pull", imageToPull)
+			if verifier != nil {
+				entry.Printf("[DRYRUN] Would verify %s", imageToPull)
+			}
+
+			entry.Printf("[DRYRUN] Would pull %s", imageToPull)
+		}
+		return nil
+	}
+
+	summary := newSyncSummary()
+	clientOptions, err := getClientOptions()
+	if err != nil {
+		return fmt.Errorf("get client options: %w", err)
+	}
+
+	clientOptions = append(clientOptions, docker.WithTransferStats(summary.stats))
+	if len(platforms) > 0 {
+		clientOptions = append(clientOptions, docker.WithPlatform(platforms[0]))
+	}
+
+	client, err := docker.NewClient(getClientLogger(logger), clientOptions...)
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	images, err := expandImageTags(ctx, client, manifest.Images)
+	if err != nil {
+		return fmt.Errorf("expand image tags: %w", err)
+	}
+
+	imagesToPull, err := getImagesToPull(ctx, client, images, location, platforms)
+	if err != nil {
+		return fmt.Errorf("get images to pull: %w", err)
+	}
+
+	err = pullImages(ctx, logger, client, verifier, summary, imagesToPull, viper.GetInt("max-concurrent"), limiter)
+
+	if err := summary.write(os.Stdout); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	if err := writeMetricsFile(summary); err != nil {
+		return fmt.Errorf("write metrics file: %w", err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("pull images: %w", err)
+	}
+
+	if missing := summary.missingImages(); missing > 0 {
+		logger.Printf("[PULL] All images have been pulled, except %v image(s) missing at their registry", missing)
+		return nil
+	}
+
+	logger.Printf("[PULL] All images have been pulled!")
+
+	return nil
+}
+
+// getImagesToPull returns the auth of each source or target image that is missing from the host, by its
+// reference. The platforms of each image are verified at the registry, when platforms are given.
+func getImagesToPull(ctx context.Context, client docker.Client, images []SourceImage, location string, platforms []docker.Platform) (map[string]string, error) {
 	imagesToPull := make(map[string]string)
-	for _, image := range manifest.Images {
+	for _, image := range images {
 		var pullImage string
 		var auth string
 		var err error
 		if location == "target" {
 			pullImage = image.TargetImage()
-			auth, err = getEncodedTargetAuth(image.Target)
+			auth, err = getEncodedTargetAuth(ctx, image.Target)
 		} else {
 			pullImage = image.String()
-			auth, err = getEncodedSourceAuth(image)
+			auth, err = getEncodedSourceAuth(ctx, image)
 		}
 		if err != nil {
-			return fmt.Errorf("get %s auth: %w", location, err)
+			return nil, fmt.Errorf("get %s auth: %w", location, err)
+		}
+
+		// Missing images are left for the pull to skip, so that they are reported with the images that were pulled
+		if len(platforms) > 0 {
+			err := client.VerifyPlatformsAtRemote(ctx, pullImage, auth, platforms)
+			if err != nil && !(viper.GetBool("skip-missing") && docker.IsImageMissing(err)) {
+				return nil, fmt.Errorf("verify platforms: %w", err)
+			}
 		}
 
 		exists, err := client.ImageExistsOnHost(ctx, pullImage)
 		if err != nil {
-			return fmt.Errorf("image host existance: %w", err)
+			return nil, fmt.Errorf("image host existance: %w", err)
 		}
 
 		if !exists {
-			client.Logger.Printf("[PULL] Image %s is missing and will be pulled.", pullImage)
+			newImageLogEntry(client.Logger, "pull", pullImage).Printf("[PULL] Image %s is missing and will be pulled.", pullImage)
 			imagesToPull[pullImage] = auth
 		}
 	}
 
-	for image, auth := range imagesToPull {
-		if err := client.PullImageAndWait(ctx, image, auth); err != nil {
-			return fmt.Errorf("pull image: %w", err)
-		}
+	return imagesToPull, nil
+}
+
+type imagePuller interface {
+	PullImageAndWait(ctx context.Context, image string, auth string) error
+}
+
+// pullImages pulls each image using its auth with at most maxConcurrent images being pulled at the same time,
+// and at most the limit of its host when a limiter is given.
+// When a verifier is given, images are only pulled when their signature is verified. The result of each
+// image is recorded in the summary, and images that were not started before the context was cancelled, by
+// the deadline or an interrupt, are recorded as skipped. Images that are missing at their registry are
+// also recorded as skipped, rather than failed, when the skip-missing flag is set.
+func pullImages(ctx context.Context, logger *log.Logger, puller imagePuller, verifier imageVerifier, summary *syncSummary, imagesToPull map[string]string, maxConcurrent int, limiter *hostLimiter) error {
+	var images []string
+	for image := range imagesToPull {
+		images = append(images, image)
 	}
 
-	client.Logger.Printf("[PULL] All images have been pulled!")
+	err := runConcurrently(maxConcurrent, len(images), func(index int) error {
+		image := images[index]
+		release := limiter.acquire(getImageHost(image))
+		defer release()
 
-	return nil
+		if skipAfterCancel(ctx, summary, image) {
+			return nil
+		}
+
+		err := verifyImage(ctx, verifier, image)
+		if err == nil {
+			err = puller.PullImageAndWait(ctx, image, imagesToPull[image])
+		}
+
+		if err != nil && viper.GetBool("skip-missing") && docker.IsImageMissing(err) {
+			newImageLogEntry(logger, "pull", image).Warnf("[PULL] %s is missing at its registry, skipping: %s", image, err)
+			summary.skipMissing(image)
+			return nil
+		}
+
+		summary.record(image, err)
+
+		if err != nil {
+			newImageLogEntry(logger, "pull", image).Errorf("[PULL] %s failed: %s", image, err)
+		}
+
+		var rateLimitError *docker.RateLimitError
+		if errors.As(err, &rateLimitError) {
+			return fmt.Errorf("%s (authenticate to the registry for a higher rate limit): %w", image, err)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", image, err)
+		}
+
+		newImageLogEntry(logger, "pull", image).Printf("[PULL] %s complete.", image)
+
+		return nil
+	})
+
+	return checkCancelled(ctx, summary, err)
 }
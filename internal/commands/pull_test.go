@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type fakePuller struct {
+	mutex        sync.Mutex
+	running      int
+	maxRunning   int
+	pulled       []string
+	failedImage  string
+	missingImage string
+}
+
+func (f *fakePuller) PullImageAndWait(ctx context.Context, image string, auth string) error {
+	f.mutex.Lock()
+	f.running++
+	if f.running > f.maxRunning {
+		f.maxRunning = f.running
+	}
+	f.mutex.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.running--
+	f.pulled = append(f.pulled, image)
+
+	if image == f.failedImage {
+		return errors.New("pull failed")
+	}
+
+	if image == f.missingImage {
+		return fmt.Errorf("pull image: %w", &transport.Error{StatusCode: http.StatusNotFound, Errors: []transport.Diagnostic{{Code: transport.ManifestUnknownErrorCode}}})
+	}
+
+	return nil
+}
+
+func TestPullImages_MaxConcurrent(t *testing.T) {
+	const maxConcurrent = 2
+
+	imagesToPull := make(map[string]string)
+	for i := 0; i < 8; i++ {
+		imagesToPull[fmt.Sprintf("busybox:1.%v.0", i)] = ""
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	puller := fakePuller{}
+	if err := pullImages(context.Background(), logger, &puller, nil, newSyncSummary(), imagesToPull, maxConcurrent, nil); err != nil {
+		t.Fatal("pull images:", err)
+	}
+
+	if puller.maxRunning > maxConcurrent {
+		t.Errorf("expected at most %v concurrent pulls, actual %v", maxConcurrent, puller.maxRunning)
+	}
+
+	if len(puller.pulled) != len(imagesToPull) {
+		t.Errorf("expected %v images to be pulled, actual %v", len(imagesToPull), len(puller.pulled))
+	}
+}
+
+func TestPullImages_ReportsFailures(t *testing.T) {
+	imagesToPull := map[string]string{
+		"busybox:1.0.0": "",
+		"busybox:2.0.0": "",
+		"busybox:3.0.0": "",
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	puller := fakePuller{failedImage: "busybox:2.0.0"}
+	summary := newSyncSummary()
+	err := pullImages(context.Background(), logger, &puller, nil, summary, imagesToPull, 3, nil)
+	if err == nil {
+		t.Fatal("expected an error when an image fails to pull")
+	}
+
+	if len(puller.pulled) != len(imagesToPull) {
+		t.Errorf("expected all %v images to be attempted, actual %v", len(imagesToPull), len(puller.pulled))
+	}
+
+	if summary.succeeded != 2 || summary.failed != 1 {
+		t.Errorf("expected summary of 2 succeeded and 1 failed, actual %v succeeded and %v failed", summary.succeeded, summary.failed)
+	}
+}
+
+func TestPullImages_Verify(t *testing.T) {
+	imagesToPull := map[string]string{
+		"busybox:1.0.0": "",
+		"busybox:2.0.0": "",
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	puller := fakePuller{}
+	verifier := fakeVerifier{unsignedImage: "busybox:2.0.0"}
+	summary := newSyncSummary()
+	err := pullImages(context.Background(), logger, &puller, &verifier, summary, imagesToPull, 2, nil)
+	if err == nil {
+		t.Fatal("expected an error when an image fails verification")
+	}
+
+	if len(verifier.verified) != len(imagesToPull) {
+		t.Errorf("expected all %v images to be verified, actual %v", len(imagesToPull), len(verifier.verified))
+	}
+
+	expected := []string{"busybox:1.0.0"}
+	if !reflect.DeepEqual(puller.pulled, expected) {
+		t.Errorf("expected only the verified images %v to be pulled, actual %v", expected, puller.pulled)
+	}
+
+	if summary.succeeded != 1 || summary.failed != 1 {
+		t.Errorf("expected summary of 1 succeeded and 1 failed, actual %v succeeded and %v failed", summary.succeeded, summary.failed)
+	}
+}
+
+func TestPullImages_MissingImage(t *testing.T) {
+	imagesToPull := map[string]string{
+		"busybox:1.0.0": "",
+		"busybox:9.9.9": "",
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	testCases := []struct {
+		skipMissing     bool
+		expectedError   bool
+		expectedFailed  int
+		expectedSkipped int
+	}{
+		{skipMissing: false, expectedError: true, expectedFailed: 1, expectedSkipped: 0},
+		{skipMissing: true, expectedError: false, expectedFailed: 0, expectedSkipped: 1},
+	}
+
+	for _, testCase := range testCases {
+		viper.Set("skip-missing", testCase.skipMissing)
+
+		puller := fakePuller{missingImage: "busybox:9.9.9"}
+		summary := newSyncSummary()
+		err := pullImages(context.Background(), logger, &puller, nil, summary, imagesToPull, 2, nil)
+		if testCase.expectedError != (err != nil) {
+			t.Errorf("expected error to be %v when skip missing is %v, actual %v", testCase.expectedError, testCase.skipMissing, err)
+		}
+
+		if summary.succeeded != 1 || summary.failed != testCase.expectedFailed || summary.skipped != testCase.expectedSkipped {
+			t.Errorf("expected summary of 1 succeeded, %v failed, and %v skipped when skip missing is %v, actual %v succeeded, %v failed, and %v skipped",
+				testCase.expectedFailed, testCase.expectedSkipped, testCase.skipMissing, summary.succeeded, summary.failed, summary.skipped)
+		}
+
+		if testCase.skipMissing && !reflect.DeepEqual(summary.missing, []string{"busybox:9.9.9"}) {
+			t.Errorf("expected the missing image to be reported, actual %v", summary.missing)
+		}
+	}
+
+	viper.Set("skip-missing", false)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/plexsystems/sinker/internal/docker"
 
@@ -18,12 +20,111 @@ func newPushCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 		Short: "Push images in the manifest to the target repository",
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := viper.BindPFlag("dryrun", cmd.Flags().Lookup("dryrun")); err != nil {
+			if err := viper.BindPFlag("cache-dir", cmd.Flags().Lookup("cache-dir")); err != nil {
+				return fmt.Errorf("bind cache-dir flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("cache-max-size", cmd.Flags().Lookup("cache-max-size")); err != nil {
+				return fmt.Errorf("bind cache-max-size flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("create-repos", cmd.Flags().Lookup("create-repos")); err != nil {
+				return fmt.Errorf("bind create-repos flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run")); err != nil {
+				return fmt.Errorf("bind dry-run flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("force", cmd.Flags().Lookup("force")); err != nil {
+				return fmt.Errorf("bind force flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("concurrency-per-host", cmd.Flags().Lookup("concurrency-per-host")); err != nil {
+				return fmt.Errorf("bind concurrency-per-host flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-concurrent", cmd.Flags().Lookup("max-concurrent")); err != nil {
+				return fmt.Errorf("bind max-concurrent flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("metrics-file", cmd.Flags().Lookup("metrics-file")); err != nil {
+				return fmt.Errorf("bind metrics-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("mode", cmd.Flags().Lookup("mode")); err != nil {
+				return fmt.Errorf("bind mode flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("label", cmd.Flags().Lookup("label")); err != nil {
+				return fmt.Errorf("bind label flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("annotation", cmd.Flags().Lookup("annotation")); err != nil {
+				return fmt.Errorf("bind annotation flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("chunk-size", cmd.Flags().Lookup("chunk-size")); err != nil {
+				return fmt.Errorf("bind chunk-size flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("platform", cmd.Flags().Lookup("platform")); err != nil {
+				return fmt.Errorf("bind platform flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("sign", cmd.Flags().Lookup("sign")); err != nil {
+				return fmt.Errorf("bind sign flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("cosign-key", cmd.Flags().Lookup("cosign-key")); err != nil {
+				return fmt.Errorf("bind cosign-key flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("preserve-tags", cmd.Flags().Lookup("preserve-tags")); err != nil {
+				return fmt.Errorf("bind preserve-tags flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
 				return fmt.Errorf("bind target flag: %w", err)
 			}
 
+			if err := viper.BindPFlag("deadline", cmd.Flags().Lookup("deadline")); err != nil {
+				return fmt.Errorf("bind deadline flag: %w", err)
+			}
+
+			if err := bindVerifyFlags(cmd); err != nil {
+				return fmt.Errorf("bind verify flags: %w", err)
+			}
+
+			if err := viper.BindPFlag("strict-names", cmd.Flags().Lookup("strict-names")); err != nil {
+				return fmt.Errorf("bind strict-names flag: %w", err)
+			}
+
+			if err := bindTagAffixFlags(cmd); err != nil {
+				return fmt.Errorf("bind tag affix flags: %w", err)
+			}
+
+			if err := bindFilterFlags(cmd); err != nil {
+				return fmt.Errorf("bind filter flags: %w", err)
+			}
+
+			if err := viper.BindPFlag("output-manifest", cmd.Flags().Lookup("output-manifest")); err != nil {
+				return fmt.Errorf("bind output-manifest flag: %w", err)
+			}
+
+			if err := bindProgressFlags(cmd); err != nil {
+				return fmt.Errorf("bind progress flags: %w", err)
+			}
+
+			if err := bindClientFlags(cmd); err != nil {
+				return fmt.Errorf("bind client flags: %w", err)
+			}
+
+			runCtx, cancel := withDeadline(ctx, viper.GetDuration("deadline"))
+			defer cancel()
+
 			manifestPath := viper.GetString("manifest")
-			if err := runPushCommand(ctx, logger, manifestPath); err != nil {
+			if err := runPushCommand(runCtx, logger, manifestPath); err != nil {
 				return fmt.Errorf("push: %w", err)
 			}
 
@@ -31,15 +132,57 @@ func newPushCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().Bool("dryrun", false, "Print a list of images that would be pushed to the target")
+	cmd.Flags().String("cache-dir", "", "The directory of an on-disk cache of the layers copied between registries, which are reused by later pushes. Only used with --mode copy")
+	cmd.Flags().String("cache-max-size", "", "The maximum size of the layer cache (e.g. 10GB), after which the least recently used layers are evicted. Not limited when not set")
+	cmd.Flags().Bool("create-repos", false, "Create the target repository of each image when it does not exist, before it is pushed. Supported for Amazon ECR repositories and Harbor projects")
+	addDeprecatedAlias(&cmd, "create-repository", "create-repos")
+	cmd.Flags().Bool("dry-run", false, "Print the pull, tag, and push operations that would be performed without contacting any registry")
+	addDeprecatedAlias(&cmd, "dryrun", "dry-run")
+	cmd.Flags().Bool("force", false, "Push all images, even if they are already present at the target")
+	cmd.Flags().Duration("deadline", 0, "The maximum amount of time the entire push can take (e.g. 1h). When exceeded, in-flight images are cancelled and the remaining images are skipped. No deadline when not set")
+	cmd.Flags().Int("max-concurrent", 1, "The maximum number of images to push at the same time")
+	cmd.Flags().StringSlice("concurrency-per-host", []string{}, "The maximum number of images to push at the same time for a registry host, as host=limit (e.g. docker.io=2,quay.io=5). Both the source and target host of an image are limited, along with --max-concurrent")
+	cmd.Flags().String("metrics-file", "", "Write the number of images pushed and failed, and the bytes transferred, of each registry to a file in the Prometheus text format (e.g. for the node_exporter textfile collector)")
+	cmd.Flags().String("mode", daemonMode, "How images that are not multi-arch are pushed: daemon (pull, tag, and push using the Docker daemon) or copy (copy directly between registries without storing the image locally)")
+	cmd.Flags().StringSlice("label", []string{}, "Add a label to the config of each pushed image, as key=value (e.g. mirrored-by=sinker). Only used with --mode copy")
+	cmd.Flags().StringSlice("annotation", []string{}, "Add an annotation to the manifest of each pushed image, as key=value (e.g. org.opencontainers.image.source=https://github.com/org/repo). Only used with --mode copy")
+	cmd.Flags().String("chunk-size", "", "Upload layers larger than the size (e.g. 100MB) in chunks of the size, so that a failed upload is resumed from the last chunk the target acknowledged. Only used with --mode copy")
+	cmd.Flags().StringSlice("platform", []string{}, "The platforms of images to push (e.g. linux/amd64,linux/arm64). Defaults to every platform")
+	cmd.Flags().Bool("sign", false, "Sign each pushed image at the target with cosign")
+	cmd.Flags().String("cosign-key", "", "The private key (e.g. cosign.key or a KMS URI) to sign images with. Defaults to COSIGN_KEY")
+	cmd.Flags().Bool("preserve-tags", false, "Require the target image of every image to have the same tag as its source image, rather than the digest of images pinned to only a digest")
+	cmd.Flags().String("target", "", "The target (e.g. host/repository) to push every image to, instead of the target in the manifest")
+	addVerifyFlags(&cmd)
+	addTagAffixFlags(&cmd)
+	addStrictNamesFlag(&cmd)
+	addFilterFlags(&cmd)
+	addOutputManifestFlag(&cmd)
+	addProgressFlags(&cmd)
+	addClientFlags(&cmd)
+	addRateLimitFlag(&cmd)
 
 	return &cmd
 }
 
+const (
+	daemonMode = "daemon"
+	copyMode   = "copy"
+)
+
+// validatePushMode returns an error when the mode is not a known mode. When
+// no mode is set, images are pushed through the Docker daemon.
+func validatePushMode(mode string) error {
+	switch mode {
+	case "", daemonMode, copyMode:
+		return nil
+	default:
+		return fmt.Errorf("unknown mode %q, must be %s or %s", mode, daemonMode, copyMode)
+	}
+}
+
 func runPushCommand(ctx context.Context, logger *log.Logger, manifestPath string) error {
-	client, err := docker.NewClient(logger)
-	if err != nil {
-		return fmt.Errorf("new docker client: %w", err)
+	if err := validatePushMode(viper.GetString("mode")); err != nil {
+		return err
 	}
 
 	manifest, err := GetManifest(manifestPath)
@@ -51,61 +194,450 @@ func runPushCommand(ctx context.Context, logger *log.Logger, manifestPath string
 		return errors.New("no images found in the image manifest")
 	}
 
+	if viper.GetString("target") != "" {
+		manifest = manifest.WithTarget(viper.GetString("target"))
+	}
+
+	manifest, err = applyTagAffixFlags(manifest)
+	if err != nil {
+		return fmt.Errorf("apply tag affix flags: %w", err)
+	}
+
+	if hasImageFilters() {
+		manifest.Images, err = filterImages(manifest.Images, viper.GetStringSlice("include"), viper.GetStringSlice("exclude"))
+		if err != nil {
+			return fmt.Errorf("filter images: %w", err)
+		}
+
+		if len(manifest.Images) == 0 && viper.GetBool("require-match") {
+			return errors.New("no images in the image manifest match the include and exclude patterns")
+		}
+
+		if len(manifest.Images) == 0 {
+			logger.Warnf("[PUSH] No images in the image manifest match the include and exclude patterns")
+			return nil
+		}
+	}
+
+	platforms, err := getPlatforms(viper.GetStringSlice("platform"))
+	if err != nil {
+		return fmt.Errorf("get platforms: %w", err)
+	}
+
+	limiter, err := getHostLimiter()
+	if err != nil {
+		return fmt.Errorf("get host limiter: %w", err)
+	}
+
+	if viper.GetBool("preserve-tags") {
+		if err := validatePreservedTags(manifest.Images); err != nil {
+			return fmt.Errorf("preserve tags: %w", err)
+		}
+	}
+
+	if err := writeResolvedManifest(manifest); err != nil {
+		return fmt.Errorf("write resolved manifest: %w", err)
+	}
+
+	if err := manifest.ValidateTargets(); err != nil {
+		return fmt.Errorf("validate targets: %w", err)
+	}
+
+	for _, warning := range manifest.Warnings() {
+		logger.Warnf("[PUSH] %s", warning)
+	}
+
+	if err := checkTargetNames(logger, "push", manifest.Images, viper.GetBool("strict-names")); err != nil {
+		return fmt.Errorf("check target names: %w", err)
+	}
+
+	verifier, err := getImageVerifier()
+	if err != nil {
+		return fmt.Errorf("get image verifier: %w", err)
+	}
+
+	signer, err := getImageSigner()
+	if err != nil {
+		return fmt.Errorf("get image signer: %w", err)
+	}
+
+	if viper.GetBool("dry-run") {
+		for _, image := range manifest.Images {
+			entry := newImageLogEntry(logger, "push", image.String())
+			if len(image.Tags) > 0 {
+				entry.Printf("[DRYRUN] Would push the tags of %s matching %v to %s", image.String(), image.Tags, image.TargetImage())
+				continue
+			}
+
+			if verifier != nil {
+				entry.Printf("[DRYRUN] Would verify %s", image.String())
+			}
+
+			if viper.GetString("mode") == copyMode {
+				entry.Printf("[DRYRUN] Would copy %s to %s", image.String(), image.TargetImage())
+			} else {
+				entry.Printf("[DRYRUN] Would pull %s", image.String())
+				entry.Printf("[DRYRUN] Would tag %s as %s", image.String(), image.TargetImage())
+				entry.Printf("[DRYRUN] Would push %s", image.TargetImage())
+			}
+
+			if signer != nil {
+				entry.Printf("[DRYRUN] Would sign %s", image.TargetImage())
+			}
+		}
+		return nil
+	}
+
+	summary := newSyncSummary()
+	clientOptions, err := getClientOptions()
+	if err != nil {
+		return fmt.Errorf("get client options: %w", err)
+	}
+
+	clientOptions = append(clientOptions, docker.WithTransferStats(summary.stats))
+
+	if viper.GetString("cache-dir") != "" {
+		cacheOption, err := getLayerCacheOption(viper.GetString("cache-dir"), viper.GetString("cache-max-size"))
+		if err != nil {
+			return fmt.Errorf("get layer cache: %w", err)
+		}
+
+		if viper.GetString("mode") != copyMode {
+			logger.Warnf("[PUSH] The layer cache is only used when images are copied with --mode copy")
+		}
+
+		clientOptions = append(clientOptions, cacheOption)
+	}
+
+	labels, err := parseKeyValues(viper.GetStringSlice("label"))
+	if err != nil {
+		return fmt.Errorf("parse label: %w", err)
+	}
+
+	annotations, err := parseKeyValues(viper.GetStringSlice("annotation"))
+	if err != nil {
+		return fmt.Errorf("parse annotation: %w", err)
+	}
+
+	if len(labels) > 0 || len(annotations) > 0 {
+		if viper.GetString("mode") != copyMode {
+			logger.Warnf("[PUSH] Labels and annotations are only added to images copied with --mode copy")
+		}
+
+		clientOptions = append(clientOptions, docker.WithLabels(labels), docker.WithAnnotations(annotations))
+	}
+
+	if viper.GetString("chunk-size") != "" {
+		chunkSize, err := parseSize(viper.GetString("chunk-size"))
+		if err != nil {
+			return fmt.Errorf("parse chunk-size: %w", err)
+		}
+
+		if viper.GetString("mode") != copyMode {
+			logger.Warnf("[PUSH] Layers are only uploaded in chunks when images are copied with --mode copy")
+		}
+
+		clientOptions = append(clientOptions, docker.WithChunkSize(chunkSize))
+	}
+
+	if viper.GetString("rate-limit") != "" && viper.GetString("mode") != copyMode {
+		logger.Warnf("[PUSH] The transfer rate of images that are not multi-arch is only limited with --mode copy")
+	}
+
+	// A single platform is also pulled from multi-arch images that are pushed through the Docker daemon
+	if len(platforms) == 1 {
+		clientOptions = append(clientOptions, docker.WithPlatform(platforms[0]))
+	}
+
+	client, err := docker.NewClient(getClientLogger(logger), clientOptions...)
+	if err != nil {
+		return fmt.Errorf("new docker client: %w", err)
+	}
+
+	images, err := expandImageTags(ctx, client, manifest.Images)
+	if err != nil {
+		return fmt.Errorf("expand image tags: %w", err)
+	}
+
 	logger.Printf("[INFO] Finding images that do not exist at target registry ...")
 
 	var pushImages []SourceImage
-	for _, image := range manifest.Images {
-		exists, err := client.ImageExistsAtRemote(ctx, image.TargetImage())
+	for _, image := range images {
+		if viper.GetBool("force") {
+			pushImages = append(pushImages, image)
+			continue
+		}
+
+		pushRequired, err := isPushRequired(ctx, client, image)
 		if err != nil {
-			return fmt.Errorf("image exists at remote: %w", err)
+			return fmt.Errorf("is push required: %w", err)
 		}
 
-		if !exists {
-			pushImages = append(pushImages, image)
+		if !pushRequired {
+			newImageLogEntry(logger, "push", image.TargetImage()).Printf("[PUSH] Image %s already present at target, skipping", image.TargetImage())
+			continue
 		}
+
+		pushImages = append(pushImages, image)
 	}
 
 	if len(pushImages) == 0 {
 		logger.Println("[INFO] All images are up to date! 0 images pushed.")
+
+		if err := writeMetricsFile(summary); err != nil {
+			return fmt.Errorf("write metrics file: %w", err)
+		}
+
 		return nil
 	}
 
-	if viper.GetBool("dryrun") {
-		for _, image := range pushImages {
-			logger.Printf("[INFO] Image %s would be pushed as %s", image.String(), image.TargetImage())
+	err = runConcurrently(viper.GetInt("max-concurrent"), len(pushImages), func(index int) error {
+		image := pushImages[index]
+		release := limiter.acquire(getImageHost(image.String()), getImageHost(image.TargetImage()))
+		defer release()
+
+		if skipAfterCancel(ctx, summary, image.TargetImage()) {
+			return nil
+		}
+
+		err := syncImage(ctx, client, image, platforms, verifier, signer)
+		summary.record(image.TargetImage(), err)
+		if err != nil {
+			newImageLogEntry(logger, "push", image.TargetImage()).Errorf("[PUSH] %s failed: %s", image.TargetImage(), err)
+		}
+
+		var rateLimitError *docker.RateLimitError
+		if errors.As(err, &rateLimitError) {
+			return fmt.Errorf("%s (authenticate to the registry for a higher rate limit): %w", image.TargetImage(), err)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", image.TargetImage(), err)
 		}
+
+		newImageLogEntry(logger, "push", image.TargetImage()).Printf("[PUSH] %s complete.", image.TargetImage())
+
 		return nil
+	})
+	err = checkCancelled(ctx, summary, err)
+
+	if err := summary.write(os.Stdout); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	if err := writeMetricsFile(summary); err != nil {
+		return fmt.Errorf("write metrics file: %w", err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("push images: %w", err)
 	}
 
-	for _, image := range pushImages {
-		auth, err := getEncodedSourceAuth(image)
+	logger.Printf("[PUSH] All images have been pushed!")
+
+	return nil
+}
+
+// getLayerCacheOption returns the client option of the layer cache in the directory,
+// with the maximum size, when set, in decimal units (e.g. 10GB)
+func getLayerCacheOption(dir string, maxSize string) (docker.ClientOption, error) {
+	var maxSizeBytes int64
+	if maxSize != "" {
+		var err error
+		maxSizeBytes, err = parseSize(maxSize)
 		if err != nil {
-			return fmt.Errorf("get host auth: %w", err)
+			return nil, fmt.Errorf("parse cache-max-size: %w", err)
+		}
+	}
+
+	return docker.WithLayerCache(dir, maxSizeBytes), nil
+}
+
+// parseKeyValues returns the values of the key=value values of a flag
+func parseKeyValues(values []string) (map[string]string, error) {
+	keyValues := make(map[string]string)
+	for _, value := range values {
+		keyValue := strings.SplitN(value, "=", 2)
+		if len(keyValue) != 2 || keyValue[0] == "" {
+			return nil, fmt.Errorf("%q must be in the format key=value", value)
+		}
+
+		if _, exists := keyValues[keyValue[0]]; exists {
+			return nil, fmt.Errorf("key %s is given more than once", keyValue[0])
 		}
 
-		if err := client.PullImageAndWait(ctx, image.String(), auth); err != nil {
-			return fmt.Errorf("pull image and wait: %w", err)
+		keyValues[keyValue[0]] = keyValue[1]
+	}
+
+	return keyValues, nil
+}
+
+// isPushRequired returns true when the target image does not exist or its digest does not match the
+// digest of the source image. Images that are only pinned to the latest tag are always pushed, as the
+// latest tag is expected to change.
+func isPushRequired(ctx context.Context, client docker.Client, image SourceImage) (bool, error) {
+	if image.usesLatestTag() {
+		return true, nil
+	}
+
+	syncStatus, err := getImageSyncStatus(ctx, client, image)
+	if err != nil {
+		return false, fmt.Errorf("get sync status: %w", err)
+	}
+
+	return syncStatus != imageInSync, nil
+}
+
+// syncImage verifies the signature of the source image when a verifier is given, pushes the image to its
+// target, and signs the target image when a signer is given. The image is not pushed when its signature
+// could not be verified, and the push of the image fails when it could not be signed.
+func syncImage(ctx context.Context, client docker.Client, image SourceImage, platforms []docker.Platform, verifier imageVerifier, signer imageSigner) error {
+	if err := verifyImage(ctx, verifier, image.String()); err != nil {
+		return err
+	}
+
+	if err := pushImage(ctx, client.Logger, client, image, platforms); err != nil {
+		return err
+	}
+
+	if signer == nil {
+		return nil
+	}
+
+	targetAuth, err := getEncodedTargetAuth(ctx, image.Target)
+	if err != nil {
+		return fmt.Errorf("get target auth: %w", err)
+	}
+
+	if err := signImage(ctx, client, signer, image.TargetImage(), targetAuth); err != nil {
+		return fmt.Errorf("sign image: %w", err)
+	}
+
+	return nil
+}
+
+type repositoryCreator interface {
+	CreateRepositoryIfNotExists(ctx context.Context, image string, encodedAuth string) (string, error)
+}
+
+// createTargetRepository creates the target repository of the image when create is set and the
+// repository does not exist. Each repository that is created is logged, while repositories that
+// already exist, and registries that do not require repositories to be created, are left as they are.
+func createTargetRepository(ctx context.Context, logger *log.Logger, creator repositoryCreator, image SourceImage, encodedAuth string, create bool) error {
+	if !create {
+		return nil
+	}
+
+	created, err := creator.CreateRepositoryIfNotExists(ctx, image.TargetImage(), encodedAuth)
+	if err != nil {
+		return err
+	}
+
+	if created != "" {
+		newImageLogEntry(logger, "push", image.TargetImage()).Printf("[PUSH] Created %s", created)
+	}
+
+	return nil
+}
+
+type imagePusher interface {
+	repositoryCreator
+	GetArtifactTypeAtRemote(ctx context.Context, image string, auth string) (string, error)
+	VerifyPlatformsAtRemote(ctx context.Context, image string, auth string, platforms []docker.Platform) error
+	IsImageIndexAtRemote(ctx context.Context, image string, auth string) (bool, error)
+	CopyImageAndWait(ctx context.Context, source string, target string, sourceAuth string, targetAuth string) error
+	CopyImageIndexAndWait(ctx context.Context, source string, target string, platforms []docker.Platform, sourceAuth string, targetAuth string) error
+	PullImageAndWait(ctx context.Context, image string, auth string) error
+	TagImage(ctx context.Context, source string, target string) error
+	PushImageAndWait(ctx context.Context, image string, auth string) error
+}
+
+// pushImage pushes the image to its target. Multi-arch images are copied to the target
+// registry with the images of every platform, unless only some platforms are given.
+// OCI artifacts are copied to the target registry as they are.
+func pushImage(ctx context.Context, logger *log.Logger, client imagePusher, image SourceImage, platforms []docker.Platform) error {
+	sourceAuth, err := getEncodedSourceAuth(ctx, image)
+	if err != nil {
+		return fmt.Errorf("get source auth: %w", err)
+	}
+
+	targetAuth, err := getEncodedTargetAuth(ctx, image.Target)
+	if err != nil {
+		return fmt.Errorf("get target auth: %w", err)
+	}
+
+	if err := createTargetRepository(ctx, logger, client, image, targetAuth, viper.GetBool("create-repos")); err != nil {
+		return fmt.Errorf("create target repository: %w", err)
+	}
+
+	artifactType, err := client.GetArtifactTypeAtRemote(ctx, image.String(), sourceAuth)
+	if err != nil {
+		return fmt.Errorf("get artifact type: %w", err)
+	}
+
+	// OCI artifacts cannot be stored by the Docker daemon, so they are always copied between registries
+	if artifactType != "" {
+		newImageLogEntry(logger, "push", image.String()).Printf("[PUSH] Copying %s as an OCI artifact of type %s", image.String(), artifactType)
+
+		if err := client.CopyImageAndWait(ctx, image.String(), image.TargetImage(), sourceAuth, targetAuth); err != nil {
+			return fmt.Errorf("copy artifact: %w", err)
 		}
+
+		return nil
 	}
 
-	for _, image := range pushImages {
-		if err := client.DockerClient.ImageTag(ctx, image.String(), image.TargetImage()); err != nil {
-			return fmt.Errorf("tagging image: %w", err)
+	if len(platforms) > 0 {
+		if err := client.VerifyPlatformsAtRemote(ctx, image.String(), sourceAuth, platforms); err != nil {
+			return fmt.Errorf("verify platforms: %w", err)
 		}
 	}
 
-	for _, image := range pushImages {
-		auth, err := getEncodedTargetAuth(image.Target)
-		if err != nil {
-			return fmt.Errorf("get source auth: %w", err)
+	isImageIndex, err := client.IsImageIndexAtRemote(ctx, image.String(), sourceAuth)
+	if err != nil {
+		return fmt.Errorf("is image index: %w", err)
+	}
+
+	if isImageIndex {
+		if err := client.CopyImageIndexAndWait(ctx, image.String(), image.TargetImage(), platforms, sourceAuth, targetAuth); err != nil {
+			return fmt.Errorf("copy image index: %w", err)
 		}
 
-		if err := client.PushImageAndWait(ctx, image.TargetImage(), auth); err != nil {
-			return fmt.Errorf("pushing image to target: %w", err)
+		return nil
+	}
+
+	if viper.GetString("mode") == copyMode {
+		if err := client.CopyImageAndWait(ctx, image.String(), image.TargetImage(), sourceAuth, targetAuth); err != nil {
+			return fmt.Errorf("copy image: %w", err)
 		}
+
+		return nil
+	}
+
+	if err := client.PullImageAndWait(ctx, image.String(), sourceAuth); err != nil {
+		return fmt.Errorf("pull image and wait: %w", err)
+	}
+
+	if err := client.TagImage(ctx, image.String(), image.TargetImage()); err != nil {
+		return fmt.Errorf("tagging image: %w", err)
 	}
 
-	client.Logger.Printf("[PUSH] All images have been pushed!")
+	if err := client.PushImageAndWait(ctx, image.TargetImage(), targetAuth); err != nil {
+		return fmt.Errorf("pushing image to target: %w", err)
+	}
 
 	return nil
 }
+
+func getPlatforms(platforms []string) ([]docker.Platform, error) {
+	var parsedPlatforms []docker.Platform
+	for _, platform := range platforms {
+		parsedPlatform, err := docker.ParsePlatform(platform)
+		if err != nil {
+			return nil, fmt.Errorf("parse platform: %w", err)
+		}
+
+		parsedPlatforms = append(parsedPlatforms, parsedPlatform)
+	}
+
+	return parsedPlatforms, nil
+}
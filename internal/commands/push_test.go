@@ -0,0 +1,354 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	stdlog "log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func writeTestManifest(t *testing.T, contents string) string {
+	manifestDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+
+	manifestPath := filepath.Join(manifestDir, ".images.yaml")
+	if err := ioutil.WriteFile(manifestPath, []byte(contents), os.ModePerm); err != nil {
+		t.Fatal("write manifest:", err)
+	}
+
+	return manifestPath
+}
+
+// withoutDocker points the Docker client at an invalid host
+// so that any attempt to create a Docker client fails
+func withoutDocker(t *testing.T) func() {
+	dockerHost, exists := os.LookupEnv("DOCKER_HOST")
+	os.Setenv("DOCKER_HOST", "invalid-docker-host")
+
+	return func() {
+		if exists {
+			os.Setenv("DOCKER_HOST", dockerHost)
+		} else {
+			os.Unsetenv("DOCKER_HOST")
+		}
+	}
+}
+
+// newTestRegistry returns an in memory registry that does not log its requests
+func newTestRegistry() *httptest.Server {
+	return httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+}
+
+const dryRunManifest = `
+target:
+  host: target.com
+sources:
+- repository: busybox
+  tag: 1.32.0
+`
+
+func TestRunPushCommand_DryRun(t *testing.T) {
+	manifestPath := writeTestManifest(t, dryRunManifest)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+	defer withoutDocker(t)()
+
+	viper.Set("dry-run", true)
+	defer viper.Set("dry-run", false)
+
+	var output bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&output)
+
+	if err := runPushCommand(context.Background(), logger, manifestPath); err != nil {
+		t.Fatal("run push command:", err)
+	}
+
+	expectedLines := []string{
+		"Would pull busybox:1.32.0",
+		"Would tag busybox:1.32.0 as target.com/busybox:1.32.0",
+		"Would push target.com/busybox:1.32.0",
+	}
+
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(output.String(), expectedLine) {
+			t.Errorf("expected output to contain %q, actual %s", expectedLine, output.String())
+		}
+	}
+}
+
+func TestRunPushCommand_DryRunCopyMode(t *testing.T) {
+	manifestPath := writeTestManifest(t, dryRunManifest)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+	defer withoutDocker(t)()
+
+	viper.Set("dry-run", true)
+	defer viper.Set("dry-run", false)
+
+	viper.Set("mode", copyMode)
+	defer viper.Set("mode", "")
+
+	var output bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&output)
+
+	if err := runPushCommand(context.Background(), logger, manifestPath); err != nil {
+		t.Fatal("run push command:", err)
+	}
+
+	const expectedLine = "Would copy busybox:1.32.0 to target.com/busybox:1.32.0"
+	if !strings.Contains(output.String(), expectedLine) {
+		t.Errorf("expected output to contain %q, actual %s", expectedLine, output.String())
+	}
+
+	if strings.Contains(output.String(), "Would pull") {
+		t.Errorf("expected images to not be pulled in copy mode, actual %s", output.String())
+	}
+
+	viper.Set("mode", "stream")
+	if err := runPushCommand(context.Background(), logger, manifestPath); err == nil {
+		t.Error("expected an unknown mode to return an error")
+	}
+}
+
+func TestRunPushCommand_DryRunTargetOverride(t *testing.T) {
+	manifestPath := writeTestManifest(t, dryRunManifest)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+	defer withoutDocker(t)()
+
+	viper.Set("dry-run", true)
+	defer viper.Set("dry-run", false)
+
+	viper.Set("target", "throwaway.lan:5000/test")
+	defer viper.Set("target", "")
+
+	var output bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&output)
+
+	if err := runPushCommand(context.Background(), logger, manifestPath); err != nil {
+		t.Fatal("run push command:", err)
+	}
+
+	const expectedLine = "Would push throwaway.lan:5000/test/busybox:1.32.0"
+	if !strings.Contains(output.String(), expectedLine) {
+		t.Errorf("expected output to contain %q, actual %s", expectedLine, output.String())
+	}
+
+	if strings.Contains(output.String(), "target.com") {
+		t.Errorf("expected the manifest target to be overridden, actual %s", output.String())
+	}
+}
+
+func TestRunPullCommand_DryRun(t *testing.T) {
+	manifestPath := writeTestManifest(t, dryRunManifest)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+	defer withoutDocker(t)()
+
+	viper.Set("dry-run", true)
+	defer viper.Set("dry-run", false)
+
+	var output bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&output)
+
+	if err := runPullCommand(context.Background(), logger, "target", manifestPath); err != nil {
+		t.Fatal("run pull command:", err)
+	}
+
+	const expectedLine = "Would pull target.com/busybox:1.32.0"
+	if !strings.Contains(output.String(), expectedLine) {
+		t.Errorf("expected output to contain %q, actual %s", expectedLine, output.String())
+	}
+}
+
+func TestIsPushRequired(t *testing.T) {
+	registryServer := newTestRegistry()
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+
+	sourceImage, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	otherImage, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	writeImage := func(reference string, image v1.Image) {
+		imageReference, err := name.ParseReference(reference)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		if err := remote.Write(imageReference, image); err != nil {
+			t.Fatal("write image:", err)
+		}
+	}
+
+	writeImage(registryHost+"/source/app:v1.0.0", sourceImage)
+	writeImage(registryHost+"/source/stale:v1.0.0", sourceImage)
+	writeImage(registryHost+"/target/app:v1.0.0", sourceImage)
+	writeImage(registryHost+"/target/stale:v1.0.0", otherImage)
+	writeImage(registryHost+"/source/app:latest", sourceImage)
+	writeImage(registryHost+"/target/app:latest", sourceImage)
+
+	testCases := []struct {
+		repository           string
+		tag                  string
+		expectedPushRequired bool
+	}{
+		{
+			repository:           "app",
+			expectedPushRequired: false,
+		},
+		{
+			repository:           "app",
+			tag:                  "latest",
+			expectedPushRequired: true,
+		},
+		{
+			repository:           "stale",
+			expectedPushRequired: true,
+		},
+		{
+			repository:           "missing",
+			expectedPushRequired: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tag := testCase.tag
+		if tag == "" {
+			tag = "v1.0.0"
+		}
+
+		image := SourceImage{
+			Host:       registryHost + "/source",
+			Repository: testCase.repository,
+			Tag:        tag,
+			Target: Target{
+				Host:       registryHost,
+				Repository: "target",
+			},
+		}
+
+		pushRequired, err := isPushRequired(context.Background(), docker.Client{}, image)
+		if err != nil {
+			t.Fatal("is push required:", err)
+		}
+
+		if pushRequired != testCase.expectedPushRequired {
+			t.Errorf("expected push required to be %v for %s:%s, actual %v", testCase.expectedPushRequired, testCase.repository, tag, pushRequired)
+		}
+	}
+}
+
+type fakeRepositoryCreator struct {
+	existing map[string]bool
+	created  []string
+}
+
+func (f *fakeRepositoryCreator) CreateRepositoryIfNotExists(ctx context.Context, image string, encodedAuth string) (string, error) {
+	repository := docker.RegistryPath(image).Repository()
+	if f.existing[repository] {
+		return "", nil
+	}
+
+	f.existing[repository] = true
+	f.created = append(f.created, repository)
+
+	return "repository " + repository, nil
+}
+
+func TestCreateTargetRepository(t *testing.T) {
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.31.0", Target: Target{Host: "mycompany.com", Repository: "mirror"}},
+		{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "mycompany.com", Repository: "mirror"}},
+		{Repository: "nginx", Tag: "1.19.0", Target: Target{Host: "mycompany.com", Repository: "mirror"}},
+		{Repository: "redis", Tag: "6.0.0", Target: Target{Host: "mycompany.com", Repository: "mirror"}},
+	}
+
+	testCases := []struct {
+		create   bool
+		expected []string
+	}{
+		{create: false},
+		{create: true, expected: []string{"mirror/busybox", "mirror/nginx"}},
+	}
+
+	for _, testCase := range testCases {
+		var output bytes.Buffer
+		logger := log.New()
+		logger.SetOutput(&output)
+
+		creator := &fakeRepositoryCreator{existing: map[string]bool{"mirror/redis": true}}
+		for _, image := range images {
+			if err := createTargetRepository(context.Background(), logger, creator, image, "", testCase.create); err != nil {
+				t.Fatal("create target repository:", err)
+			}
+		}
+
+		if !reflect.DeepEqual(creator.created, testCase.expected) {
+			t.Errorf("expected created repositories to be %v when create is %v, actual %v", testCase.expected, testCase.create, creator.created)
+		}
+
+		// Only the repositories that did not exist are logged, once each
+		if actual := strings.Count(output.String(), "Created"); actual != len(testCase.expected) {
+			t.Errorf("expected %v created repositories to be logged, actual %v", len(testCase.expected), actual)
+		}
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	testCases := []struct {
+		values        []string
+		expected      map[string]string
+		expectedError string
+	}{
+		{values: []string{"mirrored-by=sinker"}, expected: map[string]string{"mirrored-by": "sinker"}},
+		{values: []string{"org.opencontainers.image.source=https://github.com/org/app?a=b"}, expected: map[string]string{"org.opencontainers.image.source": "https://github.com/org/app?a=b"}},
+		{values: []string{"empty="}, expected: map[string]string{"empty": ""}},
+		{values: []string{"mirrored-by"}, expectedError: "must be in the format key=value"},
+		{values: []string{"=sinker"}, expectedError: "must be in the format key=value"},
+		{values: []string{"mirrored-by=sinker", "mirrored-by=other"}, expectedError: "more than once"},
+	}
+
+	for _, testCase := range testCases {
+		keyValues, err := parseKeyValues(testCase.values)
+		if testCase.expectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Errorf("expected error to contain %q, actual %v", testCase.expectedError, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal("parse key values:", err)
+		}
+
+		if !reflect.DeepEqual(keyValues, testCase.expected) {
+			t.Errorf("expected key values %v, actual %v", testCase.expected, keyValues)
+		}
+	}
+}
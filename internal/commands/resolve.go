@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func addOutputManifestFlag(cmd *cobra.Command) {
+	cmd.Flags().String("output-manifest", "", "Write the resolved manifest, with the environment variables, target, filters, and repository rules applied to every image, to the path (use - for stdout)")
+}
+
+// resolveManifest returns the manifest of the images exactly as they are synced. Every image has its
+// own target, and the strip prefix and rewrite rules of its target are replaced by a rule that only
+// matches the repository of the image, so that the resolved manifest syncs the same images when it is
+// read again. The strip prefix and rewrite rules of the manifest target are no longer used by any image,
+// while the tag prefix and suffix of the target of each image are kept, as they apply to its tag.
+func resolveManifest(manifest Manifest) Manifest {
+	resolved := Manifest{
+		Version: currentManifestVersion,
+		Target: Target{
+			Host:       manifest.Target.Host,
+			Repository: manifest.Target.Repository,
+			Auth:       manifest.Target.Auth,
+		},
+	}
+
+	for _, image := range manifest.Images {
+		resolvedImage := SourceImage{
+			Repository: image.Repository,
+			Host:       image.Host,
+			Tag:        image.Tag,
+			Digest:     image.Digest,
+			Auth:       image.Auth,
+			Tags:       image.Tags,
+			Target: Target{
+				Host:       image.Target.Host,
+				Repository: image.Target.Repository,
+				Auth:       image.Target.Auth,
+				TagPrefix:  image.Target.TagPrefix,
+				TagSuffix:  image.Target.TagSuffix,
+			},
+		}
+
+		if repository := image.targetRepository(); repository != image.Repository {
+			resolvedImage.Target.Rewrite = []RewriteRule{
+				{Match: "^" + regexp.QuoteMeta(image.Repository) + "$", Replace: repository},
+			}
+		}
+
+		resolved.Images = append(resolved.Images, resolvedImage)
+	}
+
+	return resolved
+}
+
+// writeResolvedManifest writes the resolved manifest to the path of the output-manifest flag, if one is set
+func writeResolvedManifest(manifest Manifest) error {
+	if viper.GetString("output-manifest") == "" {
+		return nil
+	}
+
+	if err := WriteManifest(resolveManifest(manifest), viper.GetString("output-manifest")); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+
+	return nil
+}
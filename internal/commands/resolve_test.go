@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveManifest_Golden(t *testing.T) {
+	manifestPath := writeTestManifest(t, `target:
+  host: ${REGISTRY_HOST}
+  repository: mirror
+  stripPrefix: coreos
+  rewrite:
+  - match: ^library/(.*)$
+    replace: hub/$1
+sources:
+- repository: coreos/prometheus-operator
+  host: quay.io
+  tag: ${OPERATOR_VERSION:-v0.40.0}
+  digest: sha256:0f8a6d3e3b0c8e8d0f7a1f5c1b5b7e0c2a4d6f8e9a1b3c5d7e9f0a2b4c6d8e0f
+- repository: library/nginx
+  tags:
+  - 1.19.*
+- repository: jimmidyson/configmap-reload
+  tag: v0.3.0
+  target:
+    host: other.com
+    auth:
+      username: OTHER_USERNAME
+      password: OTHER_PASSWORD
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	os.Setenv("REGISTRY_HOST", "mycompany.com")
+	defer os.Unsetenv("REGISTRY_HOST")
+
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	outputPath := filepath.Join(filepath.Dir(manifestPath), "resolved.yaml")
+	if err := WriteManifest(resolveManifest(manifest), outputPath); err != nil {
+		t.Fatal("write resolved manifest:", err)
+	}
+
+	output, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal("read resolved manifest:", err)
+	}
+
+	goldenPath := filepath.Join("testdata", "resolve.golden")
+	if *update {
+		if err := ioutil.WriteFile(goldenPath, output, 0644); err != nil {
+			t.Fatal("write golden file:", err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal("read golden file:", err)
+	}
+
+	if string(output) != string(expected) {
+		t.Errorf("expected resolved manifest to be %s, actual %s", expected, output)
+	}
+
+	// The resolved manifest syncs the same images when it is read again
+	resolved, err := GetManifest(outputPath)
+	if err != nil {
+		t.Fatal("get resolved manifest:", err)
+	}
+
+	if len(resolved.Images) != len(manifest.Images) {
+		t.Fatalf("expected %v images in the resolved manifest, actual %v", len(manifest.Images), len(resolved.Images))
+	}
+
+	for i, image := range manifest.Images {
+		if resolved.Images[i].String() != image.String() || resolved.Images[i].TargetImage() != image.TargetImage() {
+			t.Errorf("expected resolved image %s to %s, actual %s to %s", image, image.TargetImage(), resolved.Images[i], resolved.Images[i].TargetImage())
+		}
+	}
+}
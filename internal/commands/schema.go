@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newSchemaCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the image manifest, for editors and linters to validate manifests with",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runSchemaCommand(os.Stdout); err != nil {
+				return fmt.Errorf("schema: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	return &cmd
+}
+
+// jsonSchema is a JSON Schema of a value of the manifest
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 string                 `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+}
+
+func runSchemaCommand(output io.Writer) error {
+	schema, err := json.MarshalIndent(getManifestSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal schema: %w", err)
+	}
+
+	if _, err := output.Write(append(schema, '\n')); err != nil {
+		return fmt.Errorf("write schema: %w", err)
+	}
+
+	return nil
+}
+
+// getManifestSchema returns the JSON Schema of the manifest, which is generated from the manifest
+// struct so that it always has the same fields as the manifest that is read by the commands
+func getManifestSchema() *jsonSchema {
+	schema := getTypeSchema(reflect.TypeOf(Manifest{}))
+	schema.Schema = "http://json-schema.org/draft-07/schema#"
+	schema.Title = "sinker image manifest"
+
+	return schema
+}
+
+// getTypeSchema returns the JSON Schema of the type, using the yaml tags of the fields of structs.
+// Unknown fields are not allowed, as they are rejected when the manifest is read. Fields that are
+// not omitted when empty are required, except for objects such as the target of an image, which
+// can be set elsewhere in the manifest.
+func getTypeSchema(valueType reflect.Type) *jsonSchema {
+	switch valueType.Kind() {
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+
+	case reflect.Int, reflect.Int64:
+		return &jsonSchema{Type: "integer"}
+
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+
+	case reflect.Slice:
+		return &jsonSchema{Type: "array", Items: getTypeSchema(valueType.Elem())}
+
+	case reflect.Struct:
+		additionalProperties := false
+		schema := jsonSchema{
+			Type:                 "object",
+			Properties:           make(map[string]*jsonSchema),
+			AdditionalProperties: &additionalProperties,
+		}
+
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			tagTokens := strings.Split(field.Tag.Get("yaml"), ",")
+			if tagTokens[0] == "-" {
+				continue
+			}
+
+			name := tagTokens[0]
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+
+			schema.Properties[name] = getTypeSchema(field.Type)
+
+			omitEmpty := false
+			for _, option := range tagTokens[1:] {
+				omitEmpty = omitEmpty || option == "omitempty"
+			}
+
+			if !omitEmpty && field.Type.Kind() != reflect.Struct {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+
+		return &schema
+
+	default:
+		panic(fmt.Sprintf("manifest schema does not support the %s type", valueType))
+	}
+}
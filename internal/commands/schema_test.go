@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+const sampleManifest = `
+version: 1
+target:
+  host: mycompany.com
+  repository: myteam
+  stripPrefix: coreos
+  rewrite:
+  - match: ^library/(.*)$
+    replace: mirror/$1
+sources:
+- repository: busybox
+  tag: 1.32.0
+- repository: coreos/prometheus-operator
+  host: quay.io
+  tags:
+  - v0.4*
+  auth:
+    username: QUAY_USER
+    password: QUAY_PASSWORD
+- repository: nginx
+  digest: sha256:0efad4d09a419dc6d574c3c3baacb804a530acd61d5eba72cb1f14e1f5ac0c8f
+  target:
+    host: other.com
+    auth:
+      token: OTHER_TOKEN
+groups:
+- targetPrefix: monitoring
+  sources:
+  - repository: prometheus/prometheus
+    host: quay.io
+    tag: v2.22.0
+`
+
+func TestManifestSchema_ValidatesSampleManifest(t *testing.T) {
+	manifestPath := writeTestManifest(t, sampleManifest)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	if _, err := GetManifest(manifestPath); err != nil {
+		t.Fatal("expected the sample manifest to be valid:", err)
+	}
+
+	if problems := validateAgainstSchema(t, sampleManifest); len(problems) > 0 {
+		t.Errorf("expected the sample manifest to match the schema, actual %v", problems)
+	}
+}
+
+func TestManifestSchema_RejectsInvalidManifests(t *testing.T) {
+	manifests := []string{
+		"target:\n  host: mycompany.com\n  hots: other.com\n",
+		"sources:\n- tag: 1.32.0\n",
+		"version: one\n",
+		"sources:\n- repository: busybox\n  tags: v1.*\n",
+	}
+
+	for _, manifest := range manifests {
+		if problems := validateAgainstSchema(t, manifest); len(problems) == 0 {
+			t.Errorf("expected manifest %q to not match the schema", manifest)
+		}
+	}
+}
+
+func TestRunSchemaCommand_Golden(t *testing.T) {
+	var output bytes.Buffer
+	if err := runSchemaCommand(&output); err != nil {
+		t.Fatal("run schema command:", err)
+	}
+
+	goldenPath := filepath.Join("testdata", "schema.golden")
+	if *update {
+		if err := ioutil.WriteFile(goldenPath, output.Bytes(), 0644); err != nil {
+			t.Fatal("write golden file:", err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal("read golden file:", err)
+	}
+
+	if output.String() != string(expected) {
+		t.Errorf("expected schema to be %s, actual %s", expected, output.String())
+	}
+}
+
+// validateAgainstSchema returns the problems found when validating the YAML manifest against the
+// printed schema, using the keywords of JSON Schema that the manifest schema is made of
+func validateAgainstSchema(t *testing.T, manifest string) []string {
+	var output bytes.Buffer
+	if err := runSchemaCommand(&output); err != nil {
+		t.Fatal("run schema command:", err)
+	}
+
+	var schema jsonSchema
+	if err := json.Unmarshal(output.Bytes(), &schema); err != nil {
+		t.Fatal("unmarshal schema:", err)
+	}
+
+	contents, err := yaml.YAMLToJSON([]byte(manifest))
+	if err != nil {
+		t.Fatal("convert manifest to json:", err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(contents, &value); err != nil {
+		t.Fatal("unmarshal manifest:", err)
+	}
+
+	return validateSchemaValue(&schema, value, "manifest")
+}
+
+func validateSchemaValue(schema *jsonSchema, value interface{}, path string) []string {
+	switch schema.Type {
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{path + ": expected a string"}
+		}
+
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != float64(int64(number)) {
+			return []string{path + ": expected an integer"}
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{path + ": expected a boolean"}
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{path + ": expected an array"}
+		}
+
+		var problems []string
+		for i, item := range items {
+			problems = append(problems, validateSchemaValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+
+		return problems
+
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{path + ": expected an object"}
+		}
+
+		var problems []string
+		for _, required := range schema.Required {
+			if _, exists := object[required]; !exists {
+				problems = append(problems, fmt.Sprintf("%s: %s is required", path, required))
+			}
+		}
+
+		for key, property := range object {
+			propertySchema, exists := schema.Properties[key]
+			if !exists {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					problems = append(problems, fmt.Sprintf("%s: unknown property %s", path, key))
+				}
+				continue
+			}
+
+			problems = append(problems, validateSchemaValue(propertySchema, property, path+"."+key)...)
+		}
+
+		return problems
+	}
+
+	return nil
+}
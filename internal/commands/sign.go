@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var verifyFlags = []string{"verify", "verify-key"}
+
+func addVerifyFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("verify", false, "Verify the cosign signature of each image before it is synced, and refuse to sync images that are unsigned or have an invalid signature")
+	cmd.Flags().String("verify-key", "", "The public key (e.g. cosign.pub or a KMS URI) to verify signatures with. Defaults to COSIGN_PUBLIC_KEY")
+}
+
+func bindVerifyFlags(cmd *cobra.Command) error {
+	for _, flag := range verifyFlags {
+		if err := viper.BindPFlag(flag, cmd.Flags().Lookup(flag)); err != nil {
+			return fmt.Errorf("bind %s flag: %w", flag, err)
+		}
+	}
+
+	return nil
+}
+
+// imageSigner signs an image at its registry
+type imageSigner interface {
+	Sign(ctx context.Context, image string) error
+}
+
+// cosignSigner signs images with the cosign CLI and a private key. The password of the key
+// is read by cosign from the COSIGN_PASSWORD environment variable, and cosign uses the
+// Docker configuration to authenticate to the registry.
+type cosignSigner struct {
+	command string
+	key     string
+}
+
+func (s cosignSigner) Sign(ctx context.Context, image string) error {
+	output, err := exec.CommandContext(ctx, s.command, "sign", "--key", s.key, "--yes", image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cosign sign: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// imageVerifier verifies the signature of an image at its registry
+type imageVerifier interface {
+	Verify(ctx context.Context, image string) error
+}
+
+// cosignVerifier verifies the signatures of images with the cosign CLI and a public key
+type cosignVerifier struct {
+	command string
+	key     string
+}
+
+func (v cosignVerifier) Verify(ctx context.Context, image string) error {
+	output, err := exec.CommandContext(ctx, v.command, "verify", "--key", v.key, image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cosign verify: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// getImageVerifier returns the verifier of the synced images, or nil when the images should not be verified
+func getImageVerifier() (imageVerifier, error) {
+	if !viper.GetBool("verify") {
+		return nil, nil
+	}
+
+	key := viper.GetString("verify-key")
+	if key == "" {
+		key = os.Getenv("COSIGN_PUBLIC_KEY")
+	}
+
+	if key == "" {
+		return nil, errors.New("a public key is required to verify images, set the verify-key flag or the COSIGN_PUBLIC_KEY environment variable")
+	}
+
+	return cosignVerifier{command: "cosign", key: key}, nil
+}
+
+// verifyImage verifies the signature of the image when a verifier is given
+func verifyImage(ctx context.Context, verifier imageVerifier, image string) error {
+	if verifier == nil {
+		return nil
+	}
+
+	if err := verifier.Verify(ctx, image); err != nil {
+		return fmt.Errorf("verify signature of %s: %w", image, err)
+	}
+
+	return nil
+}
+
+// getImageSigner returns the signer for the pushed images, or nil when the images should not be signed
+func getImageSigner() (imageSigner, error) {
+	if !viper.GetBool("sign") {
+		return nil, nil
+	}
+
+	key := viper.GetString("cosign-key")
+	if key == "" {
+		key = os.Getenv("COSIGN_KEY")
+	}
+
+	if key == "" {
+		return nil, errors.New("a cosign key is required to sign images, set the cosign-key flag or the COSIGN_KEY environment variable")
+	}
+
+	return cosignSigner{command: "cosign", key: key}, nil
+}
+
+// signImage signs the target image by its digest, so that the signature
+// is for the image that was pushed even if the tag is later moved. The digest
+// is found with the encoded auth of the target that the image was pushed with.
+func signImage(ctx context.Context, client docker.Client, signer imageSigner, image string, encodedAuth string) error {
+	digests, err := client.GetDigestsAtRemote(ctx, image, encodedAuth)
+	if err != nil {
+		return fmt.Errorf("get digest: %w", err)
+	}
+
+	if len(digests) == 0 {
+		return fmt.Errorf("image %s not found", image)
+	}
+
+	registryPath := docker.RegistryPath(image)
+	reference := registryPath.Repository() + "@" + digests[0]
+	if registryPath.Host() != "" {
+		reference = registryPath.Host() + "/" + reference
+	}
+
+	if err := signer.Sign(ctx, reference); err != nil {
+		return fmt.Errorf("sign %s: %w", reference, err)
+	}
+
+	return nil
+}
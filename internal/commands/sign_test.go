@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	log "github.com/sirupsen/logrus"
+)
+
+type fakeSigner struct {
+	err    error
+	signed []string
+}
+
+func (f *fakeSigner) Sign(ctx context.Context, image string) error {
+	f.signed = append(f.signed, image)
+	return f.err
+}
+
+type fakeVerifier struct {
+	unsignedImage string
+	verified      []string
+}
+
+func (f *fakeVerifier) Verify(ctx context.Context, image string) error {
+	f.verified = append(f.verified, image)
+	if image == f.unsignedImage {
+		return errors.New("no matching signatures")
+	}
+
+	return nil
+}
+
+func TestSyncImage(t *testing.T) {
+	registryServer := newTestRegistry()
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+
+	platformImage, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	imageIndex := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{
+		Add:        platformImage,
+		Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+	})
+
+	imageReference, err := name.ParseReference(registryHost + "/source/app:v1.0.0")
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.WriteIndex(imageReference, imageIndex); err != nil {
+		t.Fatal("write index:", err)
+	}
+
+	indexDigest, err := imageIndex.Digest()
+	if err != nil {
+		t.Fatal("index digest:", err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := docker.Client{Logger: logger, RetryPolicy: docker.RetryPolicy{Attempts: 1, Backoff: docker.FixedBackoff}}
+
+	image := SourceImage{
+		Host:       registryHost + "/source",
+		Repository: "app",
+		Tag:        "v1.0.0",
+		Target:     Target{Host: registryHost, Repository: "target"},
+	}
+
+	signer := fakeSigner{}
+	if err := syncImage(context.Background(), client, image, nil, nil, &signer); err != nil {
+		t.Fatal("sync image:", err)
+	}
+
+	expected := []string{registryHost + "/target/app@" + indexDigest.String()}
+	if !reflect.DeepEqual(signer.signed, expected) {
+		t.Errorf("expected the target image to be signed by its digest %v, actual %v", expected, signer.signed)
+	}
+
+	failingSigner := fakeSigner{err: errors.New("signing failed")}
+	if err := syncImage(context.Background(), client, image, nil, nil, &failingSigner); err == nil {
+		t.Error("expected the push to fail when the image could not be signed")
+	}
+
+	missingImage := image
+	missingImage.Repository = "missing"
+
+	unusedSigner := fakeSigner{}
+	if err := syncImage(context.Background(), client, missingImage, nil, nil, &unusedSigner); err == nil {
+		t.Error("expected the push of a missing image to fail")
+	}
+
+	if len(unusedSigner.signed) > 0 {
+		t.Errorf("expected images that failed to push to not be signed, actual %v", unusedSigner.signed)
+	}
+
+	verifier := fakeVerifier{}
+	if err := syncImage(context.Background(), client, image, nil, &verifier, nil); err != nil {
+		t.Fatal("sync verified image:", err)
+	}
+
+	if !reflect.DeepEqual(verifier.verified, []string{image.String()}) {
+		t.Errorf("expected the source image %v to be verified, actual %v", image.String(), verifier.verified)
+	}
+
+	unsignedVerifier := fakeVerifier{unsignedImage: image.String()}
+	unusedSigner = fakeSigner{}
+	if err := syncImage(context.Background(), client, image, nil, &unsignedVerifier, &unusedSigner); err == nil {
+		t.Error("expected the sync to fail when the signature of the source image could not be verified")
+	}
+
+	if len(unusedSigner.signed) > 0 {
+		t.Errorf("expected images that failed verification to not be pushed and signed, actual %v", unusedSigner.signed)
+	}
+}
@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/spf13/viper"
+)
+
+// syncSummary records the result of every image that is pulled or pushed. It is safe for
+// concurrent use by images that are pulled or pushed at the same time.
+type syncSummary struct {
+	mutex     sync.Mutex
+	succeeded int
+	failed    int
+	skipped   int
+	missing   []string
+	hosts     map[string]*hostResults
+	start     time.Time
+	stats     *docker.TransferStats
+}
+
+// hostResults are the number of images of a registry host that succeeded and failed
+type hostResults struct {
+	succeeded int
+	failed    int
+}
+
+func newSyncSummary() *syncSummary {
+	summary := syncSummary{
+		hosts: make(map[string]*hostResults),
+		start: time.Now(),
+		stats: docker.NewTransferStats(),
+	}
+
+	return &summary
+}
+
+// record records whether the image, which is the image that was pulled or pushed, succeeded or failed
+func (s *syncSummary) record(image string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	host := docker.RegistryPath(image).Host()
+	if host == "" {
+		host = "docker.io"
+	}
+
+	results, exists := s.hosts[host]
+	if !exists {
+		results = &hostResults{}
+		s.hosts[host] = results
+	}
+
+	if err != nil {
+		s.failed++
+		results.failed++
+	} else {
+		s.succeeded++
+		results.succeeded++
+	}
+}
+
+// skip records that the image was not pulled or pushed, as the deadline of the run was exceeded before it started
+func (s *syncSummary) skip(image string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.skipped++
+}
+
+// skipMissing records that the image was not pulled, as it is missing at its registry
+func (s *syncSummary) skipMissing(image string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.skipped++
+	s.missing = append(s.missing, image)
+}
+
+// skippedImages returns the number of images that were skipped
+func (s *syncSummary) skippedImages() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.skipped
+}
+
+// missingImages returns the number of images that were skipped as they are missing at their registry
+func (s *syncSummary) missingImages() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.missing)
+}
+
+// write writes a table of the number of images that succeeded, failed, and were skipped, the number of bytes
+// transferred by Docker, and the time that has elapsed since the summary was created, followed by the
+// images that were skipped as they are missing at their registry
+func (s *syncSummary) write(output io.Writer) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	elapsed := time.Since(s.start).Round(time.Second)
+
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SUCCEEDED\tFAILED\tSKIPPED\tTRANSFERRED\tELAPSED")
+	fmt.Fprintf(writer, "%v\t%v\t%v\t%s\t%s\n", s.succeeded, s.failed, s.skipped, formatSize(s.stats.Bytes()), elapsed)
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	if len(s.missing) == 0 {
+		return nil
+	}
+
+	missing := append([]string{}, s.missing...)
+	sort.Strings(missing)
+
+	if _, err := fmt.Fprintf(output, "\nSkipped %v missing image(s):\n  %s\n", len(missing), strings.Join(missing, "\n  ")); err != nil {
+		return fmt.Errorf("write missing images: %w", err)
+	}
+
+	return nil
+}
+
+// writeMetrics writes the number of images that succeeded and failed, and the number of bytes
+// transferred, of each registry host in the Prometheus text format
+func (s *syncSummary) writeMetrics(output io.Writer) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var hosts []string
+	for host := range s.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	hostBytes := s.stats.HostBytes()
+	var byteHosts []string
+	for host := range hostBytes {
+		byteHosts = append(byteHosts, host)
+	}
+	sort.Strings(byteHosts)
+
+	var metrics strings.Builder
+	writeMetricHeader(&metrics, "sinker_images_synced_total", "The number of images that were pulled or pushed.")
+	for _, host := range hosts {
+		fmt.Fprintf(&metrics, "sinker_images_synced_total{host=\"%s\"} %v\n", escapeLabelValue(host), s.hosts[host].succeeded)
+	}
+
+	writeMetricHeader(&metrics, "sinker_images_failed_total", "The number of images that failed to be pulled or pushed.")
+	for _, host := range hosts {
+		fmt.Fprintf(&metrics, "sinker_images_failed_total{host=\"%s\"} %v\n", escapeLabelValue(host), s.hosts[host].failed)
+	}
+
+	writeMetricHeader(&metrics, "sinker_transferred_bytes_total", "The number of bytes of image layers transferred with the registry.")
+	for _, host := range byteHosts {
+		fmt.Fprintf(&metrics, "sinker_transferred_bytes_total{host=\"%s\"} %v\n", escapeLabelValue(host), hostBytes[host])
+	}
+
+	if _, err := io.WriteString(output, metrics.String()); err != nil {
+		return fmt.Errorf("write metrics: %w", err)
+	}
+
+	return nil
+}
+
+// writeMetricsFile writes the metrics to the file at the path. The metrics are written to a
+// temporary file that replaces the file, so that a collector never reads incomplete metrics.
+func (s *syncSummary) writeMetricsFile(path string) error {
+	metricsFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(metricsFile.Name())
+
+	if err := s.writeMetrics(metricsFile); err != nil {
+		metricsFile.Close()
+		return fmt.Errorf("write metrics: %w", err)
+	}
+
+	if err := metricsFile.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if err := os.Chmod(metricsFile.Name(), 0644); err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+
+	if err := os.Rename(metricsFile.Name(), path); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+// writeMetricsFile writes the metrics of the summary to the file set by the metrics-file flag, if one is set
+func writeMetricsFile(summary *syncSummary) error {
+	if viper.GetString("metrics-file") == "" {
+		return nil
+	}
+
+	return summary.writeMetricsFile(viper.GetString("metrics-file"))
+}
+
+func writeMetricHeader(metrics *strings.Builder, name string, help string) {
+	fmt.Fprintf(metrics, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(metrics, "# TYPE %s counter\n", name)
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
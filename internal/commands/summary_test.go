@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+)
+
+func TestSyncSummary_Write(t *testing.T) {
+	summary := newSyncSummary()
+
+	var workers sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		workers.Add(1)
+		go func(i int) {
+			defer workers.Done()
+
+			if i%5 == 0 {
+				summary.record("busybox:1.32.0", errors.New("push failed"))
+				return
+			}
+
+			summary.stats.Update(string(rune('a'+i)), docker.Status{ID: "layer", ProgressDetail: docker.ProgressDetail{Current: 1000, Total: 1000}})
+			summary.record("busybox:1.32.0", nil)
+		}(i)
+	}
+	workers.Wait()
+
+	summary.skip("busybox:1.33.0")
+
+	var output bytes.Buffer
+	if err := summary.write(&output); err != nil {
+		t.Fatal("write summary:", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and a summary line, actual %s", output.String())
+	}
+
+	fields := strings.Fields(lines[1])
+	expectedFields := []string{"8", "2", "1", "8.0", "kB", "0s"}
+	if strings.Join(fields, " ") != strings.Join(expectedFields, " ") {
+		t.Errorf("expected summary to be %v, actual %v", expectedFields, fields)
+	}
+}
+
+func TestSyncSummary_Write_MissingImages(t *testing.T) {
+	summary := newSyncSummary()
+	summary.record("busybox:1.32.0", nil)
+	summary.skipMissing("nginx:9.9.9")
+	summary.skipMissing("busybox:9.9.9")
+
+	var output bytes.Buffer
+	if err := summary.write(&output); err != nil {
+		t.Fatal("write summary:", err)
+	}
+
+	expected := "\nSkipped 2 missing image(s):\n  busybox:9.9.9\n  nginx:9.9.9\n"
+	if !strings.HasSuffix(output.String(), expected) {
+		t.Errorf("expected summary to end with %q, actual %q", expected, output.String())
+	}
+
+	fields := strings.Fields(strings.Split(output.String(), "\n")[1])
+	if fields[0] != "1" || fields[1] != "0" || fields[2] != "2" {
+		t.Errorf("expected 1 succeeded, 0 failed, and 2 skipped, actual %v", fields)
+	}
+}
+
+func TestSyncSummary_WriteMetrics(t *testing.T) {
+	summary := newSyncSummary()
+	summary.record("mycompany.com/myrepo/busybox:1.32.0", nil)
+	summary.record("mycompany.com/myrepo/coreos/etcd:v3.4.13", errors.New("push failed"))
+	summary.record("other.com:5000/nginx:1.19.0", nil)
+	summary.stats.Update("busybox:1.32.0", docker.Status{ID: "layer", ProgressDetail: docker.ProgressDetail{Current: 1000, Total: 1000}})
+	summary.stats.Update("mycompany.com/myrepo/busybox:1.32.0", docker.Status{ID: "layer", ProgressDetail: docker.ProgressDetail{Current: 1000, Total: 1000}})
+
+	var output bytes.Buffer
+	if err := summary.writeMetrics(&output); err != nil {
+		t.Fatal("write metrics:", err)
+	}
+
+	samples, err := parseMetrics(output.String())
+	if err != nil {
+		t.Fatalf("parse metrics: %s\n%s", err, output.String())
+	}
+
+	expected := map[string]string{
+		`sinker_images_synced_total{host="mycompany.com"}`:     "1",
+		`sinker_images_synced_total{host="other.com:5000"}`:    "1",
+		`sinker_images_failed_total{host="mycompany.com"}`:     "1",
+		`sinker_images_failed_total{host="other.com:5000"}`:    "0",
+		`sinker_transferred_bytes_total{host="docker.io"}`:     "1000",
+		`sinker_transferred_bytes_total{host="mycompany.com"}`: "1000",
+	}
+
+	if len(samples) != len(expected) {
+		t.Errorf("expected %d samples, actual %v", len(expected), samples)
+	}
+
+	for sample, value := range expected {
+		if samples[sample] != value {
+			t.Errorf("expected %s to be %s, actual %s", sample, value, samples[sample])
+		}
+	}
+}
+
+var (
+	metricCommentPattern = regexp.MustCompile(`^# (HELP|TYPE) ([a-zA-Z_:][a-zA-Z0-9_:]*) (.+)$`)
+	metricSamplePattern  = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\]|\\.)*"(?:,[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\]|\\.)*")*\})? ([0-9]+(?:\.[0-9]+)?)$`)
+)
+
+// parseMetrics parses the Prometheus text format, returning the value of each sample. Every
+// sample must follow the TYPE of its metric, and every metric must be a counter.
+func parseMetrics(metrics string) (map[string]string, error) {
+	typedMetrics := make(map[string]bool)
+	samples := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSuffix(metrics, "\n"), "\n") {
+		if strings.HasPrefix(line, "#") {
+			match := metricCommentPattern.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("invalid comment %q", line)
+			}
+
+			if match[1] == "TYPE" && match[3] != "counter" {
+				return nil, fmt.Errorf("expected %s to be a counter, actual %s", match[2], match[3])
+			}
+
+			if match[1] == "TYPE" {
+				typedMetrics[match[2]] = true
+			}
+
+			continue
+		}
+
+		match := metricSamplePattern.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("invalid sample %q", line)
+		}
+
+		if !typedMetrics[match[1]] {
+			return nil, fmt.Errorf("sample %q has no type", line)
+		}
+
+		samples[match[1]+match[2]] = match[3]
+	}
+
+	return samples, nil
+}
+
+func TestSyncSummary_WriteMetricsFile(t *testing.T) {
+	metricsDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(metricsDir)
+
+	summary := newSyncSummary()
+	summary.record("mycompany.com/myrepo/busybox:1.32.0", nil)
+
+	metricsPath := filepath.Join(metricsDir, "sinker.prom")
+	if err := summary.writeMetricsFile(metricsPath); err != nil {
+		t.Fatal("write metrics file:", err)
+	}
+
+	contents, err := ioutil.ReadFile(metricsPath)
+	if err != nil {
+		t.Fatal("read metrics file:", err)
+	}
+
+	if !strings.Contains(string(contents), `sinker_images_synced_total{host="mycompany.com"} 1`) {
+		t.Errorf("expected metrics file to contain the synced images, actual %s", contents)
+	}
+
+	files, err := ioutil.ReadDir(metricsDir)
+	if err != nil {
+		t.Fatal("read dir:", err)
+	}
+
+	if len(files) != 1 {
+		t.Errorf("expected the temp file to be removed, actual %d files", len(files))
+	}
+}
@@ -0,0 +1,333 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newSyncCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "sync",
+		Short: "Pull, tag, and push each image in the manifest to the target repository in a single pass",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run")); err != nil {
+				return fmt.Errorf("bind dry-run flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("force", cmd.Flags().Lookup("force")); err != nil {
+				return fmt.Errorf("bind force flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("keep-images", cmd.Flags().Lookup("keep-images")); err != nil {
+				return fmt.Errorf("bind keep-images flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("concurrency-per-host", cmd.Flags().Lookup("concurrency-per-host")); err != nil {
+				return fmt.Errorf("bind concurrency-per-host flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-concurrent", cmd.Flags().Lookup("max-concurrent")); err != nil {
+				return fmt.Errorf("bind max-concurrent flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("preserve-tags", cmd.Flags().Lookup("preserve-tags")); err != nil {
+				return fmt.Errorf("bind preserve-tags flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
+				return fmt.Errorf("bind target flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("strict-names", cmd.Flags().Lookup("strict-names")); err != nil {
+				return fmt.Errorf("bind strict-names flag: %w", err)
+			}
+
+			if err := bindTagAffixFlags(cmd); err != nil {
+				return fmt.Errorf("bind tag affix flags: %w", err)
+			}
+
+			if err := bindFilterFlags(cmd); err != nil {
+				return fmt.Errorf("bind filter flags: %w", err)
+			}
+
+			if err := viper.BindPFlag("output-manifest", cmd.Flags().Lookup("output-manifest")); err != nil {
+				return fmt.Errorf("bind output-manifest flag: %w", err)
+			}
+
+			if err := bindProgressFlags(cmd); err != nil {
+				return fmt.Errorf("bind progress flags: %w", err)
+			}
+
+			if err := bindClientFlags(cmd); err != nil {
+				return fmt.Errorf("bind client flags: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runSyncCommand(ctx, logger, manifestPath); err != nil {
+				return fmt.Errorf("sync: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Print the pull, tag, and push operations that would be performed without contacting any registry")
+	cmd.Flags().Bool("force", false, "Sync all images, even if they are already present at the target")
+	cmd.Flags().Bool("keep-images", false, "Keep the pulled and tagged images on the host once they have been pushed")
+	cmd.Flags().Int("max-concurrent", 1, "The maximum number of images to sync at the same time")
+	cmd.Flags().StringSlice("concurrency-per-host", []string{}, "The maximum number of images to sync at the same time for a registry host, as host=limit (e.g. docker.io=2,quay.io=5). Both the source and target host of an image are limited, along with --max-concurrent")
+	cmd.Flags().Bool("preserve-tags", false, "Require the target image of every image to have the same tag as its source image, rather than the digest of images pinned to only a digest")
+	cmd.Flags().String("target", "", "The target (e.g. host/repository) to sync every image to, instead of the target in the manifest")
+	addTagAffixFlags(&cmd)
+	addStrictNamesFlag(&cmd)
+	addFilterFlags(&cmd)
+	addOutputManifestFlag(&cmd)
+	addProgressFlags(&cmd)
+	addClientFlags(&cmd)
+	addRateLimitFlag(&cmd)
+
+	return &cmd
+}
+
+func runSyncCommand(ctx context.Context, logger *log.Logger, manifestPath string) error {
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+
+	if len(manifest.Images) == 0 {
+		return errors.New("no images found in the image manifest")
+	}
+
+	if viper.GetString("target") != "" {
+		manifest = manifest.WithTarget(viper.GetString("target"))
+	}
+
+	manifest, err = applyTagAffixFlags(manifest)
+	if err != nil {
+		return fmt.Errorf("apply tag affix flags: %w", err)
+	}
+
+	if hasImageFilters() {
+		manifest.Images, err = filterImages(manifest.Images, viper.GetStringSlice("include"), viper.GetStringSlice("exclude"))
+		if err != nil {
+			return fmt.Errorf("filter images: %w", err)
+		}
+
+		if len(manifest.Images) == 0 && viper.GetBool("require-match") {
+			return errors.New("no images in the image manifest match the include and exclude patterns")
+		}
+
+		if len(manifest.Images) == 0 {
+			logger.Warnf("[SYNC] No images in the image manifest match the include and exclude patterns")
+			return nil
+		}
+	}
+
+	if viper.GetBool("preserve-tags") {
+		if err := validatePreservedTags(manifest.Images); err != nil {
+			return fmt.Errorf("preserve tags: %w", err)
+		}
+	}
+
+	if err := writeResolvedManifest(manifest); err != nil {
+		return fmt.Errorf("write resolved manifest: %w", err)
+	}
+
+	limiter, err := getHostLimiter()
+	if err != nil {
+		return fmt.Errorf("get host limiter: %w", err)
+	}
+
+	if err := manifest.ValidateTargets(); err != nil {
+		return fmt.Errorf("validate targets: %w", err)
+	}
+
+	for _, warning := range manifest.Warnings() {
+		logger.Warnf("[SYNC] %s", warning)
+	}
+
+	if err := checkTargetNames(logger, "sync", manifest.Images, viper.GetBool("strict-names")); err != nil {
+		return fmt.Errorf("check target names: %w", err)
+	}
+
+	if viper.GetBool("dry-run") {
+		for _, image := range manifest.Images {
+			entry := newImageLogEntry(logger, "sync", image.String())
+			if len(image.Tags) > 0 {
+				entry.Printf("[DRYRUN] Would sync the tags of %s matching %v to %s", image.String(), image.Tags, image.TargetImage())
+				continue
+			}
+
+			entry.Printf("[DRYRUN] Would pull %s", image.String())
+			entry.Printf("[DRYRUN] Would tag %s as %s", image.String(), image.TargetImage())
+			entry.Printf("[DRYRUN] Would push %s", image.TargetImage())
+			if !viper.GetBool("keep-images") {
+				entry.Printf("[DRYRUN] Would remove %s and %s from the host", image.String(), image.TargetImage())
+			}
+		}
+		return nil
+	}
+
+	summary := newSyncSummary()
+	clientOptions, err := getClientOptions()
+	if err != nil {
+		return fmt.Errorf("get client options: %w", err)
+	}
+
+	clientOptions = append(clientOptions, docker.WithTransferStats(summary.stats))
+
+	// Only multi-arch images and OCI artifacts are copied between registries, the other images are pulled
+	// and pushed through the Docker daemon, which does not limit the rate that they are transferred at
+	if viper.GetString("rate-limit") != "" {
+		logger.Warnf("[SYNC] The transfer rate is only limited for multi-arch images and OCI artifacts, which are copied between registries, not for images that are pulled and pushed through the Docker daemon")
+	}
+
+	client, err := docker.NewClient(getClientLogger(logger), clientOptions...)
+	if err != nil {
+		return fmt.Errorf("new docker client: %w", err)
+	}
+
+	images, err := expandImageTags(ctx, client, manifest.Images)
+	if err != nil {
+		return fmt.Errorf("expand image tags: %w", err)
+	}
+
+	logger.Printf("[INFO] Finding images that do not exist at target registry ...")
+
+	var syncImages []SourceImage
+	for _, image := range images {
+		if viper.GetBool("force") {
+			syncImages = append(syncImages, image)
+			continue
+		}
+
+		pushRequired, err := isPushRequired(ctx, client, image)
+		if err != nil {
+			return fmt.Errorf("is push required: %w", err)
+		}
+
+		if !pushRequired {
+			newImageLogEntry(logger, "sync", image.TargetImage()).Printf("[SYNC] Image %s already present at target, skipping", image.TargetImage())
+			continue
+		}
+
+		syncImages = append(syncImages, image)
+	}
+
+	if len(syncImages) == 0 {
+		logger.Println("[INFO] All images are up to date! 0 images synced.")
+		return nil
+	}
+
+	err = syncImagesThroughHost(ctx, logger, client, summary, syncImages, viper.GetInt("max-concurrent"), limiter, viper.GetBool("keep-images"))
+
+	if err := summary.write(os.Stdout); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("sync images: %w", err)
+	}
+
+	logger.Printf("[SYNC] All images have been synced!")
+
+	return nil
+}
+
+type imageSyncer interface {
+	imagePusher
+	ImageStoredOnHost(ctx context.Context, image string) (bool, error)
+	RemoveImageOnHost(ctx context.Context, image string) error
+}
+
+// syncImagesThroughHost syncs each image with at most maxConcurrent images being synced at the same time, and
+// at most the limit of its source and target host when a limiter is given.
+// Each image is pulled, tagged, and pushed before the next image is started by the same worker, and
+// unless the images are kept, the images that the sync stored on the host are removed once the image
+// has been pushed, so that only the images being synced are stored on the host. The result of each image is recorded in the summary,
+// and images that were not started before an interrupt are recorded as skipped.
+func syncImagesThroughHost(ctx context.Context, logger *log.Logger, syncer imageSyncer, summary *syncSummary, images []SourceImage, maxConcurrent int, limiter *hostLimiter, keepImages bool) error {
+	err := runConcurrently(maxConcurrent, len(images), func(index int) error {
+		image := images[index]
+		release := limiter.acquire(getImageHost(image.String()), getImageHost(image.TargetImage()))
+		defer release()
+
+		if skipAfterCancel(ctx, summary, image.TargetImage()) {
+			return nil
+		}
+
+		err := syncImageThroughHost(ctx, logger, syncer, image, keepImages)
+		summary.record(image.TargetImage(), err)
+		if err != nil {
+			newImageLogEntry(logger, "sync", image.TargetImage()).Errorf("[SYNC] %s failed: %s", image.TargetImage(), err)
+		}
+
+		var rateLimitError *docker.RateLimitError
+		if errors.As(err, &rateLimitError) {
+			return fmt.Errorf("%s (authenticate to the registry for a higher rate limit): %w", image.TargetImage(), err)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", image.TargetImage(), err)
+		}
+
+		newImageLogEntry(logger, "sync", image.TargetImage()).Printf("[SYNC] %s complete.", image.TargetImage())
+
+		return nil
+	})
+
+	return checkCancelled(ctx, summary, err)
+}
+
+// syncImageThroughHost pushes the image to its target the same as the push command, which pulls the source image,
+// tags it as the target image, and pushes the target image. Multi-arch images are copied directly from the source
+// registry to the target registry instead, so that the image of every platform is synced, as are OCI artifacts
+// which cannot be pulled. Unless the images are kept, the source and target images are then removed from the
+// host, except for the images that were already on the host before the sync, such as images built locally.
+func syncImageThroughHost(ctx context.Context, logger *log.Logger, syncer imageSyncer, image SourceImage, keepImages bool) error {
+	var hostImages []string
+	if !keepImages {
+		for _, hostImage := range []string{image.TargetImage(), image.String()} {
+			stored, err := syncer.ImageStoredOnHost(ctx, hostImage)
+			if err != nil {
+				return fmt.Errorf("image stored on host: %w", err)
+			}
+
+			if !stored {
+				hostImages = append(hostImages, hostImage)
+			}
+		}
+	}
+
+	if err := pushImage(ctx, logger, syncer, image, nil); err != nil {
+		return err
+	}
+
+	// Images that were copied between registries were never stored on the host
+	for _, hostImage := range hostImages {
+		stored, err := syncer.ImageStoredOnHost(ctx, hostImage)
+		if err != nil {
+			return fmt.Errorf("image stored on host: %w", err)
+		}
+
+		if !stored {
+			continue
+		}
+
+		if err := syncer.RemoveImageOnHost(ctx, hostImage); err != nil {
+			return fmt.Errorf("remove image: %w", err)
+		}
+	}
+
+	return nil
+}
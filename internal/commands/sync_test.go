@@ -0,0 +1,297 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type fakeSyncer struct {
+	mutex         sync.Mutex
+	operations    []string
+	indexImage    string
+	artifactImage string
+	failedImage   string
+
+	// stored are the images on the host, which are added when they are pulled or tagged
+	stored map[string]bool
+}
+
+func (f *fakeSyncer) record(operation string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.operations = append(f.operations, operation)
+}
+
+// indexOf returns the position of the operation in the order the operations were performed, or -1
+func (f *fakeSyncer) indexOf(operation string) int {
+	for i, performed := range f.operations {
+		if performed == operation {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (f *fakeSyncer) store(image string, stored bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.stored == nil {
+		f.stored = make(map[string]bool)
+	}
+
+	f.stored[image] = stored
+}
+
+func (f *fakeSyncer) ImageStoredOnHost(ctx context.Context, image string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.stored[image], nil
+}
+
+func (f *fakeSyncer) CreateRepositoryIfNotExists(ctx context.Context, image string, encodedAuth string) (string, error) {
+	return "", nil
+}
+
+func (f *fakeSyncer) VerifyPlatformsAtRemote(ctx context.Context, image string, auth string, platforms []docker.Platform) error {
+	return nil
+}
+
+func (f *fakeSyncer) IsImageIndexAtRemote(ctx context.Context, image string, auth string) (bool, error) {
+	return image == f.indexImage, nil
+}
+
+func (f *fakeSyncer) GetArtifactTypeAtRemote(ctx context.Context, image string, auth string) (string, error) {
+	if image == f.artifactImage {
+		return "application/vnd.cncf.helm.config.v1+json", nil
+	}
+
+	return "", nil
+}
+
+func (f *fakeSyncer) CopyImageAndWait(ctx context.Context, source string, target string, sourceAuth string, targetAuth string) error {
+	f.record("copy " + source + " " + target)
+	return nil
+}
+
+func (f *fakeSyncer) CopyImageIndexAndWait(ctx context.Context, source string, target string, platforms []docker.Platform, sourceAuth string, targetAuth string) error {
+	f.record("copy " + source + " " + target)
+	return nil
+}
+
+func (f *fakeSyncer) PullImageAndWait(ctx context.Context, image string, auth string) error {
+	f.record("pull " + image)
+	if image == f.failedImage {
+		return errors.New("pull failed")
+	}
+
+	f.store(image, true)
+
+	return nil
+}
+
+func (f *fakeSyncer) TagImage(ctx context.Context, source string, target string) error {
+	f.record("tag " + source + " " + target)
+	f.store(target, true)
+
+	return nil
+}
+
+func (f *fakeSyncer) PushImageAndWait(ctx context.Context, image string, auth string) error {
+	f.record("push " + image)
+	return nil
+}
+
+func (f *fakeSyncer) RemoveImageOnHost(ctx context.Context, image string) error {
+	f.record("remove " + image)
+	f.store(image, false)
+
+	return nil
+}
+
+func TestSyncImagesThroughHost_PullsThenPushesEachImage(t *testing.T) {
+	var images []SourceImage
+	for i := 0; i < 6; i++ {
+		images = append(images, SourceImage{
+			Host:       "quay.io",
+			Repository: "coreos/etcd",
+			Tag:        fmt.Sprintf("v3.4.%v", i),
+			Target:     Target{Host: "mycompany.com", Repository: "myrepo"},
+		})
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	syncer := fakeSyncer{}
+	summary := newSyncSummary()
+	if err := syncImagesThroughHost(context.Background(), logger, &syncer, summary, images, 3, nil, false); err != nil {
+		t.Fatal("sync images:", err)
+	}
+
+	for _, image := range images {
+		expectedOrder := []string{
+			"pull " + image.String(),
+			"tag " + image.String() + " " + image.TargetImage(),
+			"push " + image.TargetImage(),
+			"remove " + image.TargetImage(),
+			"remove " + image.String(),
+		}
+
+		previous := -1
+		for _, operation := range expectedOrder {
+			index := syncer.indexOf(operation)
+			if index <= previous {
+				t.Errorf("expected %s to be performed after the previous operations of %v, actual %v", operation, expectedOrder, syncer.operations)
+			}
+
+			previous = index
+		}
+	}
+
+	if summary.succeeded != len(images) {
+		t.Errorf("expected %v images to succeed, actual %v", len(images), summary.succeeded)
+	}
+}
+
+func TestSyncImagesThroughHost_FailedPullIsNotPushed(t *testing.T) {
+	images := []SourceImage{
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.0", Target: Target{Host: "mycompany.com"}},
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.1", Target: Target{Host: "mycompany.com"}},
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.2", Target: Target{Host: "mycompany.com"}},
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	syncer := fakeSyncer{failedImage: images[1].String(), indexImage: images[2].String()}
+	summary := newSyncSummary()
+	err := syncImagesThroughHost(context.Background(), logger, &syncer, summary, images, 1, nil, true)
+	if err == nil {
+		t.Fatal("expected an error when an image fails to pull")
+	}
+
+	if syncer.indexOf("push "+images[1].TargetImage()) >= 0 {
+		t.Errorf("expected the image that failed to pull to not be pushed, actual %v", syncer.operations)
+	}
+
+	if syncer.indexOf("remove "+images[0].TargetImage()) >= 0 {
+		t.Errorf("expected kept images to not be removed, actual %v", syncer.operations)
+	}
+
+	if syncer.indexOf("copy "+images[2].String()+" "+images[2].TargetImage()) < 0 || syncer.indexOf("pull "+images[2].String()) >= 0 {
+		t.Errorf("expected the multi-arch image to be copied instead of pulled, actual %v", syncer.operations)
+	}
+
+	if summary.succeeded != 2 || summary.failed != 1 {
+		t.Errorf("expected summary of 2 succeeded and 1 failed, actual %v succeeded and %v failed", summary.succeeded, summary.failed)
+	}
+}
+
+func TestSyncImagesThroughHost_CopiesArtifacts(t *testing.T) {
+	images := []SourceImage{
+		{Host: "ghcr.io", Repository: "charts/app", Tag: "1.0.0", Target: Target{Host: "mycompany.com"}},
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	syncer := fakeSyncer{artifactImage: images[0].String()}
+	if err := syncImagesThroughHost(context.Background(), logger, &syncer, newSyncSummary(), images, 1, nil, false); err != nil {
+		t.Fatal("sync images:", err)
+	}
+
+	expected := []string{"copy " + images[0].String() + " " + images[0].TargetImage()}
+	if fmt.Sprint(syncer.operations) != fmt.Sprint(expected) {
+		t.Errorf("expected the artifact to only be copied %v, actual %v", expected, syncer.operations)
+	}
+}
+
+func TestSyncImagesThroughHost_KeepsImagesAlreadyOnHost(t *testing.T) {
+	images := []SourceImage{
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.0", Target: Target{Host: "mycompany.com"}},
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	syncer := fakeSyncer{}
+	syncer.store(images[0].String(), true)
+
+	if err := syncImagesThroughHost(context.Background(), logger, &syncer, newSyncSummary(), images, 1, nil, false); err != nil {
+		t.Fatal("sync images:", err)
+	}
+
+	if syncer.indexOf("remove "+images[0].String()) >= 0 {
+		t.Errorf("expected the source image that was already on the host to not be removed, actual %v", syncer.operations)
+	}
+
+	if syncer.indexOf("remove "+images[0].TargetImage()) < 0 {
+		t.Errorf("expected the target image that the sync tagged to be removed, actual %v", syncer.operations)
+	}
+}
+
+// interruptingSyncer cancels the run while the image is being pulled, the same as an interrupt
+type interruptingSyncer struct {
+	fakeSyncer
+	cancel         context.CancelFunc
+	interruptImage string
+}
+
+func (i *interruptingSyncer) PullImageAndWait(ctx context.Context, image string, auth string) error {
+	i.record("pull " + image)
+	if image == i.interruptImage {
+		i.cancel()
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+func TestSyncImagesThroughHost_Interrupted(t *testing.T) {
+	var images []SourceImage
+	for i := 0; i < 5; i++ {
+		images = append(images, SourceImage{
+			Host:       "quay.io",
+			Repository: "coreos/etcd",
+			Tag:        fmt.Sprintf("v3.4.%v", i),
+			Target:     Target{Host: "mycompany.com"},
+		})
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	syncer := interruptingSyncer{cancel: cancel, interruptImage: images[1].String()}
+	summary := newSyncSummary()
+	err := syncImagesThroughHost(ctx, logger, &syncer, summary, images, 1, nil, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the sync to be cancelled, actual %v", err)
+	}
+
+	if syncer.indexOf("pull "+images[2].String()) >= 0 {
+		t.Errorf("expected no images to be started after the interrupt, actual %v", syncer.operations)
+	}
+
+	if syncer.indexOf("push "+images[1].TargetImage()) >= 0 {
+		t.Errorf("expected the interrupted image to not be pushed, actual %v", syncer.operations)
+	}
+
+	if summary.succeeded != 1 || summary.failed != 1 || summary.skipped != 3 {
+		t.Errorf("expected summary of 1 succeeded, 1 failed, and 3 skipped, actual %v succeeded, %v failed, and %v skipped", summary.succeeded, summary.failed, summary.skipped)
+	}
+}
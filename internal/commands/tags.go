@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var tagAffixFlags = []string{"tag-prefix", "tag-suffix"}
+
+func addTagAffixFlags(cmd *cobra.Command) {
+	cmd.Flags().String("tag-prefix", "", "The prefix (e.g. staging-) added to the tag of each target image, instead of the tagPrefix of the targets in the manifest. Images pinned to only a digest are not changed")
+	cmd.Flags().String("tag-suffix", "", "The suffix (e.g. -staging) added to the tag of each target image, instead of the tagSuffix of the targets in the manifest. Images pinned to only a digest are not changed")
+}
+
+func bindTagAffixFlags(cmd *cobra.Command) error {
+	for _, flag := range tagAffixFlags {
+		if err := viper.BindPFlag(flag, cmd.Flags().Lookup(flag)); err != nil {
+			return fmt.Errorf("bind %s flag: %w", flag, err)
+		}
+	}
+
+	return nil
+}
+
+// applyTagAffixFlags returns the manifest with the tag prefix and suffix of the
+// tag-prefix and tag-suffix flags, when they are set, and valid parts of a tag
+func applyTagAffixFlags(manifest Manifest) (Manifest, error) {
+	prefix := viper.GetString("tag-prefix")
+	if prefix != "" && !tagPrefixPattern.MatchString(prefix) {
+		return Manifest{}, fmt.Errorf("invalid tag prefix %s, must start with a letter, digit, or underscore and only contain letters, digits, underscores, periods, and dashes", prefix)
+	}
+
+	suffix := viper.GetString("tag-suffix")
+	if suffix != "" && !tagSuffixPattern.MatchString(suffix) {
+		return Manifest{}, fmt.Errorf("invalid tag suffix %s, must only contain letters, digits, underscores, periods, and dashes", suffix)
+	}
+
+	return manifest.WithTagAffixes(prefix, suffix), nil
+}
+
+// addNoLatestFlag adds the no-latest flag, which fails when any source image uses the latest tag
+func addNoLatestFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-latest", false, "Fail when any source image is only pinned to the latest tag, or has no tag or digest, rather than warning about it")
+}
+
+// getLatestTagProblems returns a problem for each image that is only pinned to the latest tag, as the
+// image it refers to changes whenever the latest tag is updated at the source registry
+func getLatestTagProblems(images []SourceImage) []string {
+	var problems []string
+	for i, image := range images {
+		if !image.usesLatestTag() {
+			continue
+		}
+
+		problems = append(problems, getLatestTagProblem(getImageName(i, image)))
+	}
+
+	return problems
+}
+
+func getLatestTagProblem(imageName string) string {
+	return fmt.Sprintf("%s: only pinned to the latest tag, which changes when the source image is updated. Pin the image to a digest to sync the same image every time", imageName)
+}
+
+type tagLister interface {
+	GetTagsForRepo(ctx context.Context, host string, repository string, encodedAuth string) ([]string, error)
+}
+
+// expandImageTags replaces each image that has tags patterns with an image
+// for every tag in its source repository that matches one of the patterns
+func expandImageTags(ctx context.Context, lister tagLister, images []SourceImage) ([]SourceImage, error) {
+	var expandedImages []SourceImage
+	for _, image := range images {
+		if len(image.Tags) == 0 {
+			expandedImages = append(expandedImages, image)
+			continue
+		}
+
+		auth, err := getEncodedSourceAuth(ctx, image)
+		if err != nil {
+			return nil, fmt.Errorf("get %s auth: %w", image.String(), err)
+		}
+
+		tags, err := lister.GetTagsForRepo(ctx, image.Host, image.Repository, auth)
+		if err != nil {
+			return nil, fmt.Errorf("get tags for %s: %w", image.String(), err)
+		}
+
+		matchingTags, err := getMatchingTags(tags, image.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("match tags for %s: %w", image.String(), err)
+		}
+
+		for _, tag := range matchingTags {
+			expandedImage := image
+			expandedImage.Tag = tag
+			expandedImage.Tags = nil
+
+			expandedImages = append(expandedImages, expandedImage)
+		}
+	}
+
+	return expandedImages, nil
+}
+
+// getMatchingTags returns the tags that match at least one of the glob patterns
+func getMatchingTags(tags []string, patterns []string) ([]string, error) {
+	var matchingTags []string
+	for _, tag := range tags {
+		for _, pattern := range patterns {
+			matched, err := path.Match(pattern, tag)
+			if err != nil {
+				return nil, fmt.Errorf("match pattern %s: %w", pattern, err)
+			}
+
+			if matched {
+				matchingTags = append(matchingTags, tag)
+				break
+			}
+		}
+	}
+
+	return matchingTags, nil
+}
+
+// validatePreservedTags returns a ValidationError when the target tag of any image would not be the
+// same tag as the tag of its source image, such as an image that is pinned to only a digest, or an image
+// whose target adds a tag prefix or suffix. Images with tags patterns are expanded to images with their
+// matching source tags, so they preserve them unless their target adds a tag prefix or suffix.
+func validatePreservedTags(images []SourceImage) error {
+	var problems []string
+	for i, image := range images {
+		if image.Target.TagPrefix != "" || image.Target.TagSuffix != "" {
+			problems = append(problems, fmt.Sprintf("%s: would not preserve its source tags, as its target adds a tag prefix or suffix, and would be pushed as %s", getImageName(i, image), image.TargetImage()))
+			continue
+		}
+
+		if len(image.Tags) > 0 {
+			continue
+		}
+
+		if image.targetTag() != image.Tag {
+			problems = append(problems, fmt.Sprintf("%s: has no source tag to preserve, and would be pushed as %s", getImageName(i, image), image.TargetImage()))
+		}
+	}
+
+	if len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+type fakeTagLister struct {
+	tags map[string][]string
+}
+
+func (f fakeTagLister) GetTagsForRepo(ctx context.Context, host string, repository string, encodedAuth string) ([]string, error) {
+	return f.tags[host+"/"+repository], nil
+}
+
+func TestGetMatchingTags(t *testing.T) {
+	tags := []string{"latest", "v1.0.0", "v1.1.0", "v1.1.0-rc.1", "v2.0.0", "v10.0.0"}
+
+	testCases := []struct {
+		patterns     []string
+		expectedTags []string
+	}{
+		{
+			patterns:     []string{"v1.*"},
+			expectedTags: []string{"v1.0.0", "v1.1.0", "v1.1.0-rc.1"},
+		},
+		{
+			patterns:     []string{"v1.*", "latest"},
+			expectedTags: []string{"latest", "v1.0.0", "v1.1.0", "v1.1.0-rc.1"},
+		},
+		{
+			patterns:     []string{"v?.?.0"},
+			expectedTags: []string{"v1.0.0", "v1.1.0", "v2.0.0"},
+		},
+		{
+			patterns:     []string{"v[12].*.0"},
+			expectedTags: []string{"v1.0.0", "v1.1.0", "v2.0.0"},
+		},
+		{
+			patterns:     []string{"v3.*"},
+			expectedTags: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := getMatchingTags(tags, testCase.patterns)
+		if err != nil {
+			t.Fatal("get matching tags:", err)
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expectedTags) {
+			t.Errorf("expected tags matching %v to be %v, actual %v", testCase.patterns, testCase.expectedTags, actual)
+		}
+	}
+}
+
+func TestExpandImageTags(t *testing.T) {
+	lister := fakeTagLister{
+		tags: map[string][]string{
+			"quay.io/coreos/prometheus-operator": {"v0.39.0", "v0.40.0", "v0.41.0", "latest"},
+		},
+	}
+
+	target := Target{Host: "mycompany.com"}
+	images := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0", Target: target},
+		{Repository: "coreos/prometheus-operator", Host: "quay.io", Tags: []string{"v0.4*"}, Target: target},
+	}
+
+	actual, err := expandImageTags(context.Background(), lister, images)
+	if err != nil {
+		t.Fatal("expand image tags:", err)
+	}
+
+	expected := []SourceImage{
+		{Repository: "busybox", Tag: "1.32.0", Target: target},
+		{Repository: "coreos/prometheus-operator", Host: "quay.io", Tag: "v0.40.0", Target: target},
+		{Repository: "coreos/prometheus-operator", Host: "quay.io", Tag: "v0.41.0", Target: target},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected expanded images to be %v, actual %v", expected, actual)
+	}
+}
+
+func TestValidatePreservedTags(t *testing.T) {
+	images := []SourceImage{
+		{Repository: "nginx", Tag: "1.25", Target: Target{Host: "target.com"}},
+		{Repository: "nginx", Tag: "1.25", Digest: "sha256:123", Target: Target{Host: "target.com"}},
+		{Repository: "busybox", Digest: "sha256:123", Target: Target{Host: "target.com"}},
+		{Repository: "coreos/etcd", Host: "quay.io", Tags: []string{"v3.4.*"}, Target: Target{Host: "target.com"}},
+		{Repository: "coreos/etcd", Host: "quay.io", Tags: []string{"v3.5.*"}, Target: Target{Host: "target.com", TagSuffix: "-staging"}},
+	}
+
+	err := validatePreservedTags(images)
+
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, actual %v", err)
+	}
+
+	expected := []string{
+		"sources[2] (busybox@sha256:123): has no source tag to preserve, and would be pushed as target.com/busybox:123",
+		"sources[4] (quay.io/coreos/etcd): would not preserve its source tags, as its target adds a tag prefix or suffix, and would be pushed as target.com/coreos/etcd",
+	}
+	if !reflect.DeepEqual(validationErr.Problems, expected) {
+		t.Errorf("expected problems %v, actual %v", expected, validationErr.Problems)
+	}
+
+	if err := validatePreservedTags(images[:2]); err != nil {
+		t.Errorf("expected images with source tags to preserve their tags, actual %v", err)
+	}
+}
+
+func TestApplyTagAffixFlags(t *testing.T) {
+	manifest := Manifest{
+		Target: Target{Host: "mycompany.com"},
+		Images: []SourceImage{{Repository: "nginx", Tag: "1.25", Target: Target{Host: "mycompany.com"}}},
+	}
+
+	viper.Set("tag-prefix", "v")
+	defer viper.Set("tag-prefix", "")
+	viper.Set("tag-suffix", "-staging")
+	defer viper.Set("tag-suffix", "")
+
+	actual, err := applyTagAffixFlags(manifest)
+	if err != nil {
+		t.Fatal("apply tag affix flags:", err)
+	}
+
+	if actual.Images[0].TargetImage() != "mycompany.com/nginx:v1.25-staging" {
+		t.Errorf("expected target image mycompany.com/nginx:v1.25-staging, actual %s", actual.Images[0].TargetImage())
+	}
+
+	viper.Set("tag-suffix", "/staging")
+	if _, err := applyTagAffixFlags(manifest); err == nil {
+		t.Error("expected an invalid tag suffix to return an error")
+	}
+}
+
+func TestGetLatestTagProblems(t *testing.T) {
+	images := []SourceImage{
+		{Repository: "nginx", Tag: "1.25"},
+		{Repository: "nginx", Tag: "latest"},
+		{Repository: "busybox"},
+		{Repository: "busybox", Digest: "sha256:123"},
+		{Repository: "busybox", Tag: "latest", Digest: "sha256:123"},
+		{Repository: "coreos/etcd", Host: "quay.io", Tags: []string{"v3.4.*"}},
+	}
+
+	expected := []string{
+		"sources[1] (nginx:latest): only pinned to the latest tag, which changes when the source image is updated. Pin the image to a digest to sync the same image every time",
+		"sources[2] (busybox): only pinned to the latest tag, which changes when the source image is updated. Pin the image to a digest to sync the same image every time",
+	}
+
+	actual := getLatestTagProblems(images)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected problems %v, actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addTmpDirFlag adds the tmp-dir flag, which falls back to the path in
+// the SINKER_TMPDIR environment variable when the flag is not set
+func addTmpDirFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("tmp-dir", "", "The directory where large intermediate files, such as the tarball of export, are staged (defaults to SINKER_TMPDIR, or the directory of the output file)")
+	viper.BindPFlag("tmp-dir", cmd.PersistentFlags().Lookup("tmp-dir"))
+	viper.BindEnv("tmp-dir", "SINKER_TMPDIR")
+}
+
+// validateTmpDir returns an error when a file can not be written to the directory,
+// so that a command fails before any images are pulled rather than once they are staged
+func validateTmpDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	file, err := ioutil.TempFile(dir, ".sinker-*.tmp")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+
+	file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		return fmt.Errorf("remove test file: %w", err)
+	}
+
+	return nil
+}
+
+// createStagingFile creates the file that the contents of the output file are written to before
+// it is moved to the output path. It is created in the tmp dir when one is set, otherwise in the
+// directory of the output file.
+func createStagingFile(outputPath string) (*os.File, error) {
+	dir := viper.GetString("tmp-dir")
+	if dir == "" {
+		dir = filepath.Dir(outputPath)
+	}
+
+	return ioutil.TempFile(dir, filepath.Base(outputPath)+".*.tmp")
+}
+
+// moveStagingFile moves the staging file to the output path. The file is copied
+// when it can not be renamed, such as when the tmp dir is on another filesystem.
+func moveStagingFile(stagingPath string, outputPath string) error {
+	if err := os.Rename(stagingPath, outputPath); err == nil {
+		return nil
+	}
+
+	staging, err := os.Open(stagingPath)
+	if err != nil {
+		return fmt.Errorf("open staging file: %w", err)
+	}
+	defer staging.Close()
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+
+	if _, err := io.Copy(output, staging); err != nil {
+		output.Close()
+		os.Remove(outputPath)
+		return fmt.Errorf("copy staging file: %w", err)
+	}
+
+	if err := output.Close(); err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("close file: %w", err)
+	}
+
+	return os.Remove(stagingPath)
+}
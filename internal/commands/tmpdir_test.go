@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateTmpDir(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	filePath := filepath.Join(tmpDir, "file")
+	if err := ioutil.WriteFile(filePath, []byte("contents"), os.ModePerm); err != nil {
+		t.Fatal("write file:", err)
+	}
+
+	testCases := []struct {
+		dir           string
+		expectedError bool
+	}{
+		{dir: ""},
+		{dir: tmpDir},
+		{dir: filepath.Join(tmpDir, "missing"), expectedError: true},
+		{dir: filePath, expectedError: true},
+	}
+
+	for _, testCase := range testCases {
+		err := validateTmpDir(testCase.dir)
+		if testCase.expectedError && err == nil {
+			t.Errorf("expected an error for dir %q", testCase.dir)
+		}
+
+		if !testCase.expectedError && err != nil {
+			t.Errorf("expected no error for dir %q, actual %v", testCase.dir, err)
+		}
+	}
+
+	files, err := ioutil.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal("read dir:", err)
+	}
+
+	if len(files) != 1 {
+		t.Errorf("expected the test file to be removed from the tmp dir, actual %v files", len(files))
+	}
+}
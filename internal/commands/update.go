@@ -1,54 +1,127 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-func newUpdateCommand() *cobra.Command {
+func newUpdateCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "update <source>",
 		Short: "Update an existing image manifest",
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			sourcePath := args[0]
+			if err := viper.BindPFlag("migrate", cmd.Flags().Lookup("migrate")); err != nil {
+				return fmt.Errorf("bind migrate flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("pin-digests", cmd.Flags().Lookup("pin-digests")); err != nil {
+				return fmt.Errorf("bind pin-digests flag: %w", err)
+			}
 
 			manifestPath := viper.GetString("manifest")
-			if err := runUpdateCommand(sourcePath, manifestPath); err != nil {
-				return fmt.Errorf("update: %w", err)
+			if viper.GetBool("migrate") {
+				if err := runMigrateManifest(manifestPath); err != nil {
+					return fmt.Errorf("update: %w", err)
+				}
+
+				return nil
+			}
+
+			if len(args) == 0 && !viper.GetBool("pin-digests") {
+				return errors.New("update: a source path is required unless --migrate or --pin-digests is set")
+			}
+
+			if len(args) > 0 {
+				sourcePath := args[0]
+				if err := runUpdateCommand(sourcePath, manifestPath); err != nil {
+					return fmt.Errorf("update: %w", err)
+				}
+			}
+
+			if viper.GetBool("pin-digests") {
+				client, err := docker.NewClient(logger)
+				if err != nil {
+					return fmt.Errorf("update: new client: %w", err)
+				}
+
+				if err := runPinDigests(ctx, client, manifestPath); err != nil {
+					return fmt.Errorf("update: %w", err)
+				}
 			}
 
 			return nil
 		},
 	}
 
+	cmd.Flags().Bool("migrate", false, "Migrate the manifest to the current manifest version in place")
+	cmd.Flags().Bool("pin-digests", false, "Add the current digest of the tag of each source image to the manifest")
+
 	return &cmd
 }
 
+func runMigrateManifest(manifestPath string) error {
+	manifestContents, err := readManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("reading manifest: %w", err)
+	}
+
+	migratedContents, err := migrateManifest(manifestContents)
+	if err != nil {
+		return fmt.Errorf("migrate manifest: %w", err)
+	}
+
+	migratedContents = setManifestVersion(migratedContents, currentManifestVersion)
+	if err := writeManifest(migratedContents, manifestPath); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	return nil
+}
+
 func runUpdateCommand(path string, manifestPath string) error {
+	if err := validateSingleManifest(manifestPath); err != nil {
+		return err
+	}
+
+	// The images are matched using the current manifest, which has its environment variables
+	// expanded, while the auth and targets of the decoded manifest are written back as they
+	// were written, so that the values of the environment variables are not written to the file.
 	currentManifest, err := GetManifest(manifestPath)
 	if err != nil {
 		return fmt.Errorf("get current manifest: %w", err)
 	}
 
+	decodedManifest, err := decodeManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("decode manifest: %w", err)
+	}
+
 	updatedManifest, err := NewAutodetectManifest(currentManifest.Target.String(), path)
 	if err != nil {
 		return fmt.Errorf("get current manifest: %w", err)
 	}
+	updatedManifest.Target = decodedManifest.Target
 
 	for i := range updatedManifest.Images {
-		for _, currentImage := range currentManifest.Images {
+		for j, currentImage := range currentManifest.Images {
 			if currentImage.Repository != updatedManifest.Images[i].Repository || currentImage.Host != updatedManifest.Images[i].Host {
 				continue
 			}
 
-			updatedManifest.Images[i].Auth = currentImage.Auth
+			decodedImage := decodedManifest.getImage(j)
+			updatedManifest.Images[i].Auth = decodedImage.Auth
 
 			if currentManifest.Target.String() != "" {
-				updatedManifest.Target = currentImage.Target
+				updatedManifest.Target = decodedImage.withDefaultTarget(decodedManifest.Target).Target
 			}
 		}
 	}
@@ -59,3 +132,64 @@ func runUpdateCommand(path string, manifestPath string) error {
 
 	return nil
 }
+
+type digestResolver interface {
+	GetDigestsAtRemote(ctx context.Context, image string, encodedAuth string) ([]string, error)
+}
+
+// runPinDigests sets the digest of each source image that has a tag to the
+// digest the tag currently refers to at the source registry
+func runPinDigests(ctx context.Context, resolver digestResolver, manifestPath string) error {
+	// The images are resolved using the loaded manifest, which has its environment
+	// variables expanded, while the decoded manifest is written back as it was written.
+	manifest, err := loadManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("load manifest: %w", err)
+	}
+
+	decodedManifest, err := decodeManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("decode manifest: %w", err)
+	}
+
+	for i, image := range manifest.Images {
+		if image.Tag == "" {
+			continue
+		}
+
+		digest, err := resolveDigest(ctx, resolver, image)
+		if err != nil {
+			return fmt.Errorf("resolve digest: %w", err)
+		}
+
+		decodedManifest.getImage(i).Digest = digest
+	}
+
+	if err := WriteManifest(decodedManifest, manifestPath); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	return nil
+}
+
+// resolveDigest returns the digest that the tag of the image currently refers to at the source registry
+func resolveDigest(ctx context.Context, resolver digestResolver, image SourceImage) (string, error) {
+	taggedImage := image
+	taggedImage.Digest = ""
+
+	auth, err := getEncodedSourceAuth(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("get source auth: %w", err)
+	}
+
+	digests, err := resolver.GetDigestsAtRemote(ctx, taggedImage.String(), auth)
+	if err != nil {
+		return "", fmt.Errorf("get digest of %s: %w", taggedImage.String(), err)
+	}
+
+	if len(digests) == 0 {
+		return "", fmt.Errorf("image %s not found at source", taggedImage.String())
+	}
+
+	return digests[0], nil
+}
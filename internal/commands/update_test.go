@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestRunPinDigests(t *testing.T) {
+	registryServer := newTestRegistry()
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	imageReference, err := name.ParseReference(registryHost + "/busybox:1.32.0")
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(imageReference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	digest, err := image.Digest()
+	if err != nil {
+		t.Fatal("image digest:", err)
+	}
+
+	manifestPath := writeTestManifest(t, `
+target:
+  host: target.com
+sources:
+- repository: busybox
+  host: `+registryHost+`
+  tag: 1.32.0
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	if err := runPinDigests(context.Background(), docker.Client{}, manifestPath); err != nil {
+		t.Fatal("pin digests:", err)
+	}
+
+	pinnedContents, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal("read manifest:", err)
+	}
+
+	manifest, err := GetManifest(manifestPath)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	if manifest.Images[0].Tag != "1.32.0" {
+		t.Errorf("expected tag to be kept, actual %s", manifest.Images[0].Tag)
+	}
+
+	if manifest.Images[0].Digest != digest.String() {
+		t.Errorf("expected digest to be %s, actual %s", digest, manifest.Images[0].Digest)
+	}
+
+	if manifest.Images[0].Target.Host != "target.com" {
+		t.Errorf("expected target to be target.com, actual %s", manifest.Images[0].Target)
+	}
+
+	if err := runPinDigests(context.Background(), docker.Client{}, manifestPath); err != nil {
+		t.Fatal("pin digests again:", err)
+	}
+
+	repinnedContents, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal("read manifest:", err)
+	}
+
+	if string(repinnedContents) != string(pinnedContents) {
+		t.Errorf("expected pinning again to not change the manifest. expected %s, actual %s", pinnedContents, repinnedContents)
+	}
+
+	if strings.Count(string(pinnedContents), "target:") != 1 {
+		t.Errorf("expected the target to not be written for each image, actual %s", pinnedContents)
+	}
+}
+
+func TestRunPinDigests_Groups(t *testing.T) {
+	manifestPath := writeTestManifest(t, `
+target:
+  host: target.com
+sources:
+- repository: busybox
+  tag: 1.32.0
+groups:
+- targetPrefix: web
+  sources:
+  - repository: nginx
+    tag: 1.19.0
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	resolver := fakePruner{
+		digests: map[string]string{
+			"busybox:1.32.0": "sha256:111",
+			"nginx:1.19.0":   "sha256:222",
+		},
+	}
+
+	if err := runPinDigests(context.Background(), &resolver, manifestPath); err != nil {
+		t.Fatal("pin digests:", err)
+	}
+
+	decodedManifest, err := decodeManifest(manifestPath)
+	if err != nil {
+		t.Fatal("decode manifest:", err)
+	}
+
+	if decodedManifest.Images[0].Digest != "sha256:111" {
+		t.Errorf("expected the digest of busybox to be sha256:111, actual %s", decodedManifest.Images[0].Digest)
+	}
+
+	if len(decodedManifest.Groups) != 1 || decodedManifest.Groups[0].Images[0].Digest != "sha256:222" {
+		t.Errorf("expected the group to be kept with the digest of nginx, actual %+v", decodedManifest.Groups)
+	}
+}
+
+func TestRunUpdateCommand_KeepsEnvironmentVariables(t *testing.T) {
+	os.Setenv("SINKER_TEST_UPDATE_HOST", "secret.mycompany.com")
+	defer os.Unsetenv("SINKER_TEST_UPDATE_HOST")
+
+	manifestPath := writeTestManifest(t, `
+target:
+  host: ${SINKER_TEST_UPDATE_HOST}
+  repository: myteam
+sources:
+- repository: busybox
+  tag: 1.32.0
+  auth:
+    username: ${SINKER_TEST_UPDATE_USER}
+    password: ${SINKER_TEST_UPDATE_PASSWORD}
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	if err := runUpdateCommand(filepath.Join("testdata", "kubernetes"), manifestPath); err != nil {
+		t.Fatal("update manifest:", err)
+	}
+
+	updatedContents, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal("read manifest:", err)
+	}
+
+	if strings.Contains(string(updatedContents), "secret.mycompany.com") {
+		t.Errorf("expected the value of the environment variable to not be written to the manifest, actual %s", updatedContents)
+	}
+
+	for _, expected := range []string{"${SINKER_TEST_UPDATE_HOST}", "${SINKER_TEST_UPDATE_USER}", "${SINKER_TEST_UPDATE_PASSWORD}", "repository: nginx"} {
+		if !strings.Contains(string(updatedContents), expected) {
+			t.Errorf("expected the updated manifest to contain %s, actual %s", expected, updatedContents)
+		}
+	}
+}
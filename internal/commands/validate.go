@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newValidateCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "validate",
+		Short: "Validate the image manifest and report every problem found",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("no-latest", cmd.Flags().Lookup("no-latest")); err != nil {
+				return fmt.Errorf("bind no-latest flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("strict-names", cmd.Flags().Lookup("strict-names")); err != nil {
+				return fmt.Errorf("bind strict-names flag: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runValidateCommand(os.Stdout, manifestPath); err != nil {
+				return fmt.Errorf("validate: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	addNoLatestFlag(&cmd)
+	addStrictNamesFlag(&cmd)
+
+	return &cmd
+}
+
+func runValidateCommand(output io.Writer, manifestPath string) error {
+	manifest, err := loadManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("load manifest: %w", err)
+	}
+
+	warnings := manifest.Warnings()
+	problems := getManifestProblems(manifest)
+	if viper.GetBool("no-latest") {
+		problems = append(problems, getLatestTagProblems(manifest.Images)...)
+	} else {
+		warnings = append(warnings, getLatestTagProblems(manifest.Images)...)
+	}
+
+	if viper.GetBool("strict-names") {
+		problems = append(problems, getUppercaseNameProblems(manifest.Images)...)
+	} else {
+		warnings = append(warnings, getUppercaseNameProblems(manifest.Images)...)
+	}
+
+	for _, warning := range warnings {
+		if _, err := fmt.Fprintf(output, "warning: %s\n", warning); err != nil {
+			return fmt.Errorf("writing warning: %w", err)
+		}
+	}
+
+	for _, problem := range problems {
+		if _, err := fmt.Fprintln(output, problem); err != nil {
+			return fmt.Errorf("writing problem: %w", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%v problem(s) found", len(problems))
+	}
+
+	return nil
+}
+
+// getManifestProblems returns every problem found with the manifest,
+// including the images whose references do not parse
+func getManifestProblems(manifest Manifest) []string {
+	var problems []string
+
+	var validationErr ValidationError
+	if err := manifest.Validate(); errors.As(err, &validationErr) {
+		problems = append(problems, validationErr.Problems...)
+	}
+
+	for i, image := range manifest.Images {
+		if image.Repository == "" {
+			continue
+		}
+
+		problems = append(problems, getReferenceProblems(getImageName(i, image), image)...)
+	}
+
+	return problems
+}
+
+// getReferenceProblems returns the problems found when parsing the source and
+// target references of the image, such as invalid characters or a repository
+// that would be mistaken for a host
+func getReferenceProblems(imageName string, image SourceImage) []string {
+	var problems []string
+	for _, reference := range []string{image.String(), image.TargetImage()} {
+		if _, err := name.ParseReference(reference); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid reference %s: %s", imageName, reference, err))
+		}
+	}
+
+	path := docker.RegistryPath(image.String())
+	if path.Tag() != image.Tag || (image.Tag == "" && path.Digest() != image.Digest) {
+		problems = append(problems, fmt.Sprintf("%s: reference is parsed as tag %q and digest %q", imageName, path.Tag(), path.Digest()))
+	}
+
+	return problems
+}
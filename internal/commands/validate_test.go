@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetManifestProblems(t *testing.T) {
+	manifest := Manifest{
+		Images: []SourceImage{
+			{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "target.com"}},
+			{Repository: "library/busybox", Host: "docker.io", Tag: "1.32.0", Target: Target{Host: "target.com"}},
+			{Repository: "nginx", Tag: "1.19:0", Target: Target{Host: "target.com"}},
+			{Repository: "Invalid", Tag: "1.0.0", Target: Target{Host: "target.com"}},
+			{Tag: "1.0.0"},
+		},
+	}
+
+	expected := []string{
+		"sources[1] (docker.io/library/busybox:1.32.0): duplicate of sources[0] (busybox:1.32.0)",
+		"sources[4] (:1.0.0): repository is required",
+		"sources[4] (:1.0.0): target host or repository is required",
+		"sources[2] (nginx:1.19:0): invalid reference nginx:1.19:0: could not parse reference: nginx:1.19:0",
+		"sources[2] (nginx:1.19:0): invalid reference target.com/nginx:1.19:0: could not parse reference: target.com/nginx:1.19:0",
+		`sources[2] (nginx:1.19:0): reference is parsed as tag "0" and digest ""`,
+		"sources[3] (Invalid:1.0.0): invalid reference Invalid:1.0.0: could not parse reference: Invalid:1.0.0",
+	}
+
+	actual := getManifestProblems(manifest)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected manifest problems. expected %v actual %v", expected, actual)
+	}
+}
+
+func TestRunValidateCommand(t *testing.T) {
+	manifestPath := writeTestManifest(t, `
+target:
+  host: target.com
+sources:
+- repository: busybox
+  tag: 1.32.0
+- repository: busybox
+  tag: 1.32.0
+- tag: 1.0.0
+- repository: busybox
+  host: quay.io
+  tag: 1.32.0
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	var output bytes.Buffer
+	err := runValidateCommand(&output, manifestPath)
+	if err == nil {
+		t.Fatal("expected invalid manifest to return an error")
+	}
+
+	location := getManifestLocation(manifestPath)
+	expectedOutput := "warning: sources[3] (quay.io/busybox:1.32.0) at " + location + ":10: pushed to the same target target.com/busybox:1.32.0 as sources[0] (busybox:1.32.0) at " + location + ":5\n" +
+		"sources[1] (busybox:1.32.0) at " + location + ":7: duplicate of sources[0] (busybox:1.32.0) at " + location + ":5\n" +
+		"sources[2] (:1.0.0) at " + location + ":9: repository is required\n"
+	if output.String() != expectedOutput {
+		t.Errorf("expected output to be %s, actual %s", expectedOutput, output.String())
+	}
+
+	if err.Error() != "2 problem(s) found" {
+		t.Errorf("expected error to be 2 problem(s) found, actual %v", err)
+	}
+}
+
+func TestRunValidateCommand_NoLatest(t *testing.T) {
+	manifestPath := writeTestManifest(t, `
+target:
+  host: target.com
+sources:
+- repository: busybox
+  tag: 1.32.0
+- repository: nginx
+  tag: latest
+- repository: busybox
+  tag: latest
+  digest: sha256:0efad4d09a419dc6d574c3c3baacb804a530acd61d5eba72cb1f14e1f5ac0c8f
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	location := getManifestLocation(manifestPath)
+	problem := "sources[1] (nginx:latest) at " + location + ":7: only pinned to the latest tag, which changes when the source image is updated. Pin the image to a digest to sync the same image every time\n"
+
+	var output bytes.Buffer
+	if err := runValidateCommand(&output, manifestPath); err != nil {
+		t.Fatal("expected the latest tag to only be a warning:", err)
+	}
+
+	if output.String() != "warning: "+problem {
+		t.Errorf("expected output to be %s, actual %s", "warning: "+problem, output.String())
+	}
+
+	viper.Set("no-latest", true)
+	defer viper.Set("no-latest", false)
+
+	output.Reset()
+	err := runValidateCommand(&output, manifestPath)
+	if err == nil || err.Error() != "1 problem(s) found" {
+		t.Errorf("expected error to be 1 problem(s) found, actual %v", err)
+	}
+
+	if output.String() != problem {
+		t.Errorf("expected output to be %s, actual %s", problem, output.String())
+	}
+}
+
+func TestRunValidateCommand_StrictNames(t *testing.T) {
+	manifestPath := writeTestManifest(t, `
+target:
+  host: target.com
+  repository: MyTeam
+sources:
+- repository: busybox
+  tag: 1.32.0
+`)
+	defer os.RemoveAll(filepath.Dir(manifestPath))
+
+	location := getManifestLocation(manifestPath)
+	problem := "sources[0] (busybox:1.32.0) at " + location + ":6: target target.com/MyTeam/busybox:1.32.0 has uppercase letters, which are not allowed in repositories, and is pushed as target.com/myteam/busybox:1.32.0\n"
+
+	var output bytes.Buffer
+	if err := runValidateCommand(&output, manifestPath); err != nil {
+		t.Fatal("expected the uppercase target to only be a warning:", err)
+	}
+
+	if output.String() != "warning: "+problem {
+		t.Errorf("expected output to be %s, actual %s", "warning: "+problem, output.String())
+	}
+
+	viper.Set("strict-names", true)
+	defer viper.Set("strict-names", false)
+
+	output.Reset()
+	err := runValidateCommand(&output, manifestPath)
+	if err == nil || err.Error() != "1 problem(s) found" {
+		t.Errorf("expected error to be 1 problem(s) found, actual %v", err)
+	}
+
+	if output.String() != problem {
+		t.Errorf("expected output to be %s, actual %s", problem, output.String())
+	}
+}
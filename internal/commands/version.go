@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/version"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newVersionCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "version",
+		Short: "Print the version and build information of sinker",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			if err := runVersionCommand(os.Stdout, viper.GetString("output")); err != nil {
+				return fmt.Errorf("version: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	addOutputFlag(&cmd)
+
+	return &cmd
+}
+
+func runVersionCommand(output io.Writer, format string) error {
+	if err := validateOutputFormat(format); err != nil {
+		return fmt.Errorf("validate output: %w", err)
+	}
+
+	info := version.Get()
+	writeTable := func(output io.Writer) error {
+		_, err := fmt.Fprintf(output, "Version:    %s\nGit commit: %s\nBuild date: %s\nGo version: %s\n", info.Version, info.GitCommit, info.BuildDate, info.GoVersion)
+		return err
+	}
+
+	if err := writeOutput(output, format, info, writeTable); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	return nil
+}
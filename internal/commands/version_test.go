@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/version"
+)
+
+func TestRunVersionCommand_JSON(t *testing.T) {
+	var output bytes.Buffer
+	if err := runVersionCommand(&output, jsonOutput); err != nil {
+		t.Fatal("run version command:", err)
+	}
+
+	var actual map[string]string
+	if err := json.Unmarshal(output.Bytes(), &actual); err != nil {
+		t.Fatal("unmarshal version:", err)
+	}
+
+	expected := map[string]string{
+		"version":   version.Version,
+		"gitCommit": version.GitCommit,
+		"buildDate": version.BuildDate,
+		"goVersion": runtime.Version(),
+	}
+
+	if len(actual) != len(expected) {
+		t.Errorf("expected %v fields, actual %v", len(expected), actual)
+	}
+
+	for key, value := range expected {
+		if actual[key] != value {
+			t.Errorf("expected %s to be %s, actual %s", key, value, actual[key])
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	acrHostSuffix = ".azurecr.io"
+
+	// acrRefreshTokenUsername is the username that the registry expects
+	// when the password is an ACR refresh token
+	acrRefreshTokenUsername = "00000000-0000-0000-0000-000000000000"
+
+	defaultAzureAuthorityHost = "https://login.microsoftonline.com"
+	azureManagementScope      = "https://management.azure.com/.default"
+
+	// acrTokenRefreshWindow is how long before it expires that an ACR refresh token is refreshed
+	acrTokenRefreshWindow = 15 * time.Minute
+
+	// acrRefreshTokenLifetime is how long an ACR refresh token is valid for,
+	// which is used when the expiry of the refresh token can not be read from it
+	acrRefreshTokenLifetime = 3 * time.Hour
+)
+
+// azureServicePrincipal is the service principal used to get an
+// Azure Active Directory access token for Azure Container Registry
+type azureServicePrincipal struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+}
+
+// acrTokenExchange exchanges the credentials of a service principal for an ACR refresh token
+type acrTokenExchange struct {
+	httpClient *http.Client
+
+	// authorityHost is the Azure Active Directory host that issues the access token
+	authorityHost string
+
+	// registryURL returns the URL of the registry that issues the refresh token
+	registryURL func(host string) string
+}
+
+// newACRTokenExchange returns the token exchange that sends its requests with the transport
+func newACRTokenExchange(transport http.RoundTripper) acrTokenExchange {
+	authorityHost := os.Getenv("AZURE_AUTHORITY_HOST")
+	if authorityHost == "" {
+		authorityHost = defaultAzureAuthorityHost
+	}
+
+	return acrTokenExchange{
+		httpClient:    &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		authorityHost: strings.TrimSuffix(authorityHost, "/"),
+		registryURL: func(host string) string {
+			return "https://" + host
+		},
+	}
+}
+
+// isAzureContainerRegistry returns true when the host is an Azure Container Registry (e.g. myregistry.azurecr.io)
+func isAzureContainerRegistry(host string) bool {
+	return strings.HasSuffix(strings.ToLower(host), acrHostSuffix)
+}
+
+// getAzureServicePrincipal returns the service principal set in the AZURE_TENANT_ID,
+// AZURE_CLIENT_ID, and AZURE_CLIENT_SECRET environment variables, if all of them are set
+func getAzureServicePrincipal() (azureServicePrincipal, bool) {
+	principal := azureServicePrincipal{
+		TenantID:     os.Getenv("AZURE_TENANT_ID"),
+		ClientID:     os.Getenv("AZURE_CLIENT_ID"),
+		ClientSecret: os.Getenv("AZURE_CLIENT_SECRET"),
+	}
+
+	if principal.TenantID == "" || principal.ClientID == "" || principal.ClientSecret == "" {
+		return azureServicePrincipal{}, false
+	}
+
+	return principal, true
+}
+
+// acrTokenCache caches the ACR refresh token of each registry until it is about to expire. The requests
+// of the token exchange are sent with the registry transport of the Docker client, so that they use the
+// same proxy and certificate authorities as the requests to the registries.
+type acrTokenCache struct {
+	tokens *tokenCache
+
+	mutex     sync.Mutex
+	transport http.RoundTripper
+}
+
+var acrTokens = &acrTokenCache{
+	tokens:    newTokenCache(acrTokenRefreshWindow),
+	transport: http.DefaultTransport,
+}
+
+// setTransport sets the transport that the requests of the token exchange are sent with
+func (c *acrTokenCache) setTransport(transport http.RoundTripper) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.transport = transport
+}
+
+// getEncodedAuth returns the Base64 encoded auth for the registry host, which is only
+// exchanged for a new refresh token when there is no cached token or it is about to expire
+func (c *acrTokenCache) getEncodedAuth(ctx context.Context, host string, principal azureServicePrincipal) (string, error) {
+	c.mutex.Lock()
+	exchange := newACRTokenExchange(c.transport)
+	c.mutex.Unlock()
+
+	return c.tokens.getEncodedAuth(ctx, strings.ToLower(host)+"/"+principal.ClientID, func(ctx context.Context) (registryToken, error) {
+		return exchange.getToken(ctx, host, principal)
+	})
+}
+
+// getToken returns the Base64 encoded auth for the registry host, which uses the ACR
+// refresh token exchanged for the credentials of the service principal as its password
+func (e acrTokenExchange) getToken(ctx context.Context, host string, principal azureServicePrincipal) (registryToken, error) {
+	accessToken, err := e.getAccessToken(ctx, principal)
+	if err != nil {
+		return registryToken{}, fmt.Errorf("get access token: %w", err)
+	}
+
+	refreshToken, err := e.getRefreshToken(ctx, host, principal.TenantID, accessToken)
+	if err != nil {
+		return registryToken{}, fmt.Errorf("get refresh token: %w", err)
+	}
+
+	encodedAuth, err := GetEncodedBasicAuth(acrRefreshTokenUsername, refreshToken)
+	if err != nil {
+		return registryToken{}, fmt.Errorf("get encoded basic auth: %w", err)
+	}
+
+	expiresAt, exists := getTokenExpiry(refreshToken)
+	if !exists {
+		expiresAt = time.Now().Add(acrRefreshTokenLifetime)
+	}
+
+	return registryToken{encodedAuth: encodedAuth, expiresAt: expiresAt}, nil
+}
+
+// getTokenExpiry returns when the token expires from the exp claim of the token, which is a JWT,
+// and false when the token is not a JWT or does not have an exp claim
+func getTokenExpiry(token string) (time.Time, bool) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segments[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.ExpiresAt == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(claims.ExpiresAt, 0), true
+}
+
+func (e acrTokenExchange) getAccessToken(ctx context.Context, principal azureServicePrincipal) (string, error) {
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", e.authorityHost, url.PathEscape(principal.TenantID))
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {principal.ClientID},
+		"client_secret": {principal.ClientSecret},
+		"scope":         {azureManagementScope},
+	}
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := e.postForm(ctx, tokenURL, form, &response); err != nil {
+		return "", fmt.Errorf("post token request: %w", err)
+	}
+
+	if response.AccessToken == "" {
+		return "", fmt.Errorf("no access token returned by %s", e.authorityHost)
+	}
+
+	return response.AccessToken, nil
+}
+
+func (e acrTokenExchange) getRefreshToken(ctx context.Context, host string, tenantID string, accessToken string) (string, error) {
+	exchangeURL := e.registryURL(host) + "/oauth2/exchange"
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"tenant":       {tenantID},
+		"access_token": {accessToken},
+	}
+
+	var response struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := e.postForm(ctx, exchangeURL, form, &response); err != nil {
+		return "", fmt.Errorf("post exchange request: %w", err)
+	}
+
+	if response.RefreshToken == "" {
+		return "", fmt.Errorf("no refresh token returned by %s", host)
+	}
+
+	return response.RefreshToken, nil
+}
+
+func (e acrTokenExchange) postForm(ctx context.Context, requestURL string, form url.Values, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := e.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("post form: %w", err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsAzureContainerRegistry(t *testing.T) {
+	testCases := []struct {
+		host     string
+		expected bool
+	}{
+		{host: "myregistry.azurecr.io", expected: true},
+		{host: "MyRegistry.AzureCR.io", expected: true},
+		{host: "azurecr.io.mycompany.com", expected: false},
+		{host: "mycompany.com", expected: false},
+	}
+
+	for _, testCase := range testCases {
+		actual := isAzureContainerRegistry(testCase.host)
+		if actual != testCase.expected {
+			t.Errorf("expected azure container registry to be %v for %s, actual %v", testCase.expected, testCase.host, actual)
+		}
+	}
+}
+
+func TestACRTokenExchange_GetEncodedAuth(t *testing.T) {
+	requests := make(map[string]url.Values)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal("parse form:", err)
+		}
+
+		requests[r.URL.Path] = r.PostForm
+
+		switch r.URL.Path {
+		case "/mytenant/oauth2/v2.0/token":
+			w.Write([]byte(`{"token_type":"Bearer","access_token":"aad-access-token"}`))
+		case "/oauth2/exchange":
+			w.Write([]byte(`{"refresh_token":"acr-refresh-token"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	exchange := acrTokenExchange{
+		httpClient:    server.Client(),
+		authorityHost: server.URL,
+		registryURL: func(host string) string {
+			return server.URL
+		},
+	}
+
+	principal := azureServicePrincipal{TenantID: "mytenant", ClientID: "myclient", ClientSecret: "mysecret"}
+	token, err := exchange.getToken(context.Background(), "myregistry.azurecr.io", principal)
+	if err != nil {
+		t.Fatal("get token:", err)
+	}
+
+	expectedRequests := map[string]url.Values{
+		"/mytenant/oauth2/v2.0/token": {
+			"grant_type":    {"client_credentials"},
+			"client_id":     {"myclient"},
+			"client_secret": {"mysecret"},
+			"scope":         {"https://management.azure.com/.default"},
+		},
+		"/oauth2/exchange": {
+			"grant_type":   {"access_token"},
+			"service":      {"myregistry.azurecr.io"},
+			"tenant":       {"mytenant"},
+			"access_token": {"aad-access-token"},
+		},
+	}
+
+	if !reflect.DeepEqual(requests, expectedRequests) {
+		t.Errorf("expected requests %v, actual %v", expectedRequests, requests)
+	}
+
+	authConfig := decodeTestAuth(t, token.encodedAuth)
+	if authConfig.Username != acrRefreshTokenUsername || authConfig.Password != "acr-refresh-token" {
+		t.Errorf("expected the refresh token to be the password, actual %+v", authConfig)
+	}
+}
+
+func TestACRTokenExchange_GetEncodedAuth_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+	}))
+	defer server.Close()
+
+	exchange := acrTokenExchange{
+		httpClient:    server.Client(),
+		authorityHost: server.URL,
+		registryURL: func(host string) string {
+			return server.URL
+		},
+	}
+
+	principal := azureServicePrincipal{TenantID: "mytenant", ClientID: "myclient", ClientSecret: "wrong"}
+	if _, err := exchange.getToken(context.Background(), "myregistry.azurecr.io", principal); err == nil {
+		t.Error("expected an error when the service principal is rejected")
+	}
+}
+
+func TestACRTokenCache_GetEncodedAuth(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	refreshToken := "header." + base64.RawURLEncoding.EncodeToString([]byte(`{"exp":`+strconv.FormatInt(expiresAt.Unix(), 10)+`}`)) + ".signature"
+
+	var exchanges int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/mytenant/oauth2/v2.0/token":
+			w.Write([]byte(`{"access_token":"aad-access-token"}`))
+		case "/oauth2/exchange":
+			exchanges++
+			w.Write([]byte(`{"refresh_token":"` + refreshToken + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv("AZURE_AUTHORITY_HOST", server.URL)
+	defer os.Unsetenv("AZURE_AUTHORITY_HOST")
+
+	// The registry URL of the exchange is the registry host, so requests
+	// to the registry are sent to the test server by the transport
+	cache := acrTokenCache{
+		tokens: newTokenCache(acrTokenRefreshWindow),
+		transport: roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			request.URL.Scheme = "http"
+			request.URL.Host = strings.TrimPrefix(server.URL, "http://")
+			return http.DefaultTransport.RoundTrip(request)
+		}),
+	}
+
+	now := time.Now()
+	cache.tokens.now = func() time.Time { return now }
+
+	principal := azureServicePrincipal{TenantID: "mytenant", ClientID: "myclient", ClientSecret: "mysecret"}
+	for i := 0; i < 3; i++ {
+		if _, err := cache.getEncodedAuth(context.Background(), "myregistry.azurecr.io", principal); err != nil {
+			t.Fatal("get encoded auth:", err)
+		}
+	}
+
+	if exchanges != 1 {
+		t.Errorf("expected the refresh token to be exchanged once, actual %v", exchanges)
+	}
+
+	if cached := cache.tokens.tokens["myregistry.azurecr.io/myclient"]; !cached.expiresAt.Equal(expiresAt) {
+		t.Errorf("expected the token to expire at %s, actual %s", expiresAt, cached.expiresAt)
+	}
+
+	now = expiresAt.Add(-acrTokenRefreshWindow)
+	if _, err := cache.getEncodedAuth(context.Background(), "myregistry.azurecr.io", principal); err != nil {
+		t.Fatal("get encoded auth:", err)
+	}
+
+	if exchanges != 2 {
+		t.Errorf("expected the refresh token to be exchanged again before it expires, actual %v exchanges", exchanges)
+	}
+}
+
+func TestACRTokenExchange_GetToken_Cancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	exchange := acrTokenExchange{
+		httpClient:    server.Client(),
+		authorityHost: server.URL,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	principal := azureServicePrincipal{TenantID: "mytenant", ClientID: "myclient", ClientSecret: "mysecret"}
+	if _, err := exchange.getToken(ctx, "myregistry.azurecr.io", principal); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context cancelled error, actual %v", err)
+	}
+}
+
+func TestGetTokenExpiry(t *testing.T) {
+	testCases := []struct {
+		token          string
+		expectedExists bool
+	}{
+		{token: "header." + base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1600000000}`)) + ".signature", expectedExists: true},
+		{token: "header." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user"}`)) + ".signature", expectedExists: false},
+		{token: "acr-refresh-token", expectedExists: false},
+	}
+
+	for _, testCase := range testCases {
+		expiresAt, exists := getTokenExpiry(testCase.token)
+		if exists != testCase.expectedExists {
+			t.Errorf("expected expiry of %s to exist to be %v, actual %v", testCase.token, testCase.expectedExists, exists)
+		}
+
+		if exists && expiresAt.Unix() != 1600000000 {
+			t.Errorf("expected expiry to be 1600000000, actual %v", expiresAt.Unix())
+		}
+	}
+}
+
+type roundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
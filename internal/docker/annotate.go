@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+)
+
+// annotatedImage is an image with extra annotations added to its manifest
+type annotatedImage struct {
+	v1.Image
+	annotations map[string]string
+}
+
+func (a annotatedImage) Manifest() (*v1.Manifest, error) {
+	manifest, err := a.Image.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	annotated := manifest.DeepCopy()
+	if annotated.Annotations == nil {
+		annotated.Annotations = make(map[string]string)
+	}
+
+	for key, value := range a.annotations {
+		annotated.Annotations[key] = value
+	}
+
+	return annotated, nil
+}
+
+func (a annotatedImage) RawManifest() ([]byte, error) {
+	manifest, err := a.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(manifest)
+}
+
+func (a annotatedImage) Digest() (v1.Hash, error) {
+	return partial.Digest(a)
+}
+
+func (a annotatedImage) Size() (int64, error) {
+	return partial.Size(a)
+}
+
+// annotateImage returns the image with the labels added to its config and the annotations added to its
+// manifest, which replace the labels and annotations of the image with the same keys. Labels are not added
+// to OCI artifacts, as they do not have the config of a container image. The image is returned unchanged
+// when there are no labels or annotations.
+func annotateImage(image v1.Image, labels map[string]string, annotations map[string]string) (v1.Image, error) {
+	if len(labels) > 0 {
+		artifact, err := isArtifact(image)
+		if err != nil {
+			return nil, fmt.Errorf("is artifact: %w", err)
+		}
+
+		if !artifact {
+			image, err = addLabels(image, labels)
+			if err != nil {
+				return nil, fmt.Errorf("add labels: %w", err)
+			}
+		}
+	}
+
+	if len(annotations) > 0 {
+		image = annotatedImage{Image: image, annotations: annotations}
+	}
+
+	return image, nil
+}
+
+func addLabels(image v1.Image, labels map[string]string) (v1.Image, error) {
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+
+	config := configFile.Config
+	if config.Labels == nil {
+		config.Labels = make(map[string]string)
+	}
+
+	for key, value := range labels {
+		config.Labels[key] = value
+	}
+
+	return mutate.Config(image, config)
+}
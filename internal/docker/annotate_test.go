@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	stdlog "log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	log "github.com/sirupsen/logrus"
+)
+
+func getRemoteImage(t *testing.T, image string) v1.Image {
+	reference, err := name.ParseReference(image)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	remoteImage, err := remote.Image(reference)
+	if err != nil {
+		t.Fatal("get image:", err)
+	}
+
+	return remoteImage
+}
+
+func TestCopyImageAndWait_Annotations(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+
+	host := strings.TrimPrefix(registryServer.URL, "http://")
+	sourceImage := host + "/source/app:v1.0.0"
+	targetImage := host + "/target/app:v1.0.0"
+
+	image, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	writeImage(t, sourceImage, image)
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := Client{
+		Logger:      logger,
+		RetryPolicy: RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+		Labels:      map[string]string{"mirrored-by": "sinker"},
+		Annotations: map[string]string{"org.opencontainers.image.source": "https://github.com/org/app"},
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), sourceImage, targetImage, "", ""); err != nil {
+		t.Fatal("copy image:", err)
+	}
+
+	copied := getRemoteImage(t, targetImage)
+
+	manifest, err := copied.Manifest()
+	if err != nil {
+		t.Fatal("manifest:", err)
+	}
+
+	if manifest.Annotations["org.opencontainers.image.source"] != "https://github.com/org/app" {
+		t.Errorf("expected the annotation to be added to the manifest, actual %v", manifest.Annotations)
+	}
+
+	configFile, err := copied.ConfigFile()
+	if err != nil {
+		t.Fatal("config file:", err)
+	}
+
+	if configFile.Config.Labels["mirrored-by"] != "sinker" {
+		t.Errorf("expected the label to be added to the config, actual %v", configFile.Config.Labels)
+	}
+
+	sourceManifest, err := image.Manifest()
+	if err != nil {
+		t.Fatal("source manifest:", err)
+	}
+
+	for i, layer := range sourceManifest.Layers {
+		if manifest.Layers[i].Digest != layer.Digest {
+			t.Errorf("expected layer %v to be copied unchanged as %s, actual %s", i, layer.Digest, manifest.Layers[i].Digest)
+		}
+	}
+}
+
+func TestAnnotateImage_Artifact(t *testing.T) {
+	annotated, err := annotateImage(newTestArtifact(t), map[string]string{"mirrored-by": "sinker"}, map[string]string{"mirrored-by": "sinker"})
+	if err != nil {
+		t.Fatal("annotate image:", err)
+	}
+
+	manifest, err := annotated.Manifest()
+	if err != nil {
+		t.Fatal("manifest:", err)
+	}
+
+	if manifest.Annotations["mirrored-by"] != "sinker" {
+		t.Errorf("expected the annotation to be added to the artifact, actual %v", manifest.Annotations)
+	}
+
+	config, err := annotated.RawConfigFile()
+	if err != nil {
+		t.Fatal("config:", err)
+	}
+
+	if string(config) != `{"name":"app","version":"1.0.0"}` {
+		t.Errorf("expected the config of the artifact to be unchanged, actual %s", config)
+	}
+}
+
+func TestAnnotateImage_Unchanged(t *testing.T) {
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	annotated, err := annotateImage(image, nil, nil)
+	if err != nil {
+		t.Fatal("annotate image:", err)
+	}
+
+	if annotated != image {
+		t.Error("expected the image to be unchanged without labels or annotations")
+	}
+}
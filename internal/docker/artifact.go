@@ -0,0 +1,63 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	v1types "github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// GetArtifactTypeAtRemote returns the media type of the config of the image at the remote registry
+// when it is an OCI artifact, such as a Helm chart or a WASM module, rather than a container image.
+// An empty artifact type is returned for container images and image indexes.
+func (c Client) GetArtifactTypeAtRemote(ctx context.Context, image string, auth string) (string, error) {
+	imageReference, err := c.parseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return "", fmt.Errorf("get authenticator: %w", err)
+	}
+
+	descriptor, err := remote.Get(imageReference, c.getRemoteOptions(ctx, imageReference.Context().Registry, remote.WithAuth(authenticator))...)
+	if err != nil {
+		return "", fmt.Errorf("get image: %w", err)
+	}
+
+	if descriptor.MediaType != v1types.OCIManifestSchema1 && descriptor.MediaType != v1types.DockerManifestSchema2 {
+		return "", nil
+	}
+
+	manifest, err := v1.ParseManifest(bytes.NewReader(descriptor.Manifest))
+	if err != nil {
+		return "", fmt.Errorf("parse manifest: %w", err)
+	}
+
+	return getArtifactType(manifest), nil
+}
+
+// getArtifactType returns the media type of the config of the manifest, or an
+// empty artifact type when the config is the config of a container image
+func getArtifactType(manifest *v1.Manifest) string {
+	switch manifest.Config.MediaType {
+	case "", v1types.DockerConfigJSON, v1types.OCIConfigJSON:
+		return ""
+	default:
+		return string(manifest.Config.MediaType)
+	}
+}
+
+// isArtifact returns true when the image is an OCI artifact rather than a container image
+func isArtifact(image v1.Image) (bool, error) {
+	manifest, err := image.Manifest()
+	if err != nil {
+		return false, fmt.Errorf("manifest: %w", err)
+	}
+
+	return getArtifactType(manifest) != "", nil
+}
@@ -0,0 +1,223 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	stdlog "log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	v1types "github.com/google/go-containerregistry/pkg/v1/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	helmConfigMediaType v1types.MediaType = "application/vnd.cncf.helm.config.v1+json"
+	helmChartMediaType  v1types.MediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+)
+
+// testArtifact is an OCI artifact with a single layer, such as a Helm chart
+type testArtifact struct {
+	config   []byte
+	content  []byte
+	manifest []byte
+}
+
+func (a *testArtifact) RawConfigFile() ([]byte, error) {
+	return a.config, nil
+}
+
+func (a *testArtifact) MediaType() (v1types.MediaType, error) {
+	return v1types.OCIManifestSchema1, nil
+}
+
+func (a *testArtifact) RawManifest() ([]byte, error) {
+	return a.manifest, nil
+}
+
+func (a *testArtifact) LayerByDigest(digest v1.Hash) (partial.CompressedLayer, error) {
+	return &testArtifactLayer{content: a.content}, nil
+}
+
+type testArtifactLayer struct {
+	content []byte
+}
+
+func (l *testArtifactLayer) Digest() (v1.Hash, error) {
+	digest, _, err := v1.SHA256(bytes.NewReader(l.content))
+	return digest, err
+}
+
+func (l *testArtifactLayer) Compressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.content)), nil
+}
+
+func (l *testArtifactLayer) Size() (int64, error) {
+	return int64(len(l.content)), nil
+}
+
+func (l *testArtifactLayer) MediaType() (v1types.MediaType, error) {
+	return helmChartMediaType, nil
+}
+
+func newTestArtifact(t *testing.T) v1.Image {
+	artifact := testArtifact{
+		config:  []byte(`{"name":"app","version":"1.0.0"}`),
+		content: []byte("chart contents"),
+	}
+
+	configDigest, configSize, err := v1.SHA256(bytes.NewReader(artifact.config))
+	if err != nil {
+		t.Fatal("config digest:", err)
+	}
+
+	contentDigest, contentSize, err := v1.SHA256(bytes.NewReader(artifact.content))
+	if err != nil {
+		t.Fatal("content digest:", err)
+	}
+
+	manifest := v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     v1types.OCIManifestSchema1,
+		Config:        v1.Descriptor{MediaType: helmConfigMediaType, Digest: configDigest, Size: configSize},
+		Layers:        []v1.Descriptor{{MediaType: helmChartMediaType, Digest: contentDigest, Size: contentSize}},
+	}
+
+	artifact.manifest, err = json.Marshal(manifest)
+	if err != nil {
+		t.Fatal("marshal manifest:", err)
+	}
+
+	image, err := partial.CompressedToImage(&artifact)
+	if err != nil {
+		t.Fatal("artifact image:", err)
+	}
+
+	return image
+}
+
+func TestCopyImageAndWait_Artifact(t *testing.T) {
+	sourceServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer sourceServer.Close()
+
+	targetServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer targetServer.Close()
+
+	sourceImage := strings.TrimPrefix(sourceServer.URL, "http://") + "/charts/app:1.0.0"
+	targetImage := strings.TrimPrefix(targetServer.URL, "http://") + "/mirror/charts/app:1.0.0"
+
+	artifact := newTestArtifact(t)
+	writeImage(t, sourceImage, artifact)
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	layerCache := newTestLayerCache(t, 0)
+	defer os.RemoveAll(layerCache.dir)
+
+	client := Client{
+		Logger:      logger,
+		RetryPolicy: RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+		layerCache:  layerCache,
+	}
+
+	artifactType, err := client.GetArtifactTypeAtRemote(context.Background(), sourceImage, "")
+	if err != nil {
+		t.Fatal("get artifact type:", err)
+	}
+
+	if artifactType != string(helmConfigMediaType) {
+		t.Errorf("expected artifact type %s, actual %s", helmConfigMediaType, artifactType)
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), sourceImage, targetImage, "", ""); err != nil {
+		t.Fatal("copy artifact:", err)
+	}
+
+	targetReference, err := name.ParseReference(targetImage)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	copied, err := remote.Image(targetReference)
+	if err != nil {
+		t.Fatal("get copied artifact:", err)
+	}
+
+	expectedManifest, err := artifact.RawManifest()
+	if err != nil {
+		t.Fatal("artifact manifest:", err)
+	}
+
+	actualManifest, err := copied.RawManifest()
+	if err != nil {
+		t.Fatal("copied manifest:", err)
+	}
+
+	if !bytes.Equal(expectedManifest, actualManifest) {
+		t.Errorf("expected the manifest to be preserved %s, actual %s", expectedManifest, actualManifest)
+	}
+
+	actualConfig, err := copied.RawConfigFile()
+	if err != nil {
+		t.Fatal("copied config:", err)
+	}
+
+	if string(actualConfig) != `{"name":"app","version":"1.0.0"}` {
+		t.Errorf("expected the config to be preserved, actual %s", actualConfig)
+	}
+
+	layers, err := copied.Layers()
+	if err != nil {
+		t.Fatal("copied layers:", err)
+	}
+
+	content, err := layers[0].Compressed()
+	if err != nil {
+		t.Fatal("copied content:", err)
+	}
+	defer content.Close()
+
+	actualContent, err := ioutil.ReadAll(content)
+	if err != nil {
+		t.Fatal("read copied content:", err)
+	}
+
+	if string(actualContent) != "chart contents" {
+		t.Errorf("expected the content to be preserved, actual %s", actualContent)
+	}
+}
+
+func TestGetArtifactTypeAtRemote_Image(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+
+	image := strings.TrimPrefix(registryServer.URL, "http://") + "/app:v1.0.0"
+
+	contents, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	writeImage(t, image, contents)
+
+	artifactType, err := Client{}.GetArtifactTypeAtRemote(context.Background(), image, "")
+	if err != nil {
+		t.Fatal("get artifact type:", err)
+	}
+
+	if artifactType != "" {
+		t.Errorf("expected a container image to not be an artifact, actual %s", artifactType)
+	}
+}
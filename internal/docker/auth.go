@@ -1,13 +1,21 @@
 package docker
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/docker/cli/cli/config"
 	"github.com/docker/cli/cli/config/credentials"
 	"github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
 // GetEncodedBasicAuth encodes a username and password into Base64
@@ -23,13 +31,79 @@ func GetEncodedBasicAuth(username string, password string) (string, error) {
 	}
 
 	return base64.URLEncoding.EncodeToString(jsonAuth), nil
+}
+
+// GetEncodedTokenAuth encodes a Base64 Docker auth token (username:password)
+// into the auth expected by the Docker client
+func GetEncodedTokenAuth(token string) (string, error) {
+	decodedToken, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("decode token: %w", err)
+	}
+
+	tokenTokens := strings.SplitN(string(decodedToken), ":", 2)
+	if len(tokenTokens) != 2 {
+		return "", fmt.Errorf("token is not in the format username:password")
+	}
 
+	return GetEncodedBasicAuth(tokenTokens[0], tokenTokens[1])
 }
 
-// GetEncodedAuthForHost returns a Base64 encoded auth
-// for the host defined in the Docker configuration
-func GetEncodedAuthForHost(host string) (string, error) {
-	cfg, err := config.Load(config.Dir())
+// GetEncodedAuthForHost returns a Base64 encoded auth for the host. The auth is selected from the
+// environment variables for the host, then the Azure service principal for Azure Container Registry hosts,
+// then the AWS credentials for Amazon ECR hosts, then the Application Default Credentials for Google
+// Container Registry and Artifact Registry hosts, then the credentials saved by sinker login, and lastly
+// the Docker configuration, which includes the credential helper configured for the host.
+func GetEncodedAuthForHost(ctx context.Context, host string) (string, error) {
+	usernameVariable, passwordVariable := getAuthEnvVariables(host)
+	if password := os.Getenv(passwordVariable); password != "" {
+		return GetEncodedBasicAuth(os.Getenv(usernameVariable), password)
+	}
+
+	registryHost := getRegistryHost(host)
+	if principal, exists := getAzureServicePrincipal(); exists && isAzureContainerRegistry(registryHost) {
+		encodedAuth, err := acrTokens.getEncodedAuth(ctx, registryHost, principal)
+		if err != nil {
+			return "", fmt.Errorf("get acr auth: %w", err)
+		}
+
+		return encodedAuth, nil
+	}
+
+	// ECR hosts fall back to the other auth when there are no AWS credentials
+	if registry, exists := parseECRHost(registryHost); exists {
+		encodedAuth, err := ecrTokens.getEncodedAuth(ctx, registry)
+		if err == nil {
+			return encodedAuth, nil
+		}
+
+		if !isNoAWSCredentials(err) {
+			return "", fmt.Errorf("get ecr auth: %w", err)
+		}
+	}
+
+	// Google hosts fall back to the other auth when there are no Application Default Credentials
+	if isGoogleRegistry(registryHost) {
+		encodedAuth, exists, err := googleTokens.getEncodedAuth()
+		if err != nil {
+			return "", fmt.Errorf("get google auth: %w", err)
+		}
+
+		if exists {
+			return encodedAuth, nil
+		}
+	}
+
+	savedCredentials, err := loadCredentials()
+	if err != nil {
+		return "", fmt.Errorf("load credentials: %w", err)
+	}
+
+	if authConfig, exists := savedCredentials[host]; exists {
+		return GetEncodedBasicAuth(authConfig.Username, authConfig.Password)
+	}
+
+	cfg, err := config.Load(getDockerConfigDir())
 	if err != nil {
 		return "", fmt.Errorf("loading docker config: %w", err)
 	}
@@ -43,6 +117,11 @@ func GetEncodedAuthForHost(host string) (string, error) {
 		return "", fmt.Errorf("getting auth config: %w", err)
 	}
 
+	// When no credentials are configured for the host, registry operations are anonymous
+	if isAnonymousAuth(authConfig) {
+		return "", nil
+	}
+
 	jsonAuth, err := json.Marshal(authConfig)
 	if err != nil {
 		return "", fmt.Errorf("marshal auth: %w", err)
@@ -50,3 +129,148 @@ func GetEncodedAuthForHost(host string) (string, error) {
 
 	return base64.URLEncoding.EncodeToString(jsonAuth), nil
 }
+
+// getAuthEnvVariables returns the names of the environment variables that contain the username
+// and password for the host, e.g. SINKER_AUTH_MYCOMPANY_COM_USERNAME for mycompany.com
+func getAuthEnvVariables(host string) (string, string) {
+	host = getRegistryHost(host)
+	if host == "index.docker.io" {
+		host = "docker.io"
+	}
+
+	variablePrefix := "SINKER_AUTH_" + strings.ToUpper(nonAlphanumericPattern.ReplaceAllString(host, "_"))
+
+	return variablePrefix + "_USERNAME", variablePrefix + "_PASSWORD"
+}
+
+// getRegistryHost returns the host of the registry from the auth host,
+// e.g. index.docker.io for https://index.docker.io/v1/
+func getRegistryHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+
+	return strings.Split(host, "/")[0]
+}
+
+var nonAlphanumericPattern = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
+// getDockerConfigDir returns the directory of the Docker configuration,
+// which can be changed with the DOCKER_CONFIG environment variable
+func getDockerConfigDir() string {
+	if configDir := os.Getenv("DOCKER_CONFIG"); configDir != "" {
+		return configDir
+	}
+
+	return config.Dir()
+}
+
+// SaveCredentials saves the username and password for the host
+// to the sinker credentials file
+func SaveCredentials(host string, username string, password string) error {
+	savedCredentials, err := loadCredentials()
+	if err != nil {
+		return fmt.Errorf("load credentials: %w", err)
+	}
+
+	savedCredentials[host] = types.AuthConfig{
+		Username: username,
+		Password: password,
+	}
+
+	credentialsLocation, err := getCredentialsLocation()
+	if err != nil {
+		return fmt.Errorf("get credentials location: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(credentialsLocation), 0700); err != nil {
+		return fmt.Errorf("create credentials directory: %w", err)
+	}
+
+	credentialsContents, err := json.MarshalIndent(savedCredentials, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal credentials: %w", err)
+	}
+
+	if err := ioutil.WriteFile(credentialsLocation, credentialsContents, 0600); err != nil {
+		return fmt.Errorf("write credentials: %w", err)
+	}
+
+	return nil
+}
+
+func loadCredentials() (map[string]types.AuthConfig, error) {
+	credentialsLocation, err := getCredentialsLocation()
+	if err != nil {
+		return nil, fmt.Errorf("get credentials location: %w", err)
+	}
+
+	savedCredentials := make(map[string]types.AuthConfig)
+
+	credentialsContents, err := ioutil.ReadFile(credentialsLocation)
+	if os.IsNotExist(err) {
+		return savedCredentials, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read credentials: %w", err)
+	}
+
+	if err := json.Unmarshal(credentialsContents, &savedCredentials); err != nil {
+		return nil, fmt.Errorf("unmarshal credentials: %w", err)
+	}
+
+	return savedCredentials, nil
+}
+
+func getCredentialsLocation() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("user config dir: %w", err)
+	}
+
+	return filepath.Join(configDir, "sinker", "credentials.json"), nil
+}
+
+// getAuthenticator returns the authenticator for registry operations
+// that are not performed by Docker from the Base64 encoded auth
+func getAuthenticator(encodedAuth string) (authn.Authenticator, error) {
+	if encodedAuth == "" {
+		return authn.Anonymous, nil
+	}
+
+	jsonAuth, err := base64.URLEncoding.DecodeString(encodedAuth)
+	if err != nil {
+		return nil, fmt.Errorf("decode auth: %w", err)
+	}
+
+	var authConfig types.AuthConfig
+	if err := json.Unmarshal(jsonAuth, &authConfig); err != nil {
+		return nil, fmt.Errorf("unmarshal auth: %w", err)
+	}
+
+	if isAnonymousAuth(authConfig) {
+		return authn.Anonymous, nil
+	}
+
+	return authn.FromConfig(authn.AuthConfig{
+		Username:      authConfig.Username,
+		Password:      authConfig.Password,
+		IdentityToken: authConfig.IdentityToken,
+		RegistryToken: authConfig.RegistryToken,
+	}), nil
+}
+
+// withEncodedAuth returns the remote option that authenticates the registry operations that are
+// not performed by Docker with the encoded auth of the image, the same auth as its Docker operations,
+// so that the auth configured for the image in the manifest is used rather than only that of its host
+func withEncodedAuth(encodedAuth string) (remote.Option, error) {
+	authenticator, err := getAuthenticator(encodedAuth)
+	if err != nil {
+		return nil, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	return remote.WithAuth(authenticator), nil
+}
+
+func isAnonymousAuth(authConfig types.AuthConfig) bool {
+	return authConfig.Username == "" && authConfig.Password == "" && authConfig.Auth == "" && authConfig.IdentityToken == "" && authConfig.RegistryToken == ""
+}
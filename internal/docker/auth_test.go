@@ -0,0 +1,153 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/cli/cli/config/types"
+)
+
+func TestSaveCredentials(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(configDir)
+
+	os.Setenv("XDG_CONFIG_HOME", configDir)
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+
+	if err := SaveCredentials("host.com", "user", "pass"); err != nil {
+		t.Fatal("save credentials:", err)
+	}
+
+	actual, err := GetEncodedAuthForHost(context.Background(), "host.com")
+	if err != nil {
+		t.Fatal("get encoded auth for host:", err)
+	}
+
+	expected, err := GetEncodedBasicAuth("user", "pass")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	if actual != expected {
+		t.Errorf("expected auth to be %s, actual %s", expected, actual)
+	}
+}
+
+func decodeTestAuth(t *testing.T, encodedAuth string) types.AuthConfig {
+	jsonAuth, err := base64.URLEncoding.DecodeString(encodedAuth)
+	if err != nil {
+		t.Fatal("decode auth:", err)
+	}
+
+	var authConfig types.AuthConfig
+	if err := json.Unmarshal(jsonAuth, &authConfig); err != nil {
+		t.Fatal("unmarshal auth:", err)
+	}
+
+	return authConfig
+}
+
+func TestGetEncodedAuthForHost_DockerConfig(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(configDir)
+
+	os.Setenv("XDG_CONFIG_HOME", configDir)
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+
+	os.Setenv("DOCKER_CONFIG", configDir)
+	defer os.Unsetenv("DOCKER_CONFIG")
+
+	// The credential helper is found on the PATH by its name, docker-credential-<helper>
+	const credentialHelper = `#!/bin/sh
+echo '{"ServerURL":"helper.com","Username":"helperuser","Secret":"helperpass"}'
+`
+	if err := ioutil.WriteFile(filepath.Join(configDir, "docker-credential-fake"), []byte(credentialHelper), 0700); err != nil {
+		t.Fatal("write credential helper:", err)
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", configDir+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	const dockerConfig = `{
+  "auths": {
+    "config.com": {"auth": "Y29uZmlndXNlcjpjb25maWdwYXNz"}
+  },
+  "credHelpers": {
+    "helper.com": "fake"
+  }
+}`
+	if err := ioutil.WriteFile(filepath.Join(configDir, "config.json"), []byte(dockerConfig), 0600); err != nil {
+		t.Fatal("write docker config:", err)
+	}
+
+	os.Setenv("SINKER_AUTH_ENV_COM_USERNAME", "envuser")
+	defer os.Unsetenv("SINKER_AUTH_ENV_COM_USERNAME")
+
+	os.Setenv("SINKER_AUTH_ENV_COM_PASSWORD", "envpass")
+	defer os.Unsetenv("SINKER_AUTH_ENV_COM_PASSWORD")
+
+	testCases := []struct {
+		host             string
+		expectedUsername string
+		expectedPassword string
+	}{
+		{host: "config.com", expectedUsername: "configuser", expectedPassword: "configpass"},
+		{host: "helper.com", expectedUsername: "helperuser", expectedPassword: "helperpass"},
+		{host: "env.com", expectedUsername: "envuser", expectedPassword: "envpass"},
+		{host: "unknown.com"},
+	}
+
+	for _, testCase := range testCases {
+		encodedAuth, err := GetEncodedAuthForHost(context.Background(), testCase.host)
+		if err != nil {
+			t.Fatal("get encoded auth for host:", err)
+		}
+
+		if testCase.expectedUsername == "" {
+			if encodedAuth != "" {
+				t.Errorf("expected auth for %s to be anonymous, actual %s", testCase.host, encodedAuth)
+			}
+			continue
+		}
+
+		authConfig := decodeTestAuth(t, encodedAuth)
+		if authConfig.Username != testCase.expectedUsername || authConfig.Password != testCase.expectedPassword {
+			t.Errorf("expected auth for %s to be %s:%s, actual %s:%s", testCase.host, testCase.expectedUsername, testCase.expectedPassword, authConfig.Username, authConfig.Password)
+		}
+	}
+}
+
+func TestGetAuthEnvVariables(t *testing.T) {
+	testCases := []struct {
+		host                     string
+		expectedUsernameVariable string
+		expectedPasswordVariable string
+	}{
+		{host: "mycompany.com", expectedUsernameVariable: "SINKER_AUTH_MYCOMPANY_COM_USERNAME", expectedPasswordVariable: "SINKER_AUTH_MYCOMPANY_COM_PASSWORD"},
+		{host: "localhost:5000", expectedUsernameVariable: "SINKER_AUTH_LOCALHOST_5000_USERNAME", expectedPasswordVariable: "SINKER_AUTH_LOCALHOST_5000_PASSWORD"},
+		{host: "https://index.docker.io/v1/", expectedUsernameVariable: "SINKER_AUTH_DOCKER_IO_USERNAME", expectedPasswordVariable: "SINKER_AUTH_DOCKER_IO_PASSWORD"},
+	}
+
+	for _, testCase := range testCases {
+		usernameVariable, passwordVariable := getAuthEnvVariables(testCase.host)
+		if usernameVariable != testCase.expectedUsernameVariable {
+			t.Errorf("expected username variable to be %s, actual %s", testCase.expectedUsernameVariable, usernameVariable)
+		}
+
+		if passwordVariable != testCase.expectedPasswordVariable {
+			t.Errorf("expected password variable to be %s, actual %s", testCase.expectedPasswordVariable, passwordVariable)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithBandwidthLimit sets the maximum number of bytes per second that are transferred to and from
+// registries, across every image that is copied at the same time. The rate is not limited when it is zero.
+func WithBandwidthLimit(bytesPerSecond int64) ClientOption {
+	return func(c *Client) {
+		c.BandwidthLimit = bytesPerSecond
+	}
+}
+
+// bandwidthLimiter is a token bucket that is shared by every transfer of a client, so that the
+// aggregate rate of the transfers stays under the limit no matter how many run at the same time.
+// A transfer takes the tokens for the bytes it has read, and waits until the bucket has refilled
+// when there are not enough tokens, so the bucket can go into debt by the bytes of a single read.
+type bandwidthLimiter struct {
+	mutex          sync.Mutex
+	bytesPerSecond float64
+	burst          float64
+	tokens         float64
+	last           time.Time
+}
+
+// newBandwidthLimiter returns an empty bucket that refills at the rate, and holds up to a tenth
+// of a second of tokens, so that transfers which start after being idle do not exceed the rate
+func newBandwidthLimiter(bytesPerSecond int64) *bandwidthLimiter {
+	return &bandwidthLimiter{
+		bytesPerSecond: float64(bytesPerSecond),
+		burst:          float64(bytesPerSecond) / 10,
+		last:           time.Now(),
+	}
+}
+
+// wait takes the tokens for the bytes, and blocks until the bucket is no longer in debt
+func (l *bandwidthLimiter) wait(bytes int) {
+	if l == nil || bytes <= 0 {
+		return
+	}
+
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.bytesPerSecond
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+
+	l.last = now
+	l.tokens -= float64(bytes)
+	debt := l.tokens
+	l.mutex.Unlock()
+
+	if debt < 0 {
+		time.Sleep(time.Duration(-debt / l.bytesPerSecond * float64(time.Second)))
+	}
+}
+
+// limitedReader is a reader whose reads are limited by the bandwidth limiter
+type limitedReader struct {
+	io.ReadCloser
+	limiter *bandwidthLimiter
+}
+
+func (r limitedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.limiter.wait(n)
+
+	return n, err
+}
+
+// limitedTransport limits the bandwidth of the bodies of the requests to a registry, which are the
+// layers that are uploaded, and of the bodies of the responses, which are the layers that are downloaded
+type limitedTransport struct {
+	inner   http.RoundTripper
+	limiter *bandwidthLimiter
+}
+
+func (t limitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		limitedRequest := *request
+		limitedRequest.Body = limitedReader{ReadCloser: request.Body, limiter: t.limiter}
+		request = &limitedRequest
+	}
+
+	response, err := t.inner.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = limitedReader{ReadCloser: response.Body, limiter: t.limiter}
+
+	return response, nil
+}
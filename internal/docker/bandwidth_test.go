@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+func TestBandwidthLimiter_ConcurrentTransfers(t *testing.T) {
+	const bytesPerSecond = 1000000
+	const transfers = 4
+	const transferSize = 75000
+
+	limiter := newBandwidthLimiter(bytesPerSecond)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < transfers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			reader := limitedReader{ReadCloser: ioutil.NopCloser(bytes.NewReader(make([]byte, transferSize))), limiter: limiter}
+			if _, err := io.Copy(ioutil.Discard, reader); err != nil {
+				t.Error("copy:", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	measuredRate := float64(transfers*transferSize) / time.Since(start).Seconds()
+	if measuredRate > bytesPerSecond {
+		t.Errorf("expected the aggregate rate to stay under %d bytes per second, actual %.0f", bytesPerSecond, measuredRate)
+	}
+}
+
+func TestGetTransport_BandwidthLimit(t *testing.T) {
+	const bytesPerSecond = 1000000
+	const transferSize = 250000
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.Copy(ioutil.Discard, r.Body); err != nil {
+			t.Error("read request:", err)
+		}
+
+		w.Write(make([]byte, transferSize))
+	}))
+	defer server.Close()
+
+	registry, err := name.NewRegistry("mycompany.com")
+	if err != nil {
+		t.Fatal("new registry:", err)
+	}
+
+	client := Client{bandwidthLimiter: newBandwidthLimiter(bytesPerSecond)}
+	httpClient := http.Client{Transport: client.getTransport(registry)}
+
+	start := time.Now()
+	response, err := httpClient.Post(server.URL, "application/octet-stream", bytes.NewReader(make([]byte, transferSize)))
+	if err != nil {
+		t.Fatal("post:", err)
+	}
+	defer response.Body.Close()
+
+	if _, err := io.Copy(ioutil.Discard, response.Body); err != nil {
+		t.Fatal("read response:", err)
+	}
+
+	// Both the upload of the request and the download of the response are limited
+	measuredRate := float64(2*transferSize) / time.Since(start).Seconds()
+	if measuredRate > bytesPerSecond {
+		t.Errorf("expected the rate to stay under %d bytes per second, actual %.0f", bytesPerSecond, measuredRate)
+	}
+}
@@ -0,0 +1,220 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/cache"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// layerCache is an on-disk cache of compressed image layers, stored by their digest, so that
+// layers that were already read from a registry are read from the disk instead. When the size
+// of the cache exceeds its maximum size, the least recently used layers are evicted.
+// It is safe for concurrent use by images that are copied at the same time.
+type layerCache struct {
+	mutex   sync.Mutex
+	dir     string
+	maxSize int64
+}
+
+func newLayerCache(dir string, maxSize int64) (*layerCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+
+	return &layerCache{dir: dir, maxSize: maxSize}, nil
+}
+
+func (c *layerCache) path(digest v1.Hash) string {
+	return filepath.Join(c.dir, digest.Algorithm+"-"+digest.Hex)
+}
+
+// Get returns the cached layer with the digest, and marks it as the most recently used layer
+func (c *layerCache) Get(digest v1.Hash) (v1.Layer, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	layerPath := c.path(digest)
+	if _, err := os.Stat(layerPath); os.IsNotExist(err) {
+		return nil, cache.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("stat cached layer: %w", err)
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(layerPath, now, now); err != nil {
+		return nil, fmt.Errorf("touch cached layer: %w", err)
+	}
+
+	layer, err := tarball.LayerFromFile(layerPath)
+	if err != nil {
+		return nil, fmt.Errorf("cached layer: %w", err)
+	}
+
+	return layer, nil
+}
+
+// Put returns the layer, which writes the layer to the cache once it has been read completely
+func (c *layerCache) Put(layer v1.Layer) (v1.Layer, error) {
+	digest, err := layer.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("layer digest: %w", err)
+	}
+
+	return &cachingLayer{Layer: layer, digest: digest, cache: c}, nil
+}
+
+// Delete removes the layer with the digest from the cache
+func (c *layerCache) Delete(digest v1.Hash) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := os.Remove(c.path(digest))
+	if os.IsNotExist(err) {
+		return cache.ErrNotFound
+	}
+
+	return err
+}
+
+// add moves the completely written layer file into the cache and evicts
+// the least recently used layers when the cache exceeds its maximum size
+func (c *layerCache) add(digest v1.Hash, layerFile string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := os.Rename(layerFile, c.path(digest)); err != nil {
+		return fmt.Errorf("rename cached layer: %w", err)
+	}
+
+	return c.evict()
+}
+
+func (c *layerCache) evict() error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("read cache dir: %w", err)
+	}
+
+	var layers []os.FileInfo
+	var size int64
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) == ".tmp" {
+			continue
+		}
+
+		layers = append(layers, file)
+		size += file.Size()
+	}
+
+	sort.Slice(layers, func(i, j int) bool {
+		return layers[i].ModTime().Before(layers[j].ModTime())
+	})
+
+	for _, layer := range layers {
+		if size <= c.maxSize {
+			break
+		}
+
+		if err := os.Remove(filepath.Join(c.dir, layer.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("evict cached layer: %w", err)
+		}
+
+		size -= layer.Size()
+	}
+
+	return nil
+}
+
+// cachingLayer is a layer that is written to the cache as its compressed contents are read
+type cachingLayer struct {
+	v1.Layer
+	digest v1.Hash
+	cache  *layerCache
+}
+
+func (l *cachingLayer) Compressed() (io.ReadCloser, error) {
+	contents, err := l.Layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := ioutil.TempFile(l.cache.dir, "layer-*.tmp")
+	if err != nil {
+		contents.Close()
+		return nil, fmt.Errorf("create cache file: %w", err)
+	}
+
+	reader := cachingReader{
+		contents: contents,
+		file:     file,
+		hash:     sha256.New(),
+		digest:   l.digest,
+		cache:    l.cache,
+	}
+
+	return &reader, nil
+}
+
+// cachingReader writes the contents of a layer to a file as it is read. The file is only added to
+// the cache when the layer was read completely and matches its digest, so that the cache never
+// contains incomplete layers.
+type cachingReader struct {
+	contents io.ReadCloser
+	file     *os.File
+	hash     hash.Hash
+	digest   v1.Hash
+	cache    *layerCache
+	complete bool
+}
+
+func (r *cachingReader) Read(buffer []byte) (int, error) {
+	count, err := r.contents.Read(buffer)
+	if count > 0 {
+		if _, writeErr := r.file.Write(buffer[:count]); writeErr != nil {
+			return count, fmt.Errorf("write cache file: %w", writeErr)
+		}
+
+		r.hash.Write(buffer[:count])
+	}
+
+	if err == io.EOF {
+		r.complete = true
+	}
+
+	return count, err
+}
+
+func (r *cachingReader) Close() error {
+	closeErr := r.contents.Close()
+	if err := r.file.Close(); err != nil {
+		os.Remove(r.file.Name())
+		return fmt.Errorf("close cache file: %w", err)
+	}
+
+	if !r.complete || r.digest.Algorithm != "sha256" || hex.EncodeToString(r.hash.Sum(nil)) != r.digest.Hex {
+		os.Remove(r.file.Name())
+		return closeErr
+	}
+
+	if err := r.cache.add(r.digest, r.file.Name()); err != nil {
+		return fmt.Errorf("add cached layer: %w", err)
+	}
+
+	return closeErr
+}
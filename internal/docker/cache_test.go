@@ -0,0 +1,259 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/cache"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	log "github.com/sirupsen/logrus"
+)
+
+func newTestLayerCache(t *testing.T, maxSize int64) *layerCache {
+	cacheDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+
+	layerCache, err := newLayerCache(cacheDir, maxSize)
+	if err != nil {
+		t.Fatal("new layer cache:", err)
+	}
+
+	return layerCache
+}
+
+// putLayer puts the layer in the cache and reads it completely, so that it is cached
+func putLayer(t *testing.T, layerCache *layerCache, layer v1.Layer) v1.Hash {
+	cachingLayer, err := layerCache.Put(layer)
+	if err != nil {
+		t.Fatal("put layer:", err)
+	}
+
+	contents, err := cachingLayer.Compressed()
+	if err != nil {
+		t.Fatal("compressed:", err)
+	}
+
+	if _, err := io.Copy(ioutil.Discard, contents); err != nil {
+		t.Fatal("read layer:", err)
+	}
+
+	if err := contents.Close(); err != nil {
+		t.Fatal("close layer:", err)
+	}
+
+	digest, err := layer.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	return digest
+}
+
+func TestLayerCache_HitAndMiss(t *testing.T) {
+	layerCache := newTestLayerCache(t, 0)
+	defer os.RemoveAll(layerCache.dir)
+
+	layer, err := random.Layer(1024, "")
+	if err != nil {
+		t.Fatal("random layer:", err)
+	}
+
+	digest, err := layer.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	if _, err := layerCache.Get(digest); err != cache.ErrNotFound {
+		t.Errorf("expected a miss before the layer is cached, actual %v", err)
+	}
+
+	cachingLayer, err := layerCache.Put(layer)
+	if err != nil {
+		t.Fatal("put layer:", err)
+	}
+
+	partialContents, err := cachingLayer.Compressed()
+	if err != nil {
+		t.Fatal("compressed:", err)
+	}
+
+	if _, err := partialContents.Read(make([]byte, 10)); err != nil {
+		t.Fatal("read layer:", err)
+	}
+	partialContents.Close()
+
+	if _, err := layerCache.Get(digest); err != cache.ErrNotFound {
+		t.Errorf("expected a miss for a layer that was not read completely, actual %v", err)
+	}
+
+	putLayer(t, layerCache, layer)
+
+	cachedLayer, err := layerCache.Get(digest)
+	if err != nil {
+		t.Fatal("expected a hit once the layer is cached:", err)
+	}
+
+	cachedDigest, err := cachedLayer.Digest()
+	if err != nil {
+		t.Fatal("cached digest:", err)
+	}
+
+	if cachedDigest != digest {
+		t.Errorf("expected the cached layer to have the digest %s, actual %s", digest, cachedDigest)
+	}
+
+	files, err := ioutil.ReadDir(layerCache.dir)
+	if err != nil {
+		t.Fatal("read cache dir:", err)
+	}
+
+	if len(files) != 1 {
+		t.Errorf("expected only the complete layer in the cache, actual %v files", len(files))
+	}
+}
+
+func TestLayerCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	var layers []v1.Layer
+	for i := 0; i < 3; i++ {
+		layer, err := random.Layer(1024, "")
+		if err != nil {
+			t.Fatal("random layer:", err)
+		}
+
+		layers = append(layers, layer)
+	}
+
+	size, err := layers[0].Size()
+	if err != nil {
+		t.Fatal("size:", err)
+	}
+
+	// The cache can hold two of the layers, which have about the same size
+	layerCache := newTestLayerCache(t, size*2+size/2)
+	defer os.RemoveAll(layerCache.dir)
+
+	first := putLayer(t, layerCache, layers[0])
+	second := putLayer(t, layerCache, layers[1])
+
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(layerCache.path(first), past, past); err != nil {
+		t.Fatal("chtimes:", err)
+	}
+
+	if err := os.Chtimes(layerCache.path(second), past.Add(time.Minute), past.Add(time.Minute)); err != nil {
+		t.Fatal("chtimes:", err)
+	}
+
+	// Using the first layer makes the second layer the least recently used
+	if _, err := layerCache.Get(first); err != nil {
+		t.Fatal("get first layer:", err)
+	}
+
+	third := putLayer(t, layerCache, layers[2])
+
+	if _, err := layerCache.Get(second); err != cache.ErrNotFound {
+		t.Errorf("expected the least recently used layer to be evicted, actual %v", err)
+	}
+
+	for _, digest := range []v1.Hash{first, third} {
+		if _, err := layerCache.Get(digest); err != nil {
+			t.Errorf("expected %s to be kept in the cache, actual %v", digest, err)
+		}
+	}
+}
+
+// blobRecorder records the layers that are downloaded from a registry, which
+// are the blobs other than the config of the image
+type blobRecorder struct {
+	mutex     sync.Mutex
+	downloads int
+	config    string
+	handler   http.Handler
+}
+
+func (b *blobRecorder) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method == http.MethodGet && strings.Contains(request.URL.Path, "/blobs/sha256:") && !strings.HasSuffix(request.URL.Path, b.config) {
+		b.mutex.Lock()
+		b.downloads++
+		b.mutex.Unlock()
+	}
+
+	b.handler.ServeHTTP(writer, request)
+}
+
+func TestCopyImageAndWait_LayerCache(t *testing.T) {
+	sourceRecorder := blobRecorder{handler: registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0)))}
+	sourceServer := httptest.NewServer(&sourceRecorder)
+	defer sourceServer.Close()
+
+	var targetHosts []string
+	for i := 0; i < 2; i++ {
+		targetServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+		defer targetServer.Close()
+
+		targetHosts = append(targetHosts, strings.TrimPrefix(targetServer.URL, "http://"))
+	}
+
+	sourceImage := strings.TrimPrefix(sourceServer.URL, "http://") + "/source/app:v1.0.0"
+	image, err := random.Image(1024, 3)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+	writeImage(t, sourceImage, image)
+
+	configName, err := image.ConfigName()
+	if err != nil {
+		t.Fatal("config name:", err)
+	}
+	sourceRecorder.config = configName.String()
+
+	layerCache := newTestLayerCache(t, 0)
+	defer os.RemoveAll(layerCache.dir)
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := Client{
+		Logger:      logger,
+		RetryPolicy: RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+		layerCache:  layerCache,
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), sourceImage, targetHosts[0]+"/target/app:v1.0.0", "", ""); err != nil {
+		t.Fatal("copy image:", err)
+	}
+
+	if sourceRecorder.downloads != 3 {
+		t.Errorf("expected the 3 layers to be downloaded, actual %v downloads", sourceRecorder.downloads)
+	}
+
+	cachedLayers, err := filepath.Glob(filepath.Join(layerCache.dir, "sha256-*"))
+	if err != nil {
+		t.Fatal("glob:", err)
+	}
+
+	if len(cachedLayers) != 3 {
+		t.Errorf("expected the 3 layers to be cached, actual %v", cachedLayers)
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), sourceImage, targetHosts[1]+"/target/app:v1.0.0", "", ""); err != nil {
+		t.Fatal("copy cached image:", err)
+	}
+
+	if sourceRecorder.downloads != 3 {
+		t.Errorf("expected the cached layers to not be downloaded again, actual %v downloads", sourceRecorder.downloads)
+	}
+}
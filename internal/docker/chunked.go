@@ -0,0 +1,386 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// WithChunkSize sets the size in bytes of the chunks that layers larger than the size are uploaded
+// in when they are copied between registries. Layers are uploaded in a single request when the size is zero.
+func WithChunkSize(size int64) ClientOption {
+	return func(c *Client) {
+		c.ChunkSize = size
+	}
+}
+
+// chunkedUploads records the location of each upload of a blob that is uploaded in chunks,
+// so that an upload that fails is resumed by the next attempt rather than started again.
+// It is safe for concurrent use by images that are copied at the same time.
+type chunkedUploads struct {
+	mutex     sync.Mutex
+	locations map[string]string
+}
+
+func newChunkedUploads() *chunkedUploads {
+	return &chunkedUploads{
+		locations: make(map[string]string),
+	}
+}
+
+func (u *chunkedUploads) get(repository name.Repository, digest v1.Hash) string {
+	if u == nil {
+		return ""
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.locations[repository.Name()+"@"+digest.String()]
+}
+
+func (u *chunkedUploads) set(repository name.Repository, digest v1.Hash, location string) {
+	if u == nil {
+		return
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.locations[repository.Name()+"@"+digest.String()] = location
+}
+
+func (u *chunkedUploads) remove(repository name.Repository, digest v1.Hash) {
+	if u == nil {
+		return
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	delete(u.locations, repository.Name()+"@"+digest.String())
+}
+
+// uploadLargeLayers uploads each layer of the image that is larger than the chunk size to the
+// target repository in chunks, before the image is written, so that the layers already exist when
+// the image is written. Layers that exist at the target, or that are mounted from another repository
+// of the target registry, are not uploaded.
+func (c Client) uploadLargeLayers(ctx context.Context, target name.Repository, auth authn.Authenticator, image v1.Image) error {
+	if c.ChunkSize <= 0 {
+		return nil
+	}
+
+	layers, err := image.Layers()
+	if err != nil {
+		return fmt.Errorf("layers: %w", err)
+	}
+
+	var httpClient *http.Client
+	for _, layer := range layers {
+		size, err := layer.Size()
+		if err != nil {
+			return fmt.Errorf("layer size: %w", err)
+		}
+
+		if size <= c.ChunkSize {
+			continue
+		}
+
+		digest, err := layer.Digest()
+		if err != nil {
+			return fmt.Errorf("layer digest: %w", err)
+		}
+
+		if _, exists := c.blobMounts.find(target, digest); exists {
+			continue
+		}
+
+		if httpClient == nil {
+			scopes := []string{target.Scope(transport.PushScope)}
+			registryTransport, err := transport.New(target.Registry, auth, c.getContextTransport(ctx, target.Registry), scopes)
+			if err != nil {
+				return fmt.Errorf("new transport: %w", err)
+			}
+
+			httpClient = &http.Client{Transport: registryTransport}
+		}
+
+		exists, err := blobExists(httpClient, target, digest)
+		if err != nil {
+			return fmt.Errorf("check blob %s exists: %w", digest, err)
+		}
+
+		if exists {
+			continue
+		}
+
+		if err := c.uploadLayerInChunks(httpClient, target, digest, layer); err != nil {
+			return fmt.Errorf("upload layer %s: %w", digest, err)
+		}
+	}
+
+	return nil
+}
+
+// uploadLayerInChunks uploads the layer in chunks of the chunk size. An upload of the layer that was
+// started by a previous attempt is resumed from the offset that the registry acknowledged, when the
+// registry still has the upload, so that the chunks before the offset are not uploaded again.
+func (c Client) uploadLayerInChunks(httpClient *http.Client, target name.Repository, digest v1.Hash, layer v1.Layer) error {
+	var offset int64
+	location := c.chunkedUploads.get(target, digest)
+	if location != "" {
+		var err error
+		offset, err = getUploadOffset(httpClient, location)
+		if err != nil {
+			c.Logger.Debugf("[CHUNK] Unable to resume the upload of %s, starting a new upload: %v", digest, err)
+			location = ""
+			offset = 0
+		}
+	}
+
+	if location == "" {
+		var err error
+		location, err = startUpload(httpClient, target)
+		if err != nil {
+			return fmt.Errorf("start upload: %w", err)
+		}
+
+		c.chunkedUploads.set(target, digest, location)
+	}
+
+	if offset > 0 {
+		c.Logger.Debugf("[CHUNK] Resuming the upload of %s from byte %d", digest, offset)
+	}
+
+	blob, err := layer.Compressed()
+	if err != nil {
+		return fmt.Errorf("compressed: %w", err)
+	}
+	defer blob.Close()
+
+	// The bytes that were already acknowledged are read from the layer, but are not uploaded
+	if _, err := io.CopyN(ioutil.Discard, blob, offset); err != nil {
+		return fmt.Errorf("skip uploaded bytes: %w", err)
+	}
+
+	chunk := make([]byte, c.ChunkSize)
+	for {
+		length, readErr := io.ReadFull(blob, chunk)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return fmt.Errorf("read layer: %w", readErr)
+		}
+
+		if length > 0 {
+			location, offset, err = uploadChunk(httpClient, location, offset, chunk[:length])
+			if err != nil {
+				return fmt.Errorf("upload chunk: %w", err)
+			}
+
+			c.chunkedUploads.set(target, digest, location)
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	if err := commitUpload(httpClient, location, digest); err != nil {
+		return fmt.Errorf("commit upload: %w", err)
+	}
+
+	c.chunkedUploads.remove(target, digest)
+
+	return nil
+}
+
+// blobExists returns true when the blob of the digest exists in the repository
+func blobExists(httpClient *http.Client, repository name.Repository, digest v1.Hash) (bool, error) {
+	blobURL := url.URL{
+		Scheme: repository.Registry.Scheme(),
+		Host:   repository.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/blobs/%s", repository.RepositoryStr(), digest),
+	}
+
+	response, err := httpClient.Head(blobURL.String())
+	if err != nil {
+		return false, fmt.Errorf("head blob: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if err := transport.CheckError(response, http.StatusOK); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// startUpload starts an upload of a blob to the repository, and returns the location of the upload
+func startUpload(httpClient *http.Client, repository name.Repository) (string, error) {
+	uploadURL := url.URL{
+		Scheme: repository.Registry.Scheme(),
+		Host:   repository.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/blobs/uploads/", repository.RepositoryStr()),
+	}
+
+	response, err := httpClient.Post(uploadURL.String(), "application/octet-stream", nil)
+	if err != nil {
+		return "", fmt.Errorf("post upload: %w", err)
+	}
+	defer response.Body.Close()
+
+	if err := transport.CheckError(response, http.StatusAccepted); err != nil {
+		return "", err
+	}
+
+	return getUploadLocation(response, "")
+}
+
+// getUploadOffset returns the offset that the upload at the location is resumed from,
+// which is the byte after the last byte that the registry has acknowledged
+func getUploadOffset(httpClient *http.Client, location string) (int64, error) {
+	response, err := httpClient.Get(location)
+	if err != nil {
+		return 0, fmt.Errorf("get upload status: %w", err)
+	}
+	defer response.Body.Close()
+
+	if err := transport.CheckError(response, http.StatusNoContent); err != nil {
+		return 0, err
+	}
+
+	return parseUploadRange(response.Header.Get("Range"))
+}
+
+// uploadChunk uploads the chunk that starts at the offset, and returns the location
+// of the upload and the offset of the next chunk as acknowledged by the registry
+func uploadChunk(httpClient *http.Client, location string, offset int64, chunk []byte) (string, int64, error) {
+	request, err := http.NewRequest(http.MethodPatch, location, bytes.NewReader(chunk))
+	if err != nil {
+		return "", 0, fmt.Errorf("new request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("Content-Range", fmt.Sprintf("%d-%d", offset, offset+int64(len(chunk))-1))
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", 0, fmt.Errorf("patch upload: %w", err)
+	}
+	defer response.Body.Close()
+
+	if err := transport.CheckError(response, http.StatusAccepted, http.StatusNoContent); err != nil {
+		return "", 0, err
+	}
+
+	nextLocation, err := getUploadLocation(response, location)
+	if err != nil {
+		return "", 0, fmt.Errorf("get upload location: %w", err)
+	}
+
+	// Registries that do not return the range of the upload have acknowledged the whole chunk
+	if response.Header.Get("Range") == "" {
+		return nextLocation, offset + int64(len(chunk)), nil
+	}
+
+	nextOffset, err := parseUploadRange(response.Header.Get("Range"))
+	if err != nil {
+		return "", 0, fmt.Errorf("parse range: %w", err)
+	}
+
+	if nextOffset != offset+int64(len(chunk)) {
+		return "", 0, fmt.Errorf("registry acknowledged %d bytes of the upload, expected %d", nextOffset, offset+int64(len(chunk)))
+	}
+
+	return nextLocation, nextOffset, nil
+}
+
+// commitUpload completes the upload at the location as the blob of the digest
+func commitUpload(httpClient *http.Client, location string, digest v1.Hash) error {
+	commitURL, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("parse location: %w", err)
+	}
+
+	query := commitURL.Query()
+	query.Set("digest", digest.String())
+	commitURL.RawQuery = query.Encode()
+
+	request, err := http.NewRequest(http.MethodPut, commitURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("put upload: %w", err)
+	}
+	defer response.Body.Close()
+
+	return transport.CheckError(response, http.StatusCreated)
+}
+
+// getUploadLocation returns the absolute location of the upload from the Location header of the
+// response, or the current location of the upload when the registry does not return a new one
+func getUploadLocation(response *http.Response, location string) (string, error) {
+	header := response.Header.Get("Location")
+	if header == "" {
+		if location == "" {
+			return "", fmt.Errorf("registry did not return the location of the upload")
+		}
+
+		return location, nil
+	}
+
+	headerURL, err := url.Parse(header)
+	if err != nil {
+		return "", fmt.Errorf("parse location: %w", err)
+	}
+
+	return response.Request.URL.ResolveReference(headerURL).String(), nil
+}
+
+// parseUploadRange returns the offset after the range of bytes of an upload that the registry has
+// acknowledged (e.g. 0-1023 is an offset of 1024). Registries return a range of 0-0 for an upload
+// without any bytes, which is an offset of zero.
+func parseUploadRange(uploadRange string) (int64, error) {
+	rangeTokens := strings.Split(strings.TrimPrefix(uploadRange, "bytes="), "-")
+	if len(rangeTokens) != 2 {
+		return 0, fmt.Errorf("range %q is not in the format start-end", uploadRange)
+	}
+
+	start, err := strconv.ParseInt(rangeTokens[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse start of range %q: %w", uploadRange, err)
+	}
+
+	end, err := strconv.ParseInt(rangeTokens[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse end of range %q: %w", uploadRange, err)
+	}
+
+	if start != 0 {
+		return 0, fmt.Errorf("range %q does not start at the beginning of the upload", uploadRange)
+	}
+
+	if end == 0 {
+		return 0, nil
+	}
+
+	return end + 1, nil
+}
@@ -0,0 +1,349 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	v1types "github.com/google/go-containerregistry/pkg/v1/types"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestParseUploadRange(t *testing.T) {
+	testCases := []struct {
+		uploadRange    string
+		expectedOffset int64
+		isValid        bool
+	}{
+		{uploadRange: "0-0", expectedOffset: 0, isValid: true},
+		{uploadRange: "0-1023", expectedOffset: 1024, isValid: true},
+		{uploadRange: "bytes=0-1023", expectedOffset: 1024, isValid: true},
+		{uploadRange: "", isValid: false},
+		{uploadRange: "1024", isValid: false},
+		{uploadRange: "512-1023", isValid: false},
+		{uploadRange: "0-abc", isValid: false},
+	}
+
+	for _, testCase := range testCases {
+		offset, err := parseUploadRange(testCase.uploadRange)
+		if testCase.isValid && err != nil {
+			t.Errorf("expected range %q to be valid, actual %v", testCase.uploadRange, err)
+			continue
+		}
+
+		if !testCase.isValid {
+			if err == nil {
+				t.Errorf("expected range %q to be invalid", testCase.uploadRange)
+			}
+			continue
+		}
+
+		if offset != testCase.expectedOffset {
+			t.Errorf("expected offset of range %q to be %d, actual %d", testCase.uploadRange, testCase.expectedOffset, offset)
+		}
+	}
+}
+
+// uploadServer is a registry that only implements the chunked upload of blobs to the app repository.
+// The chunk at failOffset is rejected once, as though the connection to the registry was lost.
+type uploadServer struct {
+	mutex      sync.Mutex
+	uploads    map[string][]byte
+	blobs      map[string][]byte
+	nextUpload int
+	failOffset int
+	failed     bool
+
+	// ranges are the Content-Range of every chunk that was uploaded, including rejected chunks
+	ranges []string
+}
+
+func newUploadServer(failOffset int) *uploadServer {
+	return &uploadServer{
+		uploads:    make(map[string][]byte),
+		blobs:      make(map[string][]byte),
+		failOffset: failOffset,
+	}
+}
+
+// expire forgets every upload that is in progress, as registries do after a while
+func (s *uploadServer) expire() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.uploads = make(map[string][]byte)
+}
+
+func (s *uploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	const uploadsPath = "/v2/app/blobs/uploads/"
+	switch {
+	case r.URL.Path == "/v2/":
+		return
+
+	case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/v2/app/blobs/sha256:"):
+		if _, exists := s.blobs[strings.TrimPrefix(r.URL.Path, "/v2/app/blobs/")]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+	case r.Method == http.MethodPost && r.URL.Path == uploadsPath:
+		s.nextUpload++
+		id := fmt.Sprint(s.nextUpload)
+		s.uploads[id] = []byte{}
+
+		w.Header().Set("Location", uploadsPath+id)
+		w.Header().Set("Range", "0-0")
+		w.WriteHeader(http.StatusAccepted)
+
+	case strings.HasPrefix(r.URL.Path, uploadsPath):
+		id := strings.TrimPrefix(r.URL.Path, uploadsPath)
+		contents, exists := s.uploads[id]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Range", getTestUploadRange(contents))
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodPatch:
+			s.ranges = append(s.ranges, r.Header.Get("Content-Range"))
+			chunk, _ := ioutil.ReadAll(r.Body)
+
+			var start int
+			fmt.Sscanf(r.Header.Get("Content-Range"), "%d-", &start)
+			if start != len(contents) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+
+			if start == s.failOffset && !s.failed {
+				s.failed = true
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			s.uploads[id] = append(contents, chunk...)
+			w.Header().Set("Location", uploadsPath+id)
+			w.Header().Set("Range", getTestUploadRange(s.uploads[id]))
+			w.WriteHeader(http.StatusAccepted)
+
+		case http.MethodPut:
+			sum := sha256.Sum256(contents)
+			digest := "sha256:" + hex.EncodeToString(sum[:])
+			if digest != r.URL.Query().Get("digest") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			s.blobs[digest] = contents
+			delete(s.uploads, id)
+			w.WriteHeader(http.StatusCreated)
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func getTestUploadRange(contents []byte) string {
+	if len(contents) == 0 {
+		return "0-0"
+	}
+
+	return fmt.Sprintf("0-%d", len(contents)-1)
+}
+
+func newTestChunkedLayer(t *testing.T) (v1.Layer, []byte) {
+	layer, err := random.Layer(10, v1types.DockerLayer)
+	if err != nil {
+		t.Fatal("random layer:", err)
+	}
+
+	compressed, err := layer.Compressed()
+	if err != nil {
+		t.Fatal("compressed:", err)
+	}
+	defer compressed.Close()
+
+	contents, err := ioutil.ReadAll(compressed)
+	if err != nil {
+		t.Fatal("read layer:", err)
+	}
+
+	return layer, contents
+}
+
+func TestUploadLayerInChunks_Resume(t *testing.T) {
+	const chunkSize = 16
+
+	layer, contents := newTestChunkedLayer(t)
+	digest, err := layer.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	upload := newUploadServer(chunkSize)
+	server := httptest.NewServer(upload)
+	defer server.Close()
+
+	repository, err := name.NewRepository(strings.TrimPrefix(server.URL, "http://") + "/app")
+	if err != nil {
+		t.Fatal("new repository:", err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	client := Client{Logger: logger, ChunkSize: chunkSize, chunkedUploads: newChunkedUploads()}
+	if err := client.uploadLayerInChunks(server.Client(), repository, digest, layer); err == nil {
+		t.Fatal("expected the first attempt to fail")
+	}
+
+	if err := client.uploadLayerInChunks(server.Client(), repository, digest, layer); err != nil {
+		t.Fatal("resume upload:", err)
+	}
+
+	if !bytes.Equal(upload.blobs[digest.String()], contents) {
+		t.Error("expected the uploaded blob to be the layer")
+	}
+
+	// The first chunk was acknowledged, so the retry resumes from the second chunk
+	var expectedRanges []string
+	expectedRanges = append(expectedRanges, fmt.Sprintf("0-%d", chunkSize-1), fmt.Sprintf("%d-%d", chunkSize, 2*chunkSize-1))
+	for start := chunkSize; start < len(contents); start += chunkSize {
+		end := start + chunkSize - 1
+		if end >= len(contents) {
+			end = len(contents) - 1
+		}
+
+		expectedRanges = append(expectedRanges, fmt.Sprintf("%d-%d", start, end))
+	}
+
+	if !reflect.DeepEqual(upload.ranges, expectedRanges) {
+		t.Errorf("expected chunks %v, actual %v", expectedRanges, upload.ranges)
+	}
+
+	if location := client.chunkedUploads.get(repository, digest); location != "" {
+		t.Errorf("expected the completed upload to be removed, actual %s", location)
+	}
+}
+
+func TestUploadLayerInChunks_ExpiredUpload(t *testing.T) {
+	const chunkSize = 16
+
+	layer, contents := newTestChunkedLayer(t)
+	digest, err := layer.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	upload := newUploadServer(chunkSize)
+	server := httptest.NewServer(upload)
+	defer server.Close()
+
+	repository, err := name.NewRepository(strings.TrimPrefix(server.URL, "http://") + "/app")
+	if err != nil {
+		t.Fatal("new repository:", err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	client := Client{Logger: logger, ChunkSize: chunkSize, chunkedUploads: newChunkedUploads()}
+	if err := client.uploadLayerInChunks(server.Client(), repository, digest, layer); err == nil {
+		t.Fatal("expected the first attempt to fail")
+	}
+
+	upload.expire()
+	if err := client.uploadLayerInChunks(server.Client(), repository, digest, layer); err != nil {
+		t.Fatal("restart upload:", err)
+	}
+
+	if !bytes.Equal(upload.blobs[digest.String()], contents) {
+		t.Error("expected the uploaded blob to be the layer")
+	}
+
+	if upload.nextUpload != 2 {
+		t.Errorf("expected a new upload to be started, actual %d uploads", upload.nextUpload)
+	}
+
+	if upload.ranges[2] != fmt.Sprintf("0-%d", chunkSize-1) {
+		t.Errorf("expected the new upload to start from the beginning, actual %s", upload.ranges[2])
+	}
+}
+
+func TestCopyImageAndWait_ChunkSize(t *testing.T) {
+	sourceServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer sourceServer.Close()
+
+	targetServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer targetServer.Close()
+
+	sourceImage := strings.TrimPrefix(sourceServer.URL, "http://") + "/app:v1.0.0"
+	targetImage := strings.TrimPrefix(targetServer.URL, "http://") + "/mirror/app:v1.0.0"
+
+	contents, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	writeImage(t, sourceImage, contents)
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	client := Client{
+		Logger:         logger,
+		RetryPolicy:    RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+		ChunkSize:      256,
+		chunkedUploads: newChunkedUploads(),
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), sourceImage, targetImage, "", ""); err != nil {
+		t.Fatal("copy image:", err)
+	}
+
+	targetReference, err := name.ParseReference(targetImage)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	copied, err := remote.Image(targetReference)
+	if err != nil {
+		t.Fatal("get copied image:", err)
+	}
+
+	expectedDigest, err := contents.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	actualDigest, err := copied.Digest()
+	if err != nil {
+		t.Fatal("copied digest:", err)
+	}
+
+	if actualDigest != expectedDigest {
+		t.Errorf("expected digest %s, actual %s", expectedDigest, actualDigest)
+	}
+}
@@ -2,12 +2,16 @@ package docker
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
 	"time"
 
-	"github.com/avast/retry-go"
 	"github.com/docker/docker/client"
 	log "github.com/sirupsen/logrus"
 )
@@ -16,26 +20,245 @@ import (
 type Client struct {
 	DockerClient *client.Client
 	Logger       *log.Logger
+	RetryPolicy  RetryPolicy
+	Timeout      time.Duration
+
+	// Platform is the platform of the images that are pulled from multi-arch images.
+	// When not set, the platform of the Docker daemon is pulled.
+	Platform Platform
+
+	// OnStatus is called for every status returned while pulling or pushing an image.
+	// When not set, the status is logged periodically.
+	OnStatus StatusCallback
+
+	// InsecureRegistries are the registry hosts that are allowed to use plain HTTP
+	// and whose TLS certificates are not verified
+	InsecureRegistries []string
+
+	// CACertificates are the paths to PEM files of the certificate authorities
+	// that are trusted by registries, in addition to the system certificates
+	CACertificates []string
+
+	// ClientCertificates are the certificates presented to registries that require mutual TLS
+	ClientCertificates []ClientCertificate
+
+	// RegistryMirrors are the hosts of the mirrors that images are pulled through, such as
+	// pull-through caches, by the host of the upstream registry of the images (e.g. docker.io).
+	// Images are only pulled through their mirror, and are pushed to their target as usual.
+	RegistryMirrors map[string]string
+
+	// Labels are added to the config, and Annotations are added to the manifest, of the images
+	// that are copied between registries. Images pushed through the Docker daemon are not changed.
+	Labels      map[string]string
+	Annotations map[string]string
+
+	// Proxy is the URL of the proxy that requests to registries are sent through, and NoProxy are
+	// the hosts that are connected to directly. They override the HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+	// environment variables. Images pulled or pushed through the Docker daemon use the proxy of the daemon.
+	Proxy   string
+	NoProxy []string
+
+	// ChunkSize is the size in bytes of the chunks that layers larger than the size are uploaded in
+	// when they are copied between registries, so that a failed upload is resumed by the next attempt
+	ChunkSize int64
+
+	// BandwidthLimit is the maximum number of bytes per second that are transferred to and from registries,
+	// across every image. Images pulled or pushed through the Docker daemon are not limited.
+	BandwidthLimit int64
+
+	// CacheDir is the directory of the cache of layers copied between registries, when set.
+	// When CacheMaxSize is set, the least recently used layers are evicted to stay within the size.
+	CacheDir     string
+	CacheMaxSize int64
+
+	rootCAs            *x509.CertPool
+	clientCertificates map[string]tls.Certificate
+	blobMounts         *blobMounts
+	chunkedUploads     *chunkedUploads
+	harborRegistries   *harborRegistries
+	bandwidthLimiter   *bandwidthLimiter
+	layerCache         *layerCache
+
+	// Stats records the bytes transferred by every pull and push, when set
+	Stats *TransferStats
+
+	// Verbose logs every status returned while pulling or pushing an image
+	// along with its progress details, rather than logging the status periodically
+	Verbose bool
 }
 
-// NewClient returns a new Docker client
-func NewClient(logger *log.Logger) (Client, error) {
-	retry.DefaultDelay = 5 * time.Second
-	retry.DefaultAttempts = 3
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithRetryPolicy sets the policy used to retry pulls and pushes
+func WithRetryPolicy(retryPolicy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.RetryPolicy = retryPolicy
+	}
+}
+
+// WithTimeout sets the maximum amount of time a pull or push of a single image can take.
+// A timeout of zero means there is no timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.Timeout = timeout
+	}
+}
+
+// WithPlatform sets the platform of the images that are pulled from multi-arch images
+func WithPlatform(platform Platform) ClientOption {
+	return func(c *Client) {
+		c.Platform = platform
+	}
+}
+
+// WithInsecureRegistries sets the registry hosts (e.g. registry.lan:5000) that are allowed
+// to use plain HTTP and whose TLS certificates are not verified
+func WithInsecureRegistries(registries []string) ClientOption {
+	return func(c *Client) {
+		c.InsecureRegistries = registries
+	}
+}
+
+// WithCACertificates sets the paths to PEM files of the certificate authorities that
+// are trusted by registries, such as the internal certificate authority of a private registry
+func WithCACertificates(paths []string) ClientOption {
+	return func(c *Client) {
+		c.CACertificates = paths
+	}
+}
+
+// WithClientCertificates sets the certificates presented to registries that require mutual TLS
+func WithClientCertificates(certificates []ClientCertificate) ClientOption {
+	return func(c *Client) {
+		c.ClientCertificates = certificates
+	}
+}
+
+// WithRegistryMirrors sets the hosts of the mirrors that images are pulled through,
+// by the host of the upstream registry of the images (e.g. docker.io=mirror.internal)
+func WithRegistryMirrors(mirrors map[string]string) ClientOption {
+	return func(c *Client) {
+		c.RegistryMirrors = mirrors
+	}
+}
+
+// WithLabels sets the labels that are added to the config of the images that are copied between registries
+func WithLabels(labels map[string]string) ClientOption {
+	return func(c *Client) {
+		c.Labels = labels
+	}
+}
+
+// WithAnnotations sets the annotations that are added to the manifest of the images that are copied between registries
+func WithAnnotations(annotations map[string]string) ClientOption {
+	return func(c *Client) {
+		c.Annotations = annotations
+	}
+}
+
+// WithLayerCache sets the directory and maximum size in bytes of the cache of layers
+// copied between registries. The size of the cache is not limited when the size is zero.
+func WithLayerCache(dir string, maxSize int64) ClientOption {
+	return func(c *Client) {
+		c.CacheDir = dir
+		c.CacheMaxSize = maxSize
+	}
+}
+
+// WithTransferStats sets the stats that record the bytes transferred by every pull and push
+func WithTransferStats(stats *TransferStats) ClientOption {
+	return func(c *Client) {
+		c.Stats = stats
+	}
+}
 
+// WithVerbose sets whether every status returned while pulling or pushing an image is logged
+func WithVerbose(verbose bool) ClientOption {
+	return func(c *Client) {
+		c.Verbose = verbose
+	}
+}
+
+// WithStatusCallback sets the callback that is called for every
+// status returned while pulling or pushing an image
+func WithStatusCallback(onStatus StatusCallback) ClientOption {
+	return func(c *Client) {
+		c.OnStatus = onStatus
+	}
+}
+
+// NewClient returns a new Docker client
+func NewClient(logger *log.Logger, options ...ClientOption) (Client, error) {
 	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return Client{}, fmt.Errorf("new docker client: %w", err)
 	}
 
 	client := Client{
-		DockerClient: dockerClient,
-		Logger:       logger,
+		DockerClient:     dockerClient,
+		Logger:           logger,
+		RetryPolicy:      DefaultRetryPolicy(),
+		blobMounts:       newBlobMounts(),
+		chunkedUploads:   newChunkedUploads(),
+		harborRegistries: newHarborRegistries(),
+	}
+
+	for _, option := range options {
+		option(&client)
+	}
+
+	if err := client.RetryPolicy.Validate(); err != nil {
+		return Client{}, fmt.Errorf("validate retry policy: %w", err)
+	}
+
+	if err := validateProxy(client.Proxy); err != nil {
+		return Client{}, fmt.Errorf("validate proxy: %w", err)
+	}
+
+	if len(client.CACertificates) > 0 {
+		client.rootCAs, err = loadCACertificates(client.CACertificates)
+		if err != nil {
+			return Client{}, fmt.Errorf("load ca certificates: %w", err)
+		}
+	}
+
+	if len(client.ClientCertificates) > 0 {
+		client.clientCertificates, err = loadClientCertificates(client.ClientCertificates)
+		if err != nil {
+			return Client{}, fmt.Errorf("load client certificates: %w", err)
+		}
 	}
 
+	if client.BandwidthLimit > 0 {
+		client.bandwidthLimiter = newBandwidthLimiter(client.BandwidthLimit)
+	}
+
+	if client.CacheDir != "" {
+		client.layerCache, err = newLayerCache(client.CacheDir, client.CacheMaxSize)
+		if err != nil {
+			return Client{}, fmt.Errorf("new layer cache: %w", err)
+		}
+	}
+
+	// Auth is looked up for registry hosts without a client, so the token
+	// exchanges use the transport of the client that the images are pushed with
+	acrTokens.setTransport(client.getAuthTransport())
+
+	client.logInsecureRegistries()
+
 	return client, nil
 }
 
+// newOperationContext returns a context that is bound by the client timeout, if one is set
+func (c Client) newOperationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Timeout > 0 {
+		return context.WithTimeout(ctx, c.Timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
 // RegistryPath is a registry path for a docker image
 type RegistryPath string
 
@@ -52,46 +275,73 @@ func (r RegistryPath) Digest() string {
 
 // Tag is the tag in the registry path, if one exists
 func (r RegistryPath) Tag() string {
-	if strings.Contains(string(r), "@") || !strings.Contains(string(r), ":") {
+	path := string(r)
+	if r.Digest() != "" {
+		path = strings.TrimSuffix(path, "@"+r.Digest())
+	}
+
+	// A colon before the last slash separates the host from its port,
+	// so only a colon in the final path segment can denote a tag.
+	lastSegment := path[strings.LastIndex(path, "/")+1:]
+	if !strings.Contains(lastSegment, ":") {
 		return ""
 	}
 
-	tagTokens := strings.Split(string(r), ":")
+	tagTokens := strings.Split(lastSegment, ":")
 
-	return tagTokens[1]
+	return tagTokens[len(tagTokens)-1]
 }
 
 // Host is the host in the registry path
 func (r RegistryPath) Host() string {
 	host := string(r)
 
+	if r.Digest() != "" {
+		host = strings.TrimSuffix(host, "@"+r.Digest())
+	}
+
 	if r.Tag() != "" {
-		host = strings.ReplaceAll(host, ":"+r.Tag(), "")
+		host = strings.TrimSuffix(host, ":"+r.Tag())
 	}
 
-	if !strings.Contains(host, ".") {
+	hostTokens := strings.Split(host, "/")
+	host = hostTokens[0]
+
+	// The first path segment is only a host when it looks like a domain,
+	// includes a port, or is localhost. Otherwise it is part of a Docker Hub repository.
+	if !strings.Contains(host, ".") && !strings.Contains(host, ":") && host != "localhost" {
 		return ""
 	}
 
-	hostTokens := strings.Split(string(r), "/")
+	return host
+}
 
-	return hostTokens[0]
+// Port is the port of the host in the registry path, if one exists
+func (r RegistryPath) Port() string {
+	host := r.Host()
+	if !strings.Contains(host, ":") {
+		return ""
+	}
+
+	hostTokens := strings.Split(host, ":")
+
+	return hostTokens[1]
 }
 
 // Repository is the repository in the registry path
 func (r RegistryPath) Repository() string {
 	repository := string(r)
 
-	if r.Tag() != "" {
-		repository = strings.ReplaceAll(repository, ":"+r.Tag(), "")
+	if r.Digest() != "" {
+		repository = strings.TrimSuffix(repository, "@"+r.Digest())
 	}
 
-	if r.Digest() != "" {
-		repository = strings.ReplaceAll(repository, "@"+r.Digest(), "")
+	if r.Tag() != "" {
+		repository = strings.TrimSuffix(repository, ":"+r.Tag())
 	}
 
 	if r.Host() != "" {
-		repository = strings.ReplaceAll(repository, r.Host(), "")
+		repository = strings.TrimPrefix(repository, r.Host())
 	}
 
 	repository = strings.TrimLeft(repository, "/")
@@ -99,6 +349,32 @@ func (r RegistryPath) Repository() string {
 	return repository
 }
 
+// Reference is the fully qualified reference of the registry path.
+// Docker Hub paths are expanded to include the docker.io host (and library
+// repository for official images) and the digest is preferred over the tag.
+func (r RegistryPath) Reference() string {
+	host := r.Host()
+	repository := r.Repository()
+	if host == "" || host == "docker.io" || host == "index.docker.io" {
+		host = "docker.io"
+
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+
+	reference := host + "/" + repository
+	if r.Digest() != "" {
+		return reference + "@" + r.Digest()
+	}
+
+	if r.Tag() != "" {
+		return reference + ":" + r.Tag()
+	}
+
+	return reference
+}
+
 // ProgressDetail is the current state of pushing or pulling an image (in Bytes)
 type ProgressDetail struct {
 	Current int `json:"current"`
@@ -110,6 +386,14 @@ type Status struct {
 	Message        string         `json:"status"`
 	ID             string         `json:"id"`
 	ProgressDetail ProgressDetail `json:"progressDetail"`
+	Aux            StatusAux      `json:"aux"`
+}
+
+// StatusAux is the auxiliary output from the Docker client, which
+// includes the digest of the image once it has been pushed
+type StatusAux struct {
+	Tag    string `json:"Tag"`
+	Digest string `json:"Digest"`
 }
 
 // GetMessage returns a human friendly message from parsing the status message
@@ -125,41 +409,132 @@ func (s Status) GetMessage() string {
 	return "Processing"
 }
 
-func waitForScannerComplete(logger *log.Logger, clientScanner *bufio.Scanner, image string, command string) error {
-	type clientErrorMessage struct {
-		Error string `json:"error"`
-	}
+// StatusCallback is called for every status returned while pulling or pushing an image
+type StatusCallback func(Status)
+
+// newLogStatusCallback returns the default StatusCallback which logs the
+// status and overall progress of the Docker command every 25 statuses.
+// When verbose, every status is logged along with its progress details.
+func newLogStatusCallback(logger *log.Logger, image string, command string, verbose bool) StatusCallback {
+	progress := NewProgress()
 
-	var errorMessage clientErrorMessage
-	var status Status
+	entry := newImageLogEntry(logger, command, image)
 
 	var scans int
+	return func(status Status) {
+		progress.Update(status)
+
+		statusEntry := entry.WithFields(log.Fields{
+			"layer_id": status.ID,
+			"current":  status.ProgressDetail.Current,
+			"total":    status.ProgressDetail.Total,
+		})
+
+		if verbose {
+			statusEntry.Printf("[%s] %s %s: %s (%vB of %vB, %.0f%% complete)", command, image, status.ID, status.Message, status.ProgressDetail.Current, status.ProgressDetail.Total, progress.Percent())
+			return
+		}
+
+		// Serves as makeshift polling to occasionally print the status of the Docker command.
+		if scans%25 == 0 {
+			statusEntry.Printf("[%s] %s (%s, %.0f%% complete)", command, image, status.GetMessage(), progress.Percent())
+		}
+
+		scans++
+	}
+}
+
+// newImageLogEntry returns a log entry with the command (e.g. pull) and image as fields
+func newImageLogEntry(logger *log.Logger, command string, image string) *log.Entry {
+	return logger.WithFields(log.Fields{
+		"command": strings.ToLower(command),
+		"image":   image,
+	})
+}
+
+func (c Client) getStatusCallback(image string, command string) StatusCallback {
+	onStatus := c.OnStatus
+	if onStatus == nil {
+		onStatus = newLogStatusCallback(c.Logger, image, command, c.Verbose)
+	}
+
+	if c.Stats == nil {
+		return onStatus
+	}
+
+	return func(status Status) {
+		c.Stats.Update(image, status)
+		onStatus(status)
+	}
+}
+
+// waitForScannerComplete waits for the Docker command to finish, returning early when the context is cancelled.
+// The reader that the scanner reads from is closed when the context is cancelled, so that the scan stops, and the
+// scan is finished before it returns, so that the status callback is never called once it has returned.
+// Callers are still expected to close the reader when the command completes.
+func waitForScannerComplete(ctx context.Context, clientScanner *bufio.Scanner, reader io.Closer, image string, command string, onStatus StatusCallback) error {
+	scanComplete := make(chan error, 1)
+	go func() {
+		scanComplete <- scanUntilComplete(ctx, clientScanner, onStatus)
+	}()
+
+	select {
+	case <-ctx.Done():
+		if reader != nil {
+			reader.Close()
+		}
+		<-scanComplete
+
+		return fmt.Errorf("%s of %s cancelled: %w", strings.ToLower(command), image, ctx.Err())
+	case err := <-scanComplete:
+		return err
+	}
+}
+
+func scanUntilComplete(ctx context.Context, clientScanner *bufio.Scanner, onStatus StatusCallback) error {
+	type clientErrorDetail struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	type clientErrorMessage struct {
+		Error       string            `json:"error"`
+		ErrorDetail clientErrorDetail `json:"errorDetail"`
+	}
+
 	for clientScanner.Scan() {
+		if ctx.Err() != nil {
+			return fmt.Errorf("scan: %w", ctx.Err())
+		}
+
+		var status Status
 		if err := json.Unmarshal(clientScanner.Bytes(), &status); err != nil {
 			return fmt.Errorf("unmarshal status: %w", err)
 		}
 
+		var errorMessage clientErrorMessage
 		if err := json.Unmarshal(clientScanner.Bytes(), &errorMessage); err != nil {
 			return fmt.Errorf("unmarshal error: %w", err)
 		}
 
+		if errorMessage.ErrorDetail.Code == http.StatusTooManyRequests || isRateLimitMessage(errorMessage.Error) {
+			return fmt.Errorf("returned error: %w", newRateLimitError(errorMessage.Error))
+		}
+
 		if errorMessage.Error != "" {
 			return fmt.Errorf("returned error: %s", errorMessage.Error)
 		}
 
-		// Serves as makeshift polling to occasionally print the status of the Docker command.
-		if scans%25 == 0 {
-			logger.Printf("[%s] %s (%s)", command, image, status.GetMessage())
-		}
+		onStatus(status)
+	}
 
-		scans++
+	if ctx.Err() != nil {
+		return fmt.Errorf("scan: %w", ctx.Err())
 	}
 
 	if clientScanner.Err() != nil {
 		return fmt.Errorf("scanner: %w", clientScanner.Err())
 	}
 
-	logger.Printf("[%s] %s complete.", command, image)
-
 	return nil
 }
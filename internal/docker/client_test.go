@@ -1,10 +1,24 @@
 package docker
 
-import "testing"
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
 
 type registryPathTest struct {
 	actualPath         RegistryPath
 	expectedHost       string
+	expectedPort       string
 	expectedRepository string
 	expectedTag        string
 	expectedDigest     string
@@ -15,6 +29,10 @@ func verifyRegistryPathMethods(t *testing.T, test registryPathTest) {
 		t.Errorf("expected host to be %s, actual %s", test.expectedHost, test.actualPath.Host())
 	}
 
+	if test.actualPath.Port() != test.expectedPort {
+		t.Errorf("expected port to be %s, actual %s", test.expectedPort, test.actualPath.Port())
+	}
+
 	if test.actualPath.Repository() != test.expectedRepository {
 		t.Errorf("expected repository to be %s, actual %s", test.expectedRepository, test.actualPath.Repository())
 	}
@@ -168,3 +186,325 @@ func TestRegistryPath_Digest(t *testing.T) {
 
 	verifyRegistryPathMethods(t, test)
 }
+
+func TestRegistryPath_TagAndDigest(t *testing.T) {
+	path := RegistryPath("gcr.io/project/app:v1@sha256:abc123")
+
+	test := registryPathTest{
+		actualPath:         path,
+		expectedHost:       "gcr.io",
+		expectedRepository: "project/app",
+		expectedTag:        "v1",
+		expectedDigest:     "sha256:abc123",
+	}
+
+	verifyRegistryPathMethods(t, test)
+}
+
+func TestRegistryPath_Tag_WithPort(t *testing.T) {
+	testCases := []registryPathTest{
+		{
+			actualPath:         RegistryPath("host.com:5000/repo:v1.2.3"),
+			expectedHost:       "host.com:5000",
+			expectedPort:       "5000",
+			expectedRepository: "repo",
+			expectedTag:        "v1.2.3",
+			expectedDigest:     "",
+		},
+		{
+			actualPath:         RegistryPath("host.com:5000/repo"),
+			expectedHost:       "host.com:5000",
+			expectedPort:       "5000",
+			expectedRepository: "repo",
+			expectedTag:        "",
+			expectedDigest:     "",
+		},
+		{
+			actualPath:         RegistryPath("host.com:5000/repo@sha256:abc123"),
+			expectedHost:       "host.com:5000",
+			expectedPort:       "5000",
+			expectedRepository: "repo",
+			expectedTag:        "",
+			expectedDigest:     "sha256:abc123",
+		},
+		{
+			actualPath:         RegistryPath("repo:v1.0.0"),
+			expectedHost:       "",
+			expectedRepository: "repo",
+			expectedTag:        "v1.0.0",
+			expectedDigest:     "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		verifyRegistryPathMethods(t, testCase)
+	}
+}
+
+func TestRegistryPath_Host_Localhost(t *testing.T) {
+	testCases := []registryPathTest{
+		{
+			actualPath:         RegistryPath("localhost/app"),
+			expectedHost:       "localhost",
+			expectedRepository: "app",
+			expectedTag:        "",
+			expectedDigest:     "",
+		},
+		{
+			actualPath:         RegistryPath("localhost:5000/app:tag"),
+			expectedHost:       "localhost:5000",
+			expectedPort:       "5000",
+			expectedRepository: "app",
+			expectedTag:        "tag",
+			expectedDigest:     "",
+		},
+		{
+			actualPath:         RegistryPath("library/ubuntu"),
+			expectedHost:       "",
+			expectedRepository: "library/ubuntu",
+			expectedTag:        "",
+			expectedDigest:     "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		verifyRegistryPathMethods(t, testCase)
+	}
+}
+
+func TestRegistryPath_Port(t *testing.T) {
+	testCases := []registryPathTest{
+		{
+			actualPath:         RegistryPath("myregistry.internal:8443/team/app:tag"),
+			expectedHost:       "myregistry.internal:8443",
+			expectedPort:       "8443",
+			expectedRepository: "team/app",
+			expectedTag:        "tag",
+			expectedDigest:     "",
+		},
+		{
+			actualPath:         RegistryPath("myregistry.internal:8443/team/app"),
+			expectedHost:       "myregistry.internal:8443",
+			expectedPort:       "8443",
+			expectedRepository: "team/app",
+			expectedTag:        "",
+			expectedDigest:     "",
+		},
+		{
+			actualPath:         RegistryPath("myregistry.internal/team/app:tag"),
+			expectedHost:       "myregistry.internal",
+			expectedPort:       "",
+			expectedRepository: "team/app",
+			expectedTag:        "tag",
+			expectedDigest:     "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		verifyRegistryPathMethods(t, testCase)
+	}
+}
+
+func TestRegistryPath_Reference(t *testing.T) {
+	testCases := []struct {
+		input             RegistryPath
+		expectedReference string
+	}{
+		{
+			input:             "ubuntu",
+			expectedReference: "docker.io/library/ubuntu",
+		},
+		{
+			input:             "ubuntu:20.04",
+			expectedReference: "docker.io/library/ubuntu:20.04",
+		},
+		{
+			input:             "docker.io/plexsystems/busybox:1.30.0",
+			expectedReference: "docker.io/plexsystems/busybox:1.30.0",
+		},
+		{
+			input:             "gcr.io/project/app:v1.0.0",
+			expectedReference: "gcr.io/project/app:v1.0.0",
+		},
+		{
+			input:             "gcr.io/project/app@sha256:abc123",
+			expectedReference: "gcr.io/project/app@sha256:abc123",
+		},
+		{
+			input:             "gcr.io/project/app:v1@sha256:abc123",
+			expectedReference: "gcr.io/project/app@sha256:abc123",
+		},
+		{
+			input:             "localhost:5000/app:v1.0.0",
+			expectedReference: "localhost:5000/app:v1.0.0",
+		},
+	}
+
+	for _, testCase := range testCases {
+		reference := testCase.input.Reference()
+
+		if reference != testCase.expectedReference {
+			t.Errorf("expected reference to be %s, actual %s", testCase.expectedReference, reference)
+		}
+
+		roundTripReference := RegistryPath(reference).Reference()
+		if roundTripReference != reference {
+			t.Errorf("expected reference to round trip to %s, actual %s", reference, roundTripReference)
+		}
+	}
+}
+
+func TestWaitForScannerComplete_Cancelled(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		writer.Write([]byte(`{"status":"Pulling from library/busybox"}` + "\n"))
+		cancel()
+	}()
+
+	scanComplete := make(chan error, 1)
+	go func() {
+		scanComplete <- waitForScannerComplete(ctx, bufio.NewScanner(reader), reader, "busybox", "PULL", func(Status) {})
+	}()
+
+	select {
+	case err := <-scanComplete:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context cancelled error, actual %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected scanner to stop when the context was cancelled")
+	}
+}
+
+func TestWaitForScannerComplete_NoStatusAfterCancel(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			if _, err := writer.Write([]byte(`{"status":"Downloading"}` + "\n")); err != nil {
+				return
+			}
+		}
+	}()
+
+	var returned int32
+	var lateCallbacks int32
+	onStatus := func(status Status) {
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+
+		if atomic.LoadInt32(&returned) == 1 {
+			atomic.AddInt32(&lateCallbacks, 1)
+		}
+	}
+
+	err := waitForScannerComplete(ctx, bufio.NewScanner(reader), reader, "busybox", "PULL", onStatus)
+	atomic.StoreInt32(&returned, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context cancelled error, actual %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if callbacks := atomic.LoadInt32(&lateCallbacks); callbacks != 0 {
+		t.Errorf("expected no status callbacks after returning, actual %v", callbacks)
+	}
+}
+
+func TestWaitForScannerComplete_StatusCallback(t *testing.T) {
+	var statuses []string
+	for i := 0; i < 30; i++ {
+		statuses = append(statuses, `{"status":"Downloading","id":"abc123","progressDetail":{"current":1,"total":2}}`)
+	}
+	reader := strings.NewReader(strings.Join(statuses, "\n"))
+
+	var callbacks int
+	onStatus := func(status Status) {
+		callbacks++
+	}
+
+	if err := waitForScannerComplete(context.Background(), bufio.NewScanner(reader), nil, "busybox", "PULL", onStatus); err != nil {
+		t.Fatal("wait for scanner:", err)
+	}
+
+	if callbacks != len(statuses) {
+		t.Errorf("expected %v callbacks, actual %v", len(statuses), callbacks)
+	}
+}
+
+func TestNewLogStatusCallback(t *testing.T) {
+	testCases := []struct {
+		verbose          bool
+		expectedLogLines int
+	}{
+		{verbose: false, expectedLogLines: 2},
+		{verbose: true, expectedLogLines: 30},
+	}
+
+	for _, testCase := range testCases {
+		var output bytes.Buffer
+		logger := log.New()
+		logger.SetOutput(&output)
+
+		onStatus := newLogStatusCallback(logger, "busybox", "PULL", testCase.verbose)
+		for i := 0; i < 30; i++ {
+			onStatus(Status{ID: "abc123", Message: "Downloading", ProgressDetail: ProgressDetail{Current: i, Total: 30}})
+		}
+
+		logLines := strings.Count(output.String(), "\n")
+		if logLines != testCase.expectedLogLines {
+			t.Errorf("expected %v log lines when verbose is %v, actual %v", testCase.expectedLogLines, testCase.verbose, logLines)
+		}
+
+		if testCase.verbose && !strings.Contains(output.String(), "abc123: Downloading (29B of 30B") {
+			t.Errorf("expected verbose output to contain the progress details, actual %s", output.String())
+		}
+	}
+}
+
+func TestNewLogStatusCallback_Fields(t *testing.T) {
+	var output bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&output)
+	logger.SetFormatter(&log.JSONFormatter{})
+
+	onStatus := newLogStatusCallback(logger, "busybox:1.32.0", "PULL", false)
+	onStatus(Status{ID: "abc123", Message: "Downloading", ProgressDetail: ProgressDetail{Current: 10, Total: 30}})
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatal("unmarshal log entry:", err)
+	}
+
+	if entry["command"] != "pull" || entry["image"] != "busybox:1.32.0" {
+		t.Errorf("expected the command and image to be fields, actual %v", entry)
+	}
+
+	// Numbers are unmarshalled as float64
+	if entry["layer_id"] != "abc123" || entry["current"] != float64(10) || entry["total"] != float64(30) {
+		t.Errorf("expected the layer and its progress to be fields, actual %v", entry)
+	}
+}
+
+func TestWaitForScannerComplete_RateLimited(t *testing.T) {
+	statuses := []string{
+		`{"status":"Pulling from library/busybox"}`,
+		`{"errorDetail":{"code":429,"message":"toomanyrequests: You have reached your pull rate limit. Retry-After: 30"},"error":"toomanyrequests: You have reached your pull rate limit. Retry-After: 30"}`,
+	}
+	reader := strings.NewReader(strings.Join(statuses, "\n"))
+
+	err := waitForScannerComplete(context.Background(), bufio.NewScanner(reader), nil, "busybox", "PULL", func(Status) {})
+
+	var rateLimitError *RateLimitError
+	if !errors.As(err, &rateLimitError) {
+		t.Fatalf("expected rate limit error, actual %v", err)
+	}
+
+	if rateLimitError.RetryAfter != 30*time.Second {
+		t.Errorf("expected retry after to be 30s, actual %s", rateLimitError.RetryAfter)
+	}
+}
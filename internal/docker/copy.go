@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/v1/cache"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// CopyImageAndWait copies an image from the source registry to the target registry without storing
+// it on the host machine. The blobs of the image are streamed from the source registry, and blobs
+// that already exist at the target, or in another repository of the target that an image was
+// copied to, are not uploaded again.
+func (c Client) CopyImageAndWait(ctx context.Context, source string, target string, sourceAuth string, targetAuth string) error {
+	ctx, cancel := c.newOperationContext(ctx)
+	defer cancel()
+
+	var digest string
+	retryError := c.retry(
+		ctx,
+		func() error {
+			var err error
+			digest, err = c.tryCopyImage(ctx, source, target, sourceAuth, targetAuth)
+			if err != nil {
+				return fmt.Errorf("try copy image: %w", err)
+			}
+
+			return nil
+		},
+		func(retryAttempt uint, err error) {
+			newImageLogEntry(c.Logger, "copy", source).Printf("[RETRY] Unable to copy %v (Retrying #%v)", source, retryAttempt+1)
+			sourceAuth = refreshECRAuth(ctx, source, sourceAuth)
+			targetAuth = refreshECRAuth(ctx, target, targetAuth)
+		},
+	)
+
+	if retryError != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("copy of %s timed out after %s: %w", source, c.Timeout, retryError)
+	}
+
+	if retryError != nil {
+		return retryError
+	}
+
+	if err := c.verifyPushedDigest(ctx, target, digest, targetAuth); err != nil {
+		return fmt.Errorf("verify pushed digest: %w", err)
+	}
+
+	return nil
+}
+
+func (c Client) tryCopyImage(ctx context.Context, source string, target string, sourceAuth string, targetAuth string) (string, error) {
+	// Images are read from the mirror of the source registry, when it has one
+	source, sourceAuth, err := c.getMirrorSource(ctx, source, sourceAuth)
+	if err != nil {
+		return "", fmt.Errorf("get mirror source: %w", err)
+	}
+
+	sourceReference, err := c.parseReference(source)
+	if err != nil {
+		return "", fmt.Errorf("parse source ref: %w", err)
+	}
+
+	targetReference, err := c.parseReference(target)
+	if err != nil {
+		return "", fmt.Errorf("parse target ref: %w", err)
+	}
+
+	sourceAuthenticator, err := getAuthenticator(sourceAuth)
+	if err != nil {
+		return "", fmt.Errorf("get source authenticator: %w", err)
+	}
+
+	targetAuthenticator, err := getAuthenticator(targetAuth)
+	if err != nil {
+		return "", fmt.Errorf("get target authenticator: %w", err)
+	}
+
+	image, err := remote.Image(sourceReference, c.getRemoteOptions(ctx, sourceReference.Context().Registry, remote.WithAuth(sourceAuthenticator))...)
+	if err != nil {
+		return "", fmt.Errorf("get source image: %w", err)
+	}
+
+	image, err = annotateImage(image, c.Labels, c.Annotations)
+	if err != nil {
+		return "", fmt.Errorf("annotate image: %w", err)
+	}
+
+	// Layers that were already copied to another repository of the target registry are mounted
+	// from that repository, unless they are mounted from the source repository of the same registry.
+	// Layers that are in the layer cache are read from the cache rather than the source registry.
+	// The layers of OCI artifacts are not cached, as they are not tarballs with a diff ID.
+	// Layers larger than the chunk size are uploaded in chunks before the image is written.
+	artifact, err := isArtifact(image)
+	if err != nil {
+		return "", fmt.Errorf("is artifact: %w", err)
+	}
+
+	if sourceReference.Context().RegistryStr() != targetReference.Context().RegistryStr() {
+		if c.layerCache != nil && !artifact {
+			image = cache.Image(image, c.layerCache)
+		}
+
+		image = mountableImage{Image: image, target: targetReference.Context(), mounts: c.blobMounts}
+	}
+
+	if err := c.uploadLargeLayers(ctx, targetReference.Context(), targetAuthenticator, image); err != nil {
+		return "", fmt.Errorf("upload large layers: %w", err)
+	}
+
+	if err := remote.Write(targetReference, image, c.getRemoteOptions(ctx, targetReference.Context().Registry, remote.WithAuth(targetAuthenticator))...); err != nil {
+		return "", fmt.Errorf("write target image: %w", err)
+	}
+
+	digest, err := image.Digest()
+	if err != nil {
+		return "", fmt.Errorf("digest: %w", err)
+	}
+
+	if err := c.blobMounts.add(targetReference.Context(), image); err != nil {
+		return "", fmt.Errorf("record blob mounts: %w", err)
+	}
+
+	return digest.String(), nil
+}
@@ -0,0 +1,158 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	log "github.com/sirupsen/logrus"
+)
+
+// uploadRecorder records the blob uploads that are started at a registry
+type uploadRecorder struct {
+	mutex   sync.Mutex
+	uploads int
+	handler http.Handler
+}
+
+func (u *uploadRecorder) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, "/blobs/uploads/") {
+		u.mutex.Lock()
+		u.uploads++
+		u.mutex.Unlock()
+	}
+
+	u.handler.ServeHTTP(writer, request)
+}
+
+func TestCopyImageAndWait(t *testing.T) {
+	sourceServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer sourceServer.Close()
+
+	targetRecorder := uploadRecorder{handler: registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0)))}
+	targetServer := httptest.NewServer(&targetRecorder)
+	defer targetServer.Close()
+
+	sourceHost := strings.TrimPrefix(sourceServer.URL, "http://")
+	targetHost := strings.TrimPrefix(targetServer.URL, "http://")
+	sourceImage := sourceHost + "/source/app:v1.0.0"
+
+	image, err := random.Image(1024, 3)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	sourceReference, err := name.ParseReference(sourceImage)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(sourceReference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := Client{
+		Logger:      logger,
+		RetryPolicy: RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), sourceImage, targetHost+"/target/app:v1.0.0", "", ""); err != nil {
+		t.Fatal("copy image:", err)
+	}
+
+	sourceDigest, err := image.Digest()
+	if err != nil {
+		t.Fatal("source digest:", err)
+	}
+
+	targetDigests, err := client.GetDigestsAtRemote(context.Background(), targetHost+"/target/app:v1.0.0", "")
+	if err != nil {
+		t.Fatal("get target digests:", err)
+	}
+
+	if len(targetDigests) != 1 || targetDigests[0] != sourceDigest.String() {
+		t.Errorf("expected target to be the source image %s, actual %v", sourceDigest, targetDigests)
+	}
+
+	const expectedUploads = 4
+	if targetRecorder.uploads != expectedUploads {
+		t.Errorf("expected the %v layers and the config to be uploaded, actual %v uploads", expectedUploads-1, targetRecorder.uploads)
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), sourceImage, targetHost+"/other/app:v1.0.0", "", ""); err != nil {
+		t.Fatal("copy image to other repository:", err)
+	}
+
+	if targetRecorder.uploads != expectedUploads {
+		t.Errorf("expected blobs that exist at the target to not be uploaded again, actual %v uploads", targetRecorder.uploads)
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), sourceHost+"/source/missing:v1.0.0", targetHost+"/target/missing:v1.0.0", "", ""); err == nil {
+		t.Error("expected copying a missing image to return an error")
+	}
+}
+
+// blockingHandler blocks the blob uploads that are started at a registry until their request is cancelled
+type blockingHandler struct {
+	handler http.Handler
+}
+
+func (b blockingHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, "/blobs/uploads/") {
+		<-request.Context().Done()
+		return
+	}
+
+	b.handler.ServeHTTP(writer, request)
+}
+
+func TestCopyImageAndWait_Cancelled(t *testing.T) {
+	sourceServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer sourceServer.Close()
+
+	targetServer := httptest.NewServer(blockingHandler{handler: registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0)))})
+	defer targetServer.Close()
+
+	sourceImage := strings.TrimPrefix(sourceServer.URL, "http://") + "/source/app:v1.0.0"
+	targetImage := strings.TrimPrefix(targetServer.URL, "http://") + "/target/app:v1.0.0"
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+	writeImage(t, sourceImage, image)
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := Client{Logger: logger, RetryPolicy: RetryPolicy{Attempts: 1, Backoff: FixedBackoff}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.CopyImageAndWait(ctx, sourceImage, targetImage, "", "")
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected the copy to fail once the context is cancelled")
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the copy to stop once the context is cancelled")
+	}
+}
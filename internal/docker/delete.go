@@ -0,0 +1,35 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// DeleteImageAtRemote deletes the image from the remote registry.
+// Registries only delete images by their digest, so deleting a tag
+// also deletes every other tag that refers to the same digest.
+func (c Client) DeleteImageAtRemote(ctx context.Context, image string, encodedAuth string) error {
+	imageReference, err := c.parseReference(image)
+	if err != nil {
+		return fmt.Errorf("parse ref: %w", err)
+	}
+
+	authOption, err := withEncodedAuth(encodedAuth)
+	if err != nil {
+		return fmt.Errorf("get auth: %w", err)
+	}
+
+	descriptor, err := remote.Get(imageReference, c.getRemoteOptions(ctx, imageReference.Context().Registry, authOption)...)
+	if err != nil {
+		return fmt.Errorf("get image: %w", err)
+	}
+
+	digestReference := imageReference.Context().Digest(descriptor.Digest.String())
+	if err := remote.Delete(digestReference, c.getRemoteOptions(ctx, digestReference.Context().Registry, authOption)...); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/docker/cli/cli/config/types"
+)
+
+// ecrTokenRefreshWindow is how long before it expires that an ECR auth token is refreshed,
+// so that the token does not expire while an image is being pushed or pulled.
+const ecrTokenRefreshWindow = 15 * time.Minute
+
+// ecrTokenUsername is the username of the auth of every ECR auth token
+const ecrTokenUsername = "AWS"
+
+var ecrHostPattern = regexp.MustCompile(`^([0-9]{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+// ecrRegistry is an Amazon Elastic Container Registry
+type ecrRegistry struct {
+	AccountID string
+	Region    string
+}
+
+// parseECRHost returns the registry of an ECR host (e.g. 123456789012.dkr.ecr.us-east-1.amazonaws.com)
+// and false when the host is not an ECR registry
+func parseECRHost(host string) (ecrRegistry, bool) {
+	matches := ecrHostPattern.FindStringSubmatch(strings.ToLower(host))
+	if matches == nil {
+		return ecrRegistry{}, false
+	}
+
+	return ecrRegistry{AccountID: matches[1], Region: matches[2]}, true
+}
+
+// IsECRHost returns true when the host is an Amazon ECR registry
+func IsECRHost(host string) bool {
+	_, exists := parseECRHost(host)
+	return exists
+}
+
+// ecrAPI is the part of the ECR API that is used by sinker
+type ecrAPI interface {
+	GetAuthorizationTokenWithContext(ctx aws.Context, input *ecr.GetAuthorizationTokenInput, options ...request.Option) (*ecr.GetAuthorizationTokenOutput, error)
+	DescribeRepositoriesWithContext(ctx aws.Context, input *ecr.DescribeRepositoriesInput, options ...request.Option) (*ecr.DescribeRepositoriesOutput, error)
+	CreateRepositoryWithContext(ctx aws.Context, input *ecr.CreateRepositoryInput, options ...request.Option) (*ecr.CreateRepositoryOutput, error)
+}
+
+// newECRAPI returns the ECR API of the region. The credentials are found with the default
+// credential chain of the AWS SDK, which includes the environment variables, the shared
+// configuration and credentials files, and the role of the container or instance.
+var newECRAPI = func(region string) (ecrAPI, error) {
+	awsSession, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(region)},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new session: %w", err)
+	}
+
+	return ecr.New(awsSession), nil
+}
+
+// ecrTokenCache caches the auth token of each ECR registry until it is about to expire
+type ecrTokenCache struct {
+	tokens *tokenCache
+}
+
+var ecrTokens = &ecrTokenCache{
+	tokens: newTokenCache(ecrTokenRefreshWindow),
+}
+
+// getEncodedAuth returns the Base64 encoded auth for the ECR registry, which is only
+// requested from ECR when there is no cached token or the cached token is about to expire
+func (c *ecrTokenCache) getEncodedAuth(ctx context.Context, registry ecrRegistry) (string, error) {
+	return c.tokens.getEncodedAuth(ctx, registry.AccountID+"/"+registry.Region, func(ctx context.Context) (registryToken, error) {
+		return getECRToken(ctx, registry)
+	})
+}
+
+func getECRToken(ctx context.Context, registry ecrRegistry) (registryToken, error) {
+	api, err := newECRAPI(registry.Region)
+	if err != nil {
+		return registryToken{}, fmt.Errorf("new ecr api: %w", err)
+	}
+
+	input := ecr.GetAuthorizationTokenInput{
+		RegistryIds: []*string{aws.String(registry.AccountID)},
+	}
+
+	output, err := api.GetAuthorizationTokenWithContext(ctx, &input)
+	if err != nil {
+		return registryToken{}, fmt.Errorf("get authorization token: %w", err)
+	}
+
+	if len(output.AuthorizationData) == 0 {
+		return registryToken{}, fmt.Errorf("no authorization token returned for account %s", registry.AccountID)
+	}
+
+	authorizationData := output.AuthorizationData[0]
+	encodedAuth, err := GetEncodedTokenAuth(aws.StringValue(authorizationData.AuthorizationToken))
+	if err != nil {
+		return registryToken{}, fmt.Errorf("get encoded token auth: %w", err)
+	}
+
+	token := registryToken{
+		encodedAuth: encodedAuth,
+		expiresAt:   aws.TimeValue(authorizationData.ExpiresAt),
+	}
+
+	return token, nil
+}
+
+// refreshECRAuth returns the auth to use for another attempt of an operation on the image. The auth of ECR
+// images that is an ECR auth token is replaced with the cached token of the registry, which is refreshed
+// when it is about to expire, so that the retries of an operation that outlives its token do not fail.
+// Any other auth, or an auth that can not be refreshed, is returned as it is.
+func refreshECRAuth(ctx context.Context, image string, auth string) string {
+	registry, exists := parseECRHost(RegistryPath(image).Host())
+	if !exists || !isECRAuth(auth) {
+		return auth
+	}
+
+	refreshedAuth, err := ecrTokens.getEncodedAuth(ctx, registry)
+	if err != nil {
+		return auth
+	}
+
+	return refreshedAuth
+}
+
+// isECRAuth returns true when the encoded auth is an ECR auth token, which always has the AWS username
+func isECRAuth(encodedAuth string) bool {
+	jsonAuth, err := base64.URLEncoding.DecodeString(encodedAuth)
+	if err != nil {
+		return false
+	}
+
+	var authConfig types.AuthConfig
+	if err := json.Unmarshal(jsonAuth, &authConfig); err != nil {
+		return false
+	}
+
+	return authConfig.Username == ecrTokenUsername
+}
+
+// isNoAWSCredentials returns true when no AWS credentials were found in the default credential chain
+func isNoAWSCredentials(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == "NoCredentialProviders"
+}
+
+func createECRRepositoryIfNotExists(ctx context.Context, api ecrAPI, registry ecrRegistry, repository string) (bool, error) {
+	describeInput := ecr.DescribeRepositoriesInput{
+		RegistryId:      aws.String(registry.AccountID),
+		RepositoryNames: []*string{aws.String(repository)},
+	}
+
+	_, err := api.DescribeRepositoriesWithContext(ctx, &describeInput)
+	if err == nil {
+		return false, nil
+	}
+
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != ecr.ErrCodeRepositoryNotFoundException {
+		return false, fmt.Errorf("describe repository: %w", err)
+	}
+
+	// Repositories are always created in the account of the AWS credentials
+	createInput := ecr.CreateRepositoryInput{
+		RepositoryName: aws.String(repository),
+	}
+
+	// Another push may have created the repository since it was described
+	_, err = api.CreateRepositoryWithContext(ctx, &createInput)
+	if errors.As(err, &awsErr) && awsErr.Code() == ecr.ErrCodeRepositoryAlreadyExistsException {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("create repository: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,208 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecr"
+)
+
+type fakeECRAPI struct {
+	describeErr    error
+	createErr      error
+	created        []string
+	tokenRequests  int
+	tokenExpiresAt time.Time
+}
+
+func (f *fakeECRAPI) GetAuthorizationTokenWithContext(ctx aws.Context, input *ecr.GetAuthorizationTokenInput, options ...request.Option) (*ecr.GetAuthorizationTokenOutput, error) {
+	f.tokenRequests++
+
+	output := ecr.GetAuthorizationTokenOutput{
+		AuthorizationData: []*ecr.AuthorizationData{
+			{
+				AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:password"))),
+				ExpiresAt:          aws.Time(f.tokenExpiresAt),
+			},
+		},
+	}
+
+	return &output, nil
+}
+
+func (f *fakeECRAPI) DescribeRepositoriesWithContext(ctx aws.Context, input *ecr.DescribeRepositoriesInput, options ...request.Option) (*ecr.DescribeRepositoriesOutput, error) {
+	return &ecr.DescribeRepositoriesOutput{}, f.describeErr
+}
+
+func (f *fakeECRAPI) CreateRepositoryWithContext(ctx aws.Context, input *ecr.CreateRepositoryInput, options ...request.Option) (*ecr.CreateRepositoryOutput, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+
+	f.created = append(f.created, aws.StringValue(input.RepositoryName))
+	return &ecr.CreateRepositoryOutput{}, nil
+}
+
+func TestParseECRHost(t *testing.T) {
+	testCases := []struct {
+		host             string
+		expectedExists   bool
+		expectedRegistry ecrRegistry
+	}{
+		{
+			host:             "123456789012.dkr.ecr.us-east-1.amazonaws.com",
+			expectedExists:   true,
+			expectedRegistry: ecrRegistry{AccountID: "123456789012", Region: "us-east-1"},
+		},
+		{
+			host:             "123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com",
+			expectedExists:   true,
+			expectedRegistry: ecrRegistry{AccountID: "123456789012", Region: "us-gov-west-1"},
+		},
+		{
+			host:             "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn",
+			expectedExists:   true,
+			expectedRegistry: ecrRegistry{AccountID: "123456789012", Region: "cn-north-1"},
+		},
+		{host: "public.ecr.aws", expectedExists: false},
+		{host: "12345.dkr.ecr.us-east-1.amazonaws.com", expectedExists: false},
+		{host: "123456789012.dkr.ecr.us-east-1.amazonaws.com.mycompany.com", expectedExists: false},
+	}
+
+	for _, testCase := range testCases {
+		registry, exists := parseECRHost(testCase.host)
+		if exists != testCase.expectedExists {
+			t.Errorf("expected ecr host to be %v for %s, actual %v", testCase.expectedExists, testCase.host, exists)
+		}
+
+		if registry != testCase.expectedRegistry {
+			t.Errorf("expected registry %+v for %s, actual %+v", testCase.expectedRegistry, testCase.host, registry)
+		}
+	}
+}
+
+func TestCreateECRRepositoryIfNotExists(t *testing.T) {
+	notFound := awserr.New(ecr.ErrCodeRepositoryNotFoundException, "repository not found", nil)
+	alreadyExists := awserr.New(ecr.ErrCodeRepositoryAlreadyExistsException, "repository already exists", nil)
+
+	testCases := []struct {
+		name            string
+		api             fakeECRAPI
+		expectedCreated bool
+		expectedError   bool
+	}{
+		{name: "existing repository", api: fakeECRAPI{}, expectedCreated: false},
+		{name: "missing repository", api: fakeECRAPI{describeErr: notFound}, expectedCreated: true},
+		{name: "created by another push", api: fakeECRAPI{describeErr: notFound, createErr: alreadyExists}, expectedCreated: false},
+		{name: "describe error", api: fakeECRAPI{describeErr: errors.New("access denied")}, expectedError: true},
+	}
+
+	registry := ecrRegistry{AccountID: "123456789012", Region: "us-east-1"}
+	for _, testCase := range testCases {
+		created, err := createECRRepositoryIfNotExists(context.Background(), &testCase.api, registry, "mirror/busybox")
+		if testCase.expectedError != (err != nil) {
+			t.Errorf("expected error to be %v for %s, actual %v", testCase.expectedError, testCase.name, err)
+		}
+
+		if created != testCase.expectedCreated {
+			t.Errorf("expected created to be %v for %s, actual %v", testCase.expectedCreated, testCase.name, created)
+		}
+
+		if created && (len(testCase.api.created) != 1 || testCase.api.created[0] != "mirror/busybox") {
+			t.Errorf("expected mirror/busybox to be created for %s, actual %v", testCase.name, testCase.api.created)
+		}
+	}
+}
+
+func TestECRTokenCache_Refresh(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	api := fakeECRAPI{tokenExpiresAt: now.Add(12 * time.Hour)}
+
+	defaultNewECRAPI := newECRAPI
+	newECRAPI = func(region string) (ecrAPI, error) {
+		return &api, nil
+	}
+	defer func() { newECRAPI = defaultNewECRAPI }()
+
+	cache := ecrTokenCache{tokens: newTokenCache(ecrTokenRefreshWindow)}
+	cache.tokens.now = func() time.Time { return now }
+
+	registry := ecrRegistry{AccountID: "123456789012", Region: "us-east-1"}
+	for i := 0; i < 2; i++ {
+		encodedAuth, err := cache.getEncodedAuth(context.Background(), registry)
+		if err != nil {
+			t.Fatal("get encoded auth:", err)
+		}
+
+		authConfig := decodeTestAuth(t, encodedAuth)
+		if authConfig.Username != "AWS" || authConfig.Password != "password" {
+			t.Errorf("expected the auth of the authorization token, actual %+v", authConfig)
+		}
+	}
+
+	if api.tokenRequests != 1 {
+		t.Errorf("expected the token to be cached, actual %v requests", api.tokenRequests)
+	}
+
+	// The token is refreshed when it is about to expire
+	now = now.Add(12*time.Hour - ecrTokenRefreshWindow)
+	if _, err := cache.getEncodedAuth(context.Background(), registry); err != nil {
+		t.Fatal("get encoded auth:", err)
+	}
+
+	if api.tokenRequests != 2 {
+		t.Errorf("expected the token to be refreshed, actual %v requests", api.tokenRequests)
+	}
+}
+
+func TestRefreshECRAuth(t *testing.T) {
+	api := fakeECRAPI{tokenExpiresAt: time.Now().Add(12 * time.Hour)}
+
+	defaultNewECRAPI := newECRAPI
+	newECRAPI = func(region string) (ecrAPI, error) {
+		return &api, nil
+	}
+	defer func() { newECRAPI = defaultNewECRAPI }()
+
+	defaultECRTokens := ecrTokens
+	ecrTokens = &ecrTokenCache{tokens: newTokenCache(ecrTokenRefreshWindow)}
+	defer func() { ecrTokens = defaultECRTokens }()
+
+	expiredAuth, err := GetEncodedBasicAuth("AWS", "expired")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	userAuth, err := GetEncodedBasicAuth("user", "secret")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	testCases := []struct {
+		name             string
+		image            string
+		auth             string
+		expectedPassword string
+	}{
+		{name: "ecr token", image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/busybox:1.32.0", auth: expiredAuth, expectedPassword: "password"},
+		{name: "ecr user", image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/busybox:1.32.0", auth: userAuth, expectedPassword: "secret"},
+		{name: "other registry", image: "mycompany.com/busybox:1.32.0", auth: expiredAuth, expectedPassword: "expired"},
+	}
+
+	for _, testCase := range testCases {
+		authConfig := decodeTestAuth(t, refreshECRAuth(context.Background(), testCase.image, testCase.auth))
+		if authConfig.Password != testCase.expectedPassword {
+			t.Errorf("expected password of %s to be %s, actual %s", testCase.name, testCase.expectedPassword, authConfig.Password)
+		}
+	}
+
+	if api.tokenRequests != 1 {
+		t.Errorf("expected only the ecr token to be refreshed, actual %v requests", api.tokenRequests)
+	}
+}
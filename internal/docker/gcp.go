@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// gcpAccessTokenUsername is the username that Google Container Registry and
+	// Artifact Registry expect when the password is an OAuth2 access token
+	gcpAccessTokenUsername = "oauth2accesstoken"
+
+	gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// isGoogleRegistry returns true when the host is a Google Container Registry (e.g. gcr.io or eu.gcr.io)
+// or an Artifact Registry (e.g. us-central1-docker.pkg.dev) host
+func isGoogleRegistry(host string) bool {
+	return googleRegistryHostPattern.MatchString(strings.ToLower(host))
+}
+
+var googleRegistryHostPattern = regexp.MustCompile(`^(?:(?:[a-z0-9-]+\.)?gcr\.io|[a-z0-9-]+-docker\.pkg\.dev)$`)
+
+// findGoogleCredentials finds the Application Default Credentials, which are read from the file in the
+// GOOGLE_APPLICATION_CREDENTIALS environment variable, the credentials of gcloud auth application-default login,
+// or the service account of the Compute Engine instance, GKE node, or Cloud Run service.
+var findGoogleCredentials = func(ctx context.Context) (oauth2.TokenSource, error) {
+	credentials, err := google.FindDefaultCredentials(ctx, gcpCloudPlatformScope)
+	if err != nil {
+		return nil, fmt.Errorf("find default credentials: %w", err)
+	}
+
+	return credentials.TokenSource, nil
+}
+
+// googleTokenSource finds the Application Default Credentials once, as finding them
+// can require a request to the metadata server, and reuses their token until it expires
+type googleTokenSource struct {
+	once        sync.Once
+	tokenSource oauth2.TokenSource
+	err         error
+}
+
+var googleTokens = &googleTokenSource{}
+
+// getEncodedAuth returns the Base64 encoded auth that uses an access token of the Application Default
+// Credentials as its password, and false when there are no Application Default Credentials
+func (g *googleTokenSource) getEncodedAuth() (string, bool, error) {
+	g.once.Do(func() {
+		tokenSource, err := findGoogleCredentials(context.Background())
+		if err != nil {
+			g.err = err
+			return
+		}
+
+		g.tokenSource = oauth2.ReuseTokenSource(nil, tokenSource)
+	})
+
+	if g.err != nil {
+		return "", false, nil
+	}
+
+	token, err := g.tokenSource.Token()
+	if err != nil {
+		return "", true, fmt.Errorf("get access token: %w", err)
+	}
+
+	encodedAuth, err := GetEncodedBasicAuth(gcpAccessTokenUsername, token.AccessToken)
+	if err != nil {
+		return "", true, fmt.Errorf("get encoded basic auth: %w", err)
+	}
+
+	return encodedAuth, true, nil
+}
@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestIsGoogleRegistry(t *testing.T) {
+	testCases := []struct {
+		host     string
+		expected bool
+	}{
+		{host: "gcr.io", expected: true},
+		{host: "eu.gcr.io", expected: true},
+		{host: "us-central1-docker.pkg.dev", expected: true},
+		{host: "europe-docker.pkg.dev", expected: true},
+		{host: "docker.pkg.dev", expected: false},
+		{host: "gcr.io.mycompany.com", expected: false},
+		{host: "notgcr.io", expected: false},
+		{host: "mycompany.com", expected: false},
+	}
+
+	for _, testCase := range testCases {
+		actual := isGoogleRegistry(testCase.host)
+		if actual != testCase.expected {
+			t.Errorf("expected google registry to be %v for %s, actual %v", testCase.expected, testCase.host, actual)
+		}
+	}
+}
+
+func TestGetEncodedAuthForHost_GoogleCredentials(t *testing.T) {
+	defaultFindGoogleCredentials := findGoogleCredentials
+	findGoogleCredentials = func(ctx context.Context) (oauth2.TokenSource, error) {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "access-token"}), nil
+	}
+	defer func() { findGoogleCredentials = defaultFindGoogleCredentials }()
+
+	googleTokens = &googleTokenSource{}
+	defer func() { googleTokens = &googleTokenSource{} }()
+
+	encodedAuth, err := GetEncodedAuthForHost(context.Background(), "us-central1-docker.pkg.dev")
+	if err != nil {
+		t.Fatal("get encoded auth for host:", err)
+	}
+
+	authConfig := decodeTestAuth(t, encodedAuth)
+	if authConfig.Username != gcpAccessTokenUsername || authConfig.Password != "access-token" {
+		t.Errorf("expected the access token to be the password, actual %+v", authConfig)
+	}
+}
+
+func TestGetEncodedAuthForHost_GoogleCredentialsFallback(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(configDir)
+
+	os.Setenv("XDG_CONFIG_HOME", configDir)
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+
+	defaultFindGoogleCredentials := findGoogleCredentials
+	findGoogleCredentials = func(ctx context.Context) (oauth2.TokenSource, error) {
+		return nil, errors.New("could not find default credentials")
+	}
+	defer func() { findGoogleCredentials = defaultFindGoogleCredentials }()
+
+	googleTokens = &googleTokenSource{}
+	defer func() { googleTokens = &googleTokenSource{} }()
+
+	if err := SaveCredentials("gcr.io", "_json_key", "key"); err != nil {
+		t.Fatal("save credentials:", err)
+	}
+
+	actual, err := GetEncodedAuthForHost(context.Background(), "gcr.io")
+	if err != nil {
+		t.Fatal("get encoded auth for host:", err)
+	}
+
+	expected, err := GetEncodedBasicAuth("_json_key", "key")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	if actual != expected {
+		t.Errorf("expected the saved credentials to be used, actual %+v", decodeTestAuth(t, actual))
+	}
+}
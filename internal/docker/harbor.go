@@ -0,0 +1,171 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// harborAPIPath is the path of version 2.0 of the Harbor API, which is served by the registry host
+const harborAPIPath = "/api/v2.0"
+
+// harborRegistries records whether each registry host is a Harbor registry, so that
+// a registry is only detected once for all of the images that are pushed to it.
+// It is safe for concurrent use by images that are pushed at the same time.
+type harborRegistries struct {
+	mutex sync.Mutex
+	hosts map[string]bool
+}
+
+func newHarborRegistries() *harborRegistries {
+	return &harborRegistries{
+		hosts: make(map[string]bool),
+	}
+}
+
+func (r *harborRegistries) find(host string) (bool, bool) {
+	if r == nil {
+		return false, false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	isHarbor, exists := r.hosts[host]
+	return isHarbor, exists
+}
+
+func (r *harborRegistries) set(host string, isHarbor bool) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.hosts[host] = isHarbor
+}
+
+// isHarborRegistry returns true when the registry is a Harbor registry, which is detected from
+// the system info of the Harbor API, as Harbor registries can be served from any host
+func (c Client) isHarborRegistry(ctx context.Context, httpClient *http.Client, registry name.Registry) (bool, error) {
+	if isHarbor, exists := c.harborRegistries.find(registry.RegistryStr()); exists {
+		return isHarbor, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, getHarborURL(registry, "/systeminfo", nil), nil)
+	if err != nil {
+		return false, fmt.Errorf("new request: %w", err)
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("get system info: %w", err)
+	}
+	defer response.Body.Close()
+
+	var systemInfo struct {
+		HarborVersion string `json:"harbor_version"`
+	}
+
+	// Registries that are not Harbor do not serve the Harbor API, or serve something else at its path
+	isHarbor := response.StatusCode == http.StatusOK && json.NewDecoder(response.Body).Decode(&systemInfo) == nil && systemInfo.HarborVersion != ""
+	c.harborRegistries.set(registry.RegistryStr(), isHarbor)
+
+	return isHarbor, nil
+}
+
+// getHarborProject returns the Harbor project of the repository, which is its first path segment
+// (e.g. myteam for myteam/nginx). Harbor creates the repositories within a project when they are pushed.
+func getHarborProject(repository string) string {
+	return strings.SplitN(repository, "/", 2)[0]
+}
+
+// createHarborProjectIfNotExists creates the private Harbor project when it does not exist,
+// and returns true when the project was created
+func createHarborProjectIfNotExists(ctx context.Context, httpClient *http.Client, registry name.Registry, auth *authn.AuthConfig, project string) (bool, error) {
+	query := url.Values{}
+	query.Set("project_name", project)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, getHarborURL(registry, "/projects", query), nil)
+	if err != nil {
+		return false, fmt.Errorf("new request: %w", err)
+	}
+	setHarborAuth(request, auth)
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("head project: %w", err)
+	}
+	response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return false, nil
+	case http.StatusNotFound:
+	default:
+		return false, fmt.Errorf("head project: unexpected status %s", response.Status)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"project_name": project,
+		"metadata":     map[string]string{"public": "false"},
+	})
+	if err != nil {
+		return false, fmt.Errorf("marshal project: %w", err)
+	}
+
+	request, err = http.NewRequestWithContext(ctx, http.MethodPost, getHarborURL(registry, "/projects", nil), bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("new request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	setHarborAuth(request, auth)
+
+	response, err = httpClient.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("post project: %w", err)
+	}
+	defer response.Body.Close()
+
+	// Another push may have created the project since it was checked
+	switch response.StatusCode {
+	case http.StatusCreated:
+		return true, nil
+	case http.StatusConflict:
+		return false, nil
+	}
+
+	message, _ := ioutil.ReadAll(response.Body)
+	return false, fmt.Errorf("post project: unexpected status %s: %s", response.Status, strings.TrimSpace(string(message)))
+}
+
+// setHarborAuth sets the basic auth of the request to the Harbor API, unless the auth is anonymous
+func setHarborAuth(request *http.Request, auth *authn.AuthConfig) {
+	if auth.Username == "" && auth.Password == "" {
+		return
+	}
+
+	request.SetBasicAuth(auth.Username, auth.Password)
+}
+
+// getHarborURL returns the URL of the path of the Harbor API of the registry
+func getHarborURL(registry name.Registry, path string, query url.Values) string {
+	harborURL := url.URL{
+		Scheme:   registry.Scheme(),
+		Host:     registry.RegistryStr(),
+		Path:     harborAPIPath + path,
+		RawQuery: query.Encode(),
+	}
+
+	return harborURL.String()
+}
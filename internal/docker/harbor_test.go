@@ -0,0 +1,180 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// harborServer is a Harbor registry that only implements the system info and projects of the Harbor API.
+// Projects are reported as missing when they are checked when createConflict is set, and then fail to be
+// created because they already exist, as though another push created them at the same time.
+type harborServer struct {
+	mutex             sync.Mutex
+	projects          map[string]bool
+	systemInfoQueries int
+	createConflict    bool
+	createStatus      int
+
+	// auth is the basic auth of the last request to the projects API
+	auth string
+}
+
+func newHarborServer(projects ...string) *harborServer {
+	server := harborServer{projects: make(map[string]bool)}
+	for _, project := range projects {
+		server.projects[project] = true
+	}
+
+	return &server
+}
+
+func (s *harborServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch {
+	case r.URL.Path == "/api/v2.0/systeminfo":
+		s.systemInfoQueries++
+		w.Write([]byte(`{"auth_mode":"db_auth","harbor_version":"v2.1.0-1b9a2a4e"}`))
+
+	case r.URL.Path == "/api/v2.0/projects" && r.Method == http.MethodHead:
+		s.auth = r.Header.Get("Authorization")
+		if !s.projects[r.URL.Query().Get("project_name")] || s.createConflict {
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+	case r.URL.Path == "/api/v2.0/projects" && r.Method == http.MethodPost:
+		s.auth = r.Header.Get("Authorization")
+
+		var project struct {
+			ProjectName string `json:"project_name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if s.createStatus != 0 {
+			w.WriteHeader(s.createStatus)
+			return
+		}
+
+		if s.projects[project.ProjectName] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		s.projects[project.ProjectName] = true
+		w.WriteHeader(http.StatusCreated)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestHarborRegistry(t *testing.T, server *httptest.Server) name.Registry {
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal("new registry:", err)
+	}
+
+	return registry
+}
+
+func TestIsHarborRegistry(t *testing.T) {
+	harbor := newHarborServer()
+	server := httptest.NewServer(harbor)
+	defer server.Close()
+
+	otherServer := httptest.NewServer(http.NotFoundHandler())
+	defer otherServer.Close()
+
+	client := Client{harborRegistries: newHarborRegistries()}
+	for i := 0; i < 2; i++ {
+		isHarbor, err := client.isHarborRegistry(context.Background(), server.Client(), newTestHarborRegistry(t, server))
+		if err != nil {
+			t.Fatal("is harbor registry:", err)
+		}
+
+		if !isHarbor {
+			t.Error("expected the registry to be detected as Harbor")
+		}
+	}
+
+	if harbor.systemInfoQueries != 1 {
+		t.Errorf("expected the registry to only be detected once, actual %v queries", harbor.systemInfoQueries)
+	}
+
+	isHarbor, err := client.isHarborRegistry(context.Background(), otherServer.Client(), newTestHarborRegistry(t, otherServer))
+	if err != nil {
+		t.Fatal("is harbor registry:", err)
+	}
+
+	if isHarbor {
+		t.Error("expected a registry without the Harbor API to not be detected as Harbor")
+	}
+}
+
+func TestCreateHarborProjectIfNotExists(t *testing.T) {
+	testCases := []struct {
+		name            string
+		harbor          *harborServer
+		expectedCreated bool
+		expectedError   bool
+	}{
+		{name: "existing project", harbor: newHarborServer("myteam"), expectedCreated: false},
+		{name: "missing project", harbor: newHarborServer(), expectedCreated: true},
+		{name: "created by another push", harbor: &harborServer{projects: map[string]bool{"myteam": true}, createConflict: true}, expectedCreated: false},
+		{name: "create error", harbor: &harborServer{projects: map[string]bool{}, createStatus: http.StatusForbidden}, expectedError: true},
+	}
+
+	auth := authn.AuthConfig{Username: "admin", Password: "Harbor12345"}
+	for _, testCase := range testCases {
+		server := httptest.NewServer(testCase.harbor)
+
+		created, err := createHarborProjectIfNotExists(context.Background(), server.Client(), newTestHarborRegistry(t, server), &auth, "myteam")
+		server.Close()
+
+		if testCase.expectedError != (err != nil) {
+			t.Errorf("expected error to be %v for %s, actual %v", testCase.expectedError, testCase.name, err)
+		}
+
+		if created != testCase.expectedCreated {
+			t.Errorf("expected created to be %v for %s, actual %v", testCase.expectedCreated, testCase.name, created)
+		}
+
+		if !testCase.expectedError && !testCase.harbor.projects["myteam"] {
+			t.Errorf("expected the myteam project to exist for %s", testCase.name)
+		}
+
+		request := http.Request{Header: http.Header{"Authorization": []string{testCase.harbor.auth}}}
+		if username, password, ok := request.BasicAuth(); !ok || username != auth.Username || password != auth.Password {
+			t.Errorf("expected the basic auth of the credentials for %s, actual %s", testCase.name, testCase.harbor.auth)
+		}
+	}
+}
+
+func TestGetHarborProject(t *testing.T) {
+	testCases := []struct {
+		repository string
+		expected   string
+	}{
+		{repository: "myteam/nginx", expected: "myteam"},
+		{repository: "myteam/coreos/etcd", expected: "myteam"},
+		{repository: "nginx", expected: "nginx"},
+	}
+
+	for _, testCase := range testCases {
+		if actual := getHarborProject(testCase.repository); actual != testCase.expected {
+			t.Errorf("expected project of %s to be %s, actual %s", testCase.repository, testCase.expected, actual)
+		}
+	}
+}
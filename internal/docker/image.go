@@ -0,0 +1,27 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+)
+
+// TagImage tags the source image on the host machine as the target image
+func (c Client) TagImage(ctx context.Context, source string, target string) error {
+	if err := c.DockerClient.ImageTag(ctx, source, target); err != nil {
+		return fmt.Errorf("image tag: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveImageOnHost removes the image from the host machine. The layers of the image
+// are only removed once no other image on the host machine refers to them.
+func (c Client) RemoveImageOnHost(ctx context.Context, image string) error {
+	if _, err := c.DockerClient.ImageRemove(ctx, image, types.ImageRemoveOptions{}); err != nil {
+		return fmt.Errorf("image remove: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,318 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	v1types "github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// Platform is the operating system and architecture an image is built for
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// ParsePlatform parses a platform in the format os/architecture[/variant] (e.g. linux/arm64/v8)
+func ParsePlatform(platform string) (Platform, error) {
+	platformTokens := strings.Split(platform, "/")
+	if len(platformTokens) < 2 || len(platformTokens) > 3 || platformTokens[0] == "" || platformTokens[1] == "" {
+		return Platform{}, fmt.Errorf("platform %s is not in the format os/architecture[/variant]", platform)
+	}
+
+	parsedPlatform := Platform{
+		OS:           platformTokens[0],
+		Architecture: platformTokens[1],
+	}
+
+	if len(platformTokens) == 3 {
+		parsedPlatform.Variant = platformTokens[2]
+	}
+
+	return parsedPlatform, nil
+}
+
+func (p Platform) String() string {
+	platform := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		platform = platform + "/" + p.Variant
+	}
+
+	return platform
+}
+
+// matches returns true when the platform of an image is the platform.
+// When no variant is set, images of every variant match.
+func (p Platform) matches(platform *v1.Platform) bool {
+	if platform == nil {
+		return false
+	}
+
+	if p.Variant != "" && p.Variant != platform.Variant {
+		return false
+	}
+
+	return p.OS == platform.OS && p.Architecture == platform.Architecture
+}
+
+// IsImageIndexAtRemote returns true when the image at the remote registry
+// is a manifest list that contains an image for each of its platforms
+func (c Client) IsImageIndexAtRemote(ctx context.Context, image string, auth string) (bool, error) {
+	imageReference, err := c.parseReference(image)
+	if err != nil {
+		return false, fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return false, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	descriptor, err := remote.Get(imageReference, c.getRemoteOptions(ctx, imageReference.Context().Registry, remote.WithAuth(authenticator))...)
+	if err != nil {
+		return false, fmt.Errorf("get image: %w", err)
+	}
+
+	return descriptor.MediaType == v1types.DockerManifestList || descriptor.MediaType == v1types.OCIImageIndex, nil
+}
+
+// GetPlatformsAtRemote returns the platforms of the image at the remote registry.
+// A manifest list returns the platform of each of its images.
+func (c Client) GetPlatformsAtRemote(ctx context.Context, image string, auth string) ([]Platform, error) {
+	imageReference, err := c.parseReference(image)
+	if err != nil {
+		return nil, fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return nil, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	descriptor, err := remote.Get(imageReference, c.getRemoteOptions(ctx, imageReference.Context().Registry, remote.WithAuth(authenticator))...)
+	if err != nil {
+		return nil, fmt.Errorf("get image: %w", err)
+	}
+
+	if descriptor.MediaType == v1types.DockerManifestList || descriptor.MediaType == v1types.OCIImageIndex {
+		imageIndex, err := descriptor.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("get index: %w", err)
+		}
+
+		indexManifest, err := imageIndex.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("index manifest: %w", err)
+		}
+
+		var platforms []Platform
+		for _, manifest := range indexManifest.Manifests {
+			if manifest.Platform == nil {
+				continue
+			}
+
+			platforms = append(platforms, Platform{
+				OS:           manifest.Platform.OS,
+				Architecture: manifest.Platform.Architecture,
+				Variant:      manifest.Platform.Variant,
+			})
+		}
+
+		return platforms, nil
+	}
+
+	remoteImage, err := descriptor.Image()
+	if err != nil {
+		return nil, fmt.Errorf("get image: %w", err)
+	}
+
+	configFile, err := remoteImage.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+
+	platform := Platform{
+		OS:           configFile.OS,
+		Architecture: configFile.Architecture,
+	}
+
+	return []Platform{platform}, nil
+}
+
+// VerifyPlatformsAtRemote returns an error when any of the platforms is not present in the image at the remote registry.
+// The config of a single image does not record a variant, so only its operating system and architecture are compared.
+func (c Client) VerifyPlatformsAtRemote(ctx context.Context, image string, auth string, platforms []Platform) error {
+	availablePlatforms, err := c.GetPlatformsAtRemote(ctx, image, auth)
+	if err != nil {
+		return fmt.Errorf("get platforms: %w", err)
+	}
+
+	for _, platform := range platforms {
+		if !containsPlatform(availablePlatforms, platform) {
+			return fmt.Errorf("platform %s not found in %s (available platforms: %v)", platform, image, availablePlatforms)
+		}
+	}
+
+	return nil
+}
+
+func containsPlatform(platforms []Platform, platform Platform) bool {
+	for _, currentPlatform := range platforms {
+		if currentPlatform.OS != platform.OS || currentPlatform.Architecture != platform.Architecture {
+			continue
+		}
+
+		if currentPlatform.Variant == "" || platform.Variant == "" || currentPlatform.Variant == platform.Variant {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CopyImageIndexAndWait copies a manifest list and the images of each of its platforms from the
+// source registry to the target registry. When platforms are given, only the images of those
+// platforms are copied and the manifest list at the target only contains those images.
+func (c Client) CopyImageIndexAndWait(ctx context.Context, source string, target string, platforms []Platform, sourceAuth string, targetAuth string) error {
+	ctx, cancel := c.newOperationContext(ctx)
+	defer cancel()
+
+	var digest string
+	retryError := c.retry(
+		ctx,
+		func() error {
+			var err error
+			digest, err = c.tryCopyImageIndex(ctx, source, target, platforms, sourceAuth, targetAuth)
+			if err != nil {
+				return fmt.Errorf("try copy image index: %w", err)
+			}
+
+			return nil
+		},
+		func(retryAttempt uint, err error) {
+			newImageLogEntry(c.Logger, "copy", source).Printf("[RETRY] Unable to copy %v (Retrying #%v)", source, retryAttempt+1)
+			sourceAuth = refreshECRAuth(ctx, source, sourceAuth)
+			targetAuth = refreshECRAuth(ctx, target, targetAuth)
+		},
+	)
+
+	if retryError != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("copy of %s timed out after %s: %w", source, c.Timeout, retryError)
+	}
+
+	if retryError != nil {
+		return retryError
+	}
+
+	if err := c.verifyPushedDigest(ctx, target, digest, targetAuth); err != nil {
+		return fmt.Errorf("verify pushed digest: %w", err)
+	}
+
+	return nil
+}
+
+func (c Client) tryCopyImageIndex(ctx context.Context, source string, target string, platforms []Platform, sourceAuth string, targetAuth string) (string, error) {
+	// Images are read from the mirror of the source registry, when it has one
+	source, sourceAuth, err := c.getMirrorSource(ctx, source, sourceAuth)
+	if err != nil {
+		return "", fmt.Errorf("get mirror source: %w", err)
+	}
+
+	sourceReference, err := c.parseReference(source)
+	if err != nil {
+		return "", fmt.Errorf("parse source ref: %w", err)
+	}
+
+	targetReference, err := c.parseReference(target)
+	if err != nil {
+		return "", fmt.Errorf("parse target ref: %w", err)
+	}
+
+	sourceAuthenticator, err := getAuthenticator(sourceAuth)
+	if err != nil {
+		return "", fmt.Errorf("get source authenticator: %w", err)
+	}
+
+	targetAuthenticator, err := getAuthenticator(targetAuth)
+	if err != nil {
+		return "", fmt.Errorf("get target authenticator: %w", err)
+	}
+
+	imageIndex, err := remote.Index(sourceReference, c.getRemoteOptions(ctx, sourceReference.Context().Registry, remote.WithAuth(sourceAuthenticator))...)
+	if err != nil {
+		return "", fmt.Errorf("get source index: %w", err)
+	}
+
+	if len(platforms) > 0 {
+		imageIndex, err = filterImageIndex(imageIndex, platforms)
+		if err != nil {
+			return "", fmt.Errorf("filter %s: %w", source, err)
+		}
+	}
+
+	if err := remote.WriteIndex(targetReference, imageIndex, c.getRemoteOptions(ctx, targetReference.Context().Registry, remote.WithAuth(targetAuthenticator))...); err != nil {
+		return "", fmt.Errorf("write target index: %w", err)
+	}
+
+	digest, err := imageIndex.Digest()
+	if err != nil {
+		return "", fmt.Errorf("digest: %w", err)
+	}
+
+	return digest.String(), nil
+}
+
+// filterImageIndex returns a manifest list that only contains the images of the given platforms
+func filterImageIndex(imageIndex v1.ImageIndex, platforms []Platform) (v1.ImageIndex, error) {
+	indexManifest, err := imageIndex.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("index manifest: %w", err)
+	}
+
+	var addenda []mutate.IndexAddendum
+	for _, descriptor := range indexManifest.Manifests {
+		if !matchesAnyPlatform(platforms, descriptor.Platform) {
+			continue
+		}
+
+		image, err := imageIndex.Image(descriptor.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("get image %s: %w", descriptor.Digest, err)
+		}
+
+		addenda = append(addenda, mutate.IndexAddendum{
+			Add:        image,
+			Descriptor: descriptor,
+		})
+	}
+
+	if len(addenda) == 0 {
+		return nil, fmt.Errorf("no images found for platforms %v", platforms)
+	}
+
+	mediaType, err := imageIndex.MediaType()
+	if err != nil {
+		return nil, fmt.Errorf("media type: %w", err)
+	}
+
+	filteredIndex := mutate.AppendManifests(empty.Index, addenda...)
+
+	return mutate.IndexMediaType(filteredIndex, mediaType), nil
+}
+
+func matchesAnyPlatform(platforms []Platform, platform *v1.Platform) bool {
+	for _, currentPlatform := range platforms {
+		if currentPlatform.matches(platform) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,220 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	stdlog "log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	v1types "github.com/google/go-containerregistry/pkg/v1/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// writeMultiArchImage writes a manifest list with a linux/amd64 and linux/arm64 image to the registry
+func writeMultiArchImage(t *testing.T, image string) v1.ImageIndex {
+	var addenda []mutate.IndexAddendum
+	for _, architecture := range []string{"amd64", "arm64"} {
+		platformImage, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		addenda = append(addenda, mutate.IndexAddendum{
+			Add: platformImage,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{OS: "linux", Architecture: architecture},
+			},
+		})
+	}
+
+	imageIndex := mutate.IndexMediaType(mutate.AppendManifests(empty.Index, addenda...), v1types.DockerManifestList)
+
+	imageReference, err := name.ParseReference(image)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.WriteIndex(imageReference, imageIndex); err != nil {
+		t.Fatal("write index:", err)
+	}
+
+	return imageIndex
+}
+
+func getRemoteIndexManifest(t *testing.T, image string) *v1.IndexManifest {
+	imageReference, err := name.ParseReference(image)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	imageIndex, err := remote.Index(imageReference)
+	if err != nil {
+		t.Fatal("get index:", err)
+	}
+
+	indexManifest, err := imageIndex.IndexManifest()
+	if err != nil {
+		t.Fatal("index manifest:", err)
+	}
+
+	return indexManifest
+}
+
+func TestCopyImageIndexAndWait(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+	sourceImage := registryHost + "/source/app:v1.0.0"
+	sourceIndex := writeMultiArchImage(t, sourceImage)
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := Client{
+		Logger:      logger,
+		RetryPolicy: RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+	}
+
+	isImageIndex, err := client.IsImageIndexAtRemote(context.Background(), sourceImage, "")
+	if err != nil {
+		t.Fatal("is image index:", err)
+	}
+
+	if !isImageIndex {
+		t.Errorf("expected %s to be an image index", sourceImage)
+	}
+
+	if err := client.CopyImageIndexAndWait(context.Background(), sourceImage, registryHost+"/target/app:v1.0.0", nil, "", ""); err != nil {
+		t.Fatal("copy image index:", err)
+	}
+
+	sourceDigest, err := sourceIndex.Digest()
+	if err != nil {
+		t.Fatal("source digest:", err)
+	}
+
+	targetDigests, err := client.GetDigestsAtRemote(context.Background(), registryHost+"/target/app:v1.0.0", "")
+	if err != nil {
+		t.Fatal("get target digests:", err)
+	}
+
+	if len(targetDigests) != 3 || targetDigests[0] != sourceDigest.String() {
+		t.Errorf("expected target to be the source index %s with two images, actual %v", sourceDigest, targetDigests)
+	}
+
+	platforms := []Platform{{OS: "linux", Architecture: "arm64"}}
+	if err := client.CopyImageIndexAndWait(context.Background(), sourceImage, registryHost+"/target/app:arm64", platforms, "", ""); err != nil {
+		t.Fatal("copy filtered image index:", err)
+	}
+
+	indexManifest := getRemoteIndexManifest(t, registryHost+"/target/app:arm64")
+	if len(indexManifest.Manifests) != 1 || indexManifest.Manifests[0].Platform.Architecture != "arm64" {
+		t.Errorf("expected target to only contain the arm64 image, actual %v", indexManifest.Manifests)
+	}
+
+	if indexManifest.MediaType != v1types.DockerManifestList {
+		t.Errorf("expected media type to be %s, actual %s", v1types.DockerManifestList, indexManifest.MediaType)
+	}
+
+	platforms = []Platform{{OS: "linux", Architecture: "s390x"}}
+	if err := client.CopyImageIndexAndWait(context.Background(), sourceImage, registryHost+"/target/app:s390x", platforms, "", ""); err == nil {
+		t.Error("expected missing platform to return an error")
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	testCases := []struct {
+		platform         string
+		expectedPlatform Platform
+		expectedError    bool
+	}{
+		{platform: "linux/amd64", expectedPlatform: Platform{OS: "linux", Architecture: "amd64"}},
+		{platform: "linux/arm64/v8", expectedPlatform: Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+		{platform: "linux", expectedError: true},
+		{platform: "linux//v8", expectedError: true},
+		{platform: "linux/arm/v7/extra", expectedError: true},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := ParsePlatform(testCase.platform)
+		if testCase.expectedError {
+			if err == nil {
+				t.Errorf("expected %s to return an error", testCase.platform)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal("parse platform:", err)
+		}
+
+		if actual != testCase.expectedPlatform {
+			t.Errorf("expected platform to be %v, actual %v", testCase.expectedPlatform, actual)
+		}
+
+		if actual.String() != testCase.platform {
+			t.Errorf("expected platform string to be %s, actual %s", testCase.platform, actual.String())
+		}
+	}
+}
+
+func TestVerifyPlatformsAtRemote(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+	multiArchImage := registryHost + "/source/app:multi-arch"
+	writeMultiArchImage(t, multiArchImage)
+
+	singleArchImage, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	singleArchImage, err = mutate.ConfigFile(singleArchImage, &v1.ConfigFile{OS: "linux", Architecture: "amd64"})
+	if err != nil {
+		t.Fatal("set config file:", err)
+	}
+
+	singleArchReference, err := name.ParseReference(registryHost + "/source/app:single-arch")
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(singleArchReference, singleArchImage); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	testCases := []struct {
+		image         string
+		platform      Platform
+		expectedError bool
+	}{
+		{image: multiArchImage, platform: Platform{OS: "linux", Architecture: "arm64"}},
+		{image: multiArchImage, platform: Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+		{image: multiArchImage, platform: Platform{OS: "linux", Architecture: "s390x"}, expectedError: true},
+		{image: singleArchReference.String(), platform: Platform{OS: "linux", Architecture: "amd64"}},
+		{image: singleArchReference.String(), platform: Platform{OS: "linux", Architecture: "arm64"}, expectedError: true},
+	}
+
+	client := Client{}
+	for _, testCase := range testCases {
+		err := client.VerifyPlatformsAtRemote(context.Background(), testCase.image, "", []Platform{testCase.platform})
+		if testCase.expectedError && err == nil {
+			t.Errorf("expected platform %s of %s to return an error", testCase.platform, testCase.image)
+		}
+
+		if !testCase.expectedError && err != nil {
+			t.Errorf("expected platform %s of %s to be found, actual error %s", testCase.platform, testCase.image, err)
+		}
+	}
+}
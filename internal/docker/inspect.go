@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	v1types "github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// ImageDetails are the details of the manifest of an image at a remote registry
+type ImageDetails struct {
+	Digest    string
+	MediaType string
+
+	// Layers is the number of layers, and Size is the total compressed size of the layers. The layers
+	// of a manifest list are the layers of the images of every platform, with shared layers counted once.
+	Layers int
+	Size   int64
+
+	// Platforms are the images of each platform of a manifest list
+	Platforms []PlatformDetails
+}
+
+// PlatformDetails are the details of the manifest of the image of a platform in a manifest list
+type PlatformDetails struct {
+	Platform Platform
+	Digest   string
+	Layers   int
+	Size     int64
+}
+
+// InspectImageAtRemote returns the details of the manifest of the image at the remote registry.
+// Only the manifests of the image, and of each platform of a manifest list, are requested.
+func (c Client) InspectImageAtRemote(ctx context.Context, image string, auth string) (ImageDetails, error) {
+	imageReference, err := c.parseReference(image)
+	if err != nil {
+		return ImageDetails{}, fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return ImageDetails{}, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	descriptor, err := remote.Get(imageReference, c.getRemoteOptions(ctx, imageReference.Context().Registry, remote.WithAuth(authenticator))...)
+	if err != nil {
+		return ImageDetails{}, fmt.Errorf("get image: %w", err)
+	}
+
+	details := ImageDetails{
+		Digest:    descriptor.Digest.String(),
+		MediaType: string(descriptor.MediaType),
+	}
+
+	if descriptor.MediaType != v1types.DockerManifestList && descriptor.MediaType != v1types.OCIImageIndex {
+		remoteImage, err := descriptor.Image()
+		if err != nil {
+			return ImageDetails{}, fmt.Errorf("get image: %w", err)
+		}
+
+		details.Layers, details.Size, err = getLayerTotals(remoteImage)
+		if err != nil {
+			return ImageDetails{}, fmt.Errorf("get layer totals: %w", err)
+		}
+
+		return details, nil
+	}
+
+	imageIndex, err := descriptor.ImageIndex()
+	if err != nil {
+		return ImageDetails{}, fmt.Errorf("get index: %w", err)
+	}
+
+	indexManifest, err := imageIndex.IndexManifest()
+	if err != nil {
+		return ImageDetails{}, fmt.Errorf("index manifest: %w", err)
+	}
+
+	layerSizes := make(map[string]int64)
+	for _, manifest := range indexManifest.Manifests {
+		platformImage, err := imageIndex.Image(manifest.Digest)
+		if err != nil {
+			return ImageDetails{}, fmt.Errorf("get image %s: %w", manifest.Digest, err)
+		}
+
+		platform := PlatformDetails{
+			Platform: getDescriptorPlatform(manifest),
+			Digest:   manifest.Digest.String(),
+		}
+
+		platform.Layers, platform.Size, err = getLayerTotals(platformImage)
+		if err != nil {
+			return ImageDetails{}, fmt.Errorf("get layer totals of %s: %w", manifest.Digest, err)
+		}
+
+		if err := addLayerSizes(layerSizes, platformImage); err != nil {
+			return ImageDetails{}, fmt.Errorf("add layer sizes of %s: %w", manifest.Digest, err)
+		}
+
+		details.Platforms = append(details.Platforms, platform)
+	}
+
+	details.Layers = len(layerSizes)
+	for _, size := range layerSizes {
+		details.Size += size
+	}
+
+	return details, nil
+}
+
+// getDescriptorPlatform returns the platform of an image in a manifest list, which is
+// empty when the manifest list does not record one, such as for attestations
+func getDescriptorPlatform(descriptor v1.Descriptor) Platform {
+	if descriptor.Platform == nil {
+		return Platform{}
+	}
+
+	return Platform{
+		OS:           descriptor.Platform.OS,
+		Architecture: descriptor.Platform.Architecture,
+		Variant:      descriptor.Platform.Variant,
+	}
+}
+
+// getLayerTotals returns the number of layers of the image and their total compressed size
+func getLayerTotals(image v1.Image) (int, int64, error) {
+	manifest, err := image.Manifest()
+	if err != nil {
+		return 0, 0, fmt.Errorf("manifest: %w", err)
+	}
+
+	var size int64
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+
+	return len(manifest.Layers), size, nil
+}
@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	stdlog "log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	v1types "github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+func TestInspectImageAtRemote_Image(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+
+	image := strings.TrimPrefix(registryServer.URL, "http://") + "/app:v1.0.0"
+
+	contents, err := random.Image(1024, 3)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	writeImage(t, image, contents)
+
+	details, err := Client{}.InspectImageAtRemote(context.Background(), image, "")
+	if err != nil {
+		t.Fatal("inspect image:", err)
+	}
+
+	expectedDigest, err := contents.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	manifest, err := contents.Manifest()
+	if err != nil {
+		t.Fatal("manifest:", err)
+	}
+
+	var expectedSize int64
+	for _, layer := range manifest.Layers {
+		expectedSize += layer.Size
+	}
+
+	if details.Digest != expectedDigest.String() {
+		t.Errorf("expected digest %s, actual %s", expectedDigest, details.Digest)
+	}
+
+	if details.MediaType != string(v1types.DockerManifestSchema2) {
+		t.Errorf("expected media type %s, actual %s", v1types.DockerManifestSchema2, details.MediaType)
+	}
+
+	if details.Layers != 3 {
+		t.Errorf("expected 3 layers, actual %d", details.Layers)
+	}
+
+	if details.Size != expectedSize {
+		t.Errorf("expected size %d, actual %d", expectedSize, details.Size)
+	}
+
+	if len(details.Platforms) != 0 {
+		t.Errorf("expected an image to not have platforms, actual %v", details.Platforms)
+	}
+}
+
+func TestInspectImageAtRemote_ManifestList(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+
+	image := strings.TrimPrefix(registryServer.URL, "http://") + "/app:v1.0.0"
+	imageIndex := writeMultiArchImage(t, image)
+
+	details, err := Client{}.InspectImageAtRemote(context.Background(), image, "")
+	if err != nil {
+		t.Fatal("inspect image:", err)
+	}
+
+	expectedDigest, err := imageIndex.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	if details.Digest != expectedDigest.String() {
+		t.Errorf("expected digest %s, actual %s", expectedDigest, details.Digest)
+	}
+
+	if details.MediaType != string(v1types.DockerManifestList) {
+		t.Errorf("expected media type %s, actual %s", v1types.DockerManifestList, details.MediaType)
+	}
+
+	indexManifest, err := imageIndex.IndexManifest()
+	if err != nil {
+		t.Fatal("index manifest:", err)
+	}
+
+	if len(details.Platforms) != len(indexManifest.Manifests) {
+		t.Fatalf("expected %d platforms, actual %d", len(indexManifest.Manifests), len(details.Platforms))
+	}
+
+	var expectedSize int64
+	expectedPlatforms := []string{"linux/amd64", "linux/arm64"}
+	for i, platform := range details.Platforms {
+		if platform.Platform.String() != expectedPlatforms[i] {
+			t.Errorf("expected platform %s, actual %s", expectedPlatforms[i], platform.Platform)
+		}
+
+		if platform.Digest != indexManifest.Manifests[i].Digest.String() {
+			t.Errorf("expected digest of %s to be %s, actual %s", platform.Platform, indexManifest.Manifests[i].Digest, platform.Digest)
+		}
+
+		if platform.Layers != 1 {
+			t.Errorf("expected %s to have 1 layer, actual %d", platform.Platform, platform.Layers)
+		}
+
+		expectedSize += platform.Size
+	}
+
+	if details.Layers != 2 {
+		t.Errorf("expected the layers of every platform, actual %d", details.Layers)
+	}
+
+	if details.Size != expectedSize {
+		t.Errorf("expected size %d, actual %d", expectedSize, details.Size)
+	}
+}
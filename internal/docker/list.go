@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types"
-	"github.com/google/go-containerregistry/pkg/authn"
-	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	v1types "github.com/google/go-containerregistry/pkg/v1/types"
 )
 
 // ImageExistsOnHost returns true if the image exists on the host machine
@@ -19,6 +22,12 @@ func (c Client) ImageExistsOnHost(ctx context.Context, image string) (bool, erro
 		return false, nil
 	}
 
+	return c.ImageStoredOnHost(ctx, image)
+}
+
+// ImageStoredOnHost returns true if the image is stored on the host machine. Unlike ImageExistsOnHost,
+// images with the latest tag are found, as ImageExistsOnHost reports them as missing so that they are pulled.
+func (c Client) ImageStoredOnHost(ctx context.Context, image string) (bool, error) {
 	var images []string
 	var err error
 	if strings.Contains(image, "@") {
@@ -38,6 +47,13 @@ func (c Client) ImageExistsOnHost(ctx context.Context, image string) (bool, erro
 }
 
 func imageExists(image string, images []string) bool {
+	// Images on the host are only referenced by their repository and digest,
+	// so the tag of an image with both a tag and a digest is ignored
+	imagePath := RegistryPath(image)
+	if imagePath.Digest() != "" && imagePath.Tag() != "" {
+		image = strings.TrimSuffix(strings.Split(image, "@")[0], ":"+imagePath.Tag()) + "@" + imagePath.Digest()
+	}
+
 	// When an image is sourced from docker hub, the image tag does
 	// not include docker.io (or library) on the local machine
 	image = strings.ReplaceAll(image, "docker.io/library/", "")
@@ -53,25 +69,24 @@ func imageExists(image string, images []string) bool {
 }
 
 // ImageExistsAtRemote returns true if the image exists at the remote registry
-func (c Client) ImageExistsAtRemote(ctx context.Context, image string) (bool, error) {
+func (c Client) ImageExistsAtRemote(ctx context.Context, image string, encodedAuth string) (bool, error) {
 	if hasLatestTag(image) {
 		return false, nil
 	}
 
-	imageReference, err := name.ParseReference(image, name.WeakValidation)
+	imageReference, err := c.parseReference(image)
 	if err != nil {
 		return false, fmt.Errorf("parse ref: %w", err)
 	}
 
-	_, err = remote.Get(imageReference, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	authOption, err := withEncodedAuth(encodedAuth)
+	if err != nil {
+		return false, fmt.Errorf("get auth: %w", err)
+	}
 
-	var transportError *transport.Error
-	if errors.As(err, &transportError) {
-		for _, diagnostic := range transportError.Errors {
-			if strings.EqualFold("MANIFEST_UNKNOWN", string(diagnostic.Code)) {
-				return false, nil
-			}
-		}
+	_, err = remote.Get(imageReference, c.getRemoteOptions(ctx, imageReference.Context().Registry, authOption)...)
+	if isImageNotFound(err) {
+		return false, nil
 	}
 
 	if err != nil {
@@ -81,6 +96,183 @@ func (c Client) ImageExistsAtRemote(ctx context.Context, image string) (bool, er
 	return true, nil
 }
 
+// ImageAvailability is the availability of an image at a remote registry
+type ImageAvailability string
+
+const (
+	// ImageAvailable is an image that exists and can be pulled
+	ImageAvailable ImageAvailability = "available"
+
+	// ImageMissing is an image that does not exist
+	ImageMissing ImageAvailability = "missing"
+
+	// ImageUnauthorized is an image that could not be accessed with the configured auth
+	ImageUnauthorized ImageAvailability = "unauthorized"
+)
+
+// GetImageAvailabilityAtRemote returns the availability of the image at the remote registry
+// by requesting the manifest of the image (HEAD) without downloading it.
+func (c Client) GetImageAvailabilityAtRemote(ctx context.Context, image string, encodedAuth string) (ImageAvailability, error) {
+	imageReference, err := c.parseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("parse ref: %w", err)
+	}
+
+	registry := imageReference.Context().Registry
+	auth, err := getAuthenticator(encodedAuth)
+	if err != nil {
+		return "", fmt.Errorf("get authenticator: %w", err)
+	}
+
+	scopes := []string{imageReference.Scope(transport.PullScope)}
+	registryTransport, err := transport.New(registry, auth, c.getContextTransport(ctx, registry), scopes)
+	if isUnauthorized(err) {
+		return ImageUnauthorized, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("new transport: %w", err)
+	}
+
+	manifestURL := url.URL{
+		Scheme: registry.Scheme(),
+		Host:   registry.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/manifests/%s", imageReference.Context().RepositoryStr(), imageReference.Identifier()),
+	}
+
+	request, err := http.NewRequest(http.MethodHead, manifestURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+
+	acceptedMediaTypes := []string{
+		string(v1types.DockerManifestSchema2),
+		string(v1types.DockerManifestList),
+		string(v1types.OCIManifestSchema1),
+		string(v1types.OCIImageIndex),
+	}
+	request.Header.Set("Accept", strings.Join(acceptedMediaTypes, ","))
+
+	httpClient := http.Client{Transport: registryTransport}
+	response, err := httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("head manifest: %w", err)
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return ImageAvailable, nil
+	case http.StatusNotFound:
+		return ImageMissing, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ImageUnauthorized, nil
+	default:
+		return "", fmt.Errorf("unexpected status code %v", response.StatusCode)
+	}
+}
+
+// GetDigestsAtRemote returns the digest of the image at the remote registry.
+// When the image is a manifest list, the digests of each of the images in the
+// manifest list are also returned. No digests are returned if the image does not exist.
+func (c Client) GetDigestsAtRemote(ctx context.Context, image string, encodedAuth string) ([]string, error) {
+	imageReference, err := c.parseReference(image)
+	if err != nil {
+		return nil, fmt.Errorf("parse ref: %w", err)
+	}
+
+	authOption, err := withEncodedAuth(encodedAuth)
+	if err != nil {
+		return nil, fmt.Errorf("get auth: %w", err)
+	}
+
+	descriptor, err := remote.Get(imageReference, c.getRemoteOptions(ctx, imageReference.Context().Registry, authOption)...)
+	if isImageNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get image: %w", err)
+	}
+
+	digests := []string{descriptor.Digest.String()}
+	if descriptor.MediaType != v1types.DockerManifestList && descriptor.MediaType != v1types.OCIImageIndex {
+		return digests, nil
+	}
+
+	imageIndex, err := descriptor.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("image index: %w", err)
+	}
+
+	indexManifest, err := imageIndex.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("index manifest: %w", err)
+	}
+
+	for _, manifest := range indexManifest.Manifests {
+		digests = append(digests, manifest.Digest.String())
+	}
+
+	return digests, nil
+}
+
+// GetCreatedAtRemote returns the time the image at the remote registry was created, from the config
+// of the image. The config of the first image is used for multi-arch images. A zero time is returned
+// when the image has no creation time, such as images built reproducibly with a creation time of 0.
+func (c Client) GetCreatedAtRemote(ctx context.Context, image string, encodedAuth string) (time.Time, error) {
+	imageReference, err := c.parseReference(image)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse ref: %w", err)
+	}
+
+	authOption, err := withEncodedAuth(encodedAuth)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get auth: %w", err)
+	}
+
+	descriptor, err := remote.Get(imageReference, c.getRemoteOptions(ctx, imageReference.Context().Registry, authOption)...)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get image: %w", err)
+	}
+
+	var remoteImage v1.Image
+	if descriptor.MediaType == v1types.DockerManifestList || descriptor.MediaType == v1types.OCIImageIndex {
+		imageIndex, err := descriptor.ImageIndex()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("get index: %w", err)
+		}
+
+		indexManifest, err := imageIndex.IndexManifest()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("index manifest: %w", err)
+		}
+
+		if len(indexManifest.Manifests) == 0 {
+			return time.Time{}, nil
+		}
+
+		remoteImage, err = imageIndex.Image(indexManifest.Manifests[0].Digest)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("get index image: %w", err)
+		}
+	} else {
+		remoteImage, err = descriptor.Image()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("get image: %w", err)
+		}
+	}
+
+	configFile, err := remoteImage.ConfigFile()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("config file: %w", err)
+	}
+
+	if configFile.Created.Time.Unix() <= 0 {
+		return time.Time{}, nil
+	}
+
+	return configFile.Created.Time, nil
+}
+
 // GetAllImagesOnHost gets all of the images and their tags on the host
 func (c Client) GetAllImagesOnHost(ctx context.Context) ([]string, error) {
 	var images []string
@@ -112,7 +304,7 @@ func (c Client) GetAllDigestsOnHost(ctx context.Context) ([]string, error) {
 }
 
 // GetTagsForRepo returns all of the tags for a given repository
-func (c Client) GetTagsForRepo(ctx context.Context, host string, repository string) ([]string, error) {
+func (c Client) GetTagsForRepo(ctx context.Context, host string, repository string, encodedAuth string) ([]string, error) {
 	var imageRepository string
 	if host != "" {
 		imageRepository = host + "/" + repository
@@ -120,12 +312,18 @@ func (c Client) GetTagsForRepo(ctx context.Context, host string, repository stri
 		imageRepository = "index.docker.io/" + repository
 	}
 
-	repositoryReference, err := name.NewRepository(imageRepository)
+	repositoryReference, err := c.newRepository(imageRepository)
 	if err != nil {
 		return nil, fmt.Errorf("new repo: %w", err)
 	}
 
-	tags, err := remote.List(repositoryReference, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	authOption, err := withEncodedAuth(encodedAuth)
+	if err != nil {
+		return nil, fmt.Errorf("get auth: %w", err)
+	}
+
+	// Registries that paginate the tag list are followed using the Link header until every tag has been listed
+	tags, err := remote.ListWithContext(ctx, repositoryReference, c.getRemoteOptions(ctx, repositoryReference.Registry, authOption)...)
 	if err != nil {
 		return nil, fmt.Errorf("list: %w", err)
 	}
@@ -133,6 +331,26 @@ func (c Client) GetTagsForRepo(ctx context.Context, host string, repository stri
 	return tags, nil
 }
 
+// GetRepositoriesAtRemote returns all of the repositories in the catalog of the registry
+func (c Client) GetRepositoriesAtRemote(ctx context.Context, host string, encodedAuth string) ([]string, error) {
+	registry, err := c.newRegistry(host)
+	if err != nil {
+		return nil, fmt.Errorf("new registry: %w", err)
+	}
+
+	authOption, err := withEncodedAuth(encodedAuth)
+	if err != nil {
+		return nil, fmt.Errorf("get auth: %w", err)
+	}
+
+	repositories, err := remote.Catalog(ctx, registry, c.getRemoteOptions(ctx, registry, authOption)...)
+	if err != nil {
+		return nil, fmt.Errorf("catalog: %w", err)
+	}
+
+	return repositories, nil
+}
+
 func hasLatestTag(image string) bool {
 	if strings.Contains(image, ":latest") || !strings.Contains(image, ":") {
 		return true
@@ -140,3 +358,28 @@ func hasLatestTag(image string) bool {
 
 	return false
 }
+
+func isImageNotFound(err error) bool {
+	var transportError *transport.Error
+	if !errors.As(err, &transportError) {
+		return false
+	}
+
+	// Registries return NAME_UNKNOWN when the repository does not exist
+	for _, diagnostic := range transportError.Errors {
+		if strings.EqualFold("MANIFEST_UNKNOWN", string(diagnostic.Code)) || strings.EqualFold("NAME_UNKNOWN", string(diagnostic.Code)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isUnauthorized(err error) bool {
+	var transportError *transport.Error
+	if !errors.As(err, &transportError) {
+		return false
+	}
+
+	return transportError.StatusCode == http.StatusUnauthorized || transportError.StatusCode == http.StatusForbidden
+}
@@ -1,6 +1,21 @@
 package docker
 
-import "testing"
+import (
+	"context"
+	"io/ioutil"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	log "github.com/sirupsen/logrus"
+)
 
 func TestImageExists_DockerIO(t *testing.T) {
 	imagesOnHost := []string{"busybox:1.0.0", "plexsystems/busybox:1.0.0"}
@@ -23,3 +38,123 @@ func TestImageExists_DockerIO_WithLibrary(t *testing.T) {
 		t.Errorf("expected docker.io address to exist, but it did not.")
 	}
 }
+
+func TestImageExists_TagAndDigest(t *testing.T) {
+	imagesOnHost := []string{"busybox@sha256:123"}
+	image := "busybox:1.0.0@sha256:123"
+
+	exists := imageExists(image, imagesOnHost)
+
+	if !exists {
+		t.Errorf("expected image with tag and digest to exist, but it did not.")
+	}
+}
+
+func TestGetCreatedAtRemote(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+
+	host := strings.TrimPrefix(registryServer.URL, "http://")
+	created := time.Date(2020, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+	writeImage(t, host+"/reproducible/app:v1.0.0", image)
+
+	createdImage, err := mutate.CreatedAt(image, v1.Time{Time: created})
+	if err != nil {
+		t.Fatal("created at:", err)
+	}
+	writeImage(t, host+"/source/app:v1.0.0", createdImage)
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := Client{Logger: logger}
+
+	actual, err := client.GetCreatedAtRemote(context.Background(), host+"/source/app:v1.0.0", "")
+	if err != nil {
+		t.Fatal("get created:", err)
+	}
+
+	if !actual.Equal(created) {
+		t.Errorf("expected image to be created at %s, actual %s", created, actual)
+	}
+
+	actual, err = client.GetCreatedAtRemote(context.Background(), host+"/reproducible/app:v1.0.0", "")
+	if err != nil {
+		t.Fatal("get created of image without a creation time:", err)
+	}
+
+	if !actual.IsZero() {
+		t.Errorf("expected image without a creation time to return a zero time, actual %s", actual)
+	}
+}
+
+// basicAuthHandler only serves the requests to the registry that have the basic auth of the user
+type basicAuthHandler struct {
+	handler  http.Handler
+	username string
+	password string
+}
+
+func (h basicAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if username, password, ok := r.BasicAuth(); !ok || username != h.username || password != h.password {
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	h.handler.ServeHTTP(w, r)
+}
+
+// newAuthRegistryServers returns a registry that requires the basic auth of the user, and a server
+// of the same registry without auth, so that images can be written to the registry by the tests
+func newAuthRegistryServers(username string, password string) (*httptest.Server, *httptest.Server) {
+	handler := registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0)))
+	authServer := httptest.NewServer(basicAuthHandler{handler: handler, username: username, password: password})
+	server := httptest.NewServer(handler)
+
+	return authServer, server
+}
+
+func TestGetDigestsAtRemote_Auth(t *testing.T) {
+	authServer, server := newAuthRegistryServers("user", "pass")
+	defer authServer.Close()
+	defer server.Close()
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+	writeImage(t, strings.TrimPrefix(server.URL, "http://")+"/private/app:v1.0.0", image)
+
+	digest, err := image.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	encodedAuth, err := GetEncodedBasicAuth("user", "pass")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := Client{Logger: logger}
+
+	privateImage := strings.TrimPrefix(authServer.URL, "http://") + "/private/app:v1.0.0"
+	digests, err := client.GetDigestsAtRemote(context.Background(), privateImage, encodedAuth)
+	if err != nil {
+		t.Fatal("get digests with auth:", err)
+	}
+
+	if len(digests) != 1 || digests[0] != digest.String() {
+		t.Errorf("expected digest %s, actual %v", digest, digests)
+	}
+
+	if _, err := client.GetDigestsAtRemote(context.Background(), privateImage, ""); err == nil {
+		t.Error("expected an error without the auth of the image")
+	}
+}
@@ -0,0 +1,73 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// LoadImages loads the images in the tarball, which is in the same format as docker save, to the host
+func (c Client) LoadImages(ctx context.Context, imageArchive io.Reader) error {
+	response, err := c.DockerClient.ImageLoad(ctx, imageArchive, true)
+	if err != nil {
+		return fmt.Errorf("image load: %w", err)
+	}
+	defer response.Body.Close()
+
+	if !response.JSON {
+		return nil
+	}
+
+	clientScanner := bufio.NewScanner(response.Body)
+	if err := waitForScannerComplete(ctx, clientScanner, response.Body, "image archive", "LOAD", func(Status) {}); err != nil {
+		return fmt.Errorf("wait for scanner: %w", err)
+	}
+
+	return nil
+}
+
+// GetImageArchiveTags returns the tags of the images in the tarball, which is in the same format
+// as docker save. Images that were saved without a tag (e.g. by their digest) have no tags.
+func GetImageArchiveTags(imageArchive io.Reader) ([]string, error) {
+	type archiveManifest struct {
+		RepoTags []string
+	}
+
+	archiveReader := tar.NewReader(imageArchive)
+	for {
+		header, err := archiveReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("manifest.json not found in image archive")
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("read image archive: %w", err)
+		}
+
+		if header.Name != "manifest.json" {
+			continue
+		}
+
+		contents, err := ioutil.ReadAll(archiveReader)
+		if err != nil {
+			return nil, fmt.Errorf("read manifest: %w", err)
+		}
+
+		var manifests []archiveManifest
+		if err := json.Unmarshal(contents, &manifests); err != nil {
+			return nil, fmt.Errorf("unmarshal manifest: %w", err)
+		}
+
+		var tags []string
+		for _, manifest := range manifests {
+			tags = append(tags, manifest.RepoTags...)
+		}
+
+		return tags, nil
+	}
+}
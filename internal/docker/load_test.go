@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+)
+
+func TestGetImageArchiveTags(t *testing.T) {
+	var imageArchive bytes.Buffer
+	writeImageArchive(t, &imageArchive, []string{"busybox:1.32.0", "quay.io/coreos/etcd:v3.4.13"})
+
+	tags, err := GetImageArchiveTags(&imageArchive)
+	if err != nil {
+		t.Fatal("get image archive tags:", err)
+	}
+
+	expected := "[busybox:1.32.0 quay.io/coreos/etcd:v3.4.13]"
+	if fmt.Sprint(tags) != expected {
+		t.Errorf("expected tags to be %s, actual %v", expected, tags)
+	}
+}
+
+func TestGetImageArchiveTags_NotAnArchive(t *testing.T) {
+	if _, err := GetImageArchiveTags(strings.NewReader("")); err == nil {
+		t.Error("expected an error when the archive has no manifest")
+	}
+}
+
+func TestLoadImages_Error(t *testing.T) {
+	daemonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"errorDetail":{"message":"no space left on device"},"error":"no space left on device"}`)
+	}))
+	defer daemonServer.Close()
+
+	dockerClient, err := client.NewClientWithOpts(client.WithHost(daemonServer.URL), client.WithVersion("1.40"))
+	if err != nil {
+		t.Fatal("new docker client:", err)
+	}
+
+	testClient := Client{DockerClient: dockerClient}
+
+	var imageArchive bytes.Buffer
+	writeImageArchive(t, &imageArchive, []string{"busybox:1.32.0"})
+
+	err = testClient.LoadImages(context.Background(), &imageArchive)
+	if err == nil || !strings.Contains(err.Error(), "no space left on device") {
+		t.Errorf("expected the load error to be returned, actual %v", err)
+	}
+}
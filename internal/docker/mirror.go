@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// getMirrorSource returns the image to pull in place of the given image, along with its auth.
+// When the registry of the image has a mirror, the image is pulled from the same repository
+// of the mirror using the credentials of the mirror, e.g. busybox:1.32.0 is pulled as
+// mirror.internal/library/busybox:1.32.0 when docker.io is mirrored by mirror.internal.
+func (c Client) getMirrorSource(ctx context.Context, image string, auth string) (string, string, error) {
+	mirror, exists, err := c.getRegistryMirror(image)
+	if err != nil {
+		return "", "", fmt.Errorf("get registry mirror: %w", err)
+	}
+
+	if !exists {
+		return image, auth, nil
+	}
+
+	mirrorImage, err := getMirrorImage(image, mirror)
+	if err != nil {
+		return "", "", fmt.Errorf("get mirror image: %w", err)
+	}
+
+	mirrorAuth, err := GetEncodedAuthForHost(ctx, mirror)
+	if err != nil {
+		return "", "", fmt.Errorf("get mirror auth: %w", err)
+	}
+
+	return mirrorImage, mirrorAuth, nil
+}
+
+// getRegistryMirror returns the mirror of the registry of the image, if it has one
+func (c Client) getRegistryMirror(image string) (string, bool, error) {
+	if len(c.RegistryMirrors) == 0 {
+		return "", false, nil
+	}
+
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return "", false, fmt.Errorf("parse reference: %w", err)
+	}
+
+	// Registries are compared by their parsed names, so that docker.io
+	// matches the images of Docker Hub that do not include a host.
+	for upstream, mirror := range c.RegistryMirrors {
+		upstreamRegistry, err := name.NewRegistry(upstream, name.WeakValidation)
+		if err != nil {
+			return "", false, fmt.Errorf("parse upstream registry %s: %w", upstream, err)
+		}
+
+		if upstreamRegistry.RegistryStr() == reference.Context().RegistryStr() {
+			return mirror, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// getMirrorImage returns the image in the same repository of the mirror. The repositories
+// of official Docker Hub images include their library namespace, as that is where
+// pull-through caches store them.
+func getMirrorImage(image string, mirror string) (string, error) {
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return "", fmt.Errorf("parse reference: %w", err)
+	}
+
+	delimiter := ":"
+	if _, isDigest := reference.(name.Digest); isDigest {
+		delimiter = "@"
+	}
+
+	return mirror + "/" + reference.Context().RepositoryStr() + delimiter + reference.Identifier(), nil
+}
@@ -0,0 +1,123 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestGetMirrorSource(t *testing.T) {
+	testClient := Client{
+		RegistryMirrors: map[string]string{
+			"docker.io": "mirror.internal",
+			"quay.io":   "mirror.internal:5000",
+		},
+	}
+
+	testCases := []struct {
+		image    string
+		expected string
+	}{
+		{image: "busybox:1.32.0", expected: "mirror.internal/library/busybox:1.32.0"},
+		{image: "docker.io/library/busybox:1.32.0", expected: "mirror.internal/library/busybox:1.32.0"},
+		{image: "index.docker.io/jimmidyson/configmap-reload:v0.3.0", expected: "mirror.internal/jimmidyson/configmap-reload:v0.3.0"},
+		{image: "busybox@sha256:a2490cec4484ee6c1068ba3a05f89934010c85242f736280b35343483b2264b6", expected: "mirror.internal/library/busybox@sha256:a2490cec4484ee6c1068ba3a05f89934010c85242f736280b35343483b2264b6"},
+		{image: "quay.io/coreos/etcd:v3.4.13", expected: "mirror.internal:5000/coreos/etcd:v3.4.13"},
+		{image: "gcr.io/google-containers/pause:3.2", expected: "gcr.io/google-containers/pause:3.2"},
+	}
+
+	for _, testCase := range testCases {
+		actual, _, err := testClient.getMirrorSource(context.Background(), testCase.image, "")
+		if err != nil {
+			t.Fatal("get mirror source:", err)
+		}
+
+		if actual != testCase.expected {
+			t.Errorf("expected %s to be pulled as %s, actual %s", testCase.image, testCase.expected, actual)
+		}
+	}
+}
+
+func TestPullImageAndWait_RegistryMirror(t *testing.T) {
+	var requests []string
+	daemonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path[strings.Index(r.URL.Path, "/images/"):]
+		switch {
+		case strings.HasSuffix(path, "/create"):
+			requests = append(requests, "pull "+r.URL.Query().Get("fromImage")+":"+r.URL.Query().Get("tag"))
+			fmt.Fprintln(w, `{"status":"Status: Downloaded newer image for mirror.internal/library/busybox:1.32.0"}`)
+
+		case strings.HasSuffix(path, "/tag"):
+			requests = append(requests, "tag "+r.URL.Query().Get("repo")+":"+r.URL.Query().Get("tag"))
+			w.WriteHeader(http.StatusCreated)
+
+		case r.Method == http.MethodDelete:
+			requests = append(requests, "remove "+strings.TrimPrefix(path, "/images/"))
+			fmt.Fprintln(w, `[]`)
+		}
+	}))
+	defer daemonServer.Close()
+
+	dockerClient, err := client.NewClientWithOpts(client.WithHost(daemonServer.URL), client.WithVersion("1.40"))
+	if err != nil {
+		t.Fatal("new docker client:", err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	testClient := Client{
+		DockerClient:    dockerClient,
+		Logger:          logger,
+		RetryPolicy:     RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+		RegistryMirrors: map[string]string{"docker.io": "mirror.internal"},
+	}
+
+	const digest = "sha256:a2490cec4484ee6c1068ba3a05f89934010c85242f736280b35343483b2264b6"
+
+	testCases := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image: "busybox:1.32.0",
+			expected: []string{
+				"pull mirror.internal/library/busybox:1.32.0",
+				"tag busybox:1.32.0",
+				"remove mirror.internal/library/busybox:1.32.0",
+			},
+		},
+		{
+			image: "busybox:1.32.0@" + digest,
+			expected: []string{
+				"pull mirror.internal/library/busybox:" + digest,
+				"tag busybox:1.32.0",
+			},
+		},
+		{
+			image: "busybox@" + digest,
+			expected: []string{
+				"pull mirror.internal/library/busybox:" + digest,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		requests = nil
+		if err := testClient.PullImageAndWait(context.Background(), testCase.image, ""); err != nil {
+			t.Fatal("pull image:", err)
+		}
+
+		if !reflect.DeepEqual(requests, testCase.expected) {
+			t.Errorf("expected requests of %s to be %v, actual %v", testCase.image, testCase.expected, requests)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// blobMounts records the repositories that blobs were copied to, so that images copied to other
+// repositories of the same registry can mount the blobs instead of uploading them again.
+// It is safe for concurrent use by images that are copied at the same time.
+type blobMounts struct {
+	mutex        sync.Mutex
+	repositories map[string]map[v1.Hash]name.Repository
+}
+
+func newBlobMounts() *blobMounts {
+	return &blobMounts{
+		repositories: make(map[string]map[v1.Hash]name.Repository),
+	}
+}
+
+// add records that the layers of the image exist in the repository
+func (b *blobMounts) add(repository name.Repository, image v1.Image) error {
+	if b == nil {
+		return nil
+	}
+
+	layers, err := image.Layers()
+	if err != nil {
+		return fmt.Errorf("layers: %w", err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	registry := repository.RegistryStr()
+	if _, exists := b.repositories[registry]; !exists {
+		b.repositories[registry] = make(map[v1.Hash]name.Repository)
+	}
+
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return fmt.Errorf("layer digest: %w", err)
+		}
+
+		b.repositories[registry][digest] = repository
+	}
+
+	return nil
+}
+
+// find returns a repository of the same registry, other than the given repository, that has the blob
+func (b *blobMounts) find(repository name.Repository, digest v1.Hash) (name.Repository, bool) {
+	if b == nil {
+		return name.Repository{}, false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	mountRepository, exists := b.repositories[repository.RegistryStr()][digest]
+	if !exists || mountRepository.RepositoryStr() == repository.RepositoryStr() {
+		return name.Repository{}, false
+	}
+
+	return mountRepository, true
+}
+
+// mountableImage is an image whose layers are mounted from other repositories of the
+// target registry that already have them, rather than being uploaded to the target
+type mountableImage struct {
+	v1.Image
+	target name.Repository
+	mounts *blobMounts
+}
+
+func (m mountableImage) Layers() ([]v1.Layer, error) {
+	layers, err := m.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	mountableLayers := make([]v1.Layer, len(layers))
+	for i, layer := range layers {
+		mountableLayers[i] = layer
+
+		digest, err := layer.Digest()
+		if err != nil {
+			continue
+		}
+
+		mountRepository, exists := m.mounts.find(m.target, digest)
+		if !exists {
+			continue
+		}
+
+		mountableLayers[i] = &remote.MountableLayer{
+			Layer:     layer,
+			Reference: mountRepository.Digest(digest.String()),
+		}
+	}
+
+	return mountableLayers, nil
+}
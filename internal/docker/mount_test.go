@@ -0,0 +1,193 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	log "github.com/sirupsen/logrus"
+)
+
+// repositoryBlobRegistry is a registry that only has the blobs of a repository that were uploaded
+// to or mounted into that repository, like most registries, and that records the mounts it is asked for.
+type repositoryBlobRegistry struct {
+	mutex   sync.Mutex
+	blobs   map[string]map[string]bool
+	mounts  []string
+	handler http.Handler
+}
+
+func newRepositoryBlobRegistry() *repositoryBlobRegistry {
+	return &repositoryBlobRegistry{
+		blobs:   make(map[string]map[string]bool),
+		handler: registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))),
+	}
+}
+
+func (r *repositoryBlobRegistry) addBlob(repository string, digest string) {
+	if _, exists := r.blobs[repository]; !exists {
+		r.blobs[repository] = make(map[string]bool)
+	}
+
+	r.blobs[repository][digest] = true
+}
+
+func (r *repositoryBlobRegistry) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	path := strings.TrimPrefix(request.URL.Path, "/v2/")
+	blobsIndex := strings.Index(path, "/blobs/")
+	if blobsIndex < 0 {
+		r.handler.ServeHTTP(writer, request)
+		return
+	}
+
+	repository := path[:blobsIndex]
+	blobPath := path[blobsIndex+len("/blobs/"):]
+	query := request.URL.Query()
+
+	switch {
+	case request.Method == http.MethodHead && !strings.HasPrefix(blobPath, "uploads/"):
+		if !r.blobs[repository][blobPath] {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+	case request.Method == http.MethodPost && query.Get("mount") != "":
+		r.mounts = append(r.mounts, query.Get("from")+" "+query.Get("mount"))
+		if r.blobs[query.Get("from")][query.Get("mount")] {
+			r.addBlob(repository, query.Get("mount"))
+			writer.Header().Set("Location", "/v2/"+repository+"/blobs/"+query.Get("mount"))
+			writer.WriteHeader(http.StatusCreated)
+			return
+		}
+
+	case request.Method == http.MethodPut && query.Get("digest") != "":
+		r.addBlob(repository, query.Get("digest"))
+	}
+
+	r.handler.ServeHTTP(writer, request)
+}
+
+func TestCopyImageAndWait_MountsBlobsFromSiblingRepository(t *testing.T) {
+	sourceServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer sourceServer.Close()
+
+	targetRegistry := newRepositoryBlobRegistry()
+	targetServer := httptest.NewServer(targetRegistry)
+	defer targetServer.Close()
+
+	sourceHost := strings.TrimPrefix(sourceServer.URL, "http://")
+	targetHost := strings.TrimPrefix(targetServer.URL, "http://")
+
+	baseImage, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatal("random base image:", err)
+	}
+
+	appLayer, err := random.Layer(1024, "")
+	if err != nil {
+		t.Fatal("random layer:", err)
+	}
+
+	appImage, err := mutate.AppendLayers(baseImage, appLayer)
+	if err != nil {
+		t.Fatal("append layers:", err)
+	}
+
+	writeImage(t, sourceHost+"/source/base:v1.0.0", baseImage)
+	writeImage(t, sourceHost+"/source/app:v1.0.0", appImage)
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	client := Client{
+		Logger:      logger,
+		RetryPolicy: RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+		blobMounts:  newBlobMounts(),
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), sourceHost+"/source/base:v1.0.0", targetHost+"/mirror/base:v1.0.0", "", ""); err != nil {
+		t.Fatal("copy base image:", err)
+	}
+
+	if len(targetRegistry.mounts) > 0 {
+		t.Errorf("expected no mounts for the first image, actual %v", targetRegistry.mounts)
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), sourceHost+"/source/app:v1.0.0", targetHost+"/mirror/app:v1.0.0", "", ""); err != nil {
+		t.Fatal("copy app image:", err)
+	}
+
+	baseLayers, err := baseImage.Layers()
+	if err != nil {
+		t.Fatal("base layers:", err)
+	}
+
+	for _, layer := range baseLayers {
+		digest, err := layer.Digest()
+		if err != nil {
+			t.Fatal("layer digest:", err)
+		}
+
+		expectedMount := "mirror/base " + digest.String()
+		if !containsString(targetRegistry.mounts, expectedMount) {
+			t.Errorf("expected the shared layer to be mounted with %q, actual %v", expectedMount, targetRegistry.mounts)
+		}
+	}
+
+	appDigest, err := appLayer.Digest()
+	if err != nil {
+		t.Fatal("app layer digest:", err)
+	}
+
+	if containsString(targetRegistry.mounts, "mirror/base "+appDigest.String()) {
+		t.Errorf("expected the layer that is not shared to be uploaded, actual %v", targetRegistry.mounts)
+	}
+
+	sourceDigest, err := appImage.Digest()
+	if err != nil {
+		t.Fatal("app digest:", err)
+	}
+
+	targetDigests, err := client.GetDigestsAtRemote(context.Background(), targetHost+"/mirror/app:v1.0.0", "")
+	if err != nil {
+		t.Fatal("get target digests:", err)
+	}
+
+	if len(targetDigests) != 1 || targetDigests[0] != sourceDigest.String() {
+		t.Errorf("expected target to be the source image %s, actual %v", sourceDigest, targetDigests)
+	}
+}
+
+func writeImage(t *testing.T, image string, contents v1.Image) {
+	reference, err := name.ParseReference(image)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(reference, contents); err != nil {
+		t.Fatal("write image:", err)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, current := range values {
+		if current == value {
+			return true
+		}
+	}
+
+	return false
+}
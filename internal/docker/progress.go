@@ -0,0 +1,154 @@
+package docker
+
+import (
+	"strings"
+	"sync"
+)
+
+// Progress aggregates the progress of each layer of an image
+type Progress struct {
+	mutex  sync.Mutex
+	layers map[string]ProgressDetail
+}
+
+// NewProgress returns a new Progress with no known layers
+func NewProgress() *Progress {
+	progress := Progress{
+		layers: make(map[string]ProgressDetail),
+	}
+
+	return &progress
+}
+
+// Update records the progress of the layer found in the status
+func (p *Progress) Update(status Status) {
+	if status.ID == "" {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	layer := p.layers[status.ID]
+
+	// Statuses for completed layers do not include progress details,
+	// so the layer is marked as complete using the last known total.
+	if isLayerComplete(status.Message) {
+		layer.Current = layer.Total
+	} else if status.ProgressDetail.Total > 0 {
+		layer = status.ProgressDetail
+	}
+
+	p.layers[status.ID] = layer
+}
+
+// Percent is the percentage complete across all known layers.
+// Layers with an unknown total are not included.
+func (p *Progress) Percent() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var current int
+	var total int
+	for _, layer := range p.layers {
+		if layer.Total == 0 {
+			continue
+		}
+
+		current += layer.Current
+		total += layer.Total
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(current) / float64(total) * 100
+}
+
+// Bytes is the number of bytes processed across all known layers
+func (p *Progress) Bytes() int64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var current int64
+	for _, layer := range p.layers {
+		current += int64(layer.Current)
+	}
+
+	return current
+}
+
+// TransferStats records the progress of every image that is pulled or pushed,
+// so that the total number of bytes transferred can be reported. It is safe for
+// concurrent use by images that are pulled or pushed at the same time.
+type TransferStats struct {
+	mutex  sync.Mutex
+	images map[string]*Progress
+}
+
+// NewTransferStats returns a new TransferStats with no known images
+func NewTransferStats() *TransferStats {
+	transferStats := TransferStats{
+		images: make(map[string]*Progress),
+	}
+
+	return &transferStats
+}
+
+// Update records the progress of the image found in the status. Retrying an
+// image updates its existing progress, so its layers are not counted twice.
+func (t *TransferStats) Update(image string, status Status) {
+	t.mutex.Lock()
+	progress, exists := t.images[image]
+	if !exists {
+		progress = NewProgress()
+		t.images[image] = progress
+	}
+	t.mutex.Unlock()
+
+	progress.Update(status)
+}
+
+// Bytes is the number of bytes transferred across all known images
+func (t *TransferStats) Bytes() int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var bytes int64
+	for _, progress := range t.images {
+		bytes += progress.Bytes()
+	}
+
+	return bytes
+}
+
+// HostBytes is the number of bytes transferred with each registry host, where
+// images without a host are transferred with Docker Hub (docker.io)
+func (t *TransferStats) HostBytes() map[string]int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	hostBytes := make(map[string]int64)
+	for image, progress := range t.images {
+		host := RegistryPath(image).Host()
+		if host == "" {
+			host = "docker.io"
+		}
+
+		hostBytes[host] += progress.Bytes()
+	}
+
+	return hostBytes
+}
+
+func isLayerComplete(message string) bool {
+	completeMessages := []string{"Pull complete", "Download complete", "Pushed", "Already exists", "Layer already exists"}
+	for _, completeMessage := range completeMessages {
+		if strings.EqualFold(message, completeMessage) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,97 @@
+package docker
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestProgress_Percent(t *testing.T) {
+	testCases := []struct {
+		statuses        []Status
+		expectedPercent float64
+	}{
+		{
+			statuses:        []Status{},
+			expectedPercent: 0,
+		},
+		{
+			statuses: []Status{
+				{ID: "layer1", Message: "Downloading", ProgressDetail: ProgressDetail{Current: 25, Total: 100}},
+				{ID: "layer2", Message: "Downloading", ProgressDetail: ProgressDetail{Current: 75, Total: 100}},
+			},
+			expectedPercent: 50,
+		},
+		{
+			statuses: []Status{
+				{ID: "layer1", Message: "Downloading", ProgressDetail: ProgressDetail{Current: 50, Total: 100}},
+				{ID: "layer2", Message: "Waiting"},
+			},
+			expectedPercent: 50,
+		},
+		{
+			statuses: []Status{
+				{ID: "layer1", Message: "Downloading", ProgressDetail: ProgressDetail{Current: 50, Total: 100}},
+				{ID: "layer1", Message: "Download complete"},
+				{ID: "layer2", Message: "Downloading", ProgressDetail: ProgressDetail{Current: 0, Total: 100}},
+			},
+			expectedPercent: 50,
+		},
+	}
+
+	for _, testCase := range testCases {
+		progress := NewProgress()
+		for _, status := range testCase.statuses {
+			progress.Update(status)
+		}
+
+		if progress.Percent() != testCase.expectedPercent {
+			t.Errorf("expected percent to be %v, actual %v", testCase.expectedPercent, progress.Percent())
+		}
+	}
+}
+
+func TestTransferStats_Bytes(t *testing.T) {
+	stats := NewTransferStats()
+
+	var workers sync.WaitGroup
+	for _, image := range []string{"busybox:1.0.0", "busybox:2.0.0", "busybox:3.0.0"} {
+		workers.Add(1)
+		go func(image string) {
+			defer workers.Done()
+
+			for current := 0; current <= 100; current += 10 {
+				stats.Update(image, Status{ID: "layer1", Message: "Downloading", ProgressDetail: ProgressDetail{Current: current, Total: 100}})
+			}
+			stats.Update(image, Status{ID: "layer2", Message: "Downloading", ProgressDetail: ProgressDetail{Current: 50, Total: 50}})
+		}(image)
+	}
+	workers.Wait()
+
+	// A retried image replaces its progress rather than adding to it
+	stats.Update("busybox:1.0.0", Status{ID: "layer1", Message: "Download complete"})
+
+	const expectedBytes = 450
+	if stats.Bytes() != expectedBytes {
+		t.Errorf("expected %v bytes, actual %v", expectedBytes, stats.Bytes())
+	}
+}
+
+func TestTransferStats_HostBytes(t *testing.T) {
+	stats := NewTransferStats()
+	stats.Update("busybox:1.0.0", Status{ID: "layer1", ProgressDetail: ProgressDetail{Current: 100, Total: 100}})
+	stats.Update("quay.io/coreos/etcd:v3.4.13", Status{ID: "layer1", ProgressDetail: ProgressDetail{Current: 200, Total: 200}})
+	stats.Update("mycompany.com/myrepo/busybox:1.0.0", Status{ID: "layer1", ProgressDetail: ProgressDetail{Current: 100, Total: 100}})
+	stats.Update("mycompany.com/myrepo/coreos/etcd:v3.4.13", Status{ID: "layer1", ProgressDetail: ProgressDetail{Current: 200, Total: 200}})
+
+	expected := map[string]int64{"docker.io": 100, "quay.io": 200, "mycompany.com": 300}
+	actual := stats.HostBytes()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected bytes of %d hosts, actual %v", len(expected), actual)
+	}
+
+	for host, bytes := range expected {
+		if actual[host] != bytes {
+			t.Errorf("expected %v bytes for %s, actual %v", bytes, host, actual[host])
+		}
+	}
+}
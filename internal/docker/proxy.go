@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// WithProxy sets the proxy (e.g. http://proxy.internal:3128) that requests to registries are sent
+// through, and the hosts that are connected to directly, instead of the proxy environment variables
+func WithProxy(proxy string, noProxy []string) ClientOption {
+	return func(c *Client) {
+		c.Proxy = proxy
+		c.NoProxy = noProxy
+	}
+}
+
+// validateProxy returns an error when the proxy is set but is not the URL of a proxy
+func validateProxy(proxy string) error {
+	if proxy == "" {
+		return nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return fmt.Errorf("parse url: %w", err)
+	}
+
+	if proxyURL.Scheme != "http" && proxyURL.Scheme != "https" && proxyURL.Scheme != "socks5" {
+		return fmt.Errorf("proxy %s must use the http, https or socks5 scheme", proxy)
+	}
+
+	if proxyURL.Host == "" {
+		return fmt.Errorf("proxy %s does not have a host", proxy)
+	}
+
+	return nil
+}
+
+// hasProxy returns true when the proxy environment variables are overridden
+func (c Client) hasProxy() bool {
+	return c.Proxy != "" || len(c.NoProxy) > 0
+}
+
+// getProxy returns the func that selects the proxy of each request to a registry. The proxy
+// and the hosts that bypass it are read from the environment, unless they are overridden.
+// A request to a host that matches one of the hosts that bypass the proxy is not proxied.
+func (c Client) getProxy() func(*http.Request) (*url.URL, error) {
+	config := httpproxy.FromEnvironment()
+	if c.Proxy != "" {
+		config.HTTPProxy = c.Proxy
+		config.HTTPSProxy = c.Proxy
+	}
+
+	if len(c.NoProxy) > 0 {
+		config.NoProxy = strings.Join(c.NoProxy, ",")
+	}
+
+	proxyFunc := config.ProxyFunc()
+	return func(request *http.Request) (*url.URL, error) {
+		return proxyFunc(request.URL)
+	}
+}
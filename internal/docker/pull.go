@@ -3,31 +3,76 @@ package docker
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
-	"github.com/avast/retry-go"
 	"github.com/docker/docker/api/types"
 )
 
-// PullImageAndWait pulls an image and waits for it to finish pulling
+// PullImageAndWait pulls an image and waits for it to finish pulling. When the registry of the image
+// has a mirror, the image is pulled from the mirror and tagged as the image on the host machine.
 func (c Client) PullImageAndWait(ctx context.Context, image string, auth string) error {
-	retryError := retry.Do(
+	ctx, cancel := c.newOperationContext(ctx)
+	defer cancel()
+
+	pullImage, pullAuth, err := c.getMirrorSource(ctx, image, auth)
+	if err != nil {
+		return fmt.Errorf("get mirror source: %w", err)
+	}
+
+	retryError := c.retry(
+		ctx,
 		func() error {
-			if err := c.tryPullImageAndWait(ctx, image, auth); err != nil {
+			if err := c.tryPullImageAndWait(ctx, pullImage, pullAuth); err != nil {
 				return fmt.Errorf("try pull image: %w", err)
 			}
 
 			return nil
 		},
-		retry.OnRetry(func(retryAttempt uint, err error) {
-			c.Logger.Printf("[RETRY] Unable to pull %v (Retrying #%v)", image, retryAttempt+1)
-		}),
+		func(retryAttempt uint, err error) {
+			newImageLogEntry(c.Logger, "pull", image).Printf("[RETRY] Unable to pull %v (Retrying #%v)", image, retryAttempt+1)
+		},
 	)
 
+	if retryError != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("pull of %s timed out after %s: %w", image, c.Timeout, retryError)
+	}
+
 	if retryError != nil {
 		return retryError
 	}
 
+	if pullImage == image {
+		return nil
+	}
+
+	// The daemon can not tag an image with a digest, so images pinned to a digest are only
+	// tagged with their tag, if they have one. The reference to the mirror is kept, as it is
+	// the only reference to the digest that the image was pinned to on the host machine.
+	imagePath := RegistryPath(image)
+	if imagePath.Digest() != "" {
+		if imagePath.Tag() == "" {
+			return nil
+		}
+
+		if err := c.TagImage(ctx, pullImage, strings.TrimSuffix(image, "@"+imagePath.Digest())); err != nil {
+			return fmt.Errorf("tag mirror image: %w", err)
+		}
+
+		return nil
+	}
+
+	// The reference to the mirror is removed once the pulled image is tagged, so that
+	// the image is on the host machine as though it was pulled from its own registry.
+	if err := c.TagImage(ctx, pullImage, image); err != nil {
+		return fmt.Errorf("tag mirror image: %w", err)
+	}
+
+	if err := c.RemoveImageOnHost(ctx, pullImage); err != nil {
+		return fmt.Errorf("remove mirror image: %w", err)
+	}
+
 	return nil
 }
 
@@ -36,13 +81,28 @@ func (c Client) tryPullImageAndWait(ctx context.Context, image string, auth stri
 		RegistryAuth: auth,
 	}
 
+	if c.Platform != (Platform{}) {
+		opts.Platform = c.Platform.String()
+	}
+
 	reader, err := c.DockerClient.ImagePull(ctx, image, opts)
+	if err != nil && isRateLimitMessage(err.Error()) {
+		return fmt.Errorf("pull image: %w", newRateLimitError(err.Error()))
+	}
+
+	// Images are pulled anonymously when no credentials are configured for their registry,
+	// so an unauthorized error means the image is not public.
+	if err != nil && auth == "" && isUnauthorizedMessage(err.Error()) {
+		return fmt.Errorf("pull image: %s requires credentials but none are configured for its registry: %w", image, err)
+	}
+
 	if err != nil {
 		return fmt.Errorf("pull image: %w", err)
 	}
 	clientScanner := bufio.NewScanner(reader)
 
-	if err := waitForScannerComplete(c.Logger, clientScanner, image, "PULL"); err != nil {
+	if err := waitForScannerComplete(ctx, clientScanner, reader, image, "PULL", c.getStatusCallback(image, "PULL")); err != nil {
+		reader.Close()
 		return fmt.Errorf("wait for scanner: %w", err)
 	}
 
@@ -52,3 +112,16 @@ func (c Client) tryPullImageAndWait(ctx context.Context, image string, auth stri
 
 	return nil
 }
+
+func isUnauthorizedMessage(message string) bool {
+	unauthorizedMessages := []string{"unauthorized", "authentication required", "access denied", "forbidden"}
+
+	message = strings.ToLower(message)
+	for _, unauthorizedMessage := range unauthorizedMessages {
+		if strings.Contains(message, unauthorizedMessage) {
+			return true
+		}
+	}
+
+	return false
+}
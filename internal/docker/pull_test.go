@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestPullImageAndWait_Platform(t *testing.T) {
+	var actualPlatform string
+	daemonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualPlatform = r.URL.Query().Get("platform")
+		fmt.Fprintln(w, `{"status":"Status: Downloaded newer image for busybox:latest"}`)
+	}))
+	defer daemonServer.Close()
+
+	dockerClient, err := client.NewClientWithOpts(client.WithHost(daemonServer.URL), client.WithVersion("1.40"))
+	if err != nil {
+		t.Fatal("new docker client:", err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	testClient := Client{
+		DockerClient: dockerClient,
+		Logger:       logger,
+		RetryPolicy:  RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+	}
+
+	if err := testClient.PullImageAndWait(context.Background(), "busybox:latest", ""); err != nil {
+		t.Fatal("pull image:", err)
+	}
+
+	if actualPlatform != "" {
+		t.Errorf("expected no platform to be sent, actual %s", actualPlatform)
+	}
+
+	WithPlatform(Platform{OS: "linux", Architecture: "arm64"})(&testClient)
+	if err := testClient.PullImageAndWait(context.Background(), "busybox:latest", ""); err != nil {
+		t.Fatal("pull image with platform:", err)
+	}
+
+	if actualPlatform != "linux/arm64" {
+		t.Errorf("expected platform to be linux/arm64, actual %s", actualPlatform)
+	}
+}
+
+func TestPullImageAndWait_Anonymous(t *testing.T) {
+	var actualAuth []string
+	daemonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualAuth = append(actualAuth, r.Header.Get("X-Registry-Auth"))
+
+		if r.URL.Query().Get("fromImage") == "private/app" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, `{"message":"unauthorized: authentication required"}`)
+			return
+		}
+
+		fmt.Fprintln(w, `{"status":"Status: Downloaded newer image for busybox:latest"}`)
+	}))
+	defer daemonServer.Close()
+
+	dockerClient, err := client.NewClientWithOpts(client.WithHost(daemonServer.URL), client.WithVersion("1.40"))
+	if err != nil {
+		t.Fatal("new docker client:", err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	testClient := Client{
+		DockerClient: dockerClient,
+		Logger:       logger,
+		RetryPolicy:  RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+	}
+
+	if err := testClient.PullImageAndWait(context.Background(), "busybox:latest", ""); err != nil {
+		t.Fatal("pull public image:", err)
+	}
+
+	if len(actualAuth) != 1 || actualAuth[0] != "" {
+		t.Errorf("expected public image to be pulled without auth, actual %v", actualAuth)
+	}
+
+	err = testClient.PullImageAndWait(context.Background(), "private/app:latest", "")
+	if err == nil || !strings.Contains(err.Error(), "requires credentials") {
+		t.Errorf("expected unauthorized pull to return a credentials error, actual %v", err)
+	}
+}
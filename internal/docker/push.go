@@ -3,52 +3,90 @@ package docker
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 
-	"github.com/avast/retry-go"
 	"github.com/docker/docker/api/types"
 )
 
-// PushImageAndWait pushes an image and waits for it to finish pushing
+// PushImageAndWait pushes an image and waits for it to finish pushing. Once pushed, the digest of the
+// image is fetched from the registry and the push fails when it is not the digest that was pushed.
 func (c Client) PushImageAndWait(ctx context.Context, image string, auth string) error {
-	retryError := retry.Do(
+	ctx, cancel := c.newOperationContext(ctx)
+	defer cancel()
+
+	var digest string
+	retryError := c.retry(
+		ctx,
 		func() error {
-			if err := c.tryPushImageAndWait(ctx, image, auth); err != nil {
+			var err error
+			digest, err = c.tryPushImageAndWait(ctx, image, auth)
+			if err != nil {
 				return fmt.Errorf("try push image: %w", err)
 			}
 
 			return nil
 		},
-		retry.OnRetry(func(retryAttempt uint, err error) {
-			c.Logger.Printf("[RETRY] Unable to push %v (Retrying #%v)", image, retryAttempt+1)
-		}),
+		func(retryAttempt uint, err error) {
+			newImageLogEntry(c.Logger, "push", image).Printf("[RETRY] Unable to push %v (Retrying #%v)", image, retryAttempt+1)
+			auth = refreshECRAuth(ctx, image, auth)
+		},
 	)
 
+	if retryError != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("push of %s timed out after %s: %w", image, c.Timeout, retryError)
+	}
+
 	if retryError != nil {
 		return retryError
 	}
 
+	// Older versions of Docker do not return the digest of the pushed image
+	if digest == "" {
+		return nil
+	}
+
+	if err := c.verifyPushedDigest(ctx, image, digest, auth); err != nil {
+		return fmt.Errorf("verify pushed digest: %w", err)
+	}
+
 	return nil
 }
 
-func (c Client) tryPushImageAndWait(ctx context.Context, image string, auth string) error {
+// tryPushImageAndWait pushes the image and returns the digest of the pushed image
+func (c Client) tryPushImageAndWait(ctx context.Context, image string, auth string) (string, error) {
 	opts := types.ImagePushOptions{
 		RegistryAuth: auth,
 	}
 
 	reader, err := c.DockerClient.ImagePush(ctx, image, opts)
+	if err != nil && isRateLimitMessage(err.Error()) {
+		return "", fmt.Errorf("push image: %w", newRateLimitError(err.Error()))
+	}
+
 	if err != nil {
-		return fmt.Errorf("push image: %w", err)
+		return "", fmt.Errorf("push image: %w", err)
 	}
 	clientScanner := bufio.NewScanner(reader)
 
-	if err := waitForScannerComplete(c.Logger, clientScanner, image, "PUSH"); err != nil {
-		return fmt.Errorf("wait for scanner: %w", err)
+	var digest string
+	onStatus := c.getStatusCallback(image, "PUSH")
+	onPushStatus := func(status Status) {
+		if status.Aux.Digest != "" {
+			digest = status.Aux.Digest
+		}
+
+		onStatus(status)
+	}
+
+	if err := waitForScannerComplete(ctx, clientScanner, reader, image, "PUSH", onPushStatus); err != nil {
+		reader.Close()
+		return "", fmt.Errorf("wait for scanner: %w", err)
 	}
 
 	if err := reader.Close(); err != nil {
-		return fmt.Errorf("close reader: %w", err)
+		return "", fmt.Errorf("close reader: %w", err)
 	}
 
-	return nil
+	return digest, nil
 }
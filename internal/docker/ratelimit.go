@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitError is returned when a registry rejects a pull or push because
+// too many requests have been made (HTTP 429). Anonymous requests usually have
+// a lower limit, so authenticating to the registry can avoid the error.
+type RateLimitError struct {
+	Message string
+
+	// RetryAfter is how long the registry asked to wait before retrying.
+	// A retry after of zero means the registry did not say.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	message := fmt.Sprintf("rate limited by registry: %s", e.Message)
+	if e.RetryAfter > 0 {
+		message = fmt.Sprintf("%s (retry after %s)", message, e.RetryAfter)
+	}
+
+	return message
+}
+
+var retryAfterPattern = regexp.MustCompile(`(?i)retry[- ]after:?\s*((?:[0-9.]+[a-z]*)+)`)
+
+// isRateLimitMessage returns true when the message of a Docker error is a rate limit error
+func isRateLimitMessage(message string) bool {
+	message = strings.ToLower(message)
+
+	return strings.Contains(message, "toomanyrequests") || strings.Contains(message, "429 too many requests")
+}
+
+func newRateLimitError(message string) *RateLimitError {
+	rateLimitError := RateLimitError{
+		Message: message,
+	}
+
+	matches := retryAfterPattern.FindStringSubmatch(message)
+	if len(matches) == 2 {
+		rateLimitError.RetryAfter = parseRetryAfter(matches[1])
+	}
+
+	return &rateLimitError
+}
+
+// parseRetryAfter parses a retry after hint that is either a number of seconds or a duration (e.g. 1m30s)
+func parseRetryAfter(retryAfter string) time.Duration {
+	retryAfter = strings.TrimRight(retryAfter, ".")
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	duration, err := time.ParseDuration(retryAfter)
+	if err != nil {
+		return 0
+	}
+
+	return duration
+}
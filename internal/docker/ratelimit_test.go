@@ -0,0 +1,34 @@
+package docker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewRateLimitError(t *testing.T) {
+	testCases := []struct {
+		message            string
+		expectedRateLimit  bool
+		expectedRetryAfter time.Duration
+	}{
+		{message: "toomanyrequests: You have reached your pull rate limit", expectedRateLimit: true},
+		{message: "Error response from daemon: toomanyrequests: Too Many Requests. Retry-After: 60.", expectedRateLimit: true, expectedRetryAfter: time.Minute},
+		{message: "unexpected status: 429 Too Many Requests, retry after 1m30s", expectedRateLimit: true, expectedRetryAfter: 90 * time.Second},
+		{message: "manifest unknown: manifest unknown", expectedRateLimit: false},
+	}
+
+	for _, testCase := range testCases {
+		if isRateLimitMessage(testCase.message) != testCase.expectedRateLimit {
+			t.Errorf("expected rate limit to be %v for %q", testCase.expectedRateLimit, testCase.message)
+		}
+
+		if !testCase.expectedRateLimit {
+			continue
+		}
+
+		rateLimitError := newRateLimitError(testCase.message)
+		if rateLimitError.RetryAfter != testCase.expectedRetryAfter {
+			t.Errorf("expected retry after to be %s, actual %s", testCase.expectedRetryAfter, rateLimitError.RetryAfter)
+		}
+	}
+}
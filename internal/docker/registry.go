@@ -0,0 +1,231 @@
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// parseReference parses an image reference for registry operations that are not
+// performed by Docker. References to insecure registries are allowed to use plain HTTP.
+func (c Client) parseReference(image string) (name.Reference, error) {
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.isInsecureRegistry(reference.Context().RegistryStr()) {
+		return reference, nil
+	}
+
+	return name.ParseReference(image, name.WeakValidation, name.Insecure)
+}
+
+// newRepository returns the repository for registry operations that are not
+// performed by Docker. Insecure registries are allowed to use plain HTTP.
+func (c Client) newRepository(repository string) (name.Repository, error) {
+	repositoryReference, err := name.NewRepository(repository)
+	if err != nil {
+		return name.Repository{}, err
+	}
+
+	if !c.isInsecureRegistry(repositoryReference.RegistryStr()) {
+		return repositoryReference, nil
+	}
+
+	return name.NewRepository(repository, name.Insecure)
+}
+
+// newRegistry returns the registry for registry operations that are not
+// performed by Docker. Insecure registries are allowed to use plain HTTP.
+func (c Client) newRegistry(host string) (name.Registry, error) {
+	if c.isInsecureRegistry(host) {
+		return name.NewRegistry(host, name.Insecure)
+	}
+
+	return name.NewRegistry(host)
+}
+
+// getRemoteOptions returns the options for requests to the registry, which
+// do not verify the TLS certificate of the registry when it is insecure
+func (c Client) getRemoteOptions(ctx context.Context, registry name.Registry, options ...remote.Option) []remote.Option {
+	return append(options, remote.WithTransport(c.getContextTransport(ctx, registry)))
+}
+
+// getContextTransport returns the transport of requests to the registry that sends every request with
+// the context, so that the requests of remote operations that do not take a context, such as reading
+// and writing images, are cancelled when the context is cancelled or its deadline is exceeded
+func (c Client) getContextTransport(ctx context.Context, registry name.Registry) http.RoundTripper {
+	return contextTransport{inner: c.getTransport(registry), ctx: ctx}
+}
+
+type contextTransport struct {
+	inner http.RoundTripper
+	ctx   context.Context
+}
+
+func (t contextTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.inner.RoundTrip(request.WithContext(t.ctx))
+
+	// An exceeded deadline is a temporary error, which remote operations retry with a backoff,
+	// so the error of the context is wrapped to stop the operation as soon as the context is done.
+	if err != nil && t.ctx.Err() != nil {
+		return nil, fmt.Errorf("request cancelled: %w", t.ctx.Err())
+	}
+
+	return response, err
+}
+
+// getTransport returns the transport of requests to the registry, whose transfers
+// are limited by the bandwidth limit of the client, when one is set
+func (c Client) getTransport(registry name.Registry) http.RoundTripper {
+	registryTransport := c.getRegistryTransport(registry)
+	if c.bandwidthLimiter == nil {
+		return registryTransport
+	}
+
+	return limitedTransport{inner: registryTransport, limiter: c.bandwidthLimiter}
+}
+
+// getAuthTransport returns the transport of the requests that are made to get the auth of
+// registry hosts, such as token exchanges, which is the registry transport of the host of each request
+func (c Client) getAuthTransport() http.RoundTripper {
+	return authTransport(func(host string) http.RoundTripper {
+		registry, err := c.newRegistry(host)
+		if err != nil {
+			return http.DefaultTransport
+		}
+
+		return c.getRegistryTransport(registry)
+	})
+}
+
+type authTransport func(host string) http.RoundTripper
+
+func (t authTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return t(request.URL.Host).RoundTrip(request)
+}
+
+func (c Client) getRegistryTransport(registry name.Registry) http.RoundTripper {
+	isInsecure := c.isInsecureRegistry(registry.RegistryStr())
+	clientCertificate, hasClientCertificate := c.getClientCertificate(registry.RegistryStr())
+	if !isInsecure && !hasClientCertificate && c.rootCAs == nil && !c.hasProxy() {
+		return http.DefaultTransport
+	}
+
+	registryTransport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.hasProxy() {
+		registryTransport.Proxy = c.getProxy()
+	}
+
+	registryTransport.TLSClientConfig = &tls.Config{
+		RootCAs:            c.rootCAs,
+		InsecureSkipVerify: isInsecure,
+	}
+
+	if hasClientCertificate {
+		registryTransport.TLSClientConfig.Certificates = []tls.Certificate{clientCertificate}
+	}
+
+	return registryTransport
+}
+
+// getClientCertificate returns the client certificate of the registry host. A client
+// certificate for the host and port is preferred over one for only the host.
+func (c Client) getClientCertificate(host string) (tls.Certificate, bool) {
+	if clientCertificate, exists := c.clientCertificates[strings.ToLower(host)]; exists {
+		return clientCertificate, true
+	}
+
+	for registry, clientCertificate := range c.clientCertificates {
+		if matchesRegistryHost(host, []string{registry}) {
+			return clientCertificate, true
+		}
+	}
+
+	return tls.Certificate{}, false
+}
+
+// loadCACertificates returns the system certificate pool with the
+// certificates of each of the PEM files added to it
+func loadCACertificates(paths []string) (*x509.CertPool, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read ca certificate: %w", err)
+		}
+
+		if !rootCAs.AppendCertsFromPEM(contents) {
+			return nil, fmt.Errorf("no certificates found in %s", path)
+		}
+	}
+
+	return rootCAs, nil
+}
+
+// ClientCertificate is a certificate that is presented to a registry that requires mutual TLS
+type ClientCertificate struct {
+	Registry        string
+	CertificateFile string
+	KeyFile         string
+}
+
+// loadClientCertificates returns the client certificates by their registry, and
+// errors when a certificate cannot be loaded or does not match its private key
+func loadClientCertificates(clientCertificates []ClientCertificate) (map[string]tls.Certificate, error) {
+	certificates := make(map[string]tls.Certificate)
+	for _, clientCertificate := range clientCertificates {
+		certificate, err := tls.LoadX509KeyPair(clientCertificate.CertificateFile, clientCertificate.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate for %s: %w", clientCertificate.Registry, err)
+		}
+
+		certificates[strings.ToLower(clientCertificate.Registry)] = certificate
+	}
+
+	return certificates, nil
+}
+
+func (c Client) isInsecureRegistry(host string) bool {
+	return matchesRegistryHost(host, c.InsecureRegistries)
+}
+
+// matchesRegistryHost returns true when the host is one of the registries. A registry
+// with a port only matches that port, while a registry without a port matches every port.
+func matchesRegistryHost(host string, registries []string) bool {
+	host = strings.ToLower(host)
+	hostWithoutPort := strings.Split(host, ":")[0]
+
+	for _, registry := range registries {
+		registry = strings.ToLower(registry)
+		if registry == host {
+			return true
+		}
+
+		if !strings.Contains(registry, ":") && registry == hostWithoutPort {
+			return true
+		}
+	}
+
+	return false
+}
+
+// logInsecureRegistries logs each insecure registry so that it is clear
+// which registries are not required to use TLS
+func (c Client) logInsecureRegistries() {
+	for _, registry := range c.InsecureRegistries {
+		c.Logger.Warnf("[INSECURE] TLS verification is disabled and plain HTTP is allowed for registry %s", registry)
+	}
+}
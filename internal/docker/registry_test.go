@@ -0,0 +1,252 @@
+package docker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+func TestMatchesRegistryHost(t *testing.T) {
+	testCases := []struct {
+		host       string
+		registries []string
+		expected   bool
+	}{
+		{host: "registry.lan", registries: []string{"registry.lan"}, expected: true},
+		{host: "registry.lan:5000", registries: []string{"registry.lan"}, expected: true},
+		{host: "registry.lan:5000", registries: []string{"registry.lan:5000"}, expected: true},
+		{host: "registry.lan:5001", registries: []string{"registry.lan:5000"}, expected: false},
+		{host: "registry.lan", registries: []string{"registry.lan:5000"}, expected: false},
+		{host: "REGISTRY.lan:5000", registries: []string{"registry.LAN"}, expected: true},
+		{host: "other.lan:5000", registries: []string{"registry.lan", "other.lan:5000"}, expected: true},
+		{host: "registry.lan.example.com", registries: []string{"registry.lan"}, expected: false},
+		{host: "registry.lan", registries: []string{}, expected: false},
+	}
+
+	for _, testCase := range testCases {
+		actual := matchesRegistryHost(testCase.host, testCase.registries)
+		if actual != testCase.expected {
+			t.Errorf("expected %s matching %v to be %v, actual %v", testCase.host, testCase.registries, testCase.expected, actual)
+		}
+	}
+}
+
+func TestParseReference_InsecureRegistry(t *testing.T) {
+	client := Client{InsecureRegistries: []string{"registry.lan:5000"}}
+
+	testCases := []struct {
+		image          string
+		expectedScheme string
+	}{
+		{image: "registry.lan:5000/busybox:1.32.0", expectedScheme: "http"},
+		{image: "registry.lan:5001/busybox:1.32.0", expectedScheme: "https"},
+		{image: "mycompany.com/busybox:1.32.0", expectedScheme: "https"},
+	}
+
+	for _, testCase := range testCases {
+		reference, err := client.parseReference(testCase.image)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		actual := reference.Context().Registry.Scheme()
+		if actual != testCase.expectedScheme {
+			t.Errorf("expected scheme of %s to be %s, actual %s", testCase.image, testCase.expectedScheme, actual)
+		}
+	}
+}
+
+func TestGetTransport_CACertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caCertificate := writeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	defer os.Remove(caCertificate)
+
+	rootCAs, err := loadCACertificates([]string{caCertificate})
+	if err != nil {
+		t.Fatal("load ca certificates:", err)
+	}
+
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "https://"))
+	if err != nil {
+		t.Fatal("new registry:", err)
+	}
+
+	httpClient := http.Client{Transport: Client{}.getTransport(registry)}
+	if _, err := httpClient.Get(server.URL); err == nil {
+		t.Error("expected an error when the certificate authority is not trusted")
+	}
+
+	httpClient = http.Client{Transport: Client{rootCAs: rootCAs}.getTransport(registry)}
+	response, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatal("expected the certificate authority to be trusted:", err)
+	}
+	response.Body.Close()
+}
+
+func TestLoadCACertificates_SystemCertificates(t *testing.T) {
+	systemCertificates, err := x509.SystemCertPool()
+	if err != nil {
+		t.Skip("system certificates are not available:", err)
+	}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caCertificate := writeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	defer os.Remove(caCertificate)
+
+	rootCAs, err := loadCACertificates([]string{caCertificate})
+	if err != nil {
+		t.Fatal("load ca certificates:", err)
+	}
+
+	expected := len(systemCertificates.Subjects()) + 1
+	actual := len(rootCAs.Subjects())
+	if actual != expected {
+		t.Errorf("expected %d certificates including the system certificates, actual %d", expected, actual)
+	}
+}
+
+func TestLoadCACertificates_NoCertificates(t *testing.T) {
+	caCertificate := writeTempFile(t, []byte("not a certificate"))
+	defer os.Remove(caCertificate)
+
+	if _, err := loadCACertificates([]string{caCertificate}); err == nil {
+		t.Error("expected an error when the file does not contain a certificate")
+	}
+}
+
+func writeTempFile(t *testing.T, contents []byte) string {
+	file, err := ioutil.TempFile("", "sinker")
+	if err != nil {
+		t.Fatal("temp file:", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(contents); err != nil {
+		t.Fatal("write temp file:", err)
+	}
+
+	return file.Name()
+}
+
+func TestLoadClientCertificates(t *testing.T) {
+	certificateFile, keyFile := writeClientCertificate(t)
+	defer os.Remove(certificateFile)
+	defer os.Remove(keyFile)
+
+	otherCertificateFile, otherKeyFile := writeClientCertificate(t)
+	defer os.Remove(otherCertificateFile)
+	defer os.Remove(otherKeyFile)
+
+	testCases := []struct {
+		clientCertificate ClientCertificate
+		expectedError     string
+	}{
+		{clientCertificate: ClientCertificate{Registry: "registry.lan", CertificateFile: certificateFile, KeyFile: keyFile}},
+		{clientCertificate: ClientCertificate{Registry: "registry.lan", CertificateFile: certificateFile, KeyFile: otherKeyFile}, expectedError: "does not match"},
+		{clientCertificate: ClientCertificate{Registry: "registry.lan", CertificateFile: "missing.crt", KeyFile: keyFile}, expectedError: "no such file"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := loadClientCertificates([]ClientCertificate{testCase.clientCertificate})
+		if testCase.expectedError == "" && err != nil {
+			t.Errorf("expected client certificate to load, actual %s", err)
+		}
+
+		if testCase.expectedError != "" && (err == nil || !strings.Contains(err.Error(), testCase.expectedError)) {
+			t.Errorf("expected error to contain %q, actual %v", testCase.expectedError, err)
+		}
+	}
+}
+
+func TestGetTransport_ClientCertificate(t *testing.T) {
+	certificateFile, keyFile := writeClientCertificate(t)
+	defer os.Remove(certificateFile)
+	defer os.Remove(keyFile)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	clientCertificates, err := loadClientCertificates([]ClientCertificate{{Registry: host, CertificateFile: certificateFile, KeyFile: keyFile}})
+	if err != nil {
+		t.Fatal("load client certificates:", err)
+	}
+
+	targetRegistry, err := name.NewRegistry(host)
+	if err != nil {
+		t.Fatal("new registry:", err)
+	}
+
+	sourceRegistry, err := name.NewRegistry("mycompany.com")
+	if err != nil {
+		t.Fatal("new registry:", err)
+	}
+
+	client := Client{InsecureRegistries: []string{host}, clientCertificates: clientCertificates}
+	if _, exists := client.getClientCertificate(sourceRegistry.RegistryStr()); exists {
+		t.Error("expected the source registry to not have a client certificate")
+	}
+
+	httpClient := http.Client{Transport: Client{InsecureRegistries: []string{host}}.getTransport(targetRegistry)}
+	if _, err := httpClient.Get(server.URL); err == nil {
+		t.Error("expected an error when no client certificate is presented")
+	}
+
+	httpClient = http.Client{Transport: client.getTransport(targetRegistry)}
+	response, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatal("expected the client certificate to be presented:", err)
+	}
+	response.Body.Close()
+}
+
+func writeClientCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("generate key:", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sinker"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("create certificate:", err)
+	}
+
+	privateKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal("marshal key:", err)
+	}
+
+	certificateFile := writeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}))
+	keyFile := writeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateKey}))
+
+	return certificateFile, keyFile
+}
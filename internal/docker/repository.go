@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// CreateRepositoryIfNotExists creates the target repository of the image when it does not exist, for the
+// registries that require a repository to exist before an image can be pushed to it. The repository is
+// created in Amazon ECR registries, and the project of the repository is created in Harbor registries,
+// which are detected from their API. Nothing is created in other registries. It returns a description
+// of what was created (e.g. Harbor project myteam), which is empty when it already existed.
+func (c Client) CreateRepositoryIfNotExists(ctx context.Context, image string, encodedAuth string) (string, error) {
+	registryPath := RegistryPath(image)
+	if ecrRegistry, exists := parseECRHost(registryPath.Host()); exists {
+		api, err := newECRAPI(ecrRegistry.Region)
+		if err != nil {
+			return "", fmt.Errorf("new ecr api: %w", err)
+		}
+
+		created, err := createECRRepositoryIfNotExists(ctx, api, ecrRegistry, registryPath.Repository())
+		if err != nil {
+			return "", fmt.Errorf("create ecr repository: %w", err)
+		}
+
+		if !created {
+			return "", nil
+		}
+
+		return "ECR repository " + registryPath.Repository(), nil
+	}
+
+	registry, err := c.newRegistry(registryPath.Host())
+	if err != nil {
+		return "", fmt.Errorf("new registry: %w", err)
+	}
+
+	httpClient := &http.Client{Transport: c.getTransport(registry)}
+	isHarbor, err := c.isHarborRegistry(ctx, httpClient, registry)
+	if err != nil {
+		return "", fmt.Errorf("detect harbor registry: %w", err)
+	}
+
+	if !isHarbor {
+		return "", nil
+	}
+
+	authenticator, err := getAuthenticator(encodedAuth)
+	if err != nil {
+		return "", fmt.Errorf("get authenticator: %w", err)
+	}
+
+	auth, err := authenticator.Authorization()
+	if err != nil {
+		return "", fmt.Errorf("authorization: %w", err)
+	}
+
+	project := getHarborProject(registryPath.Repository())
+	created, err := createHarborProjectIfNotExists(ctx, httpClient, registry, auth, project)
+	if err != nil {
+		return "", fmt.Errorf("create harbor project: %w", err)
+	}
+
+	if !created {
+		return "", nil
+	}
+
+	return "Harbor project " + project, nil
+}
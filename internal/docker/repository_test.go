@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecr"
+)
+
+func TestCreateRepositoryIfNotExists_Harbor(t *testing.T) {
+	harbor := newHarborServer()
+	server := httptest.NewServer(harbor)
+	defer server.Close()
+
+	encodedAuth, err := GetEncodedBasicAuth("admin", "Harbor12345")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	client := Client{harborRegistries: newHarborRegistries()}
+	image := strings.TrimPrefix(server.URL, "http://") + "/myteam/nginx:1.25"
+
+	created, err := client.CreateRepositoryIfNotExists(context.Background(), image, encodedAuth)
+	if err != nil {
+		t.Fatal("create repository:", err)
+	}
+
+	if created != "Harbor project myteam" {
+		t.Errorf("expected the Harbor project myteam to be created, actual %q", created)
+	}
+
+	// The project is only created once
+	created, err = client.CreateRepositoryIfNotExists(context.Background(), image, encodedAuth)
+	if err != nil {
+		t.Fatal("create repository:", err)
+	}
+
+	if created != "" {
+		t.Errorf("expected the existing project to not be created, actual %q", created)
+	}
+}
+
+func TestCreateRepositoryIfNotExists_ECR(t *testing.T) {
+	api := fakeECRAPI{describeErr: awserr.New(ecr.ErrCodeRepositoryNotFoundException, "not found", nil)}
+
+	defaultNewECRAPI := newECRAPI
+	newECRAPI = func(region string) (ecrAPI, error) {
+		return &api, nil
+	}
+	defer func() { newECRAPI = defaultNewECRAPI }()
+
+	client := Client{harborRegistries: newHarborRegistries()}
+	created, err := client.CreateRepositoryIfNotExists(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com/mirror/busybox:1.32.0", "")
+	if err != nil {
+		t.Fatal("create repository:", err)
+	}
+
+	if created != "ECR repository mirror/busybox" {
+		t.Errorf("expected the ECR repository mirror/busybox to be created, actual %q", created)
+	}
+}
+
+func TestCreateRepositoryIfNotExists_OtherRegistry(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := Client{harborRegistries: newHarborRegistries()}
+	created, err := client.CreateRepositoryIfNotExists(context.Background(), strings.TrimPrefix(server.URL, "http://")+"/myteam/nginx:1.25", "")
+	if err != nil {
+		t.Fatal("create repository:", err)
+	}
+
+	if created != "" {
+		t.Errorf("expected nothing to be created, actual %q", created)
+	}
+
+	if len(requests) != 1 || requests[0] != "GET /api/v2.0/systeminfo" {
+		t.Errorf("expected only the Harbor API to be detected, actual %v", requests)
+	}
+}
@@ -0,0 +1,230 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/docker/docker/errdefs"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// RetryBackoff is the strategy used to delay between retries
+type RetryBackoff string
+
+const (
+	// FixedBackoff waits the same delay between each retry
+	FixedBackoff RetryBackoff = "fixed"
+
+	// ExponentialBackoff doubles the delay after each retry
+	ExponentialBackoff RetryBackoff = "exponential"
+)
+
+// RetryPolicy configures how Docker operations are retried
+type RetryPolicy struct {
+	Attempts uint
+	Delay    time.Duration
+	Backoff  RetryBackoff
+
+	// MaxDelay caps the delay between retries before any jitter is added.
+	// A max delay of zero means the delay is not capped.
+	MaxDelay time.Duration
+
+	// MaxJitter is the upper bound of the random duration added to each delay
+	// so that operations which fail at the same time do not retry at the same time.
+	MaxJitter time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts: 3,
+		Delay:    5 * time.Second,
+		Backoff:  FixedBackoff,
+	}
+}
+
+// Validate returns an error if the retry policy is not valid
+func (r RetryPolicy) Validate() error {
+	if r.Attempts == 0 {
+		return fmt.Errorf("retry attempts must be at least 1")
+	}
+
+	if r.Backoff != FixedBackoff && r.Backoff != ExponentialBackoff {
+		return fmt.Errorf("unknown retry backoff %q (must be %s or %s)", r.Backoff, FixedBackoff, ExponentialBackoff)
+	}
+
+	if r.MaxDelay < 0 || r.MaxJitter < 0 {
+		return fmt.Errorf("retry max delay and max jitter must not be negative")
+	}
+
+	if r.MaxDelay > 0 && r.MaxDelay < r.Delay {
+		return fmt.Errorf("retry max delay %s must be at least the retry delay %s", r.MaxDelay, r.Delay)
+	}
+
+	return nil
+}
+
+func (c Client) retry(ctx context.Context, operation func() error, onRetry func(retryAttempt uint, err error)) error {
+	retryPolicy := c.RetryPolicy
+	if retryPolicy.Attempts == 0 {
+		retryPolicy = DefaultRetryPolicy()
+	}
+
+	return retry.Do(
+		operation,
+		retry.Attempts(retryPolicy.Attempts),
+		retry.DelayType(func(retryAttempt uint, _ *retry.Config) time.Duration {
+			return retryPolicy.delay(retryAttempt)
+		}),
+		retry.LastErrorOnly(true),
+		retry.OnRetry(onRetry),
+		retry.RetryIf(func(err error) bool {
+			return ctx.Err() == nil && isRetryableError(err)
+		}),
+	)
+}
+
+// isRetryableError returns false for errors that fail the same way on every attempt, such as credentials
+// that are not accepted by the registry or an image that does not exist. Network errors, server errors,
+// and rate limits are retried, as are any other errors, since they are usually transient.
+func isRetryableError(err error) bool {
+	var rateLimitError *RateLimitError
+	if errors.As(err, &rateLimitError) {
+		return true
+	}
+
+	var transportError *transport.Error
+	if errors.As(err, &transportError) {
+		return isRetryableStatus(transportError)
+	}
+
+	// Errors of the Docker daemon implement the interfaces of errdefs,
+	// while errors returned in its output only include their message.
+	var notFoundError errdefs.ErrNotFound
+	var unauthorizedError errdefs.ErrUnauthorized
+	var forbiddenError errdefs.ErrForbidden
+	if errors.As(err, &notFoundError) || errors.As(err, &unauthorizedError) || errors.As(err, &forbiddenError) {
+		return false
+	}
+
+	message := err.Error()
+	if isRateLimitMessage(message) {
+		return true
+	}
+
+	return !isUnauthorizedMessage(message) && !isNotFoundMessage(message)
+}
+
+// isRetryableStatus returns false for the responses of a registry to requests that are
+// not authorized or for images that do not exist
+func isRetryableStatus(transportError *transport.Error) bool {
+	switch transportError.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return false
+	}
+
+	for _, diagnostic := range transportError.Errors {
+		switch strings.ToUpper(string(diagnostic.Code)) {
+		case "UNAUTHORIZED", "DENIED", "MANIFEST_UNKNOWN", "NAME_UNKNOWN":
+			return false
+		}
+	}
+
+	return true
+}
+
+func isNotFoundMessage(message string) bool {
+	notFoundMessages := []string{"manifest unknown", "name unknown", "not found"}
+
+	message = strings.ToLower(message)
+	for _, notFoundMessage := range notFoundMessages {
+		if strings.Contains(message, notFoundMessage) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsImageMissing returns true when the error is for an image that does not exist at its registry, such as
+// a manifest or repository that is not found, so that a missing image can be told apart from other failures
+func IsImageMissing(err error) bool {
+	var transportError *transport.Error
+	if errors.As(err, &transportError) {
+		if transportError.StatusCode == http.StatusNotFound {
+			return true
+		}
+
+		for _, diagnostic := range transportError.Errors {
+			switch strings.ToUpper(string(diagnostic.Code)) {
+			case "MANIFEST_UNKNOWN", "NAME_UNKNOWN":
+				return true
+			}
+		}
+
+		return false
+	}
+
+	var notFoundError errdefs.ErrNotFound
+	if errors.As(err, &notFoundError) {
+		return true
+	}
+
+	// Errors returned in the output of the Docker daemon only include their message
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "manifest unknown") || strings.Contains(message, "name unknown")
+}
+
+// delay returns how long to wait before the given retry attempt, starting at zero.
+// The delay is at most MaxDelay plus MaxJitter.
+func (r RetryPolicy) delay(retryAttempt uint) time.Duration {
+	delay := r.Delay
+	if r.Backoff == ExponentialBackoff {
+		for i := uint(0); i < retryAttempt; i++ {
+			if r.MaxDelay > 0 && delay >= r.MaxDelay {
+				break
+			}
+
+			// Stop doubling before the delay overflows
+			if delay > maxDuration/2 {
+				delay = maxDuration
+				break
+			}
+
+			delay *= 2
+		}
+	}
+
+	if r.MaxDelay > 0 && delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+
+	if r.MaxJitter > 0 && delay <= maxDuration-r.MaxJitter {
+		delay += randomDuration(r.MaxJitter)
+	}
+
+	return delay
+}
+
+const maxDuration = time.Duration(1<<63 - 1)
+
+var (
+	jitterMutex  sync.Mutex
+	jitterRandom = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// randomDuration returns a random duration between zero and max, inclusive.
+// The source is seeded when sinker starts so that separate processes do not retry in lockstep.
+func randomDuration(max time.Duration) time.Duration {
+	jitterMutex.Lock()
+	defer jitterMutex.Unlock()
+
+	return time.Duration(jitterRandom.Int63n(int64(max) + 1))
+}
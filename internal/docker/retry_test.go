@@ -0,0 +1,305 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRetry_TransientFailure(t *testing.T) {
+	client := Client{
+		RetryPolicy: RetryPolicy{
+			Attempts: 5,
+			Delay:    time.Millisecond,
+			Backoff:  ExponentialBackoff,
+		},
+	}
+
+	var attempts int
+	operation := func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient failure")
+		}
+
+		return nil
+	}
+
+	var retries int
+	onRetry := func(retryAttempt uint, err error) {
+		retries++
+	}
+
+	if err := client.retry(context.Background(), operation, onRetry); err != nil {
+		t.Fatal("retry:", err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, actual %v", attempts)
+	}
+
+	if retries != 2 {
+		t.Errorf("expected 2 retries, actual %v", retries)
+	}
+}
+
+func TestRetry_AllAttemptsFail(t *testing.T) {
+	client := Client{
+		RetryPolicy: RetryPolicy{
+			Attempts: 4,
+			Delay:    time.Millisecond,
+			Backoff:  FixedBackoff,
+		},
+	}
+
+	var attempts int
+	operation := func() error {
+		attempts++
+		return errors.New("failure")
+	}
+
+	if err := client.retry(context.Background(), operation, func(uint, error) {}); err == nil {
+		t.Fatal("expected retry to return an error")
+	}
+
+	if attempts != 4 {
+		t.Errorf("expected 4 attempts, actual %v", attempts)
+	}
+}
+
+func TestRetryPolicy_Validate(t *testing.T) {
+	testCases := []struct {
+		input         RetryPolicy
+		expectedValid bool
+	}{
+		{
+			input:         DefaultRetryPolicy(),
+			expectedValid: true,
+		},
+		{
+			input:         RetryPolicy{Attempts: 0, Backoff: FixedBackoff},
+			expectedValid: false,
+		},
+		{
+			input:         RetryPolicy{Attempts: 1, Backoff: "linear"},
+			expectedValid: false,
+		},
+		{
+			input:         RetryPolicy{Attempts: 1, Backoff: FixedBackoff, MaxJitter: -time.Second},
+			expectedValid: false,
+		},
+		{
+			input:         RetryPolicy{Attempts: 1, Delay: time.Minute, Backoff: ExponentialBackoff, MaxDelay: time.Second},
+			expectedValid: false,
+		},
+		{
+			input:         RetryPolicy{Attempts: 1, Delay: time.Second, Backoff: ExponentialBackoff, MaxDelay: time.Minute, MaxJitter: time.Second},
+			expectedValid: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.input.Validate()
+
+		if (err == nil) != testCase.expectedValid {
+			t.Errorf("expected valid to be %v for %v, actual error %v", testCase.expectedValid, testCase.input, err)
+		}
+	}
+}
+
+func TestRetry_ContextDone(t *testing.T) {
+	client := Client{
+		RetryPolicy: RetryPolicy{
+			Attempts: 3,
+			Delay:    time.Millisecond,
+			Backoff:  FixedBackoff,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var attempts int
+	operation := func() error {
+		attempts++
+		return ctx.Err()
+	}
+
+	if err := client.retry(ctx, operation, func(uint, error) {}); err == nil {
+		t.Fatal("expected retry to return an error")
+	}
+
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, actual %v", attempts)
+	}
+}
+
+func TestRetryPolicy_DelayWithJitter(t *testing.T) {
+	retryPolicy := RetryPolicy{
+		Attempts:  6,
+		Delay:     10 * time.Millisecond,
+		Backoff:   ExponentialBackoff,
+		MaxJitter: 5 * time.Millisecond,
+	}
+
+	var previousMaxDelay time.Duration
+	for retryAttempt := uint(0); retryAttempt < retryPolicy.Attempts; retryAttempt++ {
+		baseDelay := retryPolicy.Delay << retryAttempt
+
+		delays := make(map[time.Duration]bool)
+		var minDelay, maxDelay time.Duration
+		for i := 0; i < 50; i++ {
+			delay := retryPolicy.delay(retryAttempt)
+			if delay < baseDelay || delay > baseDelay+retryPolicy.MaxJitter {
+				t.Fatalf("expected delay of attempt %v to be between %s and %s, actual %s", retryAttempt, baseDelay, baseDelay+retryPolicy.MaxJitter, delay)
+			}
+
+			if minDelay == 0 || delay < minDelay {
+				minDelay = delay
+			}
+
+			if delay > maxDelay {
+				maxDelay = delay
+			}
+
+			delays[delay] = true
+		}
+
+		if len(delays) == 1 {
+			t.Errorf("expected delays of attempt %v to vary, actual %s every time", retryAttempt, minDelay)
+		}
+
+		if minDelay <= previousMaxDelay {
+			t.Errorf("expected delays of attempt %v to be greater than %s, actual %s", retryAttempt, previousMaxDelay, minDelay)
+		}
+
+		previousMaxDelay = maxDelay
+	}
+}
+
+func TestRetryPolicy_MaxDelay(t *testing.T) {
+	retryPolicy := RetryPolicy{
+		Attempts:  100,
+		Delay:     time.Second,
+		Backoff:   ExponentialBackoff,
+		MaxDelay:  time.Minute,
+		MaxJitter: time.Second,
+	}
+
+	for _, retryAttempt := range []uint{6, 10, 99} {
+		delay := retryPolicy.delay(retryAttempt)
+		if delay < retryPolicy.MaxDelay || delay > retryPolicy.MaxDelay+retryPolicy.MaxJitter {
+			t.Errorf("expected delay of attempt %v to be between %s and %s, actual %s", retryAttempt, retryPolicy.MaxDelay, retryPolicy.MaxDelay+retryPolicy.MaxJitter, delay)
+		}
+	}
+
+	retryPolicy.MaxDelay = 0
+	if delay := retryPolicy.delay(200); delay < 0 {
+		t.Errorf("expected uncapped delay not to overflow, actual %s", delay)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "network error", err: fmt.Errorf("get image: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}), expected: true},
+		{name: "server error", err: &transport.Error{StatusCode: http.StatusBadGateway}, expected: true},
+		{name: "rate limit", err: fmt.Errorf("pull image: %w", newRateLimitError("toomanyrequests: You have reached your pull rate limit")), expected: true},
+		{name: "rate limit status", err: &transport.Error{StatusCode: http.StatusTooManyRequests}, expected: true},
+		{name: "unauthorized", err: fmt.Errorf("write target image: %w", &transport.Error{StatusCode: http.StatusUnauthorized}), expected: false},
+		{name: "forbidden", err: &transport.Error{StatusCode: http.StatusForbidden}, expected: false},
+		{name: "not found", err: &transport.Error{StatusCode: http.StatusNotFound}, expected: false},
+		{name: "manifest unknown", err: &transport.Error{StatusCode: http.StatusBadRequest, Errors: []transport.Diagnostic{{Code: transport.ManifestUnknownErrorCode}}}, expected: false},
+		{name: "daemon unauthorized", err: errors.New("returned error: unauthorized: authentication required"), expected: false},
+		{name: "daemon manifest unknown", err: errors.New("pull image: Error response from daemon: manifest for busybox:missing not found: manifest unknown"), expected: false},
+	}
+
+	for _, testCase := range testCases {
+		if actual := isRetryableError(testCase.err); actual != testCase.expected {
+			t.Errorf("expected %s to be retryable %v, actual %v", testCase.name, testCase.expected, actual)
+		}
+	}
+}
+
+func TestIsImageMissing(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "not found", err: fmt.Errorf("get image: %w", &transport.Error{StatusCode: http.StatusNotFound}), expected: true},
+		{name: "manifest unknown", err: &transport.Error{StatusCode: http.StatusBadRequest, Errors: []transport.Diagnostic{{Code: transport.ManifestUnknownErrorCode}}}, expected: true},
+		{name: "name unknown", err: &transport.Error{StatusCode: http.StatusBadRequest, Errors: []transport.Diagnostic{{Code: transport.NameUnknownErrorCode}}}, expected: true},
+		{name: "daemon manifest unknown", err: errors.New("pull image: Error response from daemon: manifest for busybox:missing not found: manifest unknown"), expected: true},
+		{name: "unauthorized", err: &transport.Error{StatusCode: http.StatusUnauthorized}, expected: false},
+		{name: "server error", err: &transport.Error{StatusCode: http.StatusBadGateway}, expected: false},
+		{name: "network error", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, expected: false},
+	}
+
+	for _, testCase := range testCases {
+		if actual := IsImageMissing(testCase.err); actual != testCase.expected {
+			t.Errorf("expected %s to be missing %v, actual %v", testCase.name, testCase.expected, actual)
+		}
+	}
+}
+
+func TestPullImageAndWait_RetriesOnlyTransientErrors(t *testing.T) {
+	testCases := []struct {
+		name             string
+		status           int
+		message          string
+		expectedAttempts int
+	}{
+		{name: "unauthorized", status: http.StatusUnauthorized, message: "unauthorized: authentication required", expectedAttempts: 1},
+		{name: "not found", status: http.StatusNotFound, message: "manifest for private/app:v1.0.0 not found: manifest unknown", expectedAttempts: 1},
+		{name: "server error", status: http.StatusInternalServerError, message: "received unexpected HTTP status: 502 Bad Gateway", expectedAttempts: 3},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var attempts int
+			daemonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(testCase.status)
+				fmt.Fprintf(w, `{"message":%q}`+"\n", testCase.message)
+			}))
+			defer daemonServer.Close()
+
+			dockerClient, err := client.NewClientWithOpts(client.WithHost(daemonServer.URL), client.WithVersion("1.40"))
+			if err != nil {
+				t.Fatal("new docker client:", err)
+			}
+
+			logger := log.New()
+			logger.SetOutput(ioutil.Discard)
+
+			testClient := Client{
+				DockerClient: dockerClient,
+				Logger:       logger,
+				RetryPolicy:  RetryPolicy{Attempts: 3, Delay: time.Millisecond, Backoff: FixedBackoff},
+			}
+
+			if err := testClient.PullImageAndWait(context.Background(), "private/app:v1.0.0", "auth"); err == nil {
+				t.Fatal("expected pull to fail")
+			}
+
+			if attempts != testCase.expectedAttempts {
+				t.Errorf("expected %v attempts, actual %v", testCase.expectedAttempts, attempts)
+			}
+		})
+	}
+}
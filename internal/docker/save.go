@@ -0,0 +1,24 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// SaveImages writes the images on the host to the output as a single tarball in the same
+// format as docker save, which can be loaded with docker load. Only the platform of each
+// image that was pulled to the host is included, even for multi-arch images.
+func (c Client) SaveImages(ctx context.Context, images []string, output io.Writer) error {
+	imageArchive, err := c.DockerClient.ImageSave(ctx, images)
+	if err != nil {
+		return fmt.Errorf("image save: %w", err)
+	}
+	defer imageArchive.Close()
+
+	if _, err := io.Copy(output, imageArchive); err != nil {
+		return fmt.Errorf("write image archive: %w", err)
+	}
+
+	return nil
+}
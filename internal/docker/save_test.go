@@ -0,0 +1,136 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+)
+
+func TestSaveImages(t *testing.T) {
+	var requestedImages []string
+	var archive bytes.Buffer
+	daemonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.40/images/get" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		requestedImages = r.URL.Query()["names"]
+
+		archive.Reset()
+		writeImageArchive(t, &archive, requestedImages)
+		w.Write(archive.Bytes())
+	}))
+	defer daemonServer.Close()
+
+	testClient := newSaveTestClient(t, daemonServer.URL)
+
+	var output bytes.Buffer
+	images := []string{"busybox:1.32.0", "quay.io/coreos/etcd:v3.4.13"}
+	if err := testClient.SaveImages(context.Background(), images, &output); err != nil {
+		t.Fatal("save images:", err)
+	}
+
+	if fmt.Sprint(requestedImages) != fmt.Sprint(images) {
+		t.Errorf("expected saved images to be %v, actual %v", images, requestedImages)
+	}
+
+	if !bytes.Equal(output.Bytes(), archive.Bytes()) {
+		t.Errorf("expected output to be the archive returned by the daemon, actual %v bytes of %v", output.Len(), archive.Len())
+	}
+
+	tags, err := GetImageArchiveTags(&output)
+	if err != nil {
+		t.Fatal("get image archive tags:", err)
+	}
+
+	if fmt.Sprint(tags) != fmt.Sprint(images) {
+		t.Errorf("expected archive tags to be %v, actual %v", images, tags)
+	}
+}
+
+func TestSaveImages_Errors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		statusCode    int
+		output        io.Writer
+		expectedError string
+	}{
+		{name: "daemon error", statusCode: http.StatusInternalServerError, output: &bytes.Buffer{}, expectedError: "image save"},
+		{name: "write error", statusCode: http.StatusOK, output: failingWriter{}, expectedError: "write image archive"},
+	}
+
+	for _, testCase := range testCases {
+		daemonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if testCase.statusCode != http.StatusOK {
+				w.WriteHeader(testCase.statusCode)
+				fmt.Fprintln(w, `{"message":"reference does not exist"}`)
+				return
+			}
+
+			writeImageArchive(t, w, r.URL.Query()["names"])
+		}))
+
+		testClient := newSaveTestClient(t, daemonServer.URL)
+		err := testClient.SaveImages(context.Background(), []string{"busybox:1.32.0"}, testCase.output)
+		daemonServer.Close()
+
+		if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+			t.Errorf("expected %s error to contain %q, actual %v", testCase.name, testCase.expectedError, err)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func newSaveTestClient(t *testing.T, host string) Client {
+	dockerClient, err := client.NewClientWithOpts(client.WithHost(host), client.WithVersion("1.40"))
+	if err != nil {
+		t.Fatal("new docker client:", err)
+	}
+
+	return Client{DockerClient: dockerClient}
+}
+
+type archiveManifest struct {
+	RepoTags []string
+}
+
+func writeImageArchive(t *testing.T, output io.Writer, images []string) {
+	var manifest []archiveManifest
+	for _, image := range images {
+		manifest = append(manifest, archiveManifest{RepoTags: []string{image}})
+	}
+
+	contents, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal("marshal manifest:", err)
+	}
+
+	archive := tar.NewWriter(output)
+	if err := archive.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0644, Size: int64(len(contents))}); err != nil {
+		t.Fatal("write header:", err)
+	}
+
+	if _, err := archive.Write(contents); err != nil {
+		t.Fatal("write manifest:", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatal("close archive:", err)
+	}
+}
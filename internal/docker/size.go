@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	v1types "github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// GetLayerSizesAtRemote returns the compressed size of each layer of the image at the remote
+// registry, keyed by the digest of the layer. Only the manifests of the image are requested.
+// The layers of a manifest list include the layers of the images of every platform.
+func (c Client) GetLayerSizesAtRemote(ctx context.Context, image string, auth string) (map[string]int64, error) {
+	imageReference, err := c.parseReference(image)
+	if err != nil {
+		return nil, fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return nil, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	descriptor, err := remote.Get(imageReference, c.getRemoteOptions(ctx, imageReference.Context().Registry, remote.WithAuth(authenticator))...)
+	if err != nil {
+		return nil, fmt.Errorf("get image: %w", err)
+	}
+
+	layerSizes := make(map[string]int64)
+	if descriptor.MediaType != v1types.DockerManifestList && descriptor.MediaType != v1types.OCIImageIndex {
+		remoteImage, err := descriptor.Image()
+		if err != nil {
+			return nil, fmt.Errorf("get image: %w", err)
+		}
+
+		if err := addLayerSizes(layerSizes, remoteImage); err != nil {
+			return nil, fmt.Errorf("add layer sizes: %w", err)
+		}
+
+		return layerSizes, nil
+	}
+
+	imageIndex, err := descriptor.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("get index: %w", err)
+	}
+
+	indexManifest, err := imageIndex.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("index manifest: %w", err)
+	}
+
+	for _, manifest := range indexManifest.Manifests {
+		platformImage, err := imageIndex.Image(manifest.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("get image %s: %w", manifest.Digest, err)
+		}
+
+		if err := addLayerSizes(layerSizes, platformImage); err != nil {
+			return nil, fmt.Errorf("add layer sizes of %s: %w", manifest.Digest, err)
+		}
+	}
+
+	return layerSizes, nil
+}
+
+func addLayerSizes(layerSizes map[string]int64, image v1.Image) error {
+	manifest, err := image.Manifest()
+	if err != nil {
+		return fmt.Errorf("manifest: %w", err)
+	}
+
+	for _, layer := range manifest.Layers {
+		layerSizes[layer.Digest.String()] = layer.Size
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	stdlog "log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestGetLayerSizesAtRemote(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+
+	registryHost := strings.TrimPrefix(registryServer.URL, "http://")
+
+	image, err := random.Image(1024, 3)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	imageReference, err := name.ParseReference(registryHost + "/source/app:v1.0.0")
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(imageReference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	manifest, err := image.Manifest()
+	if err != nil {
+		t.Fatal("manifest:", err)
+	}
+
+	client := Client{}
+	layerSizes, err := client.GetLayerSizesAtRemote(context.Background(), imageReference.String(), "")
+	if err != nil {
+		t.Fatal("get layer sizes:", err)
+	}
+
+	if len(layerSizes) != len(manifest.Layers) {
+		t.Errorf("expected %v layers, actual %v", len(manifest.Layers), len(layerSizes))
+	}
+
+	for _, layer := range manifest.Layers {
+		if layerSizes[layer.Digest.String()] != layer.Size {
+			t.Errorf("expected size of layer %s to be %v, actual %v", layer.Digest, layer.Size, layerSizes[layer.Digest.String()])
+		}
+	}
+
+	multiArchImage := registryHost + "/source/app:multi-arch"
+	writeMultiArchImage(t, multiArchImage)
+
+	layerSizes, err = client.GetLayerSizesAtRemote(context.Background(), multiArchImage, "")
+	if err != nil {
+		t.Fatal("get layer sizes of index:", err)
+	}
+
+	if len(layerSizes) != 2 {
+		t.Errorf("expected the layers of both platforms, actual %v", layerSizes)
+	}
+}
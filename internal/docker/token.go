@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// registryToken is the encoded auth of a registry that uses a token which expires
+type registryToken struct {
+	encodedAuth string
+	expiresAt   time.Time
+}
+
+// tokenCache caches the token of each registry until it is about to expire. Tokens are fetched without
+// holding the lock of the cache, so that a slow fetch for one registry does not block the auth of the others,
+// and the lookups of a registry that is already being fetched wait for that fetch rather than starting another.
+type tokenCache struct {
+	mutex   sync.Mutex
+	tokens  map[string]registryToken
+	fetches map[string]*tokenFetch
+	now     func() time.Time
+
+	// refreshWindow is how long before it expires that a token is refreshed,
+	// so that the token does not expire while an image is being pushed or pulled
+	refreshWindow time.Duration
+}
+
+// tokenFetch is a fetch of a token that is in progress
+type tokenFetch struct {
+	done  chan struct{}
+	token registryToken
+	err   error
+}
+
+func newTokenCache(refreshWindow time.Duration) *tokenCache {
+	return &tokenCache{
+		tokens:        make(map[string]registryToken),
+		fetches:       make(map[string]*tokenFetch),
+		now:           time.Now,
+		refreshWindow: refreshWindow,
+	}
+}
+
+// getEncodedAuth returns the encoded auth of the cached token of the key, which is
+// only fetched when there is no cached token or the cached token is about to expire
+func (c *tokenCache) getEncodedAuth(ctx context.Context, key string, fetch func(ctx context.Context) (registryToken, error)) (string, error) {
+	for {
+		c.mutex.Lock()
+		token, exists := c.tokens[key]
+		if exists && c.now().Add(c.refreshWindow).Before(token.expiresAt) {
+			c.mutex.Unlock()
+			return token.encodedAuth, nil
+		}
+
+		inProgress, fetching := c.fetches[key]
+		if !fetching {
+			inProgress = &tokenFetch{done: make(chan struct{})}
+			c.fetches[key] = inProgress
+		}
+		c.mutex.Unlock()
+
+		if !fetching {
+			return c.fetch(ctx, key, inProgress, fetch)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("wait for token: %w", ctx.Err())
+		case <-inProgress.done:
+		}
+
+		// The fetch is made again when it was only stopped by the
+		// context of the lookup that started it being cancelled
+		if isContextError(inProgress.err) {
+			continue
+		}
+
+		if inProgress.err != nil {
+			return "", inProgress.err
+		}
+
+		return inProgress.token.encodedAuth, nil
+	}
+}
+
+func (c *tokenCache) fetch(ctx context.Context, key string, inProgress *tokenFetch, fetch func(ctx context.Context) (registryToken, error)) (string, error) {
+	inProgress.token, inProgress.err = fetch(ctx)
+
+	c.mutex.Lock()
+	if inProgress.err == nil {
+		c.tokens[key] = inProgress.token
+	}
+	delete(c.fetches, key)
+	c.mutex.Unlock()
+
+	close(inProgress.done)
+
+	if inProgress.err != nil {
+		return "", inProgress.err
+	}
+
+	return inProgress.token.encodedAuth, nil
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenCache_GetEncodedAuth_FetchesOnce(t *testing.T) {
+	cache := newTokenCache(time.Minute)
+
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (registryToken, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return registryToken{encodedAuth: "auth", expiresAt: time.Now().Add(time.Hour)}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.getEncodedAuth(context.Background(), "registry", fetch); err != nil {
+				t.Error("get encoded auth:", err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("expected the token to be fetched once, actual %v", fetches)
+	}
+}
+
+func TestTokenCache_GetEncodedAuth_DoesNotBlockOtherKeys(t *testing.T) {
+	cache := newTokenCache(time.Minute)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	go cache.getEncodedAuth(context.Background(), "slow", func(ctx context.Context) (registryToken, error) {
+		<-release
+		return registryToken{}, nil
+	})
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := cache.getEncodedAuth(context.Background(), "fast", func(ctx context.Context) (registryToken, error) {
+			return registryToken{encodedAuth: "auth", expiresAt: time.Now().Add(time.Hour)}, nil
+		})
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Error("get encoded auth:", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the token of another key to not wait for a slow fetch")
+	}
+}
+
+func TestTokenCache_GetEncodedAuth_Cancelled(t *testing.T) {
+	cache := newTokenCache(time.Minute)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	go cache.getEncodedAuth(context.Background(), "registry", func(ctx context.Context) (registryToken, error) {
+		<-release
+		return registryToken{}, nil
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := cache.getEncodedAuth(ctx, "registry", func(ctx context.Context) (registryToken, error) {
+		return registryToken{}, errors.New("expected to wait for the fetch in progress")
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context cancelled error, actual %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+)
+
+// DigestMismatchError is returned when the digest of an image at the registry is not the digest
+// of the image that was pushed to it, such as when the image was corrupted in transit
+type DigestMismatchError struct {
+	Image    string
+	Expected string
+	Actual   string
+}
+
+func (e *DigestMismatchError) Error() string {
+	if e.Actual == "" {
+		return fmt.Sprintf("image %s was not found at the registry after pushing %s", e.Image, e.Expected)
+	}
+
+	return fmt.Sprintf("digest of %s at the registry is %s, expected the pushed digest %s", e.Image, e.Actual, e.Expected)
+}
+
+// verifyPushedDigest fetches the digest of the image from the registry it was pushed to, with the auth
+// that it was pushed with, and returns a DigestMismatchError when it is not the digest that was pushed
+func (c Client) verifyPushedDigest(ctx context.Context, image string, digest string, encodedAuth string) error {
+	digests, err := c.GetDigestsAtRemote(ctx, image, encodedAuth)
+	if err != nil {
+		return fmt.Errorf("get digest: %w", err)
+	}
+
+	if len(digests) == 0 {
+		return &DigestMismatchError{Image: image, Expected: digest}
+	}
+
+	if digests[0] != digest {
+		return &DigestMismatchError{Image: image, Expected: digest, Actual: digests[0]}
+	}
+
+	newImageLogEntry(c.Logger, "push", image).Printf("[PUSH] Verified digest %s of %s", digest, image)
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestPushImageAndWait_VerifiesDigest(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+
+	targetImage := strings.TrimPrefix(registryServer.URL, "http://") + "/target/app:v1.0.0"
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+	writeImage(t, targetImage, image)
+
+	targetDigest, err := image.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	const corruptedDigest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+	testCases := []struct {
+		name           string
+		pushedDigest   string
+		expectMismatch bool
+	}{
+		{name: "matching digest", pushedDigest: targetDigest.String()},
+		{name: "mismatched digest", pushedDigest: corruptedDigest, expectMismatch: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			daemonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, `{"status":"The push refers to repository [target/app]"}`)
+				fmt.Fprintf(w, `{"status":"v1.0.0: digest: %s size: 528"}`+"\n", testCase.pushedDigest)
+				fmt.Fprintf(w, `{"aux":{"Tag":"v1.0.0","Digest":"%s","Size":528}}`+"\n", testCase.pushedDigest)
+			}))
+			defer daemonServer.Close()
+
+			dockerClient, err := client.NewClientWithOpts(client.WithHost(daemonServer.URL), client.WithVersion("1.40"))
+			if err != nil {
+				t.Fatal("new docker client:", err)
+			}
+
+			logger := log.New()
+			logger.SetOutput(ioutil.Discard)
+
+			testClient := Client{
+				DockerClient: dockerClient,
+				Logger:       logger,
+				RetryPolicy:  RetryPolicy{Attempts: 1, Backoff: FixedBackoff},
+			}
+
+			err = testClient.PushImageAndWait(context.Background(), targetImage, "")
+			if !testCase.expectMismatch {
+				if err != nil {
+					t.Fatal("push image:", err)
+				}
+				return
+			}
+
+			var mismatchErr *DigestMismatchError
+			if !errors.As(err, &mismatchErr) {
+				t.Fatalf("expected push to fail with a digest mismatch, actual %v", err)
+			}
+
+			if mismatchErr.Expected != corruptedDigest || mismatchErr.Actual != targetDigest.String() {
+				t.Errorf("expected mismatch of %s and %s, actual %s and %s", corruptedDigest, targetDigest, mismatchErr.Expected, mismatchErr.Actual)
+			}
+		})
+	}
+}
+
+func TestVerifyPushedDigest_TargetAuth(t *testing.T) {
+	authServer, server := newAuthRegistryServers("user", "pass")
+	defer authServer.Close()
+	defer server.Close()
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	digest, err := image.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	targetAuth, err := GetEncodedBasicAuth("user", "pass")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+
+	// The pushed image is verified with the auth it was pushed with, rather than the auth of its host
+	t.Run("push", func(t *testing.T) {
+		targetImage := strings.TrimPrefix(authServer.URL, "http://") + "/private/app:v1.0.0"
+		writeImage(t, strings.TrimPrefix(server.URL, "http://")+"/private/app:v1.0.0", image)
+
+		daemonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"aux":{"Tag":"v1.0.0","Digest":"%s","Size":528}}`+"\n", digest)
+		}))
+		defer daemonServer.Close()
+
+		dockerClient, err := client.NewClientWithOpts(client.WithHost(daemonServer.URL), client.WithVersion("1.40"))
+		if err != nil {
+			t.Fatal("new docker client:", err)
+		}
+
+		testClient := Client{DockerClient: dockerClient, Logger: logger, RetryPolicy: RetryPolicy{Attempts: 1, Backoff: FixedBackoff}}
+		if err := testClient.PushImageAndWait(context.Background(), targetImage, targetAuth); err != nil {
+			t.Fatal("push image to a target that requires auth:", err)
+		}
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		sourceServer := httptest.NewServer(registry.New(registry.Logger(stdlog.New(ioutil.Discard, "", 0))))
+		defer sourceServer.Close()
+
+		sourceImage := strings.TrimPrefix(sourceServer.URL, "http://") + "/source/app:v1.0.0"
+		writeImage(t, sourceImage, image)
+
+		testClient := Client{Logger: logger, RetryPolicy: RetryPolicy{Attempts: 1, Backoff: FixedBackoff}}
+		targetImage := strings.TrimPrefix(authServer.URL, "http://") + "/private/copy:v1.0.0"
+		if err := testClient.CopyImageAndWait(context.Background(), sourceImage, targetImage, "", targetAuth); err != nil {
+			t.Fatal("copy image to a target that requires auth:", err)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+// Package version contains the build metadata of sinker, which is set
+// at build time with -ldflags (e.g. -X github.com/plexsystems/sinker/internal/version.GitCommit=abc123)
+package version
+
+import (
+	"runtime"
+)
+
+var (
+	// Version is the released version of sinker
+	Version = "0.10.0"
+
+	// GitCommit is the git commit that sinker was built from
+	GitCommit = "unknown"
+
+	// BuildDate is the date that sinker was built, in RFC 3339 format
+	BuildDate = "unknown"
+)
+
+// Info is the build metadata of sinker
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build metadata of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+}